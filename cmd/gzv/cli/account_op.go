@@ -19,19 +19,14 @@
 package cli
 
 import (
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/zvchain/zvchain/common"
 	"github.com/zvchain/zvchain/consensus/model"
-	"github.com/zvchain/zvchain/storage/tasdb"
-	"golang.org/x/crypto/scrypt"
-
-	"github.com/syndtr/goleveldb/leveldb/filter"
-	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
 const (
@@ -40,7 +35,7 @@ const (
 )
 
 type AccountManager struct {
-	store    *tasdb.LDBDatabase
+	store    keyStore
 	accounts sync.Map
 
 	unlockAccount *AccountInfo
@@ -64,6 +59,9 @@ func (ai *AccountInfo) resetExpireTime() {
 type KeyStoreRaw struct {
 	Key     []byte
 	IsMiner bool
+	Seed    []byte    `json:",omitempty"` // BIP39 seed of the hierarchical deterministic account
+	HDPath  string    `json:",omitempty"` // BIP32 derivation path of the key from the seed
+	Miner   *MinerRaw `json:",omitempty"` // Miner keys embedded in the v3 keystore file
 }
 
 type Account struct {
@@ -72,6 +70,9 @@ type Account struct {
 	Sk       string
 	Password string
 	Miner    *MinerRaw
+	HDPath   string `json:",omitempty"`
+
+	seed []byte
 }
 
 type MinerRaw struct {
@@ -94,19 +95,12 @@ func (a *Account) MinerSk() string {
 }
 
 func newAccountOp(ks string) (*AccountManager, error) {
-	options := &opt.Options{
-		OpenFilesCacheCapacity:        10,
-		WriteBuffer:                   8 * opt.MiB, // Two of these are used internally
-		Filter:                        filter.NewBloomFilter(10),
-		CompactionTableSize:           2 * opt.MiB,
-		CompactionTableSizeMultiplier: 2,
-	}
-	db, err := tasdb.NewLDBDatabase(ks, options)
+	store, err := openKeyStore(ks)
 	if err != nil {
-		return nil, fmt.Errorf("new ldb fail:%v", err.Error())
+		return nil, err
 	}
 	return &AccountManager{
-		store: db,
+		store: store,
 	}, nil
 }
 
@@ -168,59 +162,50 @@ func (am *AccountManager) constructAccount(password string, sk *common.PrivateKe
 }
 
 func (am *AccountManager) loadAccount(addr string, password string) (*Account, error) {
-	v, err := am.store.Get([]byte(addr))
+	ksr, err := am.store.Load(addr, password)
 	if err != nil {
-		return nil, fmt.Errorf("your address %s not found in your keystore directory", addr)
+		return nil, err
 	}
 
-	salt := common.Sha256([]byte(password))
-	scryptPwd, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
-	if err != nil {
-		return nil, err
+	secKey := new(common.PrivateKey)
+	if !secKey.ImportKey(ksr.Key) {
+		return nil, ErrInternal
 	}
 
-	bs, err := common.DecryptWithKey(scryptPwd, v)
+	acc, err := am.constructAccount(password, secKey, ksr.IsMiner)
 	if err != nil {
 		return nil, err
 	}
-
-	var ksr = new(KeyStoreRaw)
-	if err = json.Unmarshal(bs, ksr); err != nil {
+	if err := checkMinerKeys(addr, acc, ksr); err != nil {
 		return nil, err
 	}
-
-	secKey := new(common.PrivateKey)
-	if !secKey.ImportKey(ksr.Key) {
-		return nil, ErrInternal
+	if ksr.Miner != nil {
+		acc.Miner = ksr.Miner
 	}
-
-	return am.constructAccount(password, secKey, ksr.IsMiner)
+	acc.seed = ksr.Seed
+	acc.HDPath = ksr.HDPath
+	return acc, nil
 }
 
-func (am *AccountManager) storeAccount(addr string, ksr *KeyStoreRaw, password string) error {
-	bs, err := json.Marshal(ksr)
-	if err != nil {
-		return err
-	}
-
-	salt := common.Sha256([]byte(password))
-	scryptPwd, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
-	if err != nil {
-		return err
-	}
-	ct, err := common.EncryptWithKey(scryptPwd, bs)
-	if err != nil {
-		return err
+// checkMinerKeys checks the miner keys stored along with the key match the ones derived from the key.
+// The account recovered has no miner keys if the key isn't marked as a miner
+func checkMinerKeys(addr string, acc *Account, ksr *KeyStoreRaw) error {
+	if ksr.Miner != nil && (acc.Miner == nil || acc.Miner.BPk != ksr.Miner.BPk) {
+		return fmt.Errorf("miner keys don't match the private key of %v", addr)
 	}
+	return nil
+}
 
-	err = am.store.Put([]byte(addr), ct)
-	return err
+func (am *AccountManager) storeAccount(addr string, ksr *KeyStoreRaw, password string) error {
+	return am.store.Store(addr, ksr, password)
 }
 
 func (am *AccountManager) getFirstMinerAccount(password string) *Account {
-	iter := am.store.NewIterator()
-	for iter.Next() {
-		addr := string(iter.Key())
+	addrs, err := am.store.Addresses()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
 		if v, ok := am.accounts.Load(addr); ok {
 			aci := v.(*AccountInfo)
 			if passwordHash(password) == aci.Password && aci.Miner != nil {
@@ -315,12 +300,7 @@ func (am *AccountManager) NewAccount(password string, miner bool) (string, error
 
 // AccountList show account list
 func (am *AccountManager) AccountList() ([]string, error) {
-	iter := am.store.NewIterator()
-	addrs := make([]string, 0)
-	for iter.Next() {
-		addrs = append(addrs, string(iter.Key()))
-	}
-	return addrs, nil
+	return am.store.Addresses()
 }
 
 // Lock lock the account by address
@@ -397,7 +377,7 @@ func (am *AccountManager) DeleteAccount() (string, error) {
 		return "", ErrUnlocked
 	}
 	am.accounts.Delete(addr)
-	err = am.store.Delete([]byte(addr))
+	err = am.store.Delete(addr)
 	if err != nil {
 		return "", err
 	}
@@ -454,3 +434,64 @@ func (am *AccountManager) ExportKey(addr string) (string, error) {
 	sk := common.HexToSecKey(acc.Sk)
 	return common.ToHex(sk.ExportKey()), nil
 }
+
+// ImportKeyStore imports the account from the version 3 keystore file encrypted by the password
+func (am *AccountManager) ImportKeyStore(file string, password string) (string, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	addr, ksr, err := decryptKeyV3(bs, password)
+	if err != nil {
+		return "", err
+	}
+	sk := new(common.PrivateKey)
+	if !sk.ImportKey(ksr.Key) {
+		return "", ErrInternal
+	}
+	account, err := am.constructAccount(password, sk, ksr.IsMiner)
+	if err != nil {
+		return "", err
+	}
+	if err := checkMinerKeys(addr, account, ksr); err != nil {
+		return "", err
+	}
+	account.seed = ksr.Seed
+	account.HDPath = ksr.HDPath
+	if err := am.storeAccount(addr, ksr, password); err != nil {
+		return "", err
+	}
+	am.accounts.Store(addr, &AccountInfo{
+		Account: *account,
+	})
+	return addr, nil
+}
+
+// ExportKeyStore exports the account to the version 3 keystore file encrypted by the same password
+func (am *AccountManager) ExportKeyStore(addr string, password string, file string) (string, error) {
+	ksr, err := am.store.Load(addr, password)
+	if err != nil {
+		return "", err
+	}
+	kdf := kdfConfigFromConf()
+	if fks, ok := am.store.(*fileKeyStore); ok {
+		kdf = fks.kdf
+	}
+	if ksr, err = ksr.withMinerKeys(); err != nil {
+		return "", err
+	}
+	bs, err := encryptKeyV3(addr, ksr, password, kdf)
+	if err != nil {
+		return "", err
+	}
+	if file == "" {
+		file = keyFileName(addr)
+	}
+	if _, err := os.Stat(file); err == nil {
+		return "", fmt.Errorf("file %v already exists", file)
+	}
+	if err := writeKeyFile(file, bs); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,128 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/common/hdwallet"
+)
+
+// HDAccount is the account derived from the BIP39 seed
+type HDAccount struct {
+	Address  string `json:"address"`
+	HDPath   string `json:"hd_path"`
+	Mnemonic string `json:"mnemonic,omitempty"` // Only returned when the mnemonic is generated, back it up offline
+}
+
+// storeHDAccount derives the account at the index from the seed and stores it with the seed, so that
+// any account of the wallet can derive the others after unlocked
+func (am *AccountManager) storeHDAccount(seed []byte, index uint32, password string, miner bool) (*HDAccount, error) {
+	master, err := hdwallet.NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	path := hdwallet.AccountPath(index)
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := key.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	account, err := am.constructAccount(password, privateKey, miner)
+	if err != nil {
+		return nil, err
+	}
+	account.seed = seed
+	account.HDPath = path.String()
+
+	ksr := &KeyStoreRaw{
+		Key:     privateKey.ExportKey(),
+		IsMiner: miner,
+		Seed:    seed,
+		HDPath:  account.HDPath,
+	}
+	if err := am.storeAccount(account.Address, ksr, password); err != nil {
+		return nil, err
+	}
+	if common.IsWeakPassword(password) {
+		output("the password is too weak. suggestions for modification")
+	}
+	am.accounts.Store(account.Address, &AccountInfo{
+		Account: *account,
+	})
+	return &HDAccount{Address: account.Address, HDPath: account.HDPath}, nil
+}
+
+// NewHDAccount generates a mnemonic and creates the first account derived from it
+func (am *AccountManager) NewHDAccount(password string, miner bool) (*HDAccount, error) {
+	mnemonic, err := hdwallet.GenerateMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hdwallet.NewSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	acc, err := am.storeHDAccount(seed, 0, password, miner)
+	if err != nil {
+		return nil, err
+	}
+	acc.Mnemonic = mnemonic
+	return acc, nil
+}
+
+// NewAccountByMnemonic imports the first account derived from the mnemonic and the optional passphrase
+func (am *AccountManager) NewAccountByMnemonic(mnemonic string, passphrase string, password string, miner bool) (string, error) {
+	seed, err := hdwallet.NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return "", err
+	}
+	acc, err := am.storeHDAccount(seed, 0, password, miner)
+	if err != nil {
+		return "", err
+	}
+	return acc.Address, nil
+}
+
+// DeriveAccount derives the account at the index from the seed of the current unlocked account.
+// The password must be the same as the unlocked account's since the seed is stored along with the new account
+func (am *AccountManager) DeriveAccount(index uint32, password string, miner bool) (*HDAccount, error) {
+	if index >= hdwallet.HardenedOffset {
+		return nil, fmt.Errorf("index should be less than %v", hdwallet.HardenedOffset)
+	}
+	addr := am.currentUnLockedAddr()
+	if addr == "" {
+		return nil, ErrUnlocked
+	}
+	aci, err := am.getAccountInfo(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !aci.unlocked() {
+		return nil, ErrUnlocked
+	}
+	if len(aci.seed) == 0 {
+		return nil, fmt.Errorf("the current account is not a hierarchical deterministic account")
+	}
+	if passwordHash(password) != aci.Password {
+		return nil, ErrPassword
+	}
+	return am.storeHDAccount(aci.seed, index, password, miner)
+}
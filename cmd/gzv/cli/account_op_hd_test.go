@@ -0,0 +1,86 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHDAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hd_keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const password = "hd_password_1"
+
+	am := newTestAccountOp(t, filepath.Join(dir, "ks1"))
+	acc, err := am.NewHDAccount(password, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Mnemonic == "" || acc.HDPath != "m/44'/372'/0'/0/0" {
+		t.Fatalf("unexpected account %+v", acc)
+	}
+	if _, err = am.DeriveAccount(1, password, false); err != ErrUnlocked {
+		t.Fatalf("expect unlocked error, got %v", err)
+	}
+	if err = am.UnLock(acc.Address, password, 60); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = am.DeriveAccount(1, "wrong_password", false); err != ErrPassword {
+		t.Fatalf("expect password error, got %v", err)
+	}
+	child, err := am.DeriveAccount(1, password, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.Address == acc.Address || child.HDPath != "m/44'/372'/0'/0/1" {
+		t.Fatalf("unexpected child account %+v", child)
+	}
+	am.Close()
+
+	// The seed is kept in the keystore, so the derived account can derive others after reopened
+	am = newTestAccountOp(t, filepath.Join(dir, "ks1"))
+	if err = am.UnLock(child.Address, password, 60); err != nil {
+		t.Fatal(err)
+	}
+	child2, err := am.DeriveAccount(2, password, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am.Close()
+
+	// Importing the mnemonic to another keystore recovers the same accounts
+	am = newTestAccountOp(t, filepath.Join(dir, "ks2"))
+	defer am.Close()
+	addr, err := am.NewAccountByMnemonic(acc.Mnemonic, "", password, false)
+	if err != nil || addr != acc.Address {
+		t.Fatalf("expect imported address %v, got %v %v", acc.Address, addr, err)
+	}
+	if err = am.UnLock(addr, password, 60); err != nil {
+		t.Fatal(err)
+	}
+	if derived, err := am.DeriveAccount(2, password, false); err != nil || derived.Address != child2.Address {
+		t.Fatalf("expect derived address %v, got %+v %v", child2.Address, derived, err)
+	}
+	if _, err = am.NewAccountByMnemonic("abandon abandon abandon", "", password, false); err == nil {
+		t.Fatal("expect invalid mnemonic error")
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/zvchain/zvchain/common"
 	"github.com/zvchain/zvchain/consensus/groupsig"
+	"github.com/zvchain/zvchain/core"
 	"github.com/zvchain/zvchain/middleware/types"
 )
 
@@ -215,6 +216,213 @@ func (ca *RemoteChainOpImpl) TxInfo(hash string) *RPCResObjCmd {
 	return ca.request("transDetail", hash)
 }
 
+// replacePriceBump is the default gas price bump percentage of the replacement transaction,
+// which should be consistent with the default value of the tx pool
+const replacePriceBump = 10
+
+// pendingTx returns the transaction of the current account waiting in the pool of the connected node
+func (ca *RemoteChainOpImpl) pendingTx(hash string) (*TxRawData, *ErrorResult) {
+	aci, err := ca.aop.AccountInfo()
+	if err != nil {
+		return nil, opErrorRes(err)
+	}
+	res := ca.request("pendingTransaction", hash)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	var tx *TxRawData
+	if res.Result != nil {
+		if err := json.Unmarshal(res.Result, &tx); err != nil {
+			return nil, opErrorRes(err)
+		}
+	}
+	if tx == nil {
+		return nil, opErrorRes(fmt.Errorf("transaction not found in the pool, it may be already on chain"))
+	}
+	if tx.Source != aci.Address {
+		return nil, opErrorRes(fmt.Errorf("the transaction is not sent by the current account"))
+	}
+	return tx, nil
+}
+
+// replacementGasPrice returns the given gas price, or bumps the gas price of the replaced transaction
+// by the default percentage if not specified
+func replacementGasPrice(old *TxRawData, gasPrice uint64) uint64 {
+	if gasPrice > 0 {
+		return gasPrice
+	}
+	return (old.GasPrice*(100+replacePriceBump) + 99) / 100
+}
+
+// SpeedUp resends the pending transaction with a higher gas price
+func (ca *RemoteChainOpImpl) SpeedUp(hash string, gasPrice uint64) *RPCResObjCmd {
+	tx, errRes := ca.pendingTx(hash)
+	if errRes != nil {
+		return &RPCResObjCmd{Error: errRes}
+	}
+	tx.GasPrice = replacementGasPrice(tx, gasPrice)
+	tx.Sign = ""
+	return ca.SendRaw(tx)
+}
+
+// Cancel replaces the pending transaction with a zero value transfer to the sender itself
+func (ca *RemoteChainOpImpl) Cancel(hash string, gasPrice uint64) *RPCResObjCmd {
+	tx, errRes := ca.pendingTx(hash)
+	if errRes != nil {
+		return &RPCResObjCmd{Error: errRes}
+	}
+	return ca.SendRaw(&TxRawData{
+		Target:   tx.Source,
+		TxType:   types.TransactionTypeTransfer,
+		GasLimit: core.TransactionGasCost,
+		GasPrice: replacementGasPrice(tx, gasPrice),
+		Nonce:    tx.Nonce,
+	})
+}
+
+// estimatedGasHeadroom is the percentage of the gas estimated added to the gas limit, so that the
+// transaction doesn't run out of gas if the state changes before it is packed
+const estimatedGasHeadroom = 20
+
+// gasLimitOfEstimated returns the gas limit with the headroom of the gas estimated, which is capped at
+// the max gas limit of a transaction
+func gasLimitOfEstimated(estimated uint64) uint64 {
+	limit := estimated * (100 + estimatedGasHeadroom) / 100
+	if limit > core.GasLimitPerTransaction {
+		limit = core.GasLimitPerTransaction
+	}
+	return limit
+}
+
+// BuildTx fills the nonce of the unsigned transaction from the chain and writes it to the file for offline signing.
+// The source is the current account if not specified, which doesn't need to be unlocked. The gas price is the min
+// one of the chain and the gas limit is estimated with the headroom if not specified
+func (ca *RemoteChainOpImpl) BuildTx(tx *TxRawData, file string) *RPCResObjCmd {
+	res := new(RPCResObjCmd)
+	if tx.Source == "" {
+		aci, err := ca.aop.AccountInfo()
+		if err != nil {
+			res.Error = opErrorRes(fmt.Errorf("please specify the source address: %v", err))
+			return res
+		}
+		tx.Source = aci.Address
+	}
+	if tx.Nonce == 0 {
+		res = ca.request("pendingNonce", tx.Source)
+		if res.Error != nil {
+			return res
+		}
+		if err := json.Unmarshal(res.Result, &tx.Nonce); err != nil {
+			res.Error = opErrorRes(err)
+			return res
+		}
+	}
+	if tx.GasPrice == 0 {
+		res = ca.request("minGasPrice")
+		if res.Error != nil {
+			return res
+		}
+		if err := json.Unmarshal(res.Result, &tx.GasPrice); err != nil {
+			res.Error = opErrorRes(err)
+			return res
+		}
+	}
+	if tx.GasLimit == 0 {
+		res = ca.request("estimateGas", tx)
+		if res.Error != nil {
+			return res
+		}
+		var estimated uint64
+		if err := json.Unmarshal(res.Result, &estimated); err != nil {
+			res.Error = opErrorRes(err)
+			return res
+		}
+		tx.GasLimit = gasLimitOfEstimated(estimated)
+	}
+	otx := newOfflineTx(tx)
+	if err := writeOfflineTx(file, otx); err != nil {
+		return &RPCResObjCmd{Error: opErrorRes(err)}
+	}
+	bs, err := json.Marshal(otx)
+	if err != nil {
+		return &RPCResObjCmd{Error: opErrorRes(err)}
+	}
+	return &RPCResObjCmd{Result: bs}
+}
+
+// SendRawTransaction submits the hex of the encoded signed transaction
+func (ca *RemoteChainOpImpl) SendRawTransaction(raw string) *RPCResObjCmd {
+	return ca.request("sendRawTransaction", raw)
+}
+
+// MultiSigCreate sends the transaction creating the multisig account and returns its address along with the hash
+func (ca *RemoteChainOpImpl) MultiSigCreate(threshold int, members []string, value, gas, gasprice uint64) *RPCResObjCmd {
+	res := new(RPCResObjCmd)
+	aci, err := ca.aop.AccountInfo()
+	if err != nil {
+		res.Error = opErrorRes(err)
+		return res
+	}
+	if threshold <= 0 || threshold > types.MaxMultiSigMembers {
+		res.Error = opErrorRes(fmt.Errorf("threshold should be in [1, %v]", types.MaxMultiSigMembers))
+		return res
+	}
+	config := &types.MultiSigConfig{Threshold: uint8(threshold)}
+	for _, m := range members {
+		m = strings.TrimSpace(m)
+		if !common.ValidateAddress(m) {
+			res.Error = opErrorRes(fmt.Errorf("wrong member address format %v", m))
+			return res
+		}
+		config.Members = append(config.Members, common.StringToAddress(m))
+	}
+	if err := config.Validate(); err != nil {
+		res.Error = opErrorRes(err)
+		return res
+	}
+	data, err := types.EncodeMultiSigConfig(config)
+	if err != nil {
+		res.Error = opErrorRes(err)
+		return res
+	}
+	nonce, errRes := ca.nonce(aci.Address)
+	if errRes != nil {
+		res.Error = errRes
+		return res
+	}
+	tx := &TxRawData{
+		Value:    value,
+		GasLimit: gas,
+		GasPrice: gasprice,
+		TxType:   types.TransactionTypeMultiSigCreate,
+		Data:     data,
+		Nonce:    nonce,
+	}
+	res = ca.SendRaw(tx)
+	if res.Error != nil {
+		return res
+	}
+	var hash string
+	if err := json.Unmarshal(res.Result, &hash); err != nil {
+		res.Error = opErrorRes(err)
+		return res
+	}
+	bs, err := json.Marshal(&MultiSigCreateResult{
+		Hash:    hash,
+		Address: types.MultiSigAddress(common.StringToAddress(aci.Address), nonce).AddrPrefixString(),
+	})
+	if err != nil {
+		res.Error = opErrorRes(err)
+		return res
+	}
+	return &RPCResObjCmd{Result: bs}
+}
+
+// MultiSigAccount queries the config and state of the multisig account
+func (ca *RemoteChainOpImpl) MultiSigAccount(addr string) *RPCResObjCmd {
+	return ca.request("multiSigAccount", addr)
+}
+
 func (ca *RemoteChainOpImpl) BlockByHash(hash string) *RPCResObjCmd {
 	return ca.request("getBlockByHash", hash)
 }
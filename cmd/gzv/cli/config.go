@@ -33,4 +33,8 @@ type minerConfig struct {
 	resetHash         string
 	cors              string
 	privateKey        string
+	syncMode          string
+	staticPeers       []string
+	trustedPeers      []string
+	noDiscover        bool
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/howeyc/gopass"
 	"github.com/peterh/liner"
 	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/common/hdwallet"
 	"github.com/zvchain/zvchain/tvm"
 )
 
@@ -48,8 +49,11 @@ func genBaseCmd(n string, h string) *baseCmd {
 
 type newAccountCmd struct {
 	baseCmd
-	password string
-	miner    bool
+	password   string
+	miner      bool
+	mnemonic   bool
+	words      string
+	passphrase string
 }
 
 func output(msg ...interface{}) {
@@ -71,9 +75,22 @@ func genNewAccountCmd() *newAccountCmd {
 	}
 	c.fs.StringVar(&c.password, "password", "", "password for the account")
 	c.fs.BoolVar(&c.miner, "miner", false, "create the account for miner if set")
+	c.fs.BoolVar(&c.mnemonic, "mnemonic", false, "create a hierarchical deterministic account from a new mnemonic if set, the mnemonic will be shown only once")
+	c.fs.StringVar(&c.words, "words", "", "import the hierarchical deterministic account from the mnemonic words instead of generating")
+	c.fs.StringVar(&c.passphrase, "passphrase", "", "the optional passphrase of the imported mnemonic")
 	return c
 }
 
+func (c *newAccountCmd) create(acm accountOp) (interface{}, error) {
+	if c.words != "" {
+		return acm.NewAccountByMnemonic(c.words, c.passphrase, c.password, c.miner)
+	}
+	if c.mnemonic {
+		return acm.NewHDAccount(c.password, c.miner)
+	}
+	return acm.NewAccount(c.password, c.miner)
+}
+
 func (c *newAccountCmd) parse(args []string) bool {
 	err := c.fs.Parse(args)
 	if err != nil {
@@ -89,6 +106,43 @@ func (c *newAccountCmd) parse(args []string) bool {
 		fmt.Printf("password length should between %d-%d \n", common.MinPasswordLength, common.MaxPasswordLength)
 		return false
 	}
+	if c.words != "" && !hdwallet.ValidateMnemonic(c.words) {
+		outputJSONErr(opErrorRes(hdwallet.ErrInvalidMnemonic))
+		return false
+	}
+	return true
+}
+
+type deriveAccountCmd struct {
+	baseCmd
+	index    uint
+	password string
+	miner    bool
+}
+
+func genDeriveAccountCmd() *deriveAccountCmd {
+	c := &deriveAccountCmd{
+		baseCmd: *genBaseCmd("deriveaccount", "derive a new account from the mnemonic of the current unlocked hierarchical deterministic account"),
+	}
+	c.fs.UintVar(&c.index, "index", 1, fmt.Sprintf("the index of the account in the derivation path m/44'/%v'/0'/0/index", hdwallet.CoinType))
+	c.fs.StringVar(&c.password, "password", "", "password of the current unlocked account, which also protects the new account")
+	c.fs.BoolVar(&c.miner, "miner", false, "create the account for miner if set")
+	return c
+}
+
+func (c *deriveAccountCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.password) == "" {
+		output("Please input password")
+		return false
+	}
+	if uint64(c.index) >= uint64(hdwallet.HardenedOffset) {
+		output(fmt.Sprintf("index should be less than %v", hdwallet.HardenedOffset))
+		return false
+	}
 	return true
 }
 
@@ -391,6 +445,55 @@ func (c *txCmd) parse(args []string) bool {
 	return true
 }
 
+type replaceTxCmd struct {
+	baseCmd
+	hash        string
+	gasPriceStr string
+	gasPrice    uint64
+}
+
+func genReplaceTxCmd(n string, h string) *replaceTxCmd {
+	c := &replaceTxCmd{
+		baseCmd: *genBaseCmd(n, h),
+	}
+	c.fs.StringVar(&c.hash, "hash", "", "the hex hash of the pending transaction")
+	c.fs.StringVar(&c.gasPriceStr, "gasprice", "", "gas price of the replacement, default bumps the gas price of the pending transaction by 10%")
+	return c
+}
+
+func genSpeedUpCmd() *replaceTxCmd {
+	return genReplaceTxCmd("speedup", "resend the pending transaction with a higher gas price")
+}
+
+func genCancelCmd() *replaceTxCmd {
+	return genReplaceTxCmd("cancel", "cancel the pending transaction by replacing it with a zero value transfer to yourself")
+}
+
+func (c *replaceTxCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.hash) == "" {
+		output("please input the transaction hash")
+		c.fs.PrintDefaults()
+		return false
+	}
+	if !validateHash(c.hash) {
+		outputJSONErr(opErrorRes(fmt.Errorf("wrong hash format")))
+		return false
+	}
+	if c.gasPriceStr != "" {
+		gp, err := common.ParseCoin(c.gasPriceStr)
+		if err != nil {
+			outputJSONErr(opErrorRes(fmt.Errorf("%v:%v, correct example: 100RA,100kRA,1mRA,1ZVC", err, c.gasPriceStr)))
+			return false
+		}
+		c.gasPrice = gp
+	}
+	return true
+}
+
 type receiptCmd struct {
 	baseCmd
 	hash string
@@ -495,6 +598,11 @@ func genSendTxCmd() *sendTxCmd {
 	c := &sendTxCmd{
 		gasBaseCmd: *genGasBaseCmd("sendtx", "send a transaction to the ZV system"),
 	}
+	c.initTxFlags()
+	return c
+}
+
+func (c *sendTxCmd) initTxFlags() {
 	c.initBase()
 	c.fs.StringVar(&c.to, "to", "", "the transaction receiver address")
 	c.fs.StringVar(&c.value, "value", "", "transfer value in ZVC unit")
@@ -503,8 +611,7 @@ func genSendTxCmd() *sendTxCmd {
 	c.fs.Uint64Var(&c.nonce, "nonce", 0, "nonce, optional. will use default nonce on chain if not specified")
 	c.fs.StringVar(&c.contractName, "contractname", "", "the name of the contract.")
 	c.fs.StringVar(&c.contractPath, "contractpath", "", "the path to the contract file.")
-	c.fs.IntVar(&c.txType, "type", 0, "transaction type: 0=general tx, 1=contract create, 2=contract call, 4=stake add ,5=miner abort, 6=stake reduce, 7=stake refund")
-	return c
+	c.fs.IntVar(&c.txType, "type", 0, "transaction type: 0=general tx, 1=contract create, 2=contract call, 4=stake add ,5=miner abort, 6=stake reduce, 7=stake refund, 12=multisig execute(build with buildtx and sign with signtx by the members)")
 }
 
 func (c *sendTxCmd) toTxRaw() *TxRawData {
@@ -530,7 +637,7 @@ func (c *sendTxCmd) parse(args []string) bool {
 		outputJSONErr(opErrorRes(fmt.Errorf("not supported transaction type")))
 		return false
 	}
-	if c.txType == types.TransactionTypeTransfer || c.txType == types.TransactionTypeContractCall {
+	if c.txType == types.TransactionTypeTransfer || c.txType == types.TransactionTypeContractCall || c.txType == types.TransactionTypeMultiSigExecute {
 		if strings.TrimSpace(c.to) == "" {
 			output("please input the target address")
 			c.fs.PrintDefaults()
@@ -614,6 +721,154 @@ func (c *sendTxCmd) parse(args []string) bool {
 	return true
 }
 
+type buildTxCmd struct {
+	sendTxCmd
+	from string
+	file string
+}
+
+func genBuildTxCmd() *buildTxCmd {
+	c := &buildTxCmd{}
+	c.gasBaseCmd = *genGasBaseCmd("buildtx", "build an unsigned transaction to the file for offline signing, the nonce and the gas price are filled from the chain and the gas limit is estimated with 20% headroom if not specified")
+	c.initTxFlags()
+	c.fs.StringVar(&c.from, "from", "", "the transaction sender address, default the current account")
+	c.fs.StringVar(&c.file, "file", "", "the file to write the unsigned transaction")
+	return c
+}
+
+func (c *buildTxCmd) parse(args []string) bool {
+	if !c.sendTxCmd.parse(args) {
+		return false
+	}
+	if strings.TrimSpace(c.file) == "" {
+		output("please input the file path")
+		c.fs.PrintDefaults()
+		return false
+	}
+	c.from = strings.TrimSpace(c.from)
+	if c.from != "" && !common.ValidateAddress(c.from) {
+		outputJSONErr(opErrorRes(fmt.Errorf("wrong address format")))
+		return false
+	}
+	return true
+}
+
+func (c *buildTxCmd) toTxRaw() *TxRawData {
+	tx := c.sendTxCmd.toTxRaw()
+	tx.Source = c.from
+	// Leave the gas to be filled from the chain instead of the flag defaults
+	set := make(map[string]bool)
+	c.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if !set["gaslimit"] {
+		tx.GasLimit = 0
+	}
+	if !set["gasprice"] {
+		tx.GasPrice = 0
+	}
+	return tx
+}
+
+type signTxCmd struct {
+	baseCmd
+	file string
+	out  string
+	key  string
+}
+
+func genSignTxCmd() *signTxCmd {
+	c := &signTxCmd{
+		baseCmd: *genBaseCmd("signtx", "sign the transaction built by buildtx, no connection needed"),
+	}
+	c.fs.StringVar(&c.file, "file", "", "the file of the unsigned transaction")
+	c.fs.StringVar(&c.out, "out", "", "the file to write the signed transaction, default overwrites the input file")
+	c.fs.StringVar(&c.key, "key", "", "the hex private key of the sender, default uses the current unlocked account")
+	return c
+}
+
+func (c *signTxCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.file) == "" {
+		output("please input the file path")
+		c.fs.PrintDefaults()
+		return false
+	}
+	if c.out == "" {
+		c.out = c.file
+	}
+	return true
+}
+
+func (c *signTxCmd) sign(acm accountOp) (interface{}, error) {
+	otx, err := readOfflineTx(c.file)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimSpace(c.key)
+	if key == "" {
+		aci, err := acm.AccountInfo()
+		if err != nil {
+			return nil, err
+		}
+		key = aci.Sk
+	}
+	sk := common.HexToSecKey(key)
+	if sk == nil {
+		return nil, fmt.Errorf("wrong private key format")
+	}
+	if err := otx.sign(sk); err != nil {
+		return nil, err
+	}
+	if err := writeOfflineTx(c.out, otx); err != nil {
+		return nil, err
+	}
+	return otx, nil
+}
+
+type sendRawTxCmd struct {
+	baseCmd
+	file string
+	raw  string
+}
+
+func genSendRawTxCmd() *sendRawTxCmd {
+	c := &sendRawTxCmd{
+		baseCmd: *genBaseCmd("sendrawtx", "send the transaction signed by signtx"),
+	}
+	c.fs.StringVar(&c.file, "file", "", "the file of the signed transaction")
+	c.fs.StringVar(&c.raw, "raw", "", "the hex of the encoded signed transaction, used if file not specified")
+	return c
+}
+
+func (c *sendRawTxCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.file) != "" {
+		otx, err := readOfflineTx(c.file)
+		if err != nil {
+			outputJSONErr(opErrorRes(err))
+			return false
+		}
+		if !otx.Signed() {
+			outputJSONErr(opErrorRes(fmt.Errorf("the transaction is not signed")))
+			return false
+		}
+		c.raw = otx.Raw
+	}
+	if strings.TrimSpace(c.raw) == "" {
+		output("please input the file path or the raw transaction")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
 func parseRaFromString(number string) (uint64, error) {
 	if len(number) == 0 {
 		return 0, nil
@@ -810,6 +1065,90 @@ func (c *stakeReduceCmd) parse(args []string) bool {
 	return c.parseGasPrice()
 }
 
+type multiSigCreateCmd struct {
+	gasBaseCmd
+	threshold  int
+	membersStr string
+	valueStr   string
+	members    []string
+	value      uint64
+}
+
+func genMultiSigCreateCmd() *multiSigCreateCmd {
+	c := &multiSigCreateCmd{
+		gasBaseCmd: *genGasBaseCmd("multisigcreate", "create a multisig account which transfers only with the signs of enough members"),
+	}
+	c.initBase()
+	c.fs.IntVar(&c.threshold, "threshold", 0, "the number of member signs required")
+	c.fs.StringVar(&c.membersStr, "members", "", fmt.Sprintf("member addresses separated by comma, %v to %v members", types.MinMultiSigMembers, types.MaxMultiSigMembers))
+	c.fs.StringVar(&c.valueStr, "value", "", "the initial value transferred to the multisig account in ZVC unit, default 0")
+	return c
+}
+
+func (c *multiSigCreateCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	c.members = c.members[:0]
+	for _, m := range strings.Split(c.membersStr, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			c.members = append(c.members, m)
+		}
+	}
+	if len(c.members) < types.MinMultiSigMembers || len(c.members) > types.MaxMultiSigMembers {
+		output(fmt.Sprintf("please input %v to %v members", types.MinMultiSigMembers, types.MaxMultiSigMembers))
+		c.fs.PrintDefaults()
+		return false
+	}
+	for _, m := range c.members {
+		if !common.ValidateAddress(m) {
+			outputJSONErr(opErrorRes(fmt.Errorf("wrong address format %v", m)))
+			return false
+		}
+	}
+	if c.threshold <= 0 || c.threshold > len(c.members) {
+		output(fmt.Sprintf("threshold should be in [1, %v]", len(c.members)))
+		return false
+	}
+	value, err := parseRaFromString(c.valueStr)
+	if err != nil {
+		outputJSONErr(opErrorRes(err))
+		return false
+	}
+	c.value = value
+	return c.parseGasPrice()
+}
+
+type multiSigInfoCmd struct {
+	baseCmd
+	addr string
+}
+
+func genMultiSigInfoCmd() *multiSigInfoCmd {
+	c := &multiSigInfoCmd{
+		baseCmd: *genBaseCmd("multisiginfo", "view the threshold, members and state of the multisig account"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "address of the multisig account")
+	return c
+}
+
+func (c *multiSigInfoCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if c.addr == "" {
+		output("please input the multisig account address")
+		return false
+	}
+	if !common.ValidateAddress(c.addr) {
+		outputJSONErr(opErrorRes(fmt.Errorf("wrong address format")))
+		return false
+	}
+	return true
+}
+
 type viewContractCmd struct {
 	baseCmd
 	addr string
@@ -912,6 +1251,74 @@ func (c *exportKeyCmd) parse(args []string) bool {
 	return true
 }
 
+type importKeyStoreCmd struct {
+	baseCmd
+	file     string
+	password string
+}
+
+func genImportKeyStoreCmd() *importKeyStoreCmd {
+	c := &importKeyStoreCmd{
+		baseCmd: *genBaseCmd("importkeystore", "import account from the v3 keystore file"),
+	}
+	c.fs.StringVar(&c.file, "file", "", "the v3 keystore file")
+	c.fs.StringVar(&c.password, "password", "", "password of the keystore file, which also protects the imported account")
+	return c
+}
+
+func (c *importKeyStoreCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.file) == "" {
+		output("please input the keystore file")
+		return false
+	}
+	if strings.TrimSpace(c.password) == "" {
+		output("Please input password")
+		return false
+	}
+	return true
+}
+
+type exportKeyStoreCmd struct {
+	baseCmd
+	addr     string
+	password string
+	file     string
+}
+
+func genExportKeyStoreCmd() *exportKeyStoreCmd {
+	c := &exportKeyStoreCmd{
+		baseCmd: *genBaseCmd("exportkeystore", "export account to the v3 keystore file"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "address of the account")
+	c.fs.StringVar(&c.password, "password", "", "password of the account, which also protects the keystore file")
+	c.fs.StringVar(&c.file, "file", "", "the output file, default is UTC--<time>--<address> in the current directory")
+	return c
+}
+
+func (c *exportKeyStoreCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		output(err.Error())
+		return false
+	}
+	if c.addr == "" {
+		output("please input the account address")
+		return false
+	}
+	if !common.ValidateAddress(c.addr) {
+		outputJSONErr(opErrorRes(fmt.Errorf("wrong address format")))
+		return false
+	}
+	if strings.TrimSpace(c.password) == "" {
+		output("Please input password")
+		return false
+	}
+	return true
+}
+
 type groupCheckCmd struct {
 	baseCmd
 	addr string
@@ -942,6 +1349,7 @@ func (c *groupCheckCmd) parse(args []string) bool {
 }
 
 var cmdNewAccount = genNewAccountCmd()
+var cmdDeriveAccount = genDeriveAccountCmd()
 var cmdExit = genBaseCmd("exit", "quit  gzv")
 var cmdHelp = genBaseCmd("help", "show help info")
 var cmdAccountList = genBaseCmd("accountlist", "list the account of the keystore")
@@ -960,6 +1368,11 @@ var cmdTx = genTxCmd()
 var cmdReceipt = genReceiptCmd()
 var cmdBlock = genBlockCmd()
 var cmdSendTx = genSendTxCmd()
+var cmdSpeedUp = genSpeedUpCmd()
+var cmdCancel = genCancelCmd()
+var cmdBuildTx = genBuildTxCmd()
+var cmdSignTx = genSignTxCmd()
+var cmdSendRawTx = genSendRawTxCmd()
 var cmdApplyGuardMiner = genApplyGuardMinerCmd()
 var cmdVoteMinerPool = genVoteMinerPoolCmd()
 
@@ -969,9 +1382,13 @@ var cmdChangeGuardNode = genChangeGuardNodeCmd()
 var cmdStakeRefund = genStakeRefundCmd()
 var cmdStakeReduce = genStakeReduceCmd()
 var cmdViewContract = genViewContractCmd()
+var cmdMultiSigCreate = genMultiSigCreateCmd()
+var cmdMultiSigInfo = genMultiSigInfoCmd()
 
 var cmdImportKey = genImportKeyCmd()
 var cmdExportKey = genExportKeyCmd()
+var cmdImportKeyStore = genImportKeyStoreCmd()
+var cmdExportKeyStore = genExportKeyStoreCmd()
 var cmdGroupCheck = genGroupCheckCmd()
 
 var list = make([]*baseCmd, 0)
@@ -979,6 +1396,7 @@ var list = make([]*baseCmd, 0)
 func init() {
 	list = append(list, cmdHelp)
 	list = append(list, &cmdNewAccount.baseCmd)
+	list = append(list, &cmdDeriveAccount.baseCmd)
 	list = append(list, cmdAccountList)
 	list = append(list, &cmdUnlock.baseCmd)
 	list = append(list, &cmdBalance.baseCmd)
@@ -997,14 +1415,23 @@ func init() {
 	list = append(list, &cmdReceipt.baseCmd)
 	list = append(list, &cmdBlock.baseCmd)
 	list = append(list, &cmdSendTx.baseCmd)
+	list = append(list, &cmdSpeedUp.baseCmd)
+	list = append(list, &cmdCancel.baseCmd)
+	list = append(list, &cmdBuildTx.baseCmd)
+	list = append(list, &cmdSignTx.baseCmd)
+	list = append(list, &cmdSendRawTx.baseCmd)
 	list = append(list, &cmdStakeAdd.baseCmd)
 	list = append(list, &cmdMinerAbort.baseCmd)
 	list = append(list, &cmdChangeGuardNode.baseCmd)
 	list = append(list, &cmdStakeRefund.baseCmd)
 	list = append(list, &cmdViewContract.baseCmd)
+	list = append(list, &cmdMultiSigCreate.baseCmd)
+	list = append(list, &cmdMultiSigInfo.baseCmd)
 	list = append(list, &cmdStakeReduce.baseCmd)
 	list = append(list, &cmdImportKey.baseCmd)
 	list = append(list, &cmdExportKey.baseCmd)
+	list = append(list, &cmdImportKeyStore.baseCmd)
+	list = append(list, &cmdExportKeyStore.baseCmd)
 	list = append(list, &cmdGroupCheck.baseCmd)
 	list = append(list, cmdExit)
 }
@@ -1160,7 +1587,14 @@ func loop(acm accountOp, chainOp chainOp) {
 			cmd := genNewAccountCmd()
 			if cmd.parse(args) {
 				handleCmdForAccount(func() (interface{}, error) {
-					return acm.NewAccount(cmd.password, cmd.miner)
+					return cmd.create(acm)
+				})
+			}
+		case cmdDeriveAccount.name:
+			cmd := genDeriveAccountCmd()
+			if cmd.parse(args) {
+				handleCmdForAccount(func() (interface{}, error) {
+					return acm.DeriveAccount(uint32(cmd.index), cmd.password, cmd.miner)
 				})
 			}
 		case cmdExit.name, "quit":
@@ -1281,6 +1715,41 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.SendRaw(cmd.toTxRaw())
 				})
 			}
+		case cmdSpeedUp.name:
+			cmd := genSpeedUpCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.SpeedUp(cmd.hash, cmd.gasPrice)
+				})
+			}
+		case cmdCancel.name:
+			cmd := genCancelCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.Cancel(cmd.hash, cmd.gasPrice)
+				})
+			}
+		case cmdBuildTx.name:
+			cmd := genBuildTxCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.BuildTx(cmd.toTxRaw(), cmd.file)
+				})
+			}
+		case cmdSignTx.name:
+			cmd := genSignTxCmd()
+			if cmd.parse(args) {
+				handleCmdForAccount(func() (interface{}, error) {
+					return cmd.sign(acm)
+				})
+			}
+		case cmdSendRawTx.name:
+			cmd := genSendRawTxCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.SendRawTransaction(cmd.raw)
+				})
+			}
 		case cmdStakeAdd.name:
 			cmd := genStakeAddCmd()
 			if cmd.parse(args) {
@@ -1316,6 +1785,20 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.StakeReduce(cmd.target, cmd.mtype, cmd.value, cmd.gaslimit, cmd.gasPrice)
 				})
 			}
+		case cmdMultiSigCreate.name:
+			cmd := genMultiSigCreateCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.MultiSigCreate(cmd.threshold, cmd.members, cmd.value, cmd.gaslimit, cmd.gasPrice)
+				})
+			}
+		case cmdMultiSigInfo.name:
+			cmd := genMultiSigInfoCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.MultiSigAccount(cmd.addr)
+				})
+			}
 		case cmdViewContract.name:
 			cmd := genViewContractCmd()
 			if cmd.parse(args) {
@@ -1337,6 +1820,20 @@ func loop(acm accountOp, chainOp chainOp) {
 					return acm.ExportKey(cmd.addr)
 				})
 			}
+		case cmdImportKeyStore.name:
+			cmd := genImportKeyStoreCmd()
+			if cmd.parse(args) {
+				handleCmdForAccount(func() (interface{}, error) {
+					return acm.ImportKeyStore(cmd.file, cmd.password)
+				})
+			}
+		case cmdExportKeyStore.name:
+			cmd := genExportKeyStoreCmd()
+			if cmd.parse(args) {
+				handleCmdForAccount(func() (interface{}, error) {
+					return acm.ExportKeyStore(cmd.addr, cmd.password, cmd.file)
+				})
+			}
 		case cmdGroupCheck.name:
 			cmd := genGroupCheckCmd()
 			if cmd.parse(args) {
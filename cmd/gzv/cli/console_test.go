@@ -92,3 +92,18 @@ func TestStringToUint(t *testing.T) {
 		t.Fatal("should be error")
 	}
 }
+
+func TestReplacementGasPrice(t *testing.T) {
+	old := &TxRawData{GasPrice: 500}
+	if p := replacementGasPrice(old, 0); p != 550 {
+		t.Fatalf("expect 550, got %v", p)
+	}
+	if p := replacementGasPrice(old, 1000); p != 1000 {
+		t.Fatalf("expect 1000, got %v", p)
+	}
+	// Rounded up so that the bumped price is always higher
+	old.GasPrice = 1
+	if p := replacementGasPrice(old, 0); p != 2 {
+		t.Fatalf("expect 2, got %v", p)
+	}
+}
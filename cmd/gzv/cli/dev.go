@@ -0,0 +1,141 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/consensus/group"
+	"github.com/zvchain/zvchain/consensus/mediator"
+	"github.com/zvchain/zvchain/consensus/model"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/middleware"
+	"github.com/zvchain/zvchain/params"
+)
+
+// devKeyFile stores the generated private key in the data directory, so the chain can be restarted
+const devKeyFile = "dev.key"
+
+// devConfig is the options of the single node development chain
+type devConfig struct {
+	dataDir string
+	period  time.Duration // Interval of the block production, blocks are produced once transactions arrive if zero
+	balance uint64        // Balance in ZVC funded to the account in the genesis block
+}
+
+// dev starts a single node development chain, whose blocks are produced by the only account
+// without the vrf proving and the group signing. Network and the consensus engine aren't started
+func (gzv *Gzv) dev(cfg *minerConfig, dc *devConfig) error {
+	params.InitDevChainConfig(cfg.chainID)
+	gzv.runtimeInit()
+	if err := initDevDataDir(dc.dataDir); err != nil {
+		return err
+	}
+	if err := middleware.InitMiddleware(); err != nil {
+		return err
+	}
+	sk, err := loadDevKey(dc.dataDir, cfg.privateKey)
+	if err != nil {
+		return err
+	}
+	acc, err := recoverAccountByPrivateKey(sk, true)
+	if err != nil {
+		return err
+	}
+	gzv.account = *acc
+	common.GlobalConf.SetString(Section, "miner", gzv.account.Address)
+
+	minerInfo, err := model.NewSelfMinerDO(sk)
+	if err != nil {
+		return err
+	}
+	alloc := map[common.Address]*big.Int{
+		minerInfo.ID.ToAddress(): new(big.Int).SetUint64(common.TAS2RA(dc.balance)),
+	}
+	group.GenerateDevGenesis(&minerInfo.MinerDO, alloc)
+
+	if err = core.InitCore(mediator.NewDevConsensusHelper(minerInfo.ID), &gzv.account); err != nil {
+		return err
+	}
+	if !mediator.DevConsensusInit(minerInfo, common.GlobalConf) {
+		return errors.New("consensus module error")
+	}
+	gzv.devMiner = core.NewDevMiner(core.BlockChainImpl, minerInfo.ID.Serialize(), dc.period)
+	gzv.devMiner.Start()
+
+	if err = gzv.startRPC(); err != nil {
+		return err
+	}
+
+	output("Dev chain data directory:", dc.dataDir)
+	output("Dev account address:", gzv.account.Address)
+	output("Dev account private key:", gzv.account.Sk)
+	balance := common.RA2TAS(core.BlockChainImpl.GetBalance(minerInfo.ID.ToAddress()).Uint64())
+	output("Dev account balance:", strconv.FormatFloat(balance, 'f', -1, 64), "ZVC")
+	if dc.period > 0 {
+		output("Producing blocks every", dc.period.String())
+	} else {
+		output("Producing blocks once transactions arrive")
+	}
+	gzv.inited = true
+	return nil
+}
+
+// initDevDataDir puts all the chain databases under the data directory
+func initDevDataDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	common.GlobalConf.SetString("chain", "db_blocks", filepath.Join(dir, "d_b"))
+	common.GlobalConf.SetString("chain", "small_db", filepath.Join(dir, "d_small"))
+	common.GlobalConf.SetString("chain", "db_cache", filepath.Join(dir, "d_cache"))
+	return nil
+}
+
+// loadDevKey returns the private key given, or the one stored in the data directory.
+// A new key is generated and stored if neither exists
+func loadDevKey(dir string, privateKey string) (*common.PrivateKey, error) {
+	keyFile := filepath.Join(dir, devKeyFile)
+	if privateKey == "" {
+		if bs, err := ioutil.ReadFile(keyFile); err == nil {
+			privateKey = strings.TrimSpace(string(bs))
+		}
+	}
+	if privateKey != "" {
+		sk := new(common.PrivateKey)
+		if !sk.ImportKey(common.FromHex(privateKey)) {
+			return nil, fmt.Errorf("invalid private key")
+		}
+		return sk, nil
+	}
+	sk, err := common.GenerateKey("")
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(keyFile, []byte(sk.Hex()), 0600); err != nil {
+		return nil, err
+	}
+	return &sk, nil
+}
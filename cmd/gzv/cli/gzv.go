@@ -23,7 +23,9 @@ import (
 	"github.com/zvchain/zvchain/cmd/gzv/cli/update"
 	"github.com/zvchain/zvchain/log"
 	"github.com/zvchain/zvchain/middleware"
+	"github.com/zvchain/zvchain/middleware/metrics"
 	"github.com/zvchain/zvchain/params"
+	"io/ioutil"
 	"os"
 	"time"
 
@@ -59,6 +61,7 @@ type Gzv struct {
 	account      Account
 	config       *minerConfig
 	rpcInstances []rpcApi
+	devMiner     *core.DevMiner
 	InitCha      chan bool
 }
 
@@ -67,6 +70,11 @@ var globalGzv *Gzv
 // miner start miner node
 func (gzv *Gzv) miner(cfg *minerConfig) error {
 	params.InitChainConfig(cfg.chainID)
+	if zip007 := common.GlobalConf.GetInt("chain", "zip007", -1); zip007 >= 0 {
+		if err := params.ScheduleZIP007(uint64(zip007)); err != nil {
+			return err
+		}
+	}
 	gzv.runtimeInit()
 	err := gzv.fullInit()
 	if err != nil {
@@ -79,6 +87,7 @@ func (gzv *Gzv) miner(cfg *minerConfig) error {
 	ok := mediator.StartMiner()
 
 	fmt.Println("Syncing block and group info from ZV net.Waiting...")
+	core.InitSnapshotSyncer(core.BlockChainImpl, cfg.syncMode == "snapshot")
 	core.InitBlockSyncer(core.BlockChainImpl)
 
 	// Auto apply miner role when balance enough
@@ -115,6 +124,9 @@ func (gzv *Gzv) exit(ctrlC <-chan bool, quit chan<- bool) {
 		return
 	}
 	fmt.Println("exiting...")
+	if gzv.devMiner != nil {
+		gzv.devMiner.Stop()
+	}
 	core.BlockChainImpl.Close()
 	//taslog.Close()
 	mediator.StopMiner()
@@ -179,9 +191,23 @@ func (gzv *Gzv) Run() {
 	// In test mode, P2P NAT is closed
 	testMode := mineCmd.Flag("test", "test mode").Bool()
 	seedAddr := mineCmd.Flag("seed", "seed address").String()
+	staticPeers := mineCmd.Flag("staticpeer", "static peer always reconnected, in the form of id@host:port, repeat for multiple peers").Strings()
+	trustedPeers := mineCmd.Flag("trustedpeer", "trusted peer exempt from the connection limits, in the form of id or id@host:port, repeat for multiple peers").Strings()
+	noDiscover := mineCmd.Flag("nodiscover", "private mode, disable the peer discovery and connect the seeds and the static peers only").Bool()
 	natAddr := mineCmd.Flag("nat", "nat server address").Default("natproxy.zvchain.io").String()
 	natPort := mineCmd.Flag("natport", "nat server port").Default("3100").Uint16()
 	chainID := mineCmd.Flag("chainid", "chain id").Default("0").Uint16()
+	syncMode := mineCmd.Flag("syncmode", "sync mode, snapshot downloads the state at the trusted checkpoint configured by [chain] snapshot_trusted_checkpoint instead of executing all blocks").Default("full").Enum("full", "snapshot")
+
+	// Dev
+	devCmd := app.Command("dev", "start a single node development chain with a pre-funded account")
+	devPeriod := devCmd.Flag("period", "block interval in seconds, blocks are produced once transactions arrive if 0").Default("0").Uint()
+	devDataDir := devCmd.Flag("datadir", "directory of the chain data, a temporary directory is used if not set").String()
+	devBalance := devCmd.Flag("balance", "balance in ZVC funded to the account at genesis").Default("1000000000").Uint64()
+	devHost := devCmd.Flag("host", "rpc service host").Short('o').Default("127.0.0.1").IP()
+	devPort := devCmd.Flag("port", "rpc service port").Short('p').Default("8101").Uint16()
+	devCors := devCmd.Flag("cors", "set cors host, set 'all' allow any host").Default("").String()
+	devChainID := devCmd.Flag("chainid", "chain id").Default("65535").Uint16()
 
 	clearCmd := app.Command("clear", "Clear the data of blockchain")
 
@@ -189,6 +215,17 @@ func (gzv *Gzv) Run() {
 	srcDir := replayCmd.Flag("src", "directory of database for replaying").Required().String()
 	destDir := replayCmd.Flag("dest", "directory of database for storing the replayed data").String()
 
+	exportCmd := app.Command("export", "export the blocks to an archive file, the node should be stopped")
+	exportDB := exportCmd.Flag("db", "directory of database to export, default is the chain database of the config").String()
+	exportFrom := exportCmd.Flag("from", "height of the first block to export").Default("1").Uint64()
+	exportTo := exportCmd.Flag("to", "height of the last block to export, default is the top").Default(strconv.FormatUint(common.MaxUint64, 10)).Uint64()
+	exportOut := exportCmd.Flag("out", "the archive file").Required().String()
+	exportCompress := exportCmd.Flag("compress", "compress the archive with gzip").Bool()
+
+	importCmd := app.Command("import", "import the blocks from an archive file, resumes from the local top if interrupted")
+	importFile := importCmd.Arg("file", "the archive file").Required().String()
+	importDest := importCmd.Flag("dest", "directory of database for storing the imported data").String()
+
 	pruneCmd := app.Command("prune", "fully prune state data offline")
 	srcDB := pruneCmd.Flag("db", "database directory for pruning").Required().String()
 	srcSmallDB := pruneCmd.Flag("sdb", "small database directory for pruning which stores for the pruning mode").Default("").String()
@@ -199,6 +236,16 @@ func (gzv *Gzv) Run() {
 	cpu := pruneCmd.Flag("cpu", "number of CPU cores used in the pruning process").Default("0").Int()
 	maxOpenFiles := pruneCmd.Flag("maxopenfiles", "max open files for the process").Default("10240").Int()
 
+	keystoreCmd := app.Command("keystore", "manage the keystore")
+	migrateCmd := keystoreCmd.Command("migrate", "convert the legacy leveldb keystore to the v3 keystore files")
+	migrateSrc := migrateCmd.Flag("src", "the legacy keystore directory, default is the keystore path").String()
+	migrateDest := migrateCmd.Flag("dest", "directory for the v3 keystore files, the legacy keystore is backed up and replaced if not set").String()
+	migratePasswords := migrateCmd.Flag("passwords", "passwords of the accounts, repeat for multiple passwords, default is the password flag").Strings()
+	migrateKDF := migrateCmd.Flag("kdf", "key derivation function, scrypt or pbkdf2").Default(kdfScrypt).Enum(kdfScrypt, kdfPBKDF2)
+	migrateScryptN := migrateCmd.Flag("scryptn", "scrypt N parameter").Default(strconv.Itoa(StandardScryptN)).Int()
+	migrateScryptP := migrateCmd.Flag("scryptp", "scrypt P parameter").Default(strconv.Itoa(StandardScryptP)).Int()
+	migrateSkipFailed := migrateCmd.Flag("skipfailed", "skip the accounts can't be decrypted by the passwords instead of aborting").Bool()
+
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
 		kingpin.Fatalf("%s, try --help", err)
@@ -206,6 +253,9 @@ func (gzv *Gzv) Run() {
 
 	gzv.simpleInit(*configFile)
 
+	// The pprof listener is for debugging and never authenticated, keep its port private. The
+	// metrics on the rpc listener are protected by the rpc authentication if enabled
+	http.Handle("/metrics", metrics.Handler())
 	go func() {
 		http.ListenAndServe(fmt.Sprintf(":%d", *pprofPort), nil)
 		runtime.SetBlockProfileRate(1)
@@ -250,6 +300,10 @@ func (gzv *Gzv) Run() {
 			resetHash:         *reset,
 			cors:              *cors,
 			privateKey:        *privKey,
+			syncMode:          *syncMode,
+			staticPeers:       *staticPeers,
+			trustedPeers:      *trustedPeers,
+			noDiscover:        *noDiscover,
 		}
 		gzv.config = cfg
 
@@ -274,6 +328,38 @@ func (gzv *Gzv) Run() {
 			"version": common.GzvVersion,
 		}).Info("versionLog")
 		gzv.InitCha <- true
+	case devCmd.FullCommand():
+		log.Init()
+		types.InitMiddleware()
+
+		dataDir := *devDataDir
+		if dataDir == "" {
+			dataDir, err = ioutil.TempDir("", "gzv_dev")
+			if err != nil {
+				output("create data directory fail:", err)
+				os.Exit(-1)
+			}
+		}
+		cfg := &minerConfig{
+			rpcLevel:   rpcLevelDev,
+			host:       devHost.String(),
+			port:       *devPort,
+			cors:       *devCors,
+			chainID:    *devChainID,
+			privateKey: *privKey,
+		}
+		gzv.config = cfg
+		dc := &devConfig{
+			dataDir: dataDir,
+			period:  time.Duration(*devPeriod) * time.Second,
+			balance: *devBalance,
+		}
+		if err := gzv.dev(cfg, dc); err != nil {
+			output("initialize fail:", err)
+			log.DefaultLogger.Errorf("initialize fail:%v", err)
+			os.Exit(-1)
+		}
+		gzv.InitCha <- true
 	case clearCmd.FullCommand():
 		err := ClearBlock()
 		if err != nil {
@@ -313,6 +399,64 @@ func (gzv *Gzv) Run() {
 		}
 		output("replay finished")
 
+	case exportCmd.FullCommand():
+		log.Init()
+		types.InitMiddleware()
+
+		provider, err := core.NewLocalBlockProvider(*exportDB)
+		if err != nil {
+			output("open database error", err)
+			os.Exit(-1)
+		}
+		f, err := os.OpenFile(*exportOut, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			output("create archive error", err)
+			os.Exit(-1)
+		}
+		cnt, err := core.ExportArchive(provider, *exportFrom, *exportTo, f, *exportCompress)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			output("export error", err)
+			os.Exit(-1)
+		}
+		output(fmt.Sprintf("exported %v blocks to %v", cnt, *exportOut))
+		os.Exit(0)
+	case importCmd.FullCommand():
+		log.Init()
+		types.InitMiddleware()
+
+		provider, err := core.NewArchiveBlockProvider(*importFile)
+		if err != nil {
+			output("open archive error", err)
+			os.Exit(-1)
+		}
+		header := provider.Header()
+		output(fmt.Sprintf("archive version %v, blocks [%v, %v]", header.Version, header.From, header.To))
+
+		cfg := &minerConfig{
+			keystore:   *keystore,
+			password:   *passWd,
+			privateKey: *privKey,
+		}
+		gzv.config = cfg
+		if *importDest != "" {
+			common.GlobalConf.SetString("chain", "db_blocks", *importDest)
+		}
+		if err := gzv.coreInit(); err != nil {
+			output("initialize fail:", err)
+			os.Exit(-1)
+		}
+		replayer := core.NewFastReplayer(provider, core.BlockChainImpl, os.Stdout)
+		err = replayer.Replay(provider, os.Stdout)
+		provider.Close()
+		if err != nil {
+			output("import error", err)
+			os.Exit(-1)
+		}
+		output("import finished")
+		os.Exit(0)
 	case pruneCmd.FullCommand():
 		cores := runtime.NumCPU()
 		use := cores
@@ -337,6 +481,34 @@ func (gzv *Gzv) Run() {
 			tailor.Pruning()
 		}
 		os.Exit(0)
+	case migrateCmd.FullCommand():
+		src := *migrateSrc
+		if src == "" {
+			src = *keystore
+		}
+		passwords := *migratePasswords
+		if len(passwords) == 0 {
+			passwords = []string{*passWd}
+		}
+		kdf := StandardKDFConfig()
+		kdf.KDF = *migrateKDF
+		kdf.ScryptN = *migrateScryptN
+		kdf.ScryptP = *migrateScryptP
+		ret, err := MigrateKeyStore(src, *migrateDest, passwords, kdf, *migrateSkipFailed)
+		if ret != nil {
+			output(fmt.Sprintf("migrated %v accounts to %v", len(ret.Migrated), ret.Dest))
+			if ret.Backup != "" {
+				output("the legacy keystore is backed up to", ret.Backup)
+			}
+			for _, addr := range ret.Failed {
+				output("skipped", addr)
+			}
+		}
+		if err != nil {
+			output("migrate fail:", err)
+			os.Exit(-1)
+		}
+		os.Exit(0)
 	}
 	<-quitChan
 }
@@ -500,6 +672,9 @@ func (gzv *Gzv) fullInit() error {
 		SeedIDs:         genesisMembers,
 		PK:              gzv.account.Pk,
 		SK:              gzv.account.Sk,
+		StaticPeers:     cfg.staticPeers,
+		TrustedPeers:    cfg.trustedPeers,
+		NoDiscover:      cfg.noDiscover,
 	}
 
 	err = network.Init(&common.GlobalConf, chandler.MessageHandler, netCfg)
@@ -0,0 +1,311 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/tasdb"
+	"golang.org/x/crypto/scrypt"
+)
+
+const keyStoreSection = "keystore"
+
+// keyStore is the storage of the encrypted account keys
+type keyStore interface {
+	// Load decrypts the key of the address with the password
+	Load(addr string, password string) (*KeyStoreRaw, error)
+	// Store encrypts the key with the password and saves it, the existing one is replaced
+	Store(addr string, ksr *KeyStoreRaw, password string) error
+	Delete(addr string) error
+	Addresses() ([]string, error)
+	Close()
+}
+
+// isLegacyKeyStore returns whether the directory is the leveldb keystore used before the v3 keystore files
+func isLegacyKeyStore(dir string) bool {
+	f, err := os.Stat(filepath.Join(dir, "CURRENT"))
+	return err == nil && !f.IsDir()
+}
+
+// openKeyStore opens the keystore in the directory, the legacy leveldb keystore is still supported
+// until migrated by the keystore migrate command
+func openKeyStore(dir string) (keyStore, error) {
+	if isLegacyKeyStore(dir) {
+		output(fmt.Sprintf("%v is a legacy keystore, run 'gzv keystore migrate' to convert it to the v3 keystore files", dir))
+		return newLDBKeyStore(dir)
+	}
+	return newFileKeyStore(dir, kdfConfigFromConf())
+}
+
+// kdfConfigFromConf reads the key derivation config from the keystore section of the config file
+func kdfConfigFromConf() *KDFConfig {
+	cfg := StandardKDFConfig()
+	if common.GlobalConf == nil {
+		return cfg
+	}
+	cfg.KDF = common.GlobalConf.GetString(keyStoreSection, "kdf", cfg.KDF)
+	cfg.ScryptN = common.GlobalConf.GetInt(keyStoreSection, "scrypt_n", cfg.ScryptN)
+	cfg.ScryptP = common.GlobalConf.GetInt(keyStoreSection, "scrypt_p", cfg.ScryptP)
+	cfg.PBKDF2C = common.GlobalConf.GetInt(keyStoreSection, "pbkdf2_c", cfg.PBKDF2C)
+	return cfg
+}
+
+// withMinerKeys returns a copy of the key with the miner keys filled if it's a miner account
+func (ksr *KeyStoreRaw) withMinerKeys() (*KeyStoreRaw, error) {
+	raw := *ksr
+	if raw.IsMiner && raw.Miner == nil {
+		sk := new(common.PrivateKey)
+		if !sk.ImportKey(raw.Key) {
+			return nil, ErrInternal
+		}
+		acc, err := recoverAccountByPrivateKey(sk, true)
+		if err != nil {
+			return nil, err
+		}
+		raw.Miner = acc.Miner
+	}
+	return &raw, nil
+}
+
+// ldbKeyStore is the legacy keystore which saves the encrypted keys in the leveldb
+type ldbKeyStore struct {
+	db *tasdb.LDBDatabase
+}
+
+func newLDBKeyStore(dir string) (*ldbKeyStore, error) {
+	options := &opt.Options{
+		OpenFilesCacheCapacity:        10,
+		WriteBuffer:                   8 * opt.MiB, // Two of these are used internally
+		Filter:                        filter.NewBloomFilter(10),
+		CompactionTableSize:           2 * opt.MiB,
+		CompactionTableSizeMultiplier: 2,
+	}
+	db, err := tasdb.NewLDBDatabase(dir, options)
+	if err != nil {
+		return nil, fmt.Errorf("new ldb fail:%v", err.Error())
+	}
+	return &ldbKeyStore{db: db}, nil
+}
+
+func legacyScryptKey(password string) ([]byte, error) {
+	salt := common.Sha256([]byte(password))
+	return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+}
+
+func (ks *ldbKeyStore) Load(addr string, password string) (*KeyStoreRaw, error) {
+	v, err := ks.db.Get([]byte(addr))
+	if err != nil {
+		return nil, fmt.Errorf("your address %s not found in your keystore directory", addr)
+	}
+	scryptPwd, err := legacyScryptKey(password)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := common.DecryptWithKey(scryptPwd, v)
+	if err != nil {
+		return nil, err
+	}
+	var ksr = new(KeyStoreRaw)
+	if err = json.Unmarshal(bs, ksr); err != nil {
+		return nil, err
+	}
+	return ksr, nil
+}
+
+func (ks *ldbKeyStore) Store(addr string, ksr *KeyStoreRaw, password string) error {
+	// The miner keys are always derived from the private key in the legacy keystore
+	raw := *ksr
+	raw.Miner = nil
+	bs, err := json.Marshal(&raw)
+	if err != nil {
+		return err
+	}
+	scryptPwd, err := legacyScryptKey(password)
+	if err != nil {
+		return err
+	}
+	ct, err := common.EncryptWithKey(scryptPwd, bs)
+	if err != nil {
+		return err
+	}
+	return ks.db.Put([]byte(addr), ct)
+}
+
+func (ks *ldbKeyStore) Delete(addr string) error {
+	return ks.db.Delete([]byte(addr))
+}
+
+func (ks *ldbKeyStore) Addresses() ([]string, error) {
+	iter := ks.db.NewIterator()
+	defer iter.Release()
+	addrs := make([]string, 0)
+	for iter.Next() {
+		addrs = append(addrs, string(iter.Key()))
+	}
+	return addrs, iter.Error()
+}
+
+func (ks *ldbKeyStore) Close() {
+	ks.db.Close()
+}
+
+// fileKeyStore saves each account in a version 3 keystore json file of the directory
+type fileKeyStore struct {
+	dir string
+	kdf *KDFConfig
+}
+
+func newFileKeyStore(dir string, kdf *KDFConfig) (*fileKeyStore, error) {
+	if err := kdf.validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &fileKeyStore{dir: dir, kdf: kdf}, nil
+}
+
+// keyFileName returns the file name like UTC--2019-08-01T08-00-00.000000000Z--zv0123...
+func keyFileName(addr string) string {
+	ts := time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z")
+	return fmt.Sprintf("UTC--%s--%s", ts, addr)
+}
+
+// readKeyFileAddress returns the address field of the keystore file
+func readKeyFileAddress(path string) (string, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	k := struct {
+		Address string `json:"address"`
+	}{}
+	if err := json.Unmarshal(bs, &k); err != nil {
+		return "", err
+	}
+	return k.Address, nil
+}
+
+// files returns the address to the file path map of the keystore files in the directory
+func (ks *fileKeyStore) files() (map[string]string, error) {
+	infos, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string)
+	for _, info := range infos {
+		// Skip the directories, hidden files and the temporary files
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || strings.HasSuffix(info.Name(), ".tmp") {
+			continue
+		}
+		path := filepath.Join(ks.dir, info.Name())
+		addr, err := readKeyFileAddress(path)
+		if err != nil || addr == "" {
+			continue
+		}
+		ret[addr] = path
+	}
+	return ret, nil
+}
+
+func (ks *fileKeyStore) find(addr string) (string, error) {
+	files, err := ks.files()
+	if err != nil {
+		return "", err
+	}
+	if path, ok := files[addr]; ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("your address %s not found in your keystore directory", addr)
+}
+
+func (ks *fileKeyStore) Load(addr string, password string) (*KeyStoreRaw, error) {
+	path, err := ks.find(addr)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, ksr, err := decryptKeyV3(bs, password)
+	return ksr, err
+}
+
+func (ks *fileKeyStore) Store(addr string, ksr *KeyStoreRaw, password string) error {
+	raw, err := ksr.withMinerKeys()
+	if err != nil {
+		return err
+	}
+	bs, err := encryptKeyV3(addr, raw, password, ks.kdf)
+	if err != nil {
+		return err
+	}
+	old, _ := ks.find(addr)
+	if err := writeKeyFile(filepath.Join(ks.dir, keyFileName(addr)), bs); err != nil {
+		return err
+	}
+	if old != "" {
+		return os.Remove(old)
+	}
+	return nil
+}
+
+func (ks *fileKeyStore) Delete(addr string) error {
+	path, err := ks.find(addr)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (ks *fileKeyStore) Addresses() ([]string, error) {
+	files, err := ks.files()
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(files))
+	for addr := range files {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+func (ks *fileKeyStore) Close() {}
+
+// writeKeyFile writes the file atomically with the permission only for the owner
+func writeKeyFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
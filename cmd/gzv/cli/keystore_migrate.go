@@ -0,0 +1,124 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const legacyBackupSuffix = ".ldb.bak"
+
+// MigrateResult is the result of the keystore migration
+type MigrateResult struct {
+	Dest     string
+	Backup   string   // Backup of the legacy keystore if migrated in place
+	Migrated []string // Addresses migrated
+	Failed   []string // Addresses can't be decrypted by any of the passwords
+}
+
+type decryptedKey struct {
+	addr     string
+	ksr      *KeyStoreRaw
+	password string
+}
+
+// MigrateKeyStore converts the legacy leveldb keystore in src to the version 3 keystore files in dest.
+// Each account is tried with the given passwords and encrypted again with the one it matches.
+// If dest is empty, the legacy keystore is moved to src.ldb.bak and the files are written to src.
+// Nothing is written if any account fails to decrypt unless skipFailed is set.
+func MigrateKeyStore(src, dest string, passwords []string, cfg *KDFConfig, skipFailed bool) (*MigrateResult, error) {
+	if !isLegacyKeyStore(src) {
+		return nil, fmt.Errorf("%v is not a legacy keystore", src)
+	}
+	if len(passwords) == 0 {
+		return nil, fmt.Errorf("no password given")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	inPlace := dest == "" || filepath.Clean(dest) == filepath.Clean(src)
+	if inPlace {
+		dest = src
+	} else if isLegacyKeyStore(dest) {
+		return nil, fmt.Errorf("%v is a legacy keystore", dest)
+	}
+	backup := filepath.Clean(src) + legacyBackupSuffix
+	if inPlace {
+		if _, err := os.Stat(backup); err == nil {
+			return nil, fmt.Errorf("backup %v already exists", backup)
+		}
+	}
+
+	keys, failed, err := decryptLegacyKeys(src, passwords)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 && !skipFailed {
+		return nil, fmt.Errorf("failed to decrypt %v with the given passwords: %v", len(failed), failed)
+	}
+
+	ret := &MigrateResult{Dest: dest, Failed: failed}
+	if inPlace {
+		if err := os.Rename(src, backup); err != nil {
+			return nil, err
+		}
+		ret.Backup = backup
+	}
+	fks, err := newFileKeyStore(dest, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if err := fks.Store(k.addr, k.ksr, k.password); err != nil {
+			return ret, fmt.Errorf("store %v fail: %v", k.addr, err)
+		}
+		ret.Migrated = append(ret.Migrated, k.addr)
+	}
+	return ret, nil
+}
+
+// decryptLegacyKeys decrypts all accounts of the legacy keystore with the passwords
+func decryptLegacyKeys(src string, passwords []string) ([]*decryptedKey, []string, error) {
+	ks, err := newLDBKeyStore(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer ks.Close()
+
+	addrs, err := ks.Addresses()
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make([]*decryptedKey, 0, len(addrs))
+	failed := make([]string, 0)
+	for _, addr := range addrs {
+		var key *decryptedKey
+		for _, password := range passwords {
+			if ksr, err := ks.Load(addr, password); err == nil {
+				key = &decryptedKey{addr: addr, ksr: ksr, password: password}
+				break
+			}
+		}
+		if key == nil {
+			failed = append(failed, addr)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, failed, nil
+}
@@ -0,0 +1,236 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+// newTestAccountOp opens the file keystore with the light kdf to speed up the tests
+func newTestAccountOp(t *testing.T, dir string) *AccountManager {
+	ks, err := newFileKeyStore(dir, LightKDFConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AccountManager{store: ks}
+}
+
+func newTestKey(t *testing.T, miner bool) (string, *KeyStoreRaw) {
+	sk, err := common.GenerateKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sk.GetPubKey().GetAddress().AddrPrefixString(), &KeyStoreRaw{Key: sk.ExportKey(), IsMiner: miner}
+}
+
+// Test vector from the ethereum web3 secret storage definition
+func TestDecryptEthereumVector(t *testing.T) {
+	data := `{
+		"cipher": "aes-128-ctr",
+		"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+		"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+		"kdf": "pbkdf2",
+		"kdfparams": {"c": 262144, "dklen": 32, "prf": "hmac-sha256", "salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},
+		"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+	}`
+	c := new(cryptoJSON)
+	if err := json.Unmarshal([]byte(data), c); err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := decryptCrypto(c, "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key) != "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d" {
+		t.Fatalf("unexpected key %x", key)
+	}
+	if _, _, err := decryptCrypto(c, "wrongpassword"); err != ErrPassword {
+		t.Fatalf("expect password error, got %v", err)
+	}
+}
+
+func TestKeyStoreV3(t *testing.T) {
+	addr, ksr := newTestKey(t, true)
+	ksr.Seed = []byte("0123456789abcdef0123456789abcdef")
+	ksr.HDPath = "m/44'/372'/0'/0/0"
+	ksr, err := ksr.withMinerKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cfg := range []*KDFConfig{LightKDFConfig(), {KDF: kdfPBKDF2, PBKDF2C: 1024}} {
+		bs, err := encryptKeyV3(addr, ksr, "password_1", cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, got, err := decryptKeyV3(bs, "password_1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != addr || !bytes.Equal(got.Key, ksr.Key) || !got.IsMiner || *got.Miner != *ksr.Miner ||
+			!bytes.Equal(got.Seed, ksr.Seed) || got.HDPath != ksr.HDPath {
+			t.Fatalf("%v: unexpected key %+v", cfg.KDF, got)
+		}
+		if _, _, err := decryptKeyV3(bs, "password_2"); err != ErrPassword {
+			t.Fatalf("%v: expect password error, got %v", cfg.KDF, err)
+		}
+	}
+
+	if _, err := encryptKeyV3(addr, ksr, "password_1", &KDFConfig{KDF: kdfScrypt, ScryptN: 1000, ScryptP: 1}); err == nil {
+		t.Fatal("expect invalid scrypt N error")
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	am := newTestAccountOp(t, filepath.Join(dir, "ks"))
+	addr, err := am.NewAccount("password_1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs, err := am.AccountList(); err != nil || len(addrs) != 1 || addrs[0] != addr {
+		t.Fatalf("unexpected account list %v %v", addrs, err)
+	}
+	if acc := am.getFirstMinerAccount("password_1"); acc == nil || acc.Address != addr {
+		t.Fatalf("expect miner account %v, got %+v", addr, acc)
+	}
+
+	file := filepath.Join(dir, "export.json")
+	if _, err := am.ExportKeyStore(addr, "password_2", file); err == nil {
+		t.Fatal("expect password error")
+	}
+	if _, err := am.ExportKeyStore(addr, "password_1", file); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected exported file %v %v", info, err)
+	}
+
+	am2 := newTestAccountOp(t, filepath.Join(dir, "ks2"))
+	if got, err := am2.ImportKeyStore(file, "password_1"); err != nil || got != addr {
+		t.Fatalf("expect imported %v, got %v %v", addr, got, err)
+	}
+	am2.accounts.Delete(addr)
+	acc, err := am2.loadAccount(addr, "password_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin, _ := am.loadAccount(addr, "password_1")
+	if acc.Sk != origin.Sk || *acc.Miner != *origin.Miner {
+		t.Fatalf("imported account mismatch")
+	}
+}
+
+func TestCheckMinerKeysNotMiner(t *testing.T) {
+	addr, ksr := newTestKey(t, true)
+	ksr, err := ksr.withMinerKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The miner keys along with a key not marked as a miner, e.g. a crafted or legacy keystore
+	sk := new(common.PrivateKey)
+	sk.ImportKey(ksr.Key)
+	acc, err := recoverAccountByPrivateKey(sk, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMinerKeys(addr, acc, ksr); err == nil {
+		t.Fatal("expect the miner keys rejected")
+	}
+	if acc, err = recoverAccountByPrivateKey(sk, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMinerKeys(addr, acc, ksr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate_keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "keystore")
+	ldb, err := newLDBKeyStore(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr1, ksr1 := newTestKey(t, true)
+	addr2, ksr2 := newTestKey(t, false)
+	if err := ldb.Store(addr1, ksr1, "password_1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldb.Store(addr2, ksr2, "password_2"); err != nil {
+		t.Fatal(err)
+	}
+	ldb.Close()
+
+	cfg := LightKDFConfig()
+	if _, err := MigrateKeyStore(src, "", []string{"password_1"}, cfg, false); err == nil {
+		t.Fatal("expect decrypt failure")
+	}
+	if !isLegacyKeyStore(src) {
+		t.Fatal("legacy keystore should be untouched")
+	}
+
+	// Migrate to another directory with skipping the failed
+	ret, err := MigrateKeyStore(src, filepath.Join(dir, "v3"), []string{"password_1"}, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Migrated) != 1 || ret.Migrated[0] != addr1 || len(ret.Failed) != 1 || ret.Failed[0] != addr2 {
+		t.Fatalf("unexpected result %+v", ret)
+	}
+
+	// Migrate in place
+	ret, err = MigrateKeyStore(src, "", []string{"password_1", "password_2"}, cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Migrated) != 2 || ret.Backup != src+legacyBackupSuffix || !isLegacyKeyStore(ret.Backup) || isLegacyKeyStore(src) {
+		t.Fatalf("unexpected result %+v", ret)
+	}
+
+	am, err := newAccountOp(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := am.store.(*fileKeyStore); !ok {
+		t.Fatal("expect file keystore")
+	}
+	acc, err := am.loadAccount(addr1, "password_1")
+	if err != nil || acc.Miner == nil {
+		t.Fatalf("load migrated miner account fail: %+v %v", acc, err)
+	}
+	if acc, err := am.loadAccount(addr2, "password_2"); err != nil || acc.Miner != nil {
+		t.Fatalf("load migrated account fail: %+v %v", acc, err)
+	}
+}
@@ -0,0 +1,383 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	keyStoreVersion = 3
+
+	kdfScrypt = "scrypt"
+	kdfPBKDF2 = "pbkdf2"
+
+	keyStoreCipher = "aes-128-ctr"
+
+	// StandardScryptN and StandardScryptP are the scrypt parameters of the standard keystore,
+	// which takes about 1 second to derive the key on a modern CPU
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP are the scrypt parameters for the low-end devices
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+
+	defaultPBKDF2C = 262144
+)
+
+// KDFConfig is the key derivation config used to encrypt the keystore
+type KDFConfig struct {
+	KDF     string // scrypt or pbkdf2
+	ScryptN int
+	ScryptP int
+	PBKDF2C int
+}
+
+// StandardKDFConfig returns the default key derivation config
+func StandardKDFConfig() *KDFConfig {
+	return &KDFConfig{KDF: kdfScrypt, ScryptN: StandardScryptN, ScryptP: StandardScryptP, PBKDF2C: defaultPBKDF2C}
+}
+
+// LightKDFConfig returns the key derivation config costs less memory and cpu
+func LightKDFConfig() *KDFConfig {
+	return &KDFConfig{KDF: kdfScrypt, ScryptN: LightScryptN, ScryptP: LightScryptP, PBKDF2C: defaultPBKDF2C}
+}
+
+func (c *KDFConfig) validate() error {
+	switch c.KDF {
+	case kdfScrypt:
+		if c.ScryptN <= 1 || c.ScryptN&(c.ScryptN-1) != 0 {
+			return fmt.Errorf("scrypt N must be a power of 2 greater than 1")
+		}
+		if c.ScryptP <= 0 {
+			return fmt.Errorf("scrypt P must be positive")
+		}
+	case kdfPBKDF2:
+		if c.PBKDF2C <= 0 {
+			return fmt.Errorf("pbkdf2 iteration count must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported kdf %v", c.KDF)
+	}
+	return nil
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+// sealedJSON is the data encrypted with the key derived by the kdf of the crypto section
+type sealedJSON struct {
+	CipherText   string           `json:"ciphertext"`
+	CipherParams cipherParamsJSON `json:"cipherparams"`
+	MAC          string           `json:"mac"`
+}
+
+// minerKeyJSON embeds the miner keys, the public keys are in plain text
+type minerKeyJSON struct {
+	BPk    string     `json:"bpk"`
+	VrfPk  string     `json:"vrfpk"`
+	Sealed sealedJSON `json:"sealed"` // Sealed minerSecretJSON
+}
+
+type minerSecretJSON struct {
+	BSk   string `json:"bsk"`
+	VrfSk string `json:"vrfsk"`
+}
+
+// hdKeyJSON embeds the seed of the hierarchical deterministic account
+type hdKeyJSON struct {
+	Path   string     `json:"path"`
+	Sealed sealedJSON `json:"sealed"` // Sealed seed
+}
+
+// encryptedKeyJSONV3 is the version 3 keystore file, compatible with the ethereum tools except the
+// address format. The miner keys and the hd seed are in the optional extension sections
+type encryptedKeyJSONV3 struct {
+	Address string        `json:"address"`
+	Crypto  cryptoJSON    `json:"crypto"`
+	ID      string        `json:"id"`
+	Version int           `json:"version"`
+	Miner   *minerKeyJSON `json:"miner,omitempty"`
+	HD      *hdKeyJSON    `json:"hd,omitempty"`
+}
+
+func randomBytes(n int) []byte {
+	bs := make([]byte, n)
+	if _, err := rand.Read(bs); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	return bs
+}
+
+func newUUID() string {
+	u := randomBytes(16)
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func aesCTRXOR(key, in, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func seal(derivedKey, data []byte) (sealedJSON, error) {
+	iv := randomBytes(aes.BlockSize)
+	ct, err := aesCTRXOR(derivedKey[:16], data, iv)
+	if err != nil {
+		return sealedJSON{}, err
+	}
+	return sealedJSON{
+		CipherText:   hex.EncodeToString(ct),
+		CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+		MAC:          hex.EncodeToString(keccak256(derivedKey[16:32], ct)),
+	}, nil
+}
+
+func unseal(derivedKey []byte, s *sealedJSON) ([]byte, error) {
+	ct, err := hex.DecodeString(s.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(s.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := hex.DecodeString(s.MAC)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(keccak256(derivedKey[16:32], ct), mac) {
+		return nil, ErrPassword
+	}
+	return aesCTRXOR(derivedKey[:16], ct, iv)
+}
+
+func deriveKey(password []byte, kdf string, params map[string]interface{}) ([]byte, error) {
+	getInt := func(name string) (int, error) {
+		// Numbers are float64 if decoded from json
+		switch v := params[name].(type) {
+		case float64:
+			return int(v), nil
+		case int:
+			return v, nil
+		}
+		return 0, fmt.Errorf("kdf param %v missing", name)
+	}
+	saltHex, _ := params["salt"].(string)
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kdf salt: %v", err)
+	}
+	dkLen, err := getInt("dklen")
+	if err != nil {
+		return nil, err
+	}
+	if dkLen < 32 {
+		return nil, fmt.Errorf("kdf dklen should be at least 32")
+	}
+	switch kdf {
+	case kdfScrypt:
+		n, err := getInt("n")
+		if err != nil {
+			return nil, err
+		}
+		r, err := getInt("r")
+		if err != nil {
+			return nil, err
+		}
+		p, err := getInt("p")
+		if err != nil {
+			return nil, err
+		}
+		return scrypt.Key(password, salt, n, r, p, dkLen)
+	case kdfPBKDF2:
+		c, err := getInt("c")
+		if err != nil {
+			return nil, err
+		}
+		if prf, _ := params["prf"].(string); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %v", prf)
+		}
+		return pbkdf2.Key(password, salt, c, dkLen, sha256.New), nil
+	}
+	return nil, fmt.Errorf("unsupported kdf %v", kdf)
+}
+
+func kdfParams(cfg *KDFConfig, salt []byte) map[string]interface{} {
+	params := map[string]interface{}{
+		"dklen": scryptDKLen,
+		"salt":  hex.EncodeToString(salt),
+	}
+	if cfg.KDF == kdfPBKDF2 {
+		params["c"] = cfg.PBKDF2C
+		params["prf"] = "hmac-sha256"
+	} else {
+		params["n"] = cfg.ScryptN
+		params["r"] = scryptR
+		params["p"] = cfg.ScryptP
+	}
+	return params
+}
+
+// encryptKeyV3 encrypts the key along with its miner keys and hd seed if any into the version 3 keystore json
+func encryptKeyV3(addr string, ksr *KeyStoreRaw, password string, cfg *KDFConfig) ([]byte, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	salt := randomBytes(32)
+	params := kdfParams(cfg, salt)
+	derivedKey, err := deriveKey([]byte(password), cfg.KDF, params)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	copy(key[32-len(ksr.Key):], ksr.Key)
+	sealed, err := seal(derivedKey, key)
+	if err != nil {
+		return nil, err
+	}
+	ret := &encryptedKeyJSONV3{
+		Address: addr,
+		Crypto: cryptoJSON{
+			Cipher:       keyStoreCipher,
+			CipherText:   sealed.CipherText,
+			CipherParams: sealed.CipherParams,
+			KDF:          cfg.KDF,
+			KDFParams:    params,
+			MAC:          sealed.MAC,
+		},
+		ID:      newUUID(),
+		Version: keyStoreVersion,
+	}
+	if miner := ksr.Miner; miner != nil {
+		secret, err := json.Marshal(&minerSecretJSON{BSk: miner.BSk, VrfSk: miner.VrfSk})
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := seal(derivedKey, secret)
+		if err != nil {
+			return nil, err
+		}
+		ret.Miner = &minerKeyJSON{BPk: miner.BPk, VrfPk: miner.VrfPk, Sealed: sealed}
+	}
+	if len(ksr.Seed) > 0 {
+		sealed, err := seal(derivedKey, ksr.Seed)
+		if err != nil {
+			return nil, err
+		}
+		ret.HD = &hdKeyJSON{Path: ksr.HDPath, Sealed: sealed}
+	}
+	return json.MarshalIndent(ret, "", "  ")
+}
+
+// decryptCrypto decrypts the crypto section and returns the plain key and the derived key
+func decryptCrypto(c *cryptoJSON, password string) (key []byte, derivedKey []byte, err error) {
+	if c.Cipher != keyStoreCipher {
+		return nil, nil, fmt.Errorf("unsupported cipher %v", c.Cipher)
+	}
+	derivedKey, err = deriveKey([]byte(password), c.KDF, c.KDFParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err = unseal(derivedKey, &sealedJSON{CipherText: c.CipherText, CipherParams: c.CipherParams, MAC: c.MAC})
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, derivedKey, nil
+}
+
+// decryptKeyV3 decrypts the version 3 keystore json and returns the address and the key
+func decryptKeyV3(data []byte, password string) (string, *KeyStoreRaw, error) {
+	k := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(data, k); err != nil {
+		return "", nil, err
+	}
+	if k.Version != keyStoreVersion {
+		return "", nil, fmt.Errorf("unsupported keystore version %v", k.Version)
+	}
+	key, derivedKey, err := decryptCrypto(&k.Crypto, password)
+	if err != nil {
+		return "", nil, err
+	}
+	sk := new(common.PrivateKey)
+	if !sk.ImportKey(key) {
+		return "", nil, ErrInternal
+	}
+	pk := sk.GetPubKey()
+	if addr := pk.GetAddress(); addr.AddrPrefixString() != k.Address {
+		return "", nil, fmt.Errorf("key doesn't match the address %v", k.Address)
+	}
+	ksr := &KeyStoreRaw{Key: sk.ExportKey()}
+	if k.Miner != nil {
+		bs, err := unseal(derivedKey, &k.Miner.Sealed)
+		if err != nil {
+			return "", nil, err
+		}
+		secret := new(minerSecretJSON)
+		if err := json.Unmarshal(bs, secret); err != nil {
+			return "", nil, err
+		}
+		ksr.IsMiner = true
+		ksr.Miner = &MinerRaw{BPk: k.Miner.BPk, BSk: secret.BSk, VrfPk: k.Miner.VrfPk, VrfSk: secret.VrfSk}
+	}
+	if k.HD != nil {
+		if ksr.Seed, err = unseal(derivedKey, &k.HD.Sealed); err != nil {
+			return "", nil, err
+		}
+		ksr.HDPath = k.HD.Path
+	}
+	return k.Address, ksr, nil
+}
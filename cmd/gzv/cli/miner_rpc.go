@@ -17,6 +17,7 @@ package cli
 
 import (
 	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+	"github.com/zvchain/zvchain/common"
 	"github.com/zvchain/zvchain/consensus/group"
 	"github.com/zvchain/zvchain/core"
 	"github.com/zvchain/zvchain/log"
@@ -55,19 +56,21 @@ func (gzv *Gzv) initRpcInstances() error {
 	gzv.rpcInstances = make([]rpcApi, 0)
 	gzv.addInstance(&RpcMinerImpl{base})
 	if level >= rpcLevelGtas {
-		gzv.addInstance(&RpcGzvImpl{rpcBaseImpl: base, routineChecker: group.GroupRoutine})
+		events := newEventSystem()
+		gzv.addInstance(&RpcGzvImpl{rpcBaseImpl: base, routineChecker: group.GroupRoutine, events: events, filters: newFilterManager(events)})
 	}
 	if level >= rpcLevelExplorer {
 		gzv.addInstance(&RpcExplorerImpl{rpcBaseImpl: base})
 	}
 	if level >= rpcLevelDev {
 		gzv.addInstance(&RpcDevImpl{rpcBaseImpl: base})
+		gzv.addInstance(&RpcDebugImpl{rpcBaseImpl: base})
 	}
 	return nil
 }
 
 // startHTTP initializes and starts the HTTP RPC endpoint.
-func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string) error {
+func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, auth *rpc.Authenticator) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -80,6 +83,9 @@ func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string,
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer(isPruneMode)
+	if auth != nil {
+		handler.SetAuthenticator(auth)
+	}
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -95,7 +101,7 @@ func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string,
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
+	go rpc.NewHTTPWSServer(cors, vhosts, handler).Serve(listener)
 	return nil
 }
 
@@ -124,9 +130,14 @@ func (gzv *Gzv) startRPC() error {
 		cors = strings.Split(gzv.config.cors, ",")
 	}
 
+	auth, err := loadRPCAuthenticator(common.GlobalConf)
+	if err != nil {
+		return fmt.Errorf("load rpc auth config error:%v", err)
+	}
+
 	for plus := 0; plus < 40; plus++ {
 		endpoint := fmt.Sprintf("%s:%d", host, port+uint16(plus))
-		err = startHTTP(endpoint, apis, []string{}, cors, []string{})
+		err = startHTTP(endpoint, apis, []string{}, cors, []string{}, auth)
 		if err == nil {
 			log.DefaultLogger.Errorf("RPC serving on %v\n", endpoint)
 			return nil
@@ -0,0 +1,209 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// offlineTxVersion is the version of the offline transaction file format
+const offlineTxVersion = 1
+
+// maxRawTxSize is the max size of the encoded transaction accepted by sendRawTransaction
+const maxRawTxSize = 1 << 20
+
+// OfflineTx is the file format of the offline signing workflow. The unsigned transaction is built on an
+// online machine, signed on an offline machine and then submitted with the Raw field
+type OfflineTx struct {
+	Version int        `json:"version"`
+	Tx      *TxRawData `json:"tx"`
+	Hash    string     `json:"hash"`            // Hash of the transaction, which is the message to sign
+	Raw     string     `json:"raw,omitempty"`   // Hex of the msgpack encoded signed RawTransaction, set after signed
+	Signs   []string   `json:"signs,omitempty"` // Member signs collected for the multisig execute transaction
+}
+
+func newOfflineTx(tx *TxRawData) *OfflineTx {
+	return &OfflineTx{
+		Version: offlineTxVersion,
+		Tx:      tx,
+		Hash:    txRawToTransaction(tx).Hash.Hex(),
+	}
+}
+
+// Signed returns whether the transaction is signed
+func (otx *OfflineTx) Signed() bool {
+	return otx.Raw != ""
+}
+
+func (otx *OfflineTx) validate() error {
+	if otx.Version != offlineTxVersion {
+		return fmt.Errorf("unsupported offline transaction version %v", otx.Version)
+	}
+	if otx.Tx == nil {
+		return fmt.Errorf("transaction is empty")
+	}
+	if !common.ValidateAddress(otx.Tx.Source) {
+		return fmt.Errorf("wrong source address")
+	}
+	if h := txRawToTransaction(otx.Tx).Hash.Hex(); h != otx.Hash {
+		return fmt.Errorf("transaction hash not match, expect %v, got %v", h, otx.Hash)
+	}
+	return nil
+}
+
+// sign signs the transaction with the private key of the source and fills the Raw field
+func (otx *OfflineTx) sign(sk *common.PrivateKey) error {
+	if err := otx.validate(); err != nil {
+		return err
+	}
+	if otx.Tx.TxType == types.TransactionTypeMultiSigExecute {
+		return otx.signMultiSig(sk)
+	}
+	pk := sk.GetPubKey()
+	if src := pk.GetAddress(); src.AddrPrefixString() != otx.Tx.Source {
+		return fmt.Errorf("the private key doesn't belong to the source %v", otx.Tx.Source)
+	}
+	otx.Tx.Sign = ""
+	tx := txRawToTransaction(otx.Tx)
+	sign, err := sk.Sign(tx.Hash.Bytes())
+	if err != nil {
+		return err
+	}
+	tx.Sign = sign.Bytes()
+	raw, err := encodeRawTx(tx.RawTransaction)
+	if err != nil {
+		return err
+	}
+	otx.Tx.Sign = sign.Hex()
+	otx.Raw = common.ToHex(raw)
+	return nil
+}
+
+// signMultiSig adds the sign of a member to the multisig execute transaction. The Raw field is updated with
+// all signs collected so far, and can be submitted once the signs reach the threshold of the source account
+func (otx *OfflineTx) signMultiSig(sk *common.PrivateKey) error {
+	unsigned := *otx.Tx
+	unsigned.Sign = ""
+	tx := txRawToTransaction(&unsigned)
+	signs := make([][]byte, 0, len(otx.Signs)+1)
+	for _, s := range otx.Signs {
+		signs = append(signs, common.FromHex(s))
+	}
+	sign, err := sk.Sign(tx.Hash.Bytes())
+	if err != nil {
+		return err
+	}
+	signs = append(signs, sign.Bytes())
+	if len(signs) > types.MaxMultiSigMembers {
+		return fmt.Errorf("too many signs")
+	}
+	// Also checks the sign of the same member is not added twice
+	if _, err := types.RecoverMultiSigSigners(tx.Hash.Bytes(), signs); err != nil {
+		return err
+	}
+	envelope, err := types.EncodeMultiSigSigns(signs)
+	if err != nil {
+		return err
+	}
+	tx.Sign = envelope
+	raw, err := encodeRawTx(tx.RawTransaction)
+	if err != nil {
+		return err
+	}
+	otx.Signs = append(otx.Signs, sign.Hex())
+	otx.Tx.Sign = common.ToHex(envelope)
+	otx.Raw = common.ToHex(raw)
+	return nil
+}
+
+func readOfflineTx(file string) (*OfflineTx, error) {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	otx := new(OfflineTx)
+	if err := json.Unmarshal(bs, otx); err != nil {
+		return nil, fmt.Errorf("decode %v fail:%v", file, err)
+	}
+	if err := otx.validate(); err != nil {
+		return nil, err
+	}
+	return otx, nil
+}
+
+func writeOfflineTx(file string, otx *OfflineTx) error {
+	bs, err := json.MarshalIndent(otx, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bs, 0600)
+}
+
+func encodeRawTx(tx *types.RawTransaction) ([]byte, error) {
+	return msgpack.Marshal(tx)
+}
+
+// decodeRawTx decodes the msgpack encoded transaction and recovers the source from the sign.
+// The source is part of the transaction hash, so it must be given and match the recovered one
+func decodeRawTx(bs []byte) (*types.Transaction, error) {
+	if len(bs) == 0 {
+		return nil, fmt.Errorf("empty transaction")
+	}
+	if len(bs) > maxRawTxSize {
+		return nil, fmt.Errorf("transaction too large")
+	}
+	var raw types.RawTransaction
+	if err := msgpack.Unmarshal(bs, &raw); err != nil {
+		return nil, fmt.Errorf("decode transaction fail:%v", err)
+	}
+	if raw.Source == nil {
+		return nil, fmt.Errorf("transaction source is empty")
+	}
+	if !validateTxType(int(raw.Type)) {
+		return nil, fmt.Errorf("not supported txType")
+	}
+	if raw.Type == types.TransactionTypeMultiSigExecute {
+		// The member signs are checked against the multisig account by the node
+		tx := types.NewTransaction(&raw, raw.GenHash())
+		signs, err := types.DecodeMultiSigSigns(raw.Sign)
+		if err != nil {
+			return nil, fmt.Errorf("decode multisig signs fail:%v", err)
+		}
+		if _, err := types.RecoverMultiSigSigners(tx.Hash.Bytes(), signs); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	sign := common.BytesToSign(raw.Sign)
+	if sign == nil {
+		return nil, fmt.Errorf("transaction sign is empty or malformed")
+	}
+	tx := types.NewTransaction(&raw, raw.GenHash())
+	pk, err := sign.RecoverPubkey(tx.Hash.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("recover source fail:%v", err)
+	}
+	if pk.GetAddress() != *raw.Source {
+		return nil, fmt.Errorf("recovered source %v not equal to the given one %v", pk.GetAddress().AddrPrefixString(), raw.Source.AddrPrefixString())
+	}
+	return tx, nil
+}
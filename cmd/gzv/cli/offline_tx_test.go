@@ -0,0 +1,206 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestOfflineTx(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline_tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sk, _ := common.GenerateKey("")
+	pk := sk.GetPubKey()
+	src := pk.GetAddress()
+	other, _ := common.GenerateKey("")
+
+	file := filepath.Join(dir, "tx.json")
+	otx := newOfflineTx(&TxRawData{
+		Source:   src.AddrPrefixString(),
+		Target:   src.AddrPrefixString(),
+		Value:    100,
+		GasLimit: 3000,
+		GasPrice: 500,
+		Nonce:    3,
+	})
+	if err := writeOfflineTx(file, otx); err != nil {
+		t.Fatal(err)
+	}
+
+	otx, err = readOfflineTx(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otx.Signed() {
+		t.Fatal("transaction should not be signed")
+	}
+	if err := otx.sign(&other); err == nil {
+		t.Fatal("expect error signing with the key of another account")
+	}
+	if err := otx.sign(&sk); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeOfflineTx(file, otx); err != nil {
+		t.Fatal(err)
+	}
+
+	otx, err = readOfflineTx(file)
+	if err != nil || !otx.Signed() {
+		t.Fatalf("read signed transaction fail:%v", err)
+	}
+	tx, err := decodeRawTx(common.FromHex(otx.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash.Hex() != otx.Hash || *tx.Source != src || tx.Nonce != 3 || tx.Value.Uint64() != 100 {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+
+	// Tampered transaction can't pass the source recovering
+	tx.Nonce = 4
+	bs, _ := encodeRawTx(tx.RawTransaction)
+	if _, err = decodeRawTx(bs); err == nil {
+		t.Fatal("expect error for tampered transaction")
+	}
+	if _, err = decodeRawTx([]byte("not a transaction")); err == nil {
+		t.Fatal("expect error for malformed transaction")
+	}
+}
+
+func TestOfflineMultiSigTx(t *testing.T) {
+	ms := types.MultiSigAddress(common.StringToAddress("zv11223344"), 1)
+	members := make([]common.PrivateKey, 3)
+	for i := range members {
+		members[i], _ = common.GenerateKey("")
+	}
+	otx := newOfflineTx(&TxRawData{
+		Source:   ms.AddrPrefixString(),
+		Target:   "zv11223344",
+		Value:    100,
+		GasLimit: 3000,
+		GasPrice: 500,
+		TxType:   types.TransactionTypeMultiSigExecute,
+		Nonce:    1,
+	})
+	for i := 0; i < 2; i++ {
+		if err := otx.sign(&members[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := otx.sign(&members[1]); err == nil {
+		t.Fatal("expect error signing twice by the same member")
+	}
+	if len(otx.Signs) != 2 || !otx.Signed() {
+		t.Fatalf("unexpected signs %v", otx.Signs)
+	}
+	tx, err := decodeRawTx(common.FromHex(otx.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash.Hex() != otx.Hash || *tx.Source != ms {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	signs, err := types.DecodeMultiSigSigns(tx.Sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := types.RecoverMultiSigSigners(tx.Hash.Bytes(), signs)
+	if err != nil || len(signers) != 2 || signers[0] != members[0].GetPubKey().GetAddress() || signers[1] != members[1].GetPubKey().GetAddress() {
+		t.Fatalf("unexpected signers %v %v", signers, err)
+	}
+	// The Sign field of the file keeps the envelope and converts back to the same transaction
+	if tx2 := txRawToTransaction(otx.Tx); tx2.Hash != tx.Hash || string(tx2.Sign) != string(tx.Sign) {
+		t.Fatal("converted transaction mismatch")
+	}
+}
+
+func TestBuildTxFillsGas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline_tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	results := map[string]uint64{"Gzv_minGasPrice": 1000, "Gzv_estimateGas": 1234}
+	called := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCReqObj
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		called[req.Method]++
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": results[req.Method]})
+	}))
+	defer server.Close()
+	ca := &RemoteChainOpImpl{base: server.URL}
+
+	cmd := genBuildTxCmd()
+	sk, _ := common.GenerateKey("")
+	pk := sk.GetPubKey()
+	src := pk.GetAddress().AddrPrefixString()
+	if !cmd.parse([]string{"-from", src, "-to", src, "-value", "1", "-nonce", "1", "-file", filepath.Join(dir, "tx.json")}) {
+		t.Fatal("parse fail")
+	}
+	res := ca.BuildTx(cmd.toTxRaw(), cmd.file)
+	if res.Error != nil {
+		t.Fatal(res.Error.Message)
+	}
+	otx, err := readOfflineTx(cmd.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otx.Tx.GasPrice != 1000 || otx.Tx.GasLimit != 1480 {
+		t.Fatalf("expect the gas filled from the chain, got price %v limit %v", otx.Tx.GasPrice, otx.Tx.GasLimit)
+	}
+
+	// The gas given is kept
+	cmd = genBuildTxCmd()
+	if !cmd.parse([]string{"-from", src, "-to", src, "-value", "1", "-nonce", "1", "-gaslimit", "3000", "-gasprice", "500RA", "-file", filepath.Join(dir, "tx2.json")}) {
+		t.Fatal("parse fail")
+	}
+	if res := ca.BuildTx(cmd.toTxRaw(), cmd.file); res.Error != nil {
+		t.Fatal(res.Error.Message)
+	}
+	if otx, err = readOfflineTx(cmd.file); err != nil || otx.Tx.GasPrice != 500 || otx.Tx.GasLimit != 3000 {
+		t.Fatalf("expect the gas given kept, err:%v", err)
+	}
+	if called["Gzv_minGasPrice"] != 1 || called["Gzv_estimateGas"] != 1 {
+		t.Fatalf("unexpected requests %v", called)
+	}
+}
+
+func TestGasLimitOfEstimated(t *testing.T) {
+	if limit := gasLimitOfEstimated(10000); limit != 12000 {
+		t.Fatalf("expect the headroom added, got %v", limit)
+	}
+	if limit := gasLimitOfEstimated(core.GasLimitPerTransaction - 1); limit != core.GasLimitPerTransaction {
+		t.Fatalf("expect capped at the max gas limit, got %v", limit)
+	}
+}
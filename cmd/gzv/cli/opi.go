@@ -65,7 +65,12 @@ func txRawToTransaction(tx *TxRawData) *types.Transaction {
 	src := common.StringToAddress(tx.Source)
 	var sign []byte
 	if tx.Sign != "" {
-		sign = common.HexToSign(tx.Sign).Bytes()
+		if tx.TxType == types.TransactionTypeMultiSigExecute {
+			// The sign is the envelope of the member signs
+			sign = common.FromHex(tx.Sign)
+		} else {
+			sign = common.HexToSign(tx.Sign).Bytes()
+		}
 	}
 
 	raw := &types.RawTransaction{
@@ -100,6 +105,16 @@ type accountOp interface {
 
 	ExportKey(addr string) (string, error)
 
+	NewHDAccount(password string, miner bool) (*HDAccount, error)
+
+	NewAccountByMnemonic(mnemonic string, passphrase string, password string, miner bool) (string, error)
+
+	DeriveAccount(index uint32, password string, miner bool) (*HDAccount, error)
+
+	ImportKeyStore(file string, password string) (string, error)
+
+	ExportKeyStore(addr string, password string, file string) (string, error)
+
 	Close()
 }
 
@@ -143,6 +158,24 @@ type chainOp interface {
 
 	TxInfo(hash string) *RPCResObjCmd
 
+	// SpeedUp resends the pending transaction with a higher gas price
+	SpeedUp(hash string, gasPrice uint64) *RPCResObjCmd
+
+	// Cancel replaces the pending transaction with a zero value transfer to the sender itself
+	Cancel(hash string, gasPrice uint64) *RPCResObjCmd
+
+	// BuildTx fills the nonce of the unsigned transaction from the chain and writes it to the file for offline signing
+	BuildTx(tx *TxRawData, file string) *RPCResObjCmd
+
+	// SendRawTransaction submits the hex of the encoded signed transaction
+	SendRawTransaction(raw string) *RPCResObjCmd
+
+	// MultiSigCreate creates a multisig account controlled by the members with the threshold
+	MultiSigCreate(threshold int, members []string, value, gas, gasprice uint64) *RPCResObjCmd
+
+	// MultiSigAccount queries the config and state of the multisig account
+	MultiSigAccount(addr string) *RPCResObjCmd
+
 	BlockByHash(hash string) *RPCResObjCmd
 
 	BlockByHeight(h uint64) *RPCResObjCmd
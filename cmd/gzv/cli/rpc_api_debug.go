@@ -0,0 +1,75 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/core"
+)
+
+// RpcDebugImpl provides api functions for tracing the executed transactions.
+// Tracing re-executes the blocks, so it's only enabled with the dev rpc level
+type RpcDebugImpl struct {
+	*rpcBaseImpl
+}
+
+func (api *RpcDebugImpl) Namespace() string {
+	return "Debug"
+}
+
+func (api *RpcDebugImpl) Version() string {
+	return "1"
+}
+
+func convertTxTrace(t *core.TxTrace) *TxTrace {
+	return &TxTrace{
+		Hash:    t.TxHash,
+		Type:    t.Type,
+		Status:  int(t.Status),
+		GasUsed: t.GasUsed,
+		Error:   t.Error,
+		Call:    t.Call,
+	}
+}
+
+// TraceTransaction re-executes the transaction in its block and returns the trace
+func (api *RpcDebugImpl) TraceTransaction(hash string) (*TxTrace, error) {
+	hash = strings.TrimSpace(hash)
+	if !validateHash(hash) {
+		return nil, fmt.Errorf("wrong hash format")
+	}
+	trace, err := core.BlockChainImpl.TraceTransaction(common.HexToHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	return convertTxTrace(trace), nil
+}
+
+// TraceBlock re-executes the block of the height and returns the traces of all transactions
+func (api *RpcDebugImpl) TraceBlock(height uint64) ([]*TxTrace, error) {
+	traces, err := core.BlockChainImpl.TraceBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*TxTrace, 0, len(traces))
+	for _, t := range traces {
+		ret = append(ret, convertTxTrace(t))
+	}
+	return ret, nil
+}
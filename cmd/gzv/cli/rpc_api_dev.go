@@ -21,6 +21,7 @@ import (
 	"math"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/pmylund/sortutil"
 	"github.com/zvchain/zvchain/common"
@@ -61,21 +62,142 @@ func (api *RpcDevImpl) ProposalTotalStake(height uint64) (uint64, error) {
 
 // ConnectedNodes query the information of the connected node
 func (api *RpcDevImpl) ConnectedNodes() ([]ConnInfo, error) {
-
-	nodes := network.GetNetInstance().ConnInfo()
 	conns := make([]ConnInfo, 0)
+	// Network isn't started on the dev chain
+	if network.GetNetInstance() == nil {
+		return conns, nil
+	}
+	nodes := network.GetNetInstance().ConnInfo()
 	for _, n := range nodes {
 		conns = append(conns, ConnInfo{ID: n.ID, IP: n.IP, TCPPort: n.Port})
 	}
 	return conns, nil
 }
 
+// PeerScores returns the reputation of the peers tracked and the peers banned, the lowest score first
+func (api *RpcDevImpl) PeerScores() ([]*PeerScore, error) {
+	scores, err := network.PeerScores()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*PeerScore, 0, len(scores))
+	for _, s := range scores {
+		ps := &PeerScore{
+			ID:        s.ID,
+			Score:     math.Round(s.Score*100) / 100,
+			Banned:    s.Banned,
+			Permanent: s.Permanent,
+			BanCount:  s.BanCount,
+			Reason:    s.Reason,
+		}
+		if !s.BannedUntil.IsZero() {
+			ps.BannedUntil = s.BannedUntil.Unix()
+		}
+		result = append(result, ps)
+	}
+	return result, nil
+}
+
+// BanPeer bans the peer for the seconds given, or permanently if 0, and disconnects it.
+// The ban is persisted across restarts
+func (api *RpcDevImpl) BanPeer(id string, seconds uint64, reason string) (bool, error) {
+	if err := network.BanPeer(strings.TrimSpace(id), time.Duration(seconds)*time.Second, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer removes the ban and resets the score of the peer
+func (api *RpcDevImpl) UnbanPeer(id string) (bool, error) {
+	if err := network.UnbanPeer(strings.TrimSpace(id)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddPeer adds the peer in the form of id@host:port as a static peer, which is trusted and always
+// reconnected. The peer given in the form of id is trusted only
+func (api *RpcDevImpl) AddPeer(peer string) (bool, error) {
+	if err := network.AddPeer(strings.TrimSpace(peer)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeer removes the static or trusted peer and disconnects it
+func (api *RpcDevImpl) RemovePeer(peer string) (bool, error) {
+	if err := network.RemovePeer(strings.TrimSpace(peer)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StaticPeers returns the static and trusted peers
+func (api *RpcDevImpl) StaticPeers() ([]*StaticPeer, error) {
+	peers := network.StaticPeers()
+	result := make([]*StaticPeer, 0, len(peers))
+	for _, p := range peers {
+		result = append(result, &StaticPeer{ID: p.ID, IP: p.IP, Port: p.Port, Connected: p.Connected})
+	}
+	return result, nil
+}
+
 // TransPool query buffer transaction information
 func (api *RpcDevImpl) TransPool() ([]*types.Transaction, error) {
 	transactions := core.BlockChainImpl.GetTransactionPool().GetReceived()
 	return transactions, nil
 }
 
+func convertTransactions(txs []*types.Transaction) []*Transaction {
+	ret := make([]*Transaction, 0, len(txs))
+	for _, tx := range txs {
+		ret = append(ret, convertTransaction(tx))
+	}
+	return ret
+}
+
+// TxPoolContent returns the pending, queued and reward transactions of the account in the pool
+// and the nonce gaps blocking the queued ones
+func (api *RpcDevImpl) TxPoolContent(addr string) (*TxPoolContent, error) {
+	addr = strings.TrimSpace(addr)
+	if !common.ValidateAddress(addr) {
+		return nil, fmt.Errorf("wrong account address format")
+	}
+	c := core.BlockChainImpl.TxPoolContent(common.StringToAddress(addr))
+	return &TxPoolContent{
+		Address:      addr,
+		StateNonce:   c.StateNonce,
+		PendingNonce: c.PendingNonce,
+		Pending:      convertTransactions(c.Pending),
+		Queued:       convertTransactions(c.Queued),
+		Rewards:      convertTransactions(c.Rewards),
+		NonceGaps:    c.NonceGaps,
+	}, nil
+}
+
+// TxPoolStatus returns the transaction count of the pool broken down by account
+func (api *RpcDevImpl) TxPoolStatus() (*TxPoolStatus, error) {
+	s := core.BlockChainImpl.TxPoolStatus()
+	ret := &TxPoolStatus{
+		Pending: s.Pending,
+		Queued:  s.Queued,
+		Rewards: s.Rewards,
+		Sources: make([]*TxPoolSourceStatus, 0, len(s.Sources)),
+	}
+	for _, src := range s.Sources {
+		ret.Sources = append(ret.Sources, &TxPoolSourceStatus{
+			Address:      src.Source.AddrPrefixString(),
+			StateNonce:   src.StateNonce,
+			PendingNonce: src.PendingNonce,
+			Pending:      src.Pending,
+			Queued:       src.Queued,
+			Rewards:      src.Rewards,
+			NonceGaps:    src.NonceGaps,
+		})
+	}
+	return ret, nil
+}
+
 func (api *RpcDevImpl) BalanceByHeight(height uint64, account string) (float64, error) {
 	if !common.ValidateAddress(strings.TrimSpace(account)) {
 		return 0, fmt.Errorf("wrong account address format")
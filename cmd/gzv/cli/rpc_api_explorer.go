@@ -146,3 +146,132 @@ func (api *RpcExplorerImpl) ExplorerGetCandidates() (*[]ExploreCandidateList, er
 	}
 	return &candidateLists, nil
 }
+
+// VerifyContract verifies the source against the code deployed at the address, and stores the source and abi
+// if matched, so that the calls and logs of the contract can be decoded by the explorers and wallets
+func (api *RpcExplorerImpl) VerifyContract(address string, source string) (*VerifiedContract, error) {
+	if !common.ValidateAddress(strings.TrimSpace(address)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	if source == "" {
+		return nil, fmt.Errorf("empty source")
+	}
+	vc, err := core.BlockChainImpl.VerifyContract(common.StringToAddress(strings.TrimSpace(address)), source)
+	if err != nil {
+		return nil, err
+	}
+	return convertVerifiedContract(vc), nil
+}
+
+// ContractABI returns the abi and source of the contract verified
+func (api *RpcExplorerImpl) ContractABI(address string) (*VerifiedContract, error) {
+	if !common.ValidateAddress(strings.TrimSpace(address)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	vc := core.BlockChainImpl.GetVerifiedContract(common.StringToAddress(strings.TrimSpace(address)))
+	if vc == nil {
+		return nil, fmt.Errorf("contract not verified")
+	}
+	return convertVerifiedContract(vc), nil
+}
+
+func convertVerifiedContract(vc *core.VerifiedContract) *VerifiedContract {
+	return &VerifiedContract{
+		Address:    vc.Address.AddrPrefixString(),
+		Name:       vc.Name,
+		Source:     vc.Source,
+		ABI:        vc.ABI,
+		CodeHash:   vc.CodeHash.Hex(),
+		TVMVersion: vc.TVMVersion,
+		Height:     vc.Height,
+	}
+}
+
+// TokenBalances returns the balances of the holder in the token contracts, indexed from the Transfer events
+func (api *RpcExplorerImpl) TokenBalances(address string) ([]*TokenBalance, error) {
+	if !common.ValidateAddress(strings.TrimSpace(address)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	balances, err := core.BlockChainImpl.TokenBalances(common.StringToAddress(strings.TrimSpace(address)))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*TokenBalance, 0, len(balances))
+	for _, b := range balances {
+		ret = append(ret, &TokenBalance{Token: b.Token.AddrPrefixString(), Balance: b.Balance})
+	}
+	return ret, nil
+}
+
+// TokenTransfers returns the token transfers from or to the address from the newest, page starts from 0
+func (api *RpcExplorerImpl) TokenTransfers(address string, page int) ([]*TokenTransfer, error) {
+	if !common.ValidateAddress(strings.TrimSpace(address)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	transfers, err := core.BlockChainImpl.TokenTransfers(common.StringToAddress(strings.TrimSpace(address)), page)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*TokenTransfer, 0, len(transfers))
+	for _, tt := range transfers {
+		ret = append(ret, convertTokenTransfer(tt))
+	}
+	return ret, nil
+}
+
+// AddressTransactions returns the transactions sent, received or rewarded by the address from the newest.
+// The cursor is empty for the first page and the next_cursor returned for the following pages.
+// categories filters the transaction types: transfer, contract, stake, reward and other, all if empty
+func (api *RpcExplorerImpl) AddressTransactions(address string, cursor string, limit int, categories []string) (*AddressTransactions, error) {
+	if !common.ValidateAddress(strings.TrimSpace(address)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	txs, next, err := core.BlockChainImpl.AddressTransactions(common.StringToAddress(strings.TrimSpace(address)), cursor, limit, categories)
+	if err != nil {
+		return nil, err
+	}
+	ret := &AddressTransactions{Transactions: make([]*AddressTransaction, 0, len(txs)), NextCursor: next}
+	for _, tx := range txs {
+		ret.Transactions = append(ret.Transactions, convertAddressTx(tx))
+	}
+	return ret, nil
+}
+
+func convertAddressTx(tx *core.AddressTx) *AddressTransaction {
+	roles := make([]string, 0)
+	if tx.Roles&core.AddressRoleSender != 0 {
+		roles = append(roles, "sender")
+	}
+	if tx.Roles&core.AddressRoleReceiver != 0 {
+		roles = append(roles, "receiver")
+	}
+	if tx.Roles&core.AddressRoleRewarded != 0 {
+		roles = append(roles, "rewarded")
+	}
+	return &AddressTransaction{
+		Hash:     tx.TxHash.Hex(),
+		Height:   tx.Height,
+		TxIndex:  tx.TxIndex,
+		Type:     tx.Type,
+		Category: tx.Category,
+		Roles:    roles,
+	}
+}
+
+func convertTokenTransfer(tt *core.TokenTransfer) *TokenTransfer {
+	ret := &TokenTransfer{
+		Token:    tt.Token.AddrPrefixString(),
+		Value:    tt.Value,
+		TxHash:   tt.TxHash.Hex(),
+		Height:   tt.Height,
+		TxIndex:  tt.TxIndex,
+		LogIndex: tt.LogIndex,
+	}
+	if tt.From != (common.Address{}) {
+		ret.From = tt.From.AddrPrefixString()
+	}
+	if tt.To != (common.Address{}) {
+		ret.To = tt.To.AddrPrefixString()
+	}
+	return ret
+}
@@ -62,6 +62,8 @@ type rpcBaseImpl struct {
 type RpcGzvImpl struct {
 	*rpcBaseImpl
 	routineChecker groupRoutineChecker
+	events         *eventSystem
+	filters        *filterManager
 }
 
 func (api *RpcGzvImpl) Namespace() string {
@@ -89,7 +91,7 @@ func (api *RpcGzvImpl) Tx(txRaw *TxRawData) (string, error) {
 	switch txRaw.TxType {
 	case types.TransactionTypeTransfer, types.TransactionTypeContractCall, types.TransactionTypeStakeAdd,
 		types.TransactionTypeStakeReduce,
-		types.TransactionTypeStakeRefund, types.TransactionTypeVoteMinerPool:
+		types.TransactionTypeStakeRefund, types.TransactionTypeVoteMinerPool, types.TransactionTypeMultiSigExecute:
 		if !common.ValidateAddress(strings.TrimSpace(txRaw.Target)) {
 			return "", fmt.Errorf("wrong target address format")
 		}
@@ -107,6 +109,86 @@ func (api *RpcGzvImpl) Tx(txRaw *TxRawData) (string, error) {
 	return trans.Hash.Hex(), nil
 }
 
+// SendRawTransaction submits the hex of the msgpack encoded signed transaction, which is usually signed
+// offline. The source is recovered from the sign and checked against the one in the transaction
+func (api *RpcGzvImpl) SendRawTransaction(raw string) (string, error) {
+	trans, err := decodeRawTx(common.FromHex(strings.TrimSpace(raw)))
+	if err != nil {
+		return "", err
+	}
+	if err := sendTransaction(trans); err != nil {
+		return "", err
+	}
+	return trans.Hash.Hex(), nil
+}
+
+// SendMultiSigTransaction submits the multisig execute transaction with the member signs collected
+// separately, each of which signs the hash of the transaction
+func (api *RpcGzvImpl) SendMultiSigTransaction(txRaw *TxRawData, signs []string) (string, error) {
+	if txRaw.TxType != types.TransactionTypeMultiSigExecute {
+		return "", fmt.Errorf("not a multisig execute transaction")
+	}
+	if !common.ValidateAddress(txRaw.Source) {
+		return "", fmt.Errorf("wrong source address")
+	}
+	if !common.ValidateAddress(strings.TrimSpace(txRaw.Target)) {
+		return "", fmt.Errorf("wrong target address format")
+	}
+	if len(signs) == 0 || len(signs) > types.MaxMultiSigMembers {
+		return "", fmt.Errorf("sign count should be in [1, %v]", types.MaxMultiSigMembers)
+	}
+	bs := make([][]byte, 0, len(signs))
+	for _, s := range signs {
+		sign := common.HexToSign(strings.TrimSpace(s))
+		if sign == nil {
+			return "", fmt.Errorf("wrong sign format %v", s)
+		}
+		bs = append(bs, sign.Bytes())
+	}
+	envelope, err := types.EncodeMultiSigSigns(bs)
+	if err != nil {
+		return "", err
+	}
+	txRaw.Sign = ""
+	trans := txRawToTransaction(txRaw)
+	trans.Sign = envelope
+	if err := sendTransaction(trans); err != nil {
+		return "", err
+	}
+	return trans.Hash.Hex(), nil
+}
+
+// MultiSigAccount returns the threshold, members and state of the multisig account
+func (api *RpcGzvImpl) MultiSigAccount(addr string) (*MultiSigAccount, error) {
+	addr = strings.TrimSpace(addr)
+	if !common.ValidateAddress(addr) {
+		return nil, fmt.Errorf("wrong address format")
+	}
+	db, err := core.BlockChainImpl.LatestAccountDB()
+	if err != nil {
+		return nil, err
+	}
+	address := common.StringToAddress(addr)
+	c, err := core.GetMultiSigConfig(db, address)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%v is not a multisig account", addr)
+	}
+	members := make([]string, 0, len(c.Members))
+	for _, m := range c.Members {
+		members = append(members, m.AddrPrefixString())
+	}
+	return &MultiSigAccount{
+		Address:   addr,
+		Threshold: c.Threshold,
+		Members:   members,
+		Balance:   common.RA2TAS(db.GetBalance(address).Uint64()),
+		Nonce:     db.GetNonce(address),
+	}, nil
+}
+
 // Balance is query balance interface
 func (api *RpcGzvImpl) Balance(account string) (float64, error) {
 	account = strings.TrimSpace(account)
@@ -136,15 +218,7 @@ func (api *RpcGzvImpl) GetBlockByHeight(height uint64) (*Block, error) {
 	if b == nil {
 		return nil, nil
 	}
-	bh := b.Header
-	preBH := core.BlockChainImpl.QueryBlockHeaderByHash(bh.PreHash)
-	block := convertBlockHeader(b)
-	if preBH != nil {
-		block.Qn = bh.TotalQN - preBH.TotalQN
-	} else {
-		block.Qn = bh.TotalQN
-	}
-	return block, nil
+	return convertBlockWithQN(b), nil
 }
 
 func (api *RpcGzvImpl) GetBlockByHash(hash string) (*Block, error) {
@@ -156,15 +230,7 @@ func (api *RpcGzvImpl) GetBlockByHash(hash string) (*Block, error) {
 	if b == nil {
 		return nil, nil
 	}
-	bh := b.Header
-	preBH := core.BlockChainImpl.QueryBlockHeaderByHash(bh.PreHash)
-	block := convertBlockHeader(b)
-	if preBH != nil {
-		block.Qn = bh.TotalQN - preBH.TotalQN
-	} else {
-		block.Qn = bh.TotalQN
-	}
-	return block, nil
+	return convertBlockWithQN(b), nil
 }
 
 func (api *RpcGzvImpl) GetTxsByBlockHash(hash string) ([]string, error) {
@@ -337,6 +403,20 @@ func (api *RpcGzvImpl) TransDetail(h string) (*Transaction, error) {
 	return nil, nil
 }
 
+// PendingTransaction returns the transaction waiting in the pool by hash, returns nil if
+// the transaction not in the pool. The result can be used to build a replacement of the transaction
+func (api *RpcGzvImpl) PendingTransaction(h string) (*TxRawData, error) {
+	h = strings.TrimSpace(h)
+	if !validateHash(h) {
+		return nil, fmt.Errorf("wrong hash format")
+	}
+	tx := core.BlockChainImpl.GetTransactionPool().GetTransaction(false, common.HexToHash(h))
+	if tx == nil || tx.IsReward() {
+		return nil, nil
+	}
+	return convertTxRawData(tx), nil
+}
+
 func (api *RpcGzvImpl) Nonce(addr string) (uint64, error) {
 	addr = strings.TrimSpace(addr)
 	if !common.ValidateAddress(addr) {
@@ -348,6 +428,21 @@ func (api *RpcGzvImpl) Nonce(addr string) (uint64, error) {
 	return nonce, nil
 }
 
+// PendingNonce returns the nonce to use for the next transaction of the account, which takes the
+// transactions already in the pool into account so that several transactions can be sent in a row
+func (api *RpcGzvImpl) PendingNonce(addr string) (uint64, error) {
+	addr = strings.TrimSpace(addr)
+	if !common.ValidateAddress(addr) {
+		return 0, fmt.Errorf("wrong account address format")
+	}
+	return core.BlockChainImpl.PendingNonce(common.StringToAddress(addr)), nil
+}
+
+// MinGasPrice returns the min gas price of the transactions packed in the next block
+func (api *RpcGzvImpl) MinGasPrice() (uint64, error) {
+	return core.MinGasPrice(core.BlockChainImpl.Height() + 1), nil
+}
+
 func (api *RpcGzvImpl) TxReceipt(h string) (*ExecutedTransaction, error) {
 	h = strings.TrimSpace(h)
 	if !validateHash(h) {
@@ -0,0 +1,95 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func convertCallResult(cr *core.CallResult) *CallResult {
+	ret := &CallResult{
+		Result:  cr.Result,
+		Logs:    cr.Logs,
+		GasUsed: cr.GasUsed,
+		Status:  int(cr.Status),
+	}
+	if cr.Error != nil {
+		ret.ErrorCode = cr.Error.Code
+		ret.ErrorMessage = cr.Error.Message
+	} else {
+		ret.ErrorMessage = cr.Message
+	}
+	return ret
+}
+
+// Call executes the contract function described by the abiJSON on the state of the given height
+// without submitting a transaction. The latest state is used if height not specified
+func (api *RpcGzvImpl) Call(from, to, abiJSON string, height *uint64) (*CallResult, error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !common.ValidateAddress(from) {
+		return nil, fmt.Errorf("wrong source address format")
+	}
+	if !common.ValidateAddress(to) {
+		return nil, fmt.Errorf("wrong contract address format")
+	}
+	h := core.BlockChainImpl.Height()
+	if height != nil && *height < h {
+		h = *height
+	}
+	source, target := common.StringToAddress(from), common.StringToAddress(to)
+	raw := &types.RawTransaction{
+		Type:   types.TransactionTypeContractCall,
+		Data:   []byte(abiJSON),
+		Source: &source,
+		Target: &target,
+	}
+	cr, err := core.BlockChainImpl.SimulateTransaction(&types.Transaction{RawTransaction: raw}, h)
+	if err != nil {
+		return nil, err
+	}
+	return convertCallResult(cr), nil
+}
+
+// EstimateGas executes the transaction on the latest state and returns the gas used.
+// The nonce and sign of the transaction are not required
+func (api *RpcGzvImpl) EstimateGas(txRaw *TxRawData) (uint64, error) {
+	if txRaw == nil {
+		return 0, fmt.Errorf("transaction is nil")
+	}
+	if !validateTxType(txRaw.TxType) {
+		return 0, fmt.Errorf("not supported txType")
+	}
+	if !common.ValidateAddress(strings.TrimSpace(txRaw.Source)) {
+		return 0, fmt.Errorf("wrong source address")
+	}
+	if txRaw.Target != "" && !common.ValidateAddress(strings.TrimSpace(txRaw.Target)) {
+		return 0, fmt.Errorf("wrong target address format")
+	}
+	cr, err := core.BlockChainImpl.SimulateTransaction(txRawToTransaction(txRaw), core.BlockChainImpl.Height())
+	if err != nil {
+		return 0, err
+	}
+	if cr.Status != types.RSSuccess {
+		ret := convertCallResult(cr)
+		return 0, fmt.Errorf("execution failed, status:%v, code:%v, message:%v", ret.Status, ret.ErrorCode, ret.ErrorMessage)
+	}
+	return cr.GasUsed, nil
+}
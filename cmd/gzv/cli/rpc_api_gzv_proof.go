@@ -0,0 +1,92 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/core"
+)
+
+// maxProofKeys is the max number of storage keys proved in a getProof request
+const maxProofKeys = 100
+
+func encodeProof(proof [][]byte) []string {
+	ret := make([]string, 0, len(proof))
+	for _, node := range proof {
+		ret = append(ret, common.ToHex(node))
+	}
+	return ret
+}
+
+func convertAccountProof(addr string, p *core.AccountProof) *AccountProof {
+	ret := &AccountProof{
+		Height:        p.Header.Height,
+		BlockHash:     p.Header.Hash,
+		StateRoot:     p.Header.StateTree,
+		Address:       addr,
+		Balance:       new(big.Int),
+		AccountProof:  encodeProof(p.Proof),
+		StorageProofs: make([]*StorageProof, 0, len(p.StorageProofs)),
+	}
+	if p.Account != nil {
+		ret.Exist = true
+		ret.Balance = p.Account.Balance
+		ret.Nonce = p.Account.Nonce
+		ret.StorageRoot = p.Account.Root
+		ret.CodeHash = common.ToHex(p.Account.CodeHash)
+	}
+	for _, sp := range p.StorageProofs {
+		sv := &StorageProof{
+			Key:   string(sp.Key),
+			Proof: encodeProof(sp.Proof),
+		}
+		if sp.Value != nil {
+			sv.Value = common.ToHex(sp.Value)
+		}
+		ret.StorageProofs = append(ret.StorageProofs, sv)
+	}
+	return ret
+}
+
+// GetProof returns the merkle proofs of the account and the given storage keys against the state root
+// of the block at the given height, so that they can be verified with a trusted header such as the latest
+// checkpoint. The latest state is used if height not specified
+func (api *RpcGzvImpl) GetProof(address string, keys []string, height *uint64) (*AccountProof, error) {
+	address = strings.TrimSpace(address)
+	if !common.ValidateAddress(address) {
+		return nil, fmt.Errorf("wrong address format")
+	}
+	if len(keys) > maxProofKeys {
+		return nil, fmt.Errorf("too many keys, max %v", maxProofKeys)
+	}
+	h := core.BlockChainImpl.Height()
+	if height != nil && *height < h {
+		h = *height
+	}
+	storageKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		storageKeys = append(storageKeys, []byte(key))
+	}
+	p, err := core.BlockChainImpl.GetProof(common.StringToAddress(address), storageKeys, h)
+	if err != nil {
+		return nil, err
+	}
+	return convertAccountProof(address, p), nil
+}
@@ -0,0 +1,55 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+)
+
+var errEventsUnavailable = fmt.Errorf("subscription is not available")
+
+// NewHeads sends a notification each time a new block is appended to the chain,
+// and a notification with removed set for each block reverted in a chain reorganization
+func (api *RpcGzvImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	if api.events == nil {
+		return &rpc.Subscription{}, errEventsUnavailable
+	}
+	return api.events.subscribe(ctx, newHeadsSubscription, nil)
+}
+
+// NewPendingTransactions sends the hash of each transaction admitted by the transaction pool
+func (api *RpcGzvImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	if api.events == nil {
+		return &rpc.Subscription{}, errEventsUnavailable
+	}
+	return api.events.subscribe(ctx, pendingTxsSubscription, nil)
+}
+
+// Logs sends the contract logs matching the given criteria in the new blocks. Logs in the
+// reverted blocks are sent again with removed set
+func (api *RpcGzvImpl) Logs(ctx context.Context, crit *LogFilterCriteria) (*rpc.Subscription, error) {
+	if api.events == nil {
+		return &rpc.Subscription{}, errEventsUnavailable
+	}
+	filter, err := newLogFilter(crit)
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+	return api.events.subscribe(ctx, logsSubscription, filter)
+}
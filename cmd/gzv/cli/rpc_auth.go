@@ -0,0 +1,90 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/log"
+)
+
+const (
+	rpcAuthSection      = "rpc_auth"
+	rpcKeySectionPrefix = "rpc_key_"
+
+	// Transaction submissions are audited by default
+	defaultAuditMethods = "Gzv_tx,Gzv_sendRawTransaction"
+)
+
+// loadRPCAuthenticator creates the rpc authenticator from the config, returns nil if the
+// authentication is not enabled. The /metrics of the rpc listener requires the "metrics"
+// permission once enabled. The config looks like:
+//
+//	[rpc_auth]
+//	enable = true
+//	keys = partner_a,partner_b
+//	audit_methods = Gzv_tx,Gzv_sendRawTransaction
+//
+//	[rpc_key_partner_a]
+//	token = <static api key>
+//	jwt_secret = <secret of the HS256 bearer tokens whose subject is partner_a>
+//	permissions = Gzv,Explorer_explorerBlockDetail,metrics
+//	rate = 10
+//	burst = 20
+//	concurrency = 4
+func loadRPCAuthenticator(conf common.ConfManager) (*rpc.Authenticator, error) {
+	if conf == nil || !conf.GetBool(rpcAuthSection, "enable", false) {
+		return nil, nil
+	}
+	names := splitConfList(conf.GetString(rpcAuthSection, "keys", ""))
+	if len(names) == 0 {
+		return nil, fmt.Errorf("rpc auth enabled without any keys")
+	}
+	keys := make([]*rpc.APIKey, 0, len(names))
+	for _, name := range names {
+		sec := conf.GetSectionManager(rpcKeySectionPrefix + name)
+		key := &rpc.APIKey{
+			Name:          name,
+			Token:         sec.GetString("token", ""),
+			Permissions:   splitConfList(sec.GetString("permissions", "")),
+			Rate:          sec.GetDouble("rate", 0),
+			Burst:         sec.GetInt("burst", 0),
+			MaxConcurrent: sec.GetInt("concurrency", 0),
+		}
+		if secret := sec.GetString("jwt_secret", ""); secret != "" {
+			key.JWTSecret = []byte(secret)
+		}
+		if len(key.Permissions) == 0 {
+			return nil, fmt.Errorf("no permissions configured for rpc key %v", name)
+		}
+		keys = append(keys, key)
+	}
+	auditMethods := splitConfList(conf.GetString(rpcAuthSection, "audit_methods", defaultAuditMethods))
+	return rpc.NewAuthenticator(keys, auditMethods, log.RPCAuditLogger)
+}
+
+func splitConfList(s string) []string {
+	ret := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
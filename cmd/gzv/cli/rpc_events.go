@@ -0,0 +1,389 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/log"
+	"github.com/zvchain/zvchain/middleware/notify"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// subscriberBufferSize is the max number of events cached for a subscriber,
+// events will be dropped if the client can't consume them in time
+const subscriberBufferSize = 2048
+
+// maxHeadRecords is the max number of heads kept to notify them removed when the chain reorganized
+const maxHeadRecords = 256
+
+type subscriptionType int
+
+const (
+	newHeadsSubscription subscriptionType = iota
+	pendingTxsSubscription
+	logsSubscription
+)
+
+// LogFilterCriteria defines the conditions for filtering contract logs.
+// Empty addresses or topics means matching any value of the field
+type LogFilterCriteria struct {
+	Addresses []string `json:"addresses"`
+	Topics    []string `json:"topics"`
+}
+
+// logFilter is the parsed LogFilterCriteria
+type logFilter struct {
+	addresses []common.Address
+	topics    []common.Hash
+}
+
+func newLogFilter(crit *LogFilterCriteria) (*logFilter, error) {
+	f := &logFilter{
+		addresses: make([]common.Address, 0),
+		topics:    make([]common.Hash, 0),
+	}
+	if crit == nil {
+		return f, nil
+	}
+	for _, addr := range crit.Addresses {
+		addr = strings.TrimSpace(addr)
+		if !common.ValidateAddress(addr) {
+			return nil, fmt.Errorf("wrong address format:%v", addr)
+		}
+		f.addresses = append(f.addresses, common.StringToAddress(addr))
+	}
+	for _, topic := range crit.Topics {
+		topic = strings.TrimSpace(topic)
+		if !validateHash(topic) {
+			return nil, fmt.Errorf("wrong topic format:%v", topic)
+		}
+		f.topics = append(f.topics, common.HexToHash(topic))
+	}
+	return f, nil
+}
+
+func (f *logFilter) match(l *types.Log) bool {
+	if len(f.addresses) > 0 {
+		found := false
+		for _, addr := range f.addresses {
+			if addr == l.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.topics) > 0 {
+		found := false
+		for _, topic := range f.topics {
+			if topic == l.Topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *logFilter) filterLogs(logs []*types.Log) []*types.Log {
+	ret := make([]*types.Log, 0)
+	for _, l := range logs {
+		if f.match(l) {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+type subscriber struct {
+	id     rpc.ID
+	typ    subscriptionType
+	filter *logFilter
+	events chan interface{}
+}
+
+// headChain is the chain followed by the event system
+type headChain interface {
+	QueryTopBlock() *types.BlockHeader
+	QueryBlockHeaderByHash(hash common.Hash) *types.BlockHeader
+	QueryBlockHeaderByHeight(height uint64) *types.BlockHeader
+	QueryBlockHeaderCeil(height uint64) *types.BlockHeader
+	QueryBlockByHash(hash common.Hash) *types.Block
+}
+
+// headRecord is a head sent with its logs. The receipts of the removed blocks are deleted,
+// so the logs are kept to notify them removed
+type headRecord struct {
+	block *types.Block
+	logs  []*types.Log
+}
+
+// eventSystem receives the chain events from the notify bus and dispatches them
+// to the rpc subscribers. The blocks added and removed are notified asynchronously and
+// may arrive out of order, so the heads are followed from the last one sent: the
+// notifications only wake up the sync routine, which removes the heads until it's on
+// the chain again and then sends the blocks after it
+type eventSystem struct {
+	subscribers map[rpc.ID]*subscriber
+	lock        sync.RWMutex
+
+	chain    headChain
+	receipts func(b *types.Block) types.Receipts
+	heads    []*headRecord // The heads sent, the last one is the current head
+	signal   chan struct{}
+}
+
+func newEventSystem() *eventSystem {
+	es := &eventSystem{
+		subscribers: make(map[rpc.ID]*subscriber),
+		receipts:    blockReceipts,
+		signal:      make(chan struct{}, 1),
+	}
+	if core.BlockChainImpl != nil {
+		es.chain = core.BlockChainImpl
+		es.anchor(es.chain.QueryTopBlock())
+	}
+	notify.BUS.Subscribe(notify.BlockAddSucc, es.onBlockChanged)
+	notify.BUS.Subscribe(notify.BlockRemoved, es.onBlockChanged)
+	notify.BUS.Subscribe(notify.NewTopBlock, es.onBlockChanged)
+	notify.BUS.Subscribe(notify.TransactionAdded, es.onTransactionAdded)
+	go es.loop()
+	return es
+}
+
+// subscribe creates a subscription on the connection of the given context and pushes the
+// events of the given type to the client until the client unsubscribes or the connection closed
+func (es *eventSystem) subscribe(ctx context.Context, typ subscriptionType, filter *logFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	sub := es.install(rpcSub.ID, typ, filter)
+
+	go func() {
+		defer es.uninstall(sub.id)
+		for {
+			select {
+			case ev := <-sub.events:
+				if err := notifier.Notify(rpcSub.ID, ev); err != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// install registers a subscriber receiving the events of the given type
+func (es *eventSystem) install(id rpc.ID, typ subscriptionType, filter *logFilter) *subscriber {
+	sub := &subscriber{
+		id:     id,
+		typ:    typ,
+		filter: filter,
+		events: make(chan interface{}, subscriberBufferSize),
+	}
+	es.lock.Lock()
+	es.subscribers[id] = sub
+	es.lock.Unlock()
+	return sub
+}
+
+// uninstall removes the subscriber of the given id
+func (es *eventSystem) uninstall(id rpc.ID) {
+	es.lock.Lock()
+	delete(es.subscribers, id)
+	es.lock.Unlock()
+}
+
+func (es *eventSystem) dispatch(typ subscriptionType, gen func(sub *subscriber) []interface{}) {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+	for id, sub := range es.subscribers {
+		if sub.typ != typ {
+			continue
+		}
+		for _, ev := range gen(sub) {
+			select {
+			case sub.events <- ev:
+			default:
+				log.DefaultLogger.Warnf("subscription %v event buffer is full, event dropped", id)
+			}
+		}
+	}
+}
+
+func (es *eventSystem) dispatchHead(b *types.Block, removed bool) {
+	var ev *HeadEvent
+	es.dispatch(newHeadsSubscription, func(sub *subscriber) []interface{} {
+		if ev == nil {
+			ev = &HeadEvent{Block: convertBlockWithQN(b), Removed: removed}
+		}
+		return []interface{}{ev}
+	})
+}
+
+// dispatchLogs dispatches the copies of the logs, as the logs are shared by the other
+// subscribers of the receipts and kept by the head records
+func (es *eventSystem) dispatchLogs(logs []*types.Log, removed bool) {
+	if len(logs) == 0 {
+		return
+	}
+	copies := make([]*types.Log, 0, len(logs))
+	for _, l := range logs {
+		cp := *l
+		cp.Removed = removed
+		copies = append(copies, &cp)
+	}
+	es.dispatch(logsSubscription, func(sub *subscriber) []interface{} {
+		ret := make([]interface{}, 0)
+		for _, l := range sub.filter.filterLogs(copies) {
+			ret = append(ret, l)
+		}
+		return ret
+	})
+}
+
+func (es *eventSystem) onBlockChanged(message notify.Message) error {
+	select {
+	case es.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (es *eventSystem) loop() {
+	for range es.signal {
+		es.sync()
+	}
+}
+
+// sync sends the heads until the last one sent is the top
+func (es *eventSystem) sync() {
+	if es.chain == nil {
+		return
+	}
+	for !es.syncStep() {
+	}
+}
+
+// syncStep removes the head if it's not on the chain, or sends the next block. done is true if the head is the top
+func (es *eventSystem) syncStep() (done bool) {
+	head := es.heads[len(es.heads)-1].block.Header
+	if bh := es.chain.QueryBlockHeaderByHeight(head.Height); bh == nil || bh.Hash != head.Hash {
+		es.removeHead()
+		return false
+	}
+	next := es.chain.QueryBlockHeaderCeil(head.Height + 1)
+	if next == nil {
+		return true
+	}
+	if next.PreHash != head.Hash {
+		es.removeHead()
+		return false
+	}
+	b := es.chain.QueryBlockByHash(next.Hash)
+	if b == nil {
+		return true
+	}
+	es.addHead(b)
+	return false
+}
+
+// anchor sets the head without sending it
+func (es *eventSystem) anchor(bh *types.BlockHeader) {
+	b := es.chain.QueryBlockByHash(bh.Hash)
+	if b == nil {
+		b = &types.Block{Header: bh}
+	}
+	es.heads = []*headRecord{{block: b, logs: receiptLogs(es.receipts(b))}}
+}
+
+func (es *eventSystem) addHead(b *types.Block) {
+	rec := &headRecord{block: b, logs: receiptLogs(es.receipts(b))}
+	es.heads = append(es.heads, rec)
+	if len(es.heads) > maxHeadRecords {
+		es.heads = es.heads[len(es.heads)-maxHeadRecords:]
+	}
+	es.dispatchHead(b, false)
+	es.dispatchLogs(rec.logs, false)
+}
+
+// removeHead notifies the head removed, and the previous head becomes the current one
+func (es *eventSystem) removeHead() {
+	rec := es.heads[len(es.heads)-1]
+	es.heads = es.heads[:len(es.heads)-1]
+	es.dispatchHead(rec.block, true)
+	es.dispatchLogs(rec.logs, true)
+	if len(es.heads) > 0 {
+		return
+	}
+	// Removed beyond the heads kept
+	if pre := es.chain.QueryBlockHeaderByHash(rec.block.Header.PreHash); pre != nil {
+		es.anchor(pre)
+		return
+	}
+	log.DefaultLogger.Warnf("the heads removed beyond %v, follow the top", rec.block.Header.Height)
+	es.anchor(es.chain.QueryTopBlock())
+}
+
+func (es *eventSystem) onTransactionAdded(message notify.Message) error {
+	tx := message.GetData().(*types.Transaction)
+	hash := tx.Hash.Hex()
+	es.dispatch(pendingTxsSubscription, func(sub *subscriber) []interface{} {
+		return []interface{}{hash}
+	})
+	return nil
+}
+
+// receiptLogs returns the logs in the receipts
+func receiptLogs(receipts types.Receipts) []*types.Log {
+	logs := make([]*types.Log, 0)
+	for _, rc := range receipts {
+		if rc != nil {
+			logs = append(logs, rc.Logs...)
+		}
+	}
+	return logs
+}
+
+// blockReceipts returns the receipts of the transactions in the given block
+func blockReceipts(b *types.Block) types.Receipts {
+	receipts := make(types.Receipts, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		rc := core.BlockChainImpl.GetTransactionPool().GetReceipt(tx.GenHash())
+		if rc != nil {
+			receipts = append(receipts, rc)
+		}
+	}
+	return receipts
+}
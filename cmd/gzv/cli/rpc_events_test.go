@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestLogFilter(t *testing.T) {
+	addr := "zv000000000000000000000000000000000000000000000000000000000000abcd"
+	topic := common.BytesToHash(common.Sha256([]byte("transfer")))
+	other := common.BytesToHash(common.Sha256([]byte("approve")))
+
+	logs := []*types.Log{
+		{Address: common.StringToAddress(addr), Topic: topic},
+		{Address: common.StringToAddress(addr), Topic: other},
+		{Address: common.Address{}, Topic: topic},
+	}
+
+	f, err := newLogFilter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.filterLogs(logs)) != 3 {
+		t.Fatal("empty criteria should match all logs")
+	}
+
+	f, err = newLogFilter(&LogFilterCriteria{Addresses: []string{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.filterLogs(logs)) != 2 {
+		t.Fatal("should match 2 logs by address")
+	}
+
+	f, err = newLogFilter(&LogFilterCriteria{Addresses: []string{addr}, Topics: []string{topic.Hex()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret := f.filterLogs(logs); len(ret) != 1 || ret[0] != logs[0] {
+		t.Fatal("should match the first log")
+	}
+
+	if _, err = newLogFilter(&LogFilterCriteria{Addresses: []string{"0x123"}}); err == nil {
+		t.Fatal("should fail on wrong address")
+	}
+	if _, err = newLogFilter(&LogFilterCriteria{Topics: []string{"abc"}}); err == nil {
+		t.Fatal("should fail on wrong topic")
+	}
+}
+
+func TestFilterManager(t *testing.T) {
+	es := &eventSystem{subscribers: make(map[rpc.ID]*subscriber)}
+	fm := newFilterManager(es)
+	topic := common.BytesToHash(common.Sha256([]byte("transfer")))
+
+	f, _ := newLogFilter(&LogFilterCriteria{Topics: []string{topic.Hex()}})
+	id := fm.install(f)
+
+	rc := &types.Receipt{Logs: []*types.Log{{Topic: topic}, {Topic: common.Hash{}}}}
+	es.dispatchLogs(rc.Logs, false)
+	logs, err := fm.changes(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Removed {
+		t.Fatalf("unexpected logs:%v", logs)
+	}
+
+	es.dispatchLogs(rc.Logs, true)
+	logs, _ = fm.changes(id)
+	if len(logs) != 1 || !logs[0].Removed {
+		t.Fatalf("should receive the removed log")
+	}
+	if rc.Logs[0].Removed {
+		t.Fatalf("the log of the receipt should not be changed")
+	}
+	if logs, _ = fm.changes(id); len(logs) != 0 {
+		t.Fatalf("changes should be cleared after polled")
+	}
+
+	if !fm.uninstall(id) {
+		t.Fatal("uninstall fail")
+	}
+	if _, err = fm.changes(id); err == nil {
+		t.Fatal("should fail after uninstalled")
+	}
+	if len(es.subscribers) != 0 {
+		t.Fatal("subscriber should be removed")
+	}
+}
+
+// testHeadChain is a chain of the blocks by height, the blocks replaced are kept queryable by hash
+type testHeadChain struct {
+	canonical []*types.BlockHeader
+	blocks    map[common.Hash]*types.BlockHeader
+}
+
+func newTestHeadChain() *testHeadChain {
+	c := &testHeadChain{blocks: make(map[common.Hash]*types.BlockHeader)}
+	c.extend(0, "genesis", 1)
+	return c
+}
+
+// extend replaces the blocks after the height with n blocks of the fork
+func (c *testHeadChain) extend(height uint64, fork string, n int) {
+	c.canonical = c.canonical[:height]
+	pre := common.Hash{}
+	if height > 0 {
+		pre = c.canonical[height-1].Hash
+	}
+	for i := 0; i < n; i++ {
+		h := height + uint64(i)
+		bh := &types.BlockHeader{Height: h, PreHash: pre, Hash: common.BytesToHash(common.Sha256([]byte(fmt.Sprintf("%v-%v", fork, h))))}
+		c.canonical = append(c.canonical, bh)
+		c.blocks[bh.Hash] = bh
+		pre = bh.Hash
+	}
+}
+
+func (c *testHeadChain) QueryTopBlock() *types.BlockHeader {
+	return c.canonical[len(c.canonical)-1]
+}
+
+func (c *testHeadChain) QueryBlockHeaderByHash(hash common.Hash) *types.BlockHeader {
+	return c.blocks[hash]
+}
+
+func (c *testHeadChain) QueryBlockHeaderByHeight(height uint64) *types.BlockHeader {
+	if height >= uint64(len(c.canonical)) {
+		return nil
+	}
+	return c.canonical[height]
+}
+
+func (c *testHeadChain) QueryBlockHeaderCeil(height uint64) *types.BlockHeader {
+	return c.QueryBlockHeaderByHeight(height)
+}
+
+func (c *testHeadChain) QueryBlockByHash(hash common.Hash) *types.Block {
+	if bh := c.blocks[hash]; bh != nil {
+		return &types.Block{Header: bh}
+	}
+	return nil
+}
+
+func TestEventSystemFollowChain(t *testing.T) {
+	chain := newTestHeadChain()
+	chain.extend(1, "a", 2)
+	es := &eventSystem{
+		subscribers: make(map[rpc.ID]*subscriber),
+		chain:       chain,
+		// Each block has a log of its hash
+		receipts: func(b *types.Block) types.Receipts {
+			return types.Receipts{{Logs: []*types.Log{{Topic: b.Header.Hash, BlockNumber: b.Header.Height}}}}
+		},
+	}
+	es.anchor(chain.QueryTopBlock())
+	f, _ := newLogFilter(nil)
+	sub := es.install(rpc.NewID(), logsSubscription, f)
+
+	type event struct {
+		hash    common.Hash
+		removed bool
+	}
+	expect := func(events ...event) {
+		t.Helper()
+		for _, e := range events {
+			select {
+			case ev := <-sub.events:
+				l := ev.(*types.Log)
+				if l.Topic != e.hash || l.Removed != e.removed {
+					t.Fatalf("expect log of %v removed %v, got %v removed %v", e.hash.Hex(), e.removed, l.Topic.Hex(), l.Removed)
+				}
+			default:
+				t.Fatalf("expect log of %v removed %v", e.hash.Hex(), e.removed)
+			}
+		}
+		if len(sub.events) != 0 {
+			t.Fatalf("unexpected %v logs", len(sub.events))
+		}
+	}
+	block := func(height int) common.Hash {
+		return chain.canonical[height].Hash
+	}
+
+	chain.extend(3, "a", 2)
+	es.sync()
+	expect(event{block(3), false}, event{block(4), false})
+
+	// Reorganized from the height 2, the blocks removed are sent from the highest one
+	a2, a3, a4 := block(2), block(3), block(4)
+	chain.extend(2, "b", 2)
+	es.sync()
+	expect(event{a4, true}, event{a3, true}, event{a2, true}, event{block(2), false}, event{block(3), false})
+
+	// Reset to the height 1, the common ancestor isn't sent again
+	b2, b3 := block(2), block(3)
+	chain.extend(2, "b", 0)
+	es.sync()
+	expect(event{b3, true}, event{b2, true})
+	es.sync()
+	expect()
+}
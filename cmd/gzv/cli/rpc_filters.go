@@ -0,0 +1,168 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zvchain/zvchain/cmd/gzv/rpc"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+const (
+	// maxLogsResult is the max number of logs returned by a getLogs query
+	maxLogsResult = 10000
+	// filterTimeout is the duration after which a filter not polled is uninstalled
+	filterTimeout = 5 * time.Minute
+)
+
+// queryLogs returns the logs in the blocks of [from, to] matching the filter
+func queryLogs(from, to uint64, filter *logFilter) ([]*types.Log, error) {
+	chain := core.BlockChainImpl
+	if top := chain.Height(); to > top {
+		to = top
+	}
+	if err := chain.CheckHistoryAvailable(from); err != nil {
+		return nil, err
+	}
+	logs := make([]*types.Log, 0)
+	for _, h := range chain.LogCandidateHeights(from, to, filter.addresses, filter.topics) {
+		b := chain.QueryBlockByHeight(h)
+		if b == nil {
+			continue
+		}
+		for _, rc := range blockReceipts(b) {
+			logs = append(logs, filter.filterLogs(rc.Logs)...)
+		}
+		if len(logs) > maxLogsResult {
+			return nil, fmt.Errorf("query returned more than %v results", maxLogsResult)
+		}
+	}
+	return logs, nil
+}
+
+// pollFilter is a filter installed by newFilter, the matched logs are cached
+// until they are fetched by getFilterChanges
+type pollFilter struct {
+	sub   *subscriber
+	timer *time.Timer
+}
+
+// filterManager manages the filters polled by the clients
+type filterManager struct {
+	events  *eventSystem
+	filters map[rpc.ID]*pollFilter
+	lock    sync.Mutex
+}
+
+func newFilterManager(events *eventSystem) *filterManager {
+	return &filterManager{
+		events:  events,
+		filters: make(map[rpc.ID]*pollFilter),
+	}
+}
+
+func (fm *filterManager) install(filter *logFilter) rpc.ID {
+	id := rpc.NewID()
+	pf := &pollFilter{sub: fm.events.install(id, logsSubscription, filter)}
+	pf.timer = time.AfterFunc(filterTimeout, func() {
+		fm.uninstall(id)
+	})
+	fm.lock.Lock()
+	fm.filters[id] = pf
+	fm.lock.Unlock()
+	return id
+}
+
+func (fm *filterManager) uninstall(id rpc.ID) bool {
+	fm.lock.Lock()
+	pf, ok := fm.filters[id]
+	delete(fm.filters, id)
+	fm.lock.Unlock()
+	if !ok {
+		return false
+	}
+	pf.timer.Stop()
+	fm.events.uninstall(id)
+	return true
+}
+
+// changes returns the logs received since last poll and resets the filter timeout
+func (fm *filterManager) changes(id rpc.ID) ([]*types.Log, error) {
+	fm.lock.Lock()
+	pf, ok := fm.filters[id]
+	fm.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("filter not found")
+	}
+	pf.timer.Reset(filterTimeout)
+
+	logs := make([]*types.Log, 0)
+	for {
+		select {
+		case ev := <-pf.sub.events:
+			logs = append(logs, ev.(*types.Log))
+		default:
+			return logs, nil
+		}
+	}
+}
+
+// GetLogs returns the contract logs in the blocks between the given heights matching the addresses and topics.
+// Empty addresses or topics matches any value
+func (api *RpcGzvImpl) GetLogs(fromHeight, toHeight uint64, addresses []string, topics []string) ([]*types.Log, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height should not be greater than to height")
+	}
+	filter, err := newLogFilter(&LogFilterCriteria{Addresses: addresses, Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+	return queryLogs(fromHeight, toHeight, filter)
+}
+
+// NewFilter installs a filter for the logs in the new blocks and returns the filter id.
+// The filter will be uninstalled if not polled for 5 minutes
+func (api *RpcGzvImpl) NewFilter(addresses []string, topics []string) (string, error) {
+	if api.filters == nil {
+		return "", errEventsUnavailable
+	}
+	filter, err := newLogFilter(&LogFilterCriteria{Addresses: addresses, Topics: topics})
+	if err != nil {
+		return "", err
+	}
+	return string(api.filters.install(filter)), nil
+}
+
+// GetFilterChanges returns the logs matched by the filter since last poll. Logs reverted
+// by the chain reorganization are returned with removed set
+func (api *RpcGzvImpl) GetFilterChanges(id string) ([]*types.Log, error) {
+	if api.filters == nil {
+		return nil, errEventsUnavailable
+	}
+	return api.filters.changes(rpc.ID(id))
+}
+
+// UninstallFilter removes the filter of the given id
+func (api *RpcGzvImpl) UninstallFilter(id string) (bool, error) {
+	if api.filters == nil {
+		return false, errEventsUnavailable
+	}
+	return api.filters.uninstall(rpc.ID(id)), nil
+}
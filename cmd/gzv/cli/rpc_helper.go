@@ -59,6 +59,32 @@ func convertTransaction(tx *types.Transaction) *Transaction {
 	return trans
 }
 
+func convertTxRawData(tx *types.Transaction) *TxRawData {
+	raw := &TxRawData{
+		Source:    tx.Source.AddrPrefixString(),
+		TxType:    int(tx.Type),
+		Nonce:     tx.Nonce,
+		Data:      tx.Data,
+		ExtraData: tx.ExtraData,
+	}
+	if tx.Target != nil {
+		raw.Target = tx.Target.AddrPrefixString()
+	}
+	if tx.Value != nil {
+		raw.Value = tx.Value.Uint64()
+	}
+	if tx.GasLimit != nil {
+		raw.GasLimit = tx.GasLimit.Uint64()
+	}
+	if tx.GasPrice != nil {
+		raw.GasPrice = tx.GasPrice.Uint64()
+	}
+	if tx.Sign != nil {
+		raw.Sign = common.ToHex(tx.Sign)
+	}
+	return raw
+}
+
 func convertExecutedTransaction(executed *types.ExecutedTransaction) *ExecutedTransaction {
 	rec := &Receipt{
 		Status:            int(executed.Receipt.Status),
@@ -98,6 +124,19 @@ func convertBlockHeader(b *types.Block) *Block {
 	return block
 }
 
+// convertBlockWithQN converts the block and calculates the qn of the block with its pre block
+func convertBlockWithQN(b *types.Block) *Block {
+	bh := b.Header
+	preBH := core.BlockChainImpl.QueryBlockHeaderByHash(bh.PreHash)
+	block := convertBlockHeader(b)
+	if preBH != nil {
+		block.Qn = bh.TotalQN - preBH.TotalQN
+	} else {
+		block.Qn = bh.TotalQN
+	}
+	return block
+}
+
 func convertRewardTransaction(tx *types.Transaction) *RewardTransaction {
 	if tx.Type != types.TransactionTypeReward {
 		return nil
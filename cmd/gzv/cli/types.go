@@ -96,6 +96,23 @@ type ConnInfo struct {
 	TCPPort string `json:"tcp_port"`
 }
 
+type PeerScore struct {
+	ID          string  `json:"id"`
+	Score       float64 `json:"score"`
+	Banned      bool    `json:"banned"`
+	Permanent   bool    `json:"permanent"`
+	BannedUntil int64   `json:"banned_until,omitempty"`
+	BanCount    int     `json:"ban_count"`
+	Reason      string  `json:"reason,omitempty"`
+}
+
+type StaticPeer struct {
+	ID        string `json:"id"`
+	IP        string `json:"ip,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Connected bool   `json:"connected"`
+}
+
 type GroupStat struct {
 	Dismissed bool  `json:"dismissed"`
 	VCount    int32 `json:"v_count"`
@@ -213,6 +230,13 @@ type Block struct {
 	Random      string      `json:"random"`
 }
 
+// HeadEvent is the notification of the newHeads subscription, removed is set
+// if the block is reverted in a chain reorganization
+type HeadEvent struct {
+	*Block
+	Removed bool `json:"removed"`
+}
+
 type BlockDetail struct {
 	Block
 	GenRewardTx   *RewardTransaction    `json:"gen_reward_tx"`
@@ -280,6 +304,92 @@ type Receipt struct {
 	TxIndex         uint16         `json:"tx_index"`
 }
 
+// CallResult is the result of the read-only contract call
+type CallResult struct {
+	Result       string       `json:"result"`
+	Logs         []*types.Log `json:"logs"`
+	GasUsed      uint64       `json:"gas_used"`
+	Status       int          `json:"status"`
+	ErrorCode    int          `json:"error_code"`
+	ErrorMessage string       `json:"error_message"`
+}
+
+// TxTrace is the execution trace of a transaction re-executed in its block
+type TxTrace struct {
+	Hash    common.Hash    `json:"hash"`
+	Type    int8           `json:"type"`
+	Status  int            `json:"status"`
+	GasUsed uint64         `json:"gas_used"`
+	Error   string         `json:"error,omitempty"`
+	Call    *tvm.CallFrame `json:"call,omitempty"`
+}
+
+// StorageProof is the merkle proof of a storage key of the account
+type StorageProof struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+// AccountProof is the merkle proof of the account and its storage keys against the state root of the block
+type AccountProof struct {
+	Height        uint64          `json:"height"`
+	BlockHash     common.Hash     `json:"block_hash"`
+	StateRoot     common.Hash     `json:"state_root"`
+	Address       string          `json:"address"`
+	Exist         bool            `json:"exist"`
+	Balance       *big.Int        `json:"balance"`
+	Nonce         uint64          `json:"nonce"`
+	StorageRoot   common.Hash     `json:"storage_root"`
+	CodeHash      string          `json:"code_hash"`
+	AccountProof  []string        `json:"account_proof"`
+	StorageProofs []*StorageProof `json:"storage_proofs"`
+}
+
+// TxPoolContent is the transactions of an account in the transaction pool
+type TxPoolContent struct {
+	Address      string         `json:"address"`
+	StateNonce   uint64         `json:"state_nonce"`
+	PendingNonce uint64         `json:"pending_nonce"`
+	Pending      []*Transaction `json:"pending"`
+	Queued       []*Transaction `json:"queued"`
+	Rewards      []*Transaction `json:"rewards"`
+	NonceGaps    []uint64       `json:"nonce_gaps"`
+}
+
+type TxPoolSourceStatus struct {
+	Address      string   `json:"address"`
+	StateNonce   uint64   `json:"state_nonce"`
+	PendingNonce uint64   `json:"pending_nonce"`
+	Pending      int      `json:"pending"`
+	Queued       int      `json:"queued"`
+	Rewards      int      `json:"rewards"`
+	NonceGaps    []uint64 `json:"nonce_gaps"`
+}
+
+// TxPoolStatus is the transaction count of the transaction pool broken down by account
+type TxPoolStatus struct {
+	Pending int                   `json:"pending"`
+	Queued  int                   `json:"queued"`
+	Rewards int                   `json:"rewards"`
+	Sources []*TxPoolSourceStatus `json:"sources"`
+}
+
+// MultiSigAccount is the M-of-N config and the state of a multisig account
+type MultiSigAccount struct {
+	Address   string   `json:"address"`
+	Threshold uint8    `json:"threshold"`
+	Members   []string `json:"members"`
+	Balance   float64  `json:"balance"`
+	Nonce     uint64   `json:"nonce"`
+}
+
+// MultiSigCreateResult is the result of creating a multisig account
+type MultiSigCreateResult struct {
+	Hash    string `json:"hash"`
+	Address string `json:"address"`
+}
+
 type ExecutedTransaction struct {
 	Receipt     *Receipt
 	Transaction *Transaction
@@ -314,6 +424,51 @@ type ExplorerAccount struct {
 	StateData map[string]interface{} `json:"state_data"`
 }
 
+// TokenBalance is the balance of a holder in a token contract
+type TokenBalance struct {
+	Token   string   `json:"token"`
+	Balance *big.Int `json:"balance"`
+}
+
+// TokenTransfer is a transfer of a token contract decoded from the Transfer event
+type TokenTransfer struct {
+	Token    string   `json:"token"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Value    *big.Int `json:"value"`
+	TxHash   string   `json:"tx_hash"`
+	Height   uint64   `json:"height"`
+	TxIndex  uint16   `json:"tx_index"`
+	LogIndex uint     `json:"log_index"`
+}
+
+// AddressTransaction is a transaction related to the address, with the roles of the address in it
+type AddressTransaction struct {
+	Hash     string   `json:"hash"`
+	Height   uint64   `json:"height"`
+	TxIndex  uint16   `json:"tx_index"`
+	Type     int8     `json:"type"`
+	Category string   `json:"category"`
+	Roles    []string `json:"roles"`
+}
+
+// AddressTransactions is a page of the transactions of an address
+type AddressTransactions struct {
+	Transactions []*AddressTransaction `json:"transactions"`
+	NextCursor   string                `json:"next_cursor"` // Empty if no more
+}
+
+// VerifiedContract is the source and abi of a contract verified against the code deployed
+type VerifiedContract struct {
+	Address    string `json:"address"`
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	ABI        string `json:"abi"`
+	CodeHash   string `json:"code_hash"`
+	TVMVersion string `json:"tvm_version"`
+	Height     uint64 `json:"height"`
+}
+
 type ExploreBlockReward struct {
 	ProposalID           string            `json:"proposal_id"`
 	ProposalReward       uint64            `json:"proposal_reward"`
@@ -45,7 +45,7 @@ func (ws *WalletServer) Start() error {
 		{Namespace: "GzvWallet", Version: "1", Service: ws, Public: true},
 	}
 	host := fmt.Sprintf("%s:%d", ws.Host, ws.Port)
-	err := startHTTP(host, apis, []string{}, []string{}, []string{})
+	err := startHTTP(host, apis, []string{}, []string{}, []string{}, nil)
 	if err == nil {
 		fmt.Printf("Wallet RPC serving on http://%s\n", host)
 		return nil
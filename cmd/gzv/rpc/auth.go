@@ -0,0 +1,390 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errMissingCredential = errors.New("missing api key or bearer token")
+	errInvalidCredential = errors.New("invalid api key or bearer token")
+	errTokenExpired      = errors.New("bearer token expired")
+)
+
+// MetricsPermission permits the key to read the metrics served at /metrics of the rpc listener
+const MetricsPermission = "metrics"
+
+// APIKey is the credential of a rpc client with the permissions and the limits
+type APIKey struct {
+	Name string
+
+	// Token is the static api key, sent by the X-API-Key header or as the bearer token
+	Token string
+
+	// JWTSecret is the secret of the HS256 signed JWT bearer tokens whose subject is the Name
+	JWTSecret []byte
+
+	// Permissions contains the namespaces like "Gzv" and the methods like "Gzv_tx"
+	// the key may call, MetricsPermission to read the metrics, "*" permits all
+	Permissions []string
+
+	Rate          float64 // Requests per second allowed, no limit if not positive
+	Burst         int     // Maximum requests allowed in a burst, defaults to the rate
+	MaxConcurrent int     // Maximum requests executed concurrently, no limit if not positive
+}
+
+// rateLimiter is a token bucket refilled at the rate
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	b := float64(burst)
+	if b < 1 {
+		b = rate
+		if b < 1 {
+			b = 1
+		}
+	}
+	return &rateLimiter{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// keyState holds the runtime state of an api key shared by all the connections using it
+type keyState struct {
+	*APIKey
+	all        bool
+	namespaces map[string]bool
+	methods    map[string]bool
+	limiter    *rateLimiter
+	slots      chan struct{}
+}
+
+func newKeyState(key *APIKey) *keyState {
+	ks := &keyState{
+		APIKey:     key,
+		namespaces: make(map[string]bool),
+		methods:    make(map[string]bool),
+	}
+	for _, p := range key.Permissions {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "*":
+			ks.all = true
+		case strings.Contains(p, serviceMethodSeparator):
+			ks.methods[p] = true
+		case p != "":
+			ks.namespaces[p] = true
+		}
+	}
+	if key.Rate > 0 {
+		ks.limiter = newRateLimiter(key.Rate, key.Burst)
+	}
+	if key.MaxConcurrent > 0 {
+		ks.slots = make(chan struct{}, key.MaxConcurrent)
+	}
+	return ks
+}
+
+func (ks *keyState) permitted(service, method string) bool {
+	return ks.all || service == MetadataAPI || ks.namespaces[service] || ks.methods[service+serviceMethodSeparator+method]
+}
+
+// acquire takes a slot of the concurrent requests, returns false if all slots are in use
+func (ks *keyState) acquire() bool {
+	if ks.slots == nil {
+		return true
+	}
+	select {
+	case ks.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ks *keyState) release() {
+	if ks.slots != nil {
+		<-ks.slots
+	}
+}
+
+// Authenticator authenticates the http and websocket rpc requests by the api keys or the
+// JWT bearer tokens, and checks the permissions and the limits of the key for each call
+type Authenticator struct {
+	tokens       map[string]*keyState // Keys by the static token
+	names        map[string]*keyState // Keys by the name, used to find the secret of the JWT
+	auditMethods map[string]bool
+	auditLogger  *logrus.Logger
+}
+
+// NewAuthenticator creates the authenticator of the keys. Calls to the auditMethods are
+// recorded to the auditLogger with the key, the params and the result
+func NewAuthenticator(keys []*APIKey, auditMethods []string, auditLogger *logrus.Logger) (*Authenticator, error) {
+	a := &Authenticator{
+		tokens:       make(map[string]*keyState),
+		names:        make(map[string]*keyState),
+		auditMethods: make(map[string]bool),
+		auditLogger:  auditLogger,
+	}
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("api key name required")
+		}
+		if key.Token == "" && len(key.JWTSecret) == 0 {
+			return nil, fmt.Errorf("api key %v has neither token nor jwt secret", key.Name)
+		}
+		if _, ok := a.names[key.Name]; ok {
+			return nil, fmt.Errorf("duplicate api key %v", key.Name)
+		}
+		ks := newKeyState(key)
+		a.names[key.Name] = ks
+		if key.Token != "" {
+			if _, ok := a.tokens[key.Token]; ok {
+				return nil, fmt.Errorf("api key %v uses the token of another key", key.Name)
+			}
+			a.tokens[key.Token] = ks
+		}
+	}
+	for _, m := range auditMethods {
+		if m = strings.TrimSpace(m); m != "" {
+			a.auditMethods[m] = true
+		}
+	}
+	return a, nil
+}
+
+// credential returns the token from the Authorization or X-API-Key header, or the api_key
+// query parameter which is used by the browser websocket clients unable to set headers
+func credential(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+		return ""
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*keyState, error) {
+	token := credential(r)
+	if token == "" {
+		return nil, errMissingCredential
+	}
+	if ks, ok := a.tokens[token]; ok {
+		return ks, nil
+	}
+	if strings.Count(token, ".") == 2 {
+		return a.verifyJWT(token, time.Now())
+	}
+	return nil, errInvalidCredential
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+	Nbf int64  `json:"nbf"`
+}
+
+// verifyJWT checks the HS256 signature of the token with the secret of the key named by the subject
+func (a *Authenticator) verifyJWT(token string, now time.Time) (*keyState, error) {
+	parts := strings.Split(token, ".")
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errInvalidCredential
+	}
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errInvalidCredential
+	}
+	ks, ok := a.names[claims.Sub]
+	if !ok || len(ks.JWTSecret) == 0 {
+		return nil, errInvalidCredential
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidCredential
+	}
+	mac := hmac.New(sha256.New, ks.JWTSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidCredential
+	}
+	if claims.Exp > 0 && now.Unix() >= claims.Exp {
+		return nil, errTokenExpired
+	}
+	if claims.Nbf > 0 && now.Unix() < claims.Nbf {
+		return nil, errInvalidCredential
+	}
+	return ks, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
+
+// SignJWT creates a HS256 signed token of the subject, which never expires if exp is zero
+func SignJWT(subject string, secret []byte, exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := jwtClaims{Sub: subject}
+	if !exp.IsZero() {
+		claims.Exp = exp.Unix()
+	}
+	bs, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(bs)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// authInfo is the authenticated key and the remote address of the connection carried by the context
+type authInfo struct {
+	key    *keyState
+	remote string
+}
+
+type authInfoKey struct{}
+
+// SetAuthenticator enables the authentication of the http and websocket requests
+func (s *Server) SetAuthenticator(a *Authenticator) {
+	s.auth = a
+}
+
+// authContext authenticates the http request and returns the context carrying the key
+func (s *Server) authContext(r *http.Request) (context.Context, error) {
+	ctx := context.Background()
+	if s.auth == nil {
+		return ctx, nil
+	}
+	ks, err := s.auth.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, authInfoKey{}, &authInfo{key: ks, remote: r.RemoteAddr}), nil
+}
+
+// metricsHandler serves the metrics only to the keys permitted if the authentication is enabled
+func (s *Server) metricsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth != nil {
+			ks, err := s.auth.authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gzv"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if !ks.all && !ks.namespaces[MetricsPermission] {
+				http.Error(w, fmt.Sprintf("api key %v is not permitted to read the metrics", ks.Name), http.StatusForbidden)
+				return
+			}
+			if ks.limiter != nil && !ks.limiter.allow(time.Now()) {
+				http.Error(w, fmt.Sprintf("request rate of api key %v exceeds %v/s", ks.Name, ks.Rate), http.StatusTooManyRequests)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the permission and the limits of the key calling the request.
+// The returned function releases the concurrency slot taken. Requests from the local
+// transports which aren't authenticated are always allowed
+func (s *Server) authorize(ctx context.Context, req *serverRequest) (func(), Error) {
+	info, ok := ctx.Value(authInfoKey{}).(*authInfo)
+	if s.auth == nil || !ok {
+		return func() {}, nil
+	}
+	ks := info.key
+	if !req.isUnsubscribe && !ks.permitted(req.svcname, formatName(req.callb.method.Name)) {
+		return nil, &unauthorizedError{fmt.Sprintf("api key %v is not permitted to call %s%s%s", ks.Name, req.svcname, serviceMethodSeparator, formatName(req.callb.method.Name))}
+	}
+	if ks.limiter != nil && !ks.limiter.allow(time.Now()) {
+		return nil, &limitExceededError{fmt.Sprintf("request rate of api key %v exceeds %v/s", ks.Name, ks.Rate)}
+	}
+	if !ks.acquire() {
+		return nil, &limitExceededError{fmt.Sprintf("concurrent requests of api key %v exceeds %v", ks.Name, ks.MaxConcurrent)}
+	}
+	return ks.release, nil
+}
+
+// audit records the call to the audit logger if the method is audited
+func (s *Server) audit(ctx context.Context, req *serverRequest, result interface{}, err error) {
+	if s.auth == nil || s.auth.auditLogger == nil {
+		return
+	}
+	method := req.svcname + serviceMethodSeparator + formatName(req.callb.method.Name)
+	if !s.auth.auditMethods[method] {
+		return
+	}
+	keyName, remote := "", ""
+	if info, ok := ctx.Value(authInfoKey{}).(*authInfo); ok {
+		keyName, remote = info.key.Name, info.remote
+	}
+	params := make([]interface{}, 0, len(req.args))
+	for _, arg := range req.args {
+		params = append(params, arg.Interface())
+	}
+	bs, _ := json.Marshal(params)
+	if err != nil {
+		s.auth.auditLogger.Infof("key=%v remote=%v method=%v params=%s error=%v", keyName, remote, method, bs, err)
+	} else {
+		s.auth.auditLogger.Infof("key=%v remote=%v method=%v params=%s result=%v", keyName, remote, method, bs, result)
+	}
+}
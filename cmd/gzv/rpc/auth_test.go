@@ -0,0 +1,230 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type authTestResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newAuthTestServer(t *testing.T, keys []*APIKey, audit *logrus.Logger) *Server {
+	server := NewServer(false)
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("other", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := NewAuthenticator(keys, []string{"test_echo"}, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticator(auth)
+	return server
+}
+
+func authTestCall(server *Server, header, value, body string) (int, *authTestResponse) {
+	req := httptest.NewRequest(http.MethodPost, "http://url.com", strings.NewReader(body))
+	req.Header.Set("content-type", contentType)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	resp := new(authTestResponse)
+	json.Unmarshal(rec.Body.Bytes(), resp)
+	return rec.Code, resp
+}
+
+const echoRequest = `{"jsonrpc":"2.0","id":1,"method":"%s_echo","params":["hello",1,{"S":"x"}]}`
+
+func echoBody(namespace string) string {
+	return fmt.Sprintf(echoRequest, namespace)
+}
+
+func TestAuthPermissions(t *testing.T) {
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "partner", Token: "secret-key", Permissions: []string{"test", "other_rets"}},
+	}, nil)
+
+	if code, _ := authTestCall(server, "", "", echoBody("test")); code != http.StatusUnauthorized {
+		t.Fatalf("expect unauthorized without credential, got %v", code)
+	}
+	if code, _ := authTestCall(server, "X-API-Key", "wrong", echoBody("test")); code != http.StatusUnauthorized {
+		t.Fatalf("expect unauthorized with wrong key, got %v", code)
+	}
+	_, resp := authTestCall(server, "X-API-Key", "secret-key", echoBody("test"))
+	if resp == nil || resp.Error != nil || !bytes.Contains(resp.Result, []byte("hello")) {
+		t.Fatalf("expect echo result, got %+v", resp)
+	}
+	_, resp = authTestCall(server, "Authorization", "Bearer secret-key", echoBody("other"))
+	if resp == nil || resp.Error == nil || resp.Error.Code != -32001 {
+		t.Fatalf("expect permission denied, got %+v", resp)
+	}
+	_, resp = authTestCall(server, "Authorization", "Bearer secret-key", `{"jsonrpc":"2.0","id":1,"method":"other_rets","params":[]}`)
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("expect method permitted, got %+v", resp)
+	}
+	_, resp = authTestCall(server, "X-API-Key", "secret-key", `{"jsonrpc":"2.0","id":1,"method":"rpc_modules","params":[]}`)
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("expect metadata permitted, got %+v", resp)
+	}
+}
+
+func TestAuthJWT(t *testing.T) {
+	secret := []byte("jwt-secret")
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "partner", JWTSecret: secret, Permissions: []string{"*"}},
+	}, nil)
+
+	token := SignJWT("partner", secret, time.Now().Add(time.Hour))
+	_, resp := authTestCall(server, "Authorization", "Bearer "+token, echoBody("other"))
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("expect valid token accepted, got %+v", resp)
+	}
+	for name, token := range map[string]string{
+		"expired":     SignJWT("partner", secret, time.Now().Add(-time.Minute)),
+		"bad secret":  SignJWT("partner", []byte("other"), time.Time{}),
+		"bad subject": SignJWT("unknown", secret, time.Time{}),
+		"malformed":   "a.b.c",
+	} {
+		if code, _ := authTestCall(server, "Authorization", "Bearer "+token, echoBody("test")); code != http.StatusUnauthorized {
+			t.Fatalf("expect %v token rejected, got %v", name, code)
+		}
+	}
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "partner", Token: "secret-key", Permissions: []string{"*"}, Rate: 0.001, Burst: 2},
+	}, nil)
+	for i := 0; i < 2; i++ {
+		if _, resp := authTestCall(server, "X-API-Key", "secret-key", echoBody("test")); resp == nil || resp.Error != nil {
+			t.Fatalf("expect request %v allowed, got %+v", i, resp)
+		}
+	}
+	_, resp := authTestCall(server, "X-API-Key", "secret-key", echoBody("test"))
+	if resp == nil || resp.Error == nil || resp.Error.Code != -32005 {
+		t.Fatalf("expect rate limited, got %+v", resp)
+	}
+}
+
+func TestAuthConcurrencyLimit(t *testing.T) {
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "partner", Token: "secret-key", Permissions: []string{"*"}, MaxConcurrent: 1},
+	}, nil)
+	sleep := `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[500000000]}`
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		authTestCall(server, "X-API-Key", "secret-key", sleep)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	_, resp := authTestCall(server, "X-API-Key", "secret-key", echoBody("test"))
+	if resp == nil || resp.Error == nil || resp.Error.Code != -32005 {
+		t.Fatalf("expect concurrency limited, got %+v", resp)
+	}
+	wg.Wait()
+	if _, resp = authTestCall(server, "X-API-Key", "secret-key", echoBody("test")); resp == nil || resp.Error != nil {
+		t.Fatalf("expect slot released, got %+v", resp)
+	}
+}
+
+func TestAuthAudit(t *testing.T) {
+	buf := new(bytes.Buffer)
+	audit := logrus.New()
+	audit.Out = buf
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "partner", Token: "secret-key", Permissions: []string{"*"}},
+	}, audit)
+
+	authTestCall(server, "X-API-Key", "secret-key", echoBody("test"))
+	authTestCall(server, "X-API-Key", "secret-key", echoBody("other"))
+	out := buf.String()
+	if !strings.Contains(out, "key=partner") || !strings.Contains(out, "method=test_echo") || !strings.Contains(out, "hello") {
+		t.Fatalf("audit record not found: %v", out)
+	}
+	if strings.Contains(out, "other_echo") {
+		t.Fatalf("unexpected audit record: %v", out)
+	}
+}
+
+func TestNewAuthenticatorInvalid(t *testing.T) {
+	cases := [][]*APIKey{
+		{{Token: "a"}},
+		{{Name: "a"}},
+		{{Name: "a", Token: "x"}, {Name: "a", Token: "y"}},
+		{{Name: "a", Token: "x"}, {Name: "b", Token: "x"}},
+	}
+	for i, keys := range cases {
+		if _, err := NewAuthenticator(keys, nil, nil); err == nil {
+			t.Fatalf("expect error of case %v", i)
+		}
+	}
+}
+
+func TestAuthMetrics(t *testing.T) {
+	server := newAuthTestServer(t, []*APIKey{
+		{Name: "monitor", Token: "monitor-key", Permissions: []string{MetricsPermission}},
+		{Name: "partner", Token: "secret-key", Permissions: []string{"test"}},
+	}, nil)
+	handler := NewHTTPWSServer(nil, nil, server).Handler
+	get := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "http://url.com/metrics", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := get(""); code != http.StatusUnauthorized {
+		t.Fatalf("expect unauthorized without credential, got %v", code)
+	}
+	if code := get("secret-key"); code != http.StatusForbidden {
+		t.Fatalf("expect forbidden without the metrics permission, got %v", code)
+	}
+	if code := get("monitor-key"); code != http.StatusOK {
+		t.Fatalf("expect metrics served, got %v", code)
+	}
+
+	server.SetAuthenticator(nil)
+	if code := get(""); code != http.StatusOK {
+		t.Fatalf("expect metrics served without authentication, got %v", code)
+	}
+}
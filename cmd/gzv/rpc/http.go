@@ -200,6 +200,16 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+	ctx := context.Background()
+	// CORS preflight requests never carry the credential
+	if r.Method != http.MethodOptions {
+		var err error
+		if ctx, err = srv.authContext(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gzv"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
@@ -207,7 +217,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
 }
 
 // validateRequest returns a non-zero response code and error message if the
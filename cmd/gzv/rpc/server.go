@@ -110,7 +110,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	return nil
 }
 
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -125,7 +125,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	if options&OptionSubscriptions == OptionSubscriptions {
@@ -190,12 +190,17 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 }
 
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec, options)
+}
+
+// serveCodec serves the codec with the context carrying the authenticated key
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(ctx, codec, false, options)
 }
 
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop stops reading new requests, waits for stopPendingRequestTimeout to allow pending
@@ -231,6 +236,12 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+	release, authErr := s.authorize(ctx, req)
+	if authErr != nil {
+		return codec.CreateErrorResponse(&req.id, authErr), nil
+	}
+	defer release()
+
 	if req.isUnsubscribe { // Cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
@@ -293,10 +304,12 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			s.audit(ctx, req, nil, e)
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
 	}
+	s.audit(ctx, req, reply[0].Interface(), nil)
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
@@ -70,6 +70,7 @@ type Server struct {
 	codecsMu    sync.Mutex
 	codecs      set.Interface
 	isPruneMode bool
+	auth        *Authenticator // Authenticates the http and websocket requests if set
 }
 
 type rpcRequest struct {
@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"github.com/zvchain/zvchain/log"
+	"github.com/zvchain/zvchain/middleware/metrics"
 	"net"
 	"net/http"
 	"net/url"
@@ -32,14 +33,53 @@ import (
 )
 
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
+	validator := wsHandshakeValidator(allowedOrigins)
 	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			if err := validator(cfg, req); err != nil {
+				return err
+			}
+			_, err := srv.authContext(req)
+			return err
+		},
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			ctx, err := srv.authContext(conn.Request())
+			if err != nil {
+				conn.Close()
+				return
+			}
+			srv.serveCodec(ctx, NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
 
+// NewHTTPWSServer creates a new HTTP RPC server which also serves the websocket
+// requests on the same listener, and the metrics at /metrics which requires the
+// MetricsPermission if the authentication is enabled
+func NewHTTPWSServer(cors []string, vhosts []string, srv *Server) *http.Server {
+	httpHandler := newCorsHandler(srv, cors)
+	wsHandler := srv.WebsocketHandler(cors)
+	metricsHandler := srv.metricsHandler(metrics.Handler())
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/metrics" {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
+		if isWebsocket(r) {
+			wsHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+	return &http.Server{Handler: handler}
+}
+
+// isWebsocket checks whether the request is a websocket upgrade request
+func isWebsocket(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
 func NewWSServer(allowedOrigins []string, srv *Server) *http.Server {
 	return &http.Server{Handler: srv.WebsocketHandler(allowedOrigins)}
 }
@@ -69,7 +109,8 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 
 	f := func(cfg *websocket.Config, req *http.Request) error {
 		origin := strings.ToLower(req.Header.Get("Origin"))
-		if allowAllOrigins || origins.Has(origin) {
+		// Non-browser clients usually don't send the origin header
+		if allowAllOrigins || origin == "" || origins.Has(origin) {
 			return nil
 		}
 		log.DefaultLogger.Warn(fmt.Sprintf("origin '%s' not allowed on WS-RPC interface\n", origin))
@@ -1,3 +1,4 @@
+transfer_event = Event("Transfer")
 
 class Token(object):
 
@@ -12,6 +13,7 @@ class Token(object):
         self.allowance = zdict()
 
         self.balanceOf['zv6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd'] = self.totalSupply
+        transfer_event.emit("", 'zv6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd', self.totalSupply)
 
         # self.owner = msg.sender
 
@@ -39,7 +41,7 @@ class Token(object):
         # 转账
         self.balanceOf[_from] -= _value
         self.balanceOf[_to] += _value
-        # Event.emit("Transfer", _from, _to, _value)
+        transfer_event.emit(_from, _to, _value)
 
     @register.public(str, int)
     def transfer(self, _to, _value):
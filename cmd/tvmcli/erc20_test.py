@@ -0,0 +1,13 @@
+# deploy: token erc20.py
+
+class TokenTest(object):
+    def __init__(self):
+        self.owner = "zv6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd"
+
+    @register.public()
+    def test_total_supply(self):
+        assert_equal(contract("token").balance_of(self.owner), 100000)
+
+    @register.public()
+    def test_transfer_without_balance(self):
+        assert_raises(contract("token").transfer, self.owner, 1)
@@ -43,13 +43,26 @@ var (
 	queryAddress = queryData.Arg("account address", "account address.").Required().String()
 	queryKey     = queryData.Arg("query key", "").Required().String()
 	queryCount   = queryData.Arg("query count", "if count > 0, key is prefix of query db.").Required().Int()
+
+	testContract = app.Command("test", "run the *_test.py contract tests in the directory.")
+	testDir      = testContract.Arg("dir", "").Required().String()
 )
 
 func main() {
 
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	// test ./cli
+	if command == testContract.FullCommand() {
+		if !runTests(*testDir) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	tvmCli := NewTvmCli()
 
-	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	switch command {
 
 	// deploy Token ./cli/erc20.py
 	case deployContract.FullCommand():
@@ -0,0 +1,196 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/tvm/tvmtest"
+)
+
+// testFileSuffix is the suffix of the contract test files run by the test command
+const testFileSuffix = "_test.py"
+
+var (
+	classRegexp      = regexp.MustCompile(`(?m)^class\s+(\w+)`)
+	testMethodRegexp = regexp.MustCompile(`(?m)^\s+def\s+(test\w*)\s*\(\s*self\s*\)`)
+	deployRegexp     = regexp.MustCompile(`(?m)^#\s*deploy:\s*(\w+)\s+(\S+)\s*$`)
+)
+
+// testPrelude is appended to each test contract, providing the deployed contracts and the assertion
+// functions. It's appended rather than prepended so that the error lines match the test file
+const testPrelude = `
+
+contracts = %s
+
+def contract(name):
+    return Contract(contracts[name])
+
+def assert_true(cond, message=""):
+    if not cond:
+        raise Exception("assert_true failed " + str(message))
+
+def assert_false(cond, message=""):
+    if cond:
+        raise Exception("assert_false failed " + str(message))
+
+def assert_equal(a, b, message=""):
+    if a != b:
+        raise Exception("assert_equal failed: " + str(a) + " != " + str(b) + " " + str(message))
+
+def assert_not_equal(a, b, message=""):
+    if a == b:
+        raise Exception("assert_not_equal failed: " + str(a) + " == " + str(b) + " " + str(message))
+
+def assert_raises(fn, *args):
+    try:
+        fn(*args)
+    except Exception:
+        return
+    raise Exception("assert_raises failed: no exception raised")
+`
+
+// contractTest is a parsed test file. The test file is a contract whose public methods named
+// test* are the cases. The contracts it tests are declared by the comment lines such as "# deploy: token erc20.py",
+// which deploys the first class in erc20.py, whose address is contracts["token"] in the test
+type contractTest struct {
+	path    string
+	name    string
+	code    string
+	deploys [][2]string // alias and the path relative to the test file
+	cases   []string
+}
+
+func parseContractTest(path string, code string) (*contractTest, error) {
+	class := classRegexp.FindStringSubmatch(code)
+	if class == nil {
+		return nil, fmt.Errorf("no class found in %v", path)
+	}
+	ct := &contractTest{path: path, name: class[1], code: code}
+	for _, m := range deployRegexp.FindAllStringSubmatch(code, -1) {
+		ct.deploys = append(ct.deploys, [2]string{m[1], m[2]})
+	}
+	for _, m := range testMethodRegexp.FindAllStringSubmatch(code, -1) {
+		ct.cases = append(ct.cases, m[1])
+	}
+	if len(ct.cases) == 0 {
+		return nil, fmt.Errorf("no test method found in %v", path)
+	}
+	return ct, nil
+}
+
+// testReport is the count of the cases run
+type testReport struct {
+	passed int
+	failed int
+}
+
+// runTests runs all the contract test files in the directory, and returns whether all passed
+func runTests(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fmt.Println("read dir", dir, "failed", err)
+		return false
+	}
+	paths := make([]string, 0)
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), testFileSuffix) {
+			paths = append(paths, filepath.Join(dir, f.Name()))
+		}
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		fmt.Println("no *" + testFileSuffix + " file found in " + dir)
+		return false
+	}
+
+	total := testReport{}
+	for _, path := range paths {
+		report, err := runTestFile(path)
+		if err != nil {
+			fmt.Printf("FAIL %v: %v\n", path, err)
+			total.failed++
+			continue
+		}
+		total.passed += report.passed
+		total.failed += report.failed
+	}
+	fmt.Printf("%d passed, %d failed\n", total.passed, total.failed)
+	return total.failed == 0
+}
+
+// runTestFile deploys the contracts the test file declared and the test contract, then calls each case
+// on a snapshot of the state, so the cases don't affect each other
+func runTestFile(path string) (*testReport, error) {
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := parseContractTest(path, string(code))
+	if err != nil {
+		return nil, err
+	}
+	env, err := tvmtest.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	sender := common.StringToAddress(DefaultAccounts[0])
+	env.SetBalance(sender, big.NewInt(200))
+
+	contracts := make([]string, 0)
+	for _, d := range ct.deploys {
+		depCode, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), d[1]))
+		if err != nil {
+			return nil, err
+		}
+		class := classRegexp.FindStringSubmatch(string(depCode))
+		if class == nil {
+			return nil, fmt.Errorf("no class found in %v", d[1])
+		}
+		addr, ret := env.Deploy(sender, class[1], string(depCode))
+		if ret.Failed() {
+			return nil, fmt.Errorf("deploy %v failed: %v", d[1], ret.Err.Message)
+		}
+		contracts = append(contracts, fmt.Sprintf("%q: %q", d[0], addr.AddrPrefixString()))
+	}
+	testCode := ct.code + fmt.Sprintf(testPrelude, "{"+strings.Join(contracts, ", ")+"}")
+	testAddr, ret := env.Deploy(sender, ct.name, testCode)
+	if ret.Failed() {
+		return nil, fmt.Errorf("deploy test contract failed: %v", ret.Err.Message)
+	}
+
+	report := &testReport{}
+	for _, c := range ct.cases {
+		snapshot := env.Snapshot()
+		ret := env.Call(sender, testAddr, c)
+		env.RevertToSnapshot(snapshot)
+		if ret.Failed() {
+			report.failed++
+			fmt.Printf("FAIL %v %v: %v\n", path, c, ret.Err.Message)
+		} else {
+			report.passed++
+			fmt.Printf("ok   %v %v (gas %d)\n", path, c, ret.GasUsed)
+		}
+	}
+	return report, nil
+}
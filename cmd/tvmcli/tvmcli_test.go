@@ -409,3 +409,20 @@ func TestTvmCli_TestStorage(t *testing.T) {
 func init() {
 	params.InitChainConfig(1)
 }
+
+func TestParseContractTest(t *testing.T) {
+	code, err := ioutil.ReadFile("erc20_test.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := parseContractTest("erc20_test.py", string(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.name != "TokenTest" || len(ct.deploys) != 1 || ct.deploys[0] != [2]string{"token", "erc20.py"} {
+		t.Fatalf("unexpected test: %+v", ct)
+	}
+	if len(ct.cases) != 2 || ct.cases[0] != "test_total_supply" || ct.cases[1] != "test_transfer_without_balance" {
+		t.Fatalf("unexpected cases: %v", ct.cases)
+	}
+}
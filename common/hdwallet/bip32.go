@@ -0,0 +1,187 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/common/secp256k1"
+)
+
+const (
+	// HardenedOffset is the index offset of the hardened child keys
+	HardenedOffset uint32 = 0x80000000
+
+	// CoinType is the BIP44 coin type of ZVC
+	CoinType uint32 = 372
+
+	masterKeySeed = "Bitcoin seed"
+)
+
+var (
+	ErrSeedLength    = errors.New("seed length must be [16, 64]")
+	ErrInvalidKey    = errors.New("derived key is invalid, use the next index")
+	ErrInvalidPath   = errors.New("invalid derivation path")
+	ErrDepthExceeded = errors.New("max depth exceeded")
+)
+
+// ExtendedKey is the BIP32 extended private key
+type ExtendedKey struct {
+	key       []byte // 32 bytes private key
+	chainCode []byte
+	depth     uint8
+	index     uint32
+}
+
+// NewMaster creates the master extended key from the seed
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrSeedLength
+	}
+	mac := hmac.New(sha512.New, []byte(masterKeySeed))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// compressedPubKey returns the compressed public key of the private key
+func (k *ExtendedKey) compressedPubKey() []byte {
+	x, y := secp256k1.S256().ScalarBaseMult(k.key)
+	ret := make([]byte, 33)
+	ret[0] = 0x02 + byte(y.Bit(0))
+	xb := x.Bytes()
+	copy(ret[33-len(xb):], xb)
+	return ret
+}
+
+// Child derives the child key at the index, indexes from HardenedOffset are hardened
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDepthExceeded
+	}
+	data := make([]byte, 0, 37)
+	if index >= HardenedOffset {
+		data = append(data, 0)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.compressedPubKey()...)
+	}
+	var ib [4]byte
+	binary.BigEndian.PutUint32(ib[:], index)
+	data = append(data, ib[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := secp256k1.S256().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	child := il.Add(il, new(big.Int).SetBytes(k.key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, ErrInvalidKey
+	}
+	key := make([]byte, 32)
+	cb := child.Bytes()
+	copy(key[32-len(cb):], cb)
+	return &ExtendedKey{key: key, chainCode: sum[32:], depth: k.depth + 1, index: index}, nil
+}
+
+// Derive derives the descendant key along the path
+func (k *ExtendedKey) Derive(path DerivationPath) (*ExtendedKey, error) {
+	var err error
+	key := k
+	for _, index := range path {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Key returns the 32 bytes private key
+func (k *ExtendedKey) Key() []byte {
+	return append([]byte{}, k.key...)
+}
+
+// PrivateKey returns the account private key of the extended key
+func (k *ExtendedKey) PrivateKey() (*common.PrivateKey, error) {
+	sk := new(common.PrivateKey)
+	if !sk.ImportKey(k.key) {
+		return nil, ErrInvalidKey
+	}
+	return sk, nil
+}
+
+// DerivationPath is the BIP32 path of child indexes from the master key
+type DerivationPath []uint32
+
+// AccountPath returns the BIP44 path m/44'/CoinType'/0'/0/index of the account at the index
+func AccountPath(index uint32) DerivationPath {
+	return DerivationPath{44 + HardenedOffset, CoinType + HardenedOffset, HardenedOffset, 0, index}
+}
+
+// ParsePath parses the path like m/44'/372'/0'/0/0, the hardened index ends with ' or h
+func ParsePath(path string) (DerivationPath, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+	ret := make(DerivationPath, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") {
+			offset = HardenedOffset
+			p = p[:len(p)-1]
+		}
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || uint32(v) >= HardenedOffset {
+			return nil, fmt.Errorf("%v: %v", ErrInvalidPath, path)
+		}
+		ret = append(ret, uint32(v)+offset)
+	}
+	return ret, nil
+}
+
+func (p DerivationPath) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range p {
+		b.WriteString("/")
+		if index >= HardenedOffset {
+			b.WriteString(strconv.FormatUint(uint64(index-HardenedOffset), 10))
+			b.WriteString("'")
+		} else {
+			b.WriteString(strconv.FormatUint(uint64(index), 10))
+		}
+	}
+	return b.String()
+}
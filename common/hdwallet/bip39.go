@@ -0,0 +1,159 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package hdwallet implements the BIP39 mnemonic and the BIP32 hierarchical deterministic key derivation
+// on the secp256k1 curve used by the ZVChain accounts
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// DefaultEntropyBits is the entropy size of the generated mnemonic, which results in 24 words
+	DefaultEntropyBits = 256
+
+	seedIterations = 2048
+	seedSaltPrefix = "mnemonic"
+)
+
+var (
+	ErrEntropyLength   = errors.New("entropy length must be [128, 256] and a multiple of 32")
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrChecksum        = errors.New("mnemonic checksum mismatch")
+	ErrPassphrase      = errors.New("only ascii passphrase is supported")
+)
+
+var wordIndex = make(map[string]int, len(englishWords))
+
+func init() {
+	for i, w := range englishWords {
+		wordIndex[w] = i
+	}
+}
+
+func validEntropyBits(bits int) bool {
+	return bits >= 128 && bits <= 256 && bits%32 == 0
+}
+
+// NewEntropy generates random entropy of the given bits
+func NewEntropy(bits int) ([]byte, error) {
+	if !validEntropyBits(bits) {
+		return nil, ErrEntropyLength
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic encodes the entropy to the mnemonic sentence
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if !validEntropyBits(bits) {
+		return "", ErrEntropyLength
+	}
+	checksumBits := bits / 32
+	hash := sha256.Sum256(entropy)
+	// Append the checksum to the entropy and split it to 11-bit groups
+	data := append(append([]byte{}, entropy...), hash[0])
+	words := make([]string, (bits+checksumBits)/11)
+	for i := range words {
+		idx := 0
+		for j := 0; j < 11; j++ {
+			bit := i*11 + j
+			idx <<= 1
+			if data[bit/8]&(1<<uint(7-bit%8)) != 0 {
+				idx |= 1
+			}
+		}
+		words[i] = englishWords[idx]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// GenerateMnemonic generates a random mnemonic sentence with the default entropy size
+func GenerateMnemonic() (string, error) {
+	entropy, err := NewEntropy(DefaultEntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return NewMnemonic(entropy)
+}
+
+// MnemonicToEntropy decodes the mnemonic sentence and verifies the checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	totalBits := len(words) * 11
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+	if len(words)%3 != 0 || !validEntropyBits(entropyBits) {
+		return nil, ErrInvalidMnemonic
+	}
+	data := make([]byte, (totalBits+7)/8)
+	for i, w := range words {
+		idx, ok := wordIndex[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("%v: unknown word %v", ErrInvalidMnemonic, w)
+		}
+		for j := 0; j < 11; j++ {
+			if idx&(1<<uint(10-j)) != 0 {
+				bit := i*11 + j
+				data[bit/8] |= 1 << uint(7-bit%8)
+			}
+		}
+	}
+	entropy := data[:entropyBits/8]
+	hash := sha256.Sum256(entropy)
+	mask := byte(0xff << uint(8-checksumBits))
+	if hash[0]&mask != data[entropyBits/8]&mask {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic returns whether the mnemonic sentence is valid
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// normalizeMnemonic lowercases the words and joins them with single spaces
+func normalizeMnemonic(mnemonic string) string {
+	return strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+}
+
+// NewSeed validates the mnemonic and creates the 64 bytes seed with the optional passphrase.
+// Only ascii passphrase is accepted because the NFKD normalization required by BIP39 is not supported
+func NewSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if !ValidateMnemonic(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	for _, r := range passphrase {
+		if r > unicode.MaxASCII {
+			return nil, ErrPassphrase
+		}
+	}
+	return pbkdf2.Key([]byte(normalizeMnemonic(mnemonic)), []byte(seedSaltPrefix+passphrase), seedIterations, 64, sha512.New), nil
+}
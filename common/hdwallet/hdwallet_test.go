@@ -0,0 +1,132 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestWordlist(t *testing.T) {
+	if len(englishWords) != 2048 {
+		t.Fatalf("unexpected wordlist size %v", len(englishWords))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(englishWords, "\n") + "\n"))
+	if hex.EncodeToString(sum[:]) != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+		t.Fatal("wordlist checksum mismatch")
+	}
+}
+
+// Test vectors from the BIP39 reference implementation
+func TestMnemonic(t *testing.T) {
+	vectors := []struct {
+		entropy, mnemonic, seed string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+	}
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		m, err := NewMnemonic(entropy)
+		if err != nil || m != v.mnemonic {
+			t.Fatalf("mnemonic of %v: expect %v, got %v %v", v.entropy, v.mnemonic, m, err)
+		}
+		e, err := MnemonicToEntropy(m)
+		if err != nil || !bytes.Equal(e, entropy) {
+			t.Fatalf("entropy of %v: got %x %v", m, e, err)
+		}
+		seed, err := NewSeed(m, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != v.seed {
+			t.Fatalf("seed of %v: got %x %v", m, seed, err)
+		}
+	}
+
+	if ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon") {
+		t.Fatal("expect checksum error")
+	}
+	if ValidateMnemonic("abandon abandon abandon") {
+		t.Fatal("expect length error")
+	}
+	m, err := GenerateMnemonic()
+	if err != nil || len(strings.Fields(m)) != 24 || !ValidateMnemonic(m) {
+		t.Fatalf("generate mnemonic fail: %v %v", m, err)
+	}
+	if _, err := NewSeed(m, "密码"); err != ErrPassphrase {
+		t.Fatalf("expect passphrase error, got %v", err)
+	}
+}
+
+// Test vector 1 from BIP32
+func TestDerive(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(master.Key()) != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Fatalf("unexpected master key %x", master.Key())
+	}
+	vectors := []struct {
+		path, key string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, v := range vectors {
+		path, err := ParsePath(v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path.String() != v.path {
+			t.Fatalf("expect path %v, got %v", v.path, path)
+		}
+		k, err := master.Derive(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(k.Key()) != v.key {
+			t.Fatalf("key of %v: expect %v, got %x", v.path, v.key, k.Key())
+		}
+	}
+
+	if AccountPath(3).String() != "m/44'/372'/0'/0/3" {
+		t.Fatalf("unexpected account path %v", AccountPath(3))
+	}
+	for _, p := range []string{"", "44'/0", "m/x", "m/2147483648"} {
+		if _, err := ParsePath(p); err == nil {
+			t.Fatalf("expect error parsing %v", p)
+		}
+	}
+}
@@ -20,8 +20,10 @@ import (
 	"github.com/zvchain/zvchain/common"
 	"github.com/zvchain/zvchain/consensus/base"
 	"github.com/zvchain/zvchain/consensus/groupsig"
+	"github.com/zvchain/zvchain/consensus/model"
 	"github.com/zvchain/zvchain/middleware/types"
 	"io/ioutil"
+	"math/big"
 	"strings"
 )
 
@@ -82,6 +84,27 @@ func GenerateGenesis() *types.GenesisInfo {
 	return info
 }
 
+// GenerateDevGenesis generates the genesis verifyGroup of the single miner for the development chain,
+// and replaces the one returned by GenerateGenesis. The alloc accounts are funded in the genesis block
+func GenerateDevGenesis(miner *model.MinerDO, alloc map[common.Address]*big.Int) *types.GenesisInfo {
+	gHeader := &groupHeader{
+		seed:          common.BytesToHash(common.Sha256([]byte("zv_dev_genesis"))),
+		workHeight:    0,
+		dismissHeight: common.MaxUint64,
+		gpk:           miner.PK,
+		threshold:     1,
+	}
+	members := []types.MemberI{&member{id: miner.ID.Serialize(), pk: miner.PK.Serialize()}}
+	info := &types.GenesisInfo{
+		Group:  &group{header: gHeader, members: members},
+		VrfPKs: [][]byte{miner.VrfPK},
+		Pks:    [][]byte{miner.PK.Serialize()},
+		Alloc:  alloc,
+	}
+	genesisGroupInfo = info
+	return info
+}
+
 func genGenesisStaticGroupInfo(f string) *genesisGroupMarshal {
 	sgiData := []byte(types.GenesisDefaultGroupInfo())
 	if strings.TrimSpace(f) != "" {
@@ -132,6 +132,7 @@ func (routine *createRoutine) onNewTopBlock(bh *types.BlockHeader) error {
 	} else {
 		if ok {
 			logger.Debugf("checkAndSendEncryptedPiecePacket sent encrypted packet at %v, seedHeight %v", bh.Height, routine.currEra().seedHeight)
+			stagePacketCounter.WithLabelValues("encrypted_piece").Inc()
 		}
 	}
 	ok, err = routine.checkAndSendMpkPacket(bh)
@@ -140,6 +141,7 @@ func (routine *createRoutine) onNewTopBlock(bh *types.BlockHeader) error {
 	} else {
 		if ok {
 			logger.Debugf("checkAndSendMpkPacket sent mpk packet at %v, seedHeight %v", bh.Height, routine.currEra().seedHeight)
+			stagePacketCounter.WithLabelValues("mpk").Inc()
 		}
 	}
 	ok, err = routine.checkAndSendOriginPiecePacket(bh)
@@ -148,6 +150,7 @@ func (routine *createRoutine) onNewTopBlock(bh *types.BlockHeader) error {
 	} else {
 		if ok {
 			logger.Debugf("checkAndSendOriginPiecePacket sent origin packet at %v, seedHeight %v", bh.Height, routine.currEra().seedHeight)
+			stagePacketCounter.WithLabelValues("origin_piece").Inc()
 		}
 	}
 	return err
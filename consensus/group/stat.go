@@ -18,9 +18,15 @@ package group
 import (
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/metrics"
 	"sync/atomic"
 )
 
+var (
+	stagePacketCounter = metrics.NewCounterVec("group_create_packets_total", "Number of the group-create packets sent by stage", "stage")
+	eraStatusCounter   = metrics.NewCounterVec("group_create_eras_total", "Number of the group-create eras finished by status", "status")
+)
+
 type createStatus int
 
 const (
@@ -50,10 +56,13 @@ func (st *createStat) onEvict(k, v interface{}) {
 	switch v.(createStatus) {
 	case createStatusIdle:
 		atomic.AddInt32(&st.idle, 1)
+		eraStatusCounter.WithLabelValues("idle").Inc()
 	case createStatusSuccess:
 		atomic.AddInt32(&st.success, 1)
+		eraStatusCounter.WithLabelValues("success").Inc()
 	case createStatusFail:
 		atomic.AddInt32(&st.fail, 1)
+		eraStatusCounter.WithLabelValues("fail").Inc()
 	}
 }
 
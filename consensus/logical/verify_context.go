@@ -23,6 +23,7 @@ import (
 	"github.com/zvchain/zvchain/common"
 	"github.com/zvchain/zvchain/consensus/groupsig"
 	"github.com/zvchain/zvchain/consensus/model"
+	"github.com/zvchain/zvchain/middleware/metrics"
 	"github.com/zvchain/zvchain/middleware/time"
 	"github.com/zvchain/zvchain/middleware/types"
 	"gopkg.in/fatih/set.v0"
@@ -51,6 +52,12 @@ const (
 	pieceFail = -1
 )
 
+var (
+	// consensusLatency measures the time from the context created to each stage of the consensus
+	consensusLatency = metrics.NewHistogramVec("consensus_latency_seconds", "Time from the verify context created to the consensus stage", nil, "stage")
+	consensusTimeout = metrics.NewCounter("consensus_timeout_total", "Number of the verify contexts timeout")
+)
+
 // VerifyContext stores the context of verification consensus of each height.
 // It is unique to each height which means replacement will take place when two different instance
 // created for only one height
@@ -105,12 +112,25 @@ func (vc *VerifyContext) isNotified() bool {
 
 func (vc *VerifyContext) markTimeout() {
 	if !vc.castSuccess() {
-		atomic.StoreInt32(&vc.consensusStatus, svTimeout)
+		if atomic.SwapInt32(&vc.consensusStatus, svTimeout) != svTimeout {
+			consensusTimeout.Inc()
+		}
 	}
 }
 
 func (vc *VerifyContext) markCastSuccess() {
-	atomic.StoreInt32(&vc.consensusStatus, svSuccess)
+	if atomic.SwapInt32(&vc.consensusStatus, svSuccess) != svSuccess {
+		vc.observeLatency("cast")
+	}
+}
+
+// observeLatency records the time elapsed since the context created for the given stage
+func (vc *VerifyContext) observeLatency(stage string) {
+	if vc.ts == nil {
+		return
+	}
+	ms := vc.ts.Now().SinceMilliSeconds(vc.createTime)
+	consensusLatency.WithLabelValues(stage).Observe(float64(ms) / 1000)
 }
 
 func (vc *VerifyContext) markNotified() {
@@ -349,15 +369,18 @@ func (vc *VerifyContext) checkNotify() *SlotContext {
 
 func (vc *VerifyContext) increaseVerifyNum() {
 	atomic.AddInt32(&vc.verifyNum, 1)
+	vc.observeLatency("verify")
 }
 
 func (vc *VerifyContext) increaseAggrNum() {
 	atomic.AddInt32(&vc.aggrNum, 1)
+	vc.observeLatency("recover")
 }
 
 func (vc *VerifyContext) markSignedBlock(bh *types.BlockHeader) {
 	vc.signedBlockHashs.Add(bh.Hash)
 	atomic.AddInt32(&vc.signedNum, 1)
+	vc.observeLatency("sign")
 	vc.updateSignedMaxWeightBlock(bh)
 }
 
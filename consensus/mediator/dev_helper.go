@@ -0,0 +1,123 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package mediator
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/consensus/group"
+	"github.com/zvchain/zvchain/consensus/groupsig"
+	"github.com/zvchain/zvchain/consensus/model"
+	"github.com/zvchain/zvchain/core"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// DevConsensusInit initializes the consensus engine of the development chain without starting it,
+// so that the rpc services can query the miner info. No group is created on the development chain
+func DevConsensusInit(mi model.SelfMinerDO, conf common.ConfManager) bool {
+	if !ConsensusInit(mi, conf) {
+		return false
+	}
+	core.GroupManagerImpl.RegisterGroupCreateChecker(&devGroupCreateChecker{})
+	return true
+}
+
+// DevConsensusHelper implements ConsensusHelper interface for the single node development chain.
+// All blocks are cast by the only genesis member, so the vrf prove and the group signature are not checked
+type DevConsensusHelper struct {
+	ID groupsig.ID
+}
+
+func NewDevConsensusHelper(id groupsig.ID) types.ConsensusHelper {
+	return &DevConsensusHelper{ID: id}
+}
+
+// GenerateGenesisInfo returns the genesis group generated by group.GenerateDevGenesis
+func (helper *DevConsensusHelper) GenerateGenesisInfo() *types.GenesisInfo {
+	return group.GenerateGenesis()
+}
+
+// VRFProve2Value returns zero since the dev blocks carry no vrf prove
+func (helper *DevConsensusHelper) VRFProve2Value(prove []byte) *big.Int {
+	return big.NewInt(0)
+}
+
+// CalculateQN returns 1 for each block
+func (helper *DevConsensusHelper) CalculateQN(bh *types.BlockHeader) uint64 {
+	return 1
+}
+
+func (helper *DevConsensusHelper) CheckProveRoot(bh *types.BlockHeader) (bool, error) {
+	return true, nil
+}
+
+func (helper *DevConsensusHelper) VerifyNewBlock(bh *types.BlockHeader, preBH *types.BlockHeader) (bool, error) {
+	return true, nil
+}
+
+func (helper *DevConsensusHelper) VerifyBlockSign(bh *types.BlockHeader) (bool, error) {
+	return true, nil
+}
+
+func (helper *DevConsensusHelper) VerifyRewardTransaction(tx *types.Transaction) (bool, error) {
+	return true, nil
+}
+
+// EstimatePreHeight returns the previous height as no height is skipped on the dev chain
+func (helper *DevConsensusHelper) EstimatePreHeight(bh *types.BlockHeader) uint64 {
+	if bh.Height == 0 {
+		return 0
+	}
+	return bh.Height - 1
+}
+
+func (helper *DevConsensusHelper) VerifyBlockHeaders(pre, bh *types.BlockHeader) (bool, error) {
+	return true, nil
+}
+
+func (helper *DevConsensusHelper) GroupSkipCountsBetween(preBH *types.BlockHeader, h uint64) map[common.Hash]uint16 {
+	return map[common.Hash]uint16{}
+}
+
+// GetBlockMinElapse returns 1 millisecond so that the blocks can be produced instantly
+func (helper *DevConsensusHelper) GetBlockMinElapse(height uint64) int32 {
+	return 1
+}
+
+// devGroupCreateChecker rejects all the group-create packets, so the genesis group works forever
+type devGroupCreateChecker struct{}
+
+func (c *devGroupCreateChecker) CheckEncryptedPiecePacket(packet types.EncryptedSharePiecePacket, ctx types.CheckerContext) error {
+	return fmt.Errorf("no group created on the dev chain")
+}
+
+func (c *devGroupCreateChecker) CheckMpkPacket(packet types.MpkPacket, ctx types.CheckerContext) error {
+	return fmt.Errorf("no group created on the dev chain")
+}
+
+func (c *devGroupCreateChecker) CheckGroupCreateResult(ctx types.CheckerContext) types.CreateResult {
+	return nil
+}
+
+func (c *devGroupCreateChecker) CheckOriginPiecePacket(packet types.OriginSharePiecePacket, ctx types.CheckerContext) error {
+	return fmt.Errorf("no group created on the dev chain")
+}
+
+func (c *devGroupCreateChecker) CheckGroupCreatePunishment(ctx types.CheckerContext) (types.PunishmentMsg, error) {
+	return nil, fmt.Errorf("no group created on the dev chain")
+}
@@ -0,0 +1,355 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const (
+	// addressIndexBackfillStep is the count of heights indexed each time by the backfill routine
+	addressIndexBackfillStep = 256
+
+	// MaxAddressTxsLimit is the max count of transactions returned in a page
+	MaxAddressTxsLimit = 100
+
+	addressTxKeyLength = 1 + common.AddressLength + 8 + 2
+)
+
+// The categories of the transactions in the address index, used to filter the query
+const (
+	AddressTxTransfer = "transfer"
+	AddressTxContract = "contract"
+	AddressTxStake    = "stake"
+	AddressTxReward   = "reward"
+	AddressTxOther    = "other"
+)
+
+// The roles of the address in the transaction, combined as a bit mask
+const (
+	AddressRoleSender   = 1 << iota // Source of the transaction
+	AddressRoleReceiver             // Target of the transaction, or the contract created
+	AddressRoleRewarded             // Target or proposer rewarded by the reward transaction
+)
+
+var (
+	addressIndexStartKey    = []byte("start")    // Top height when the index created
+	addressIndexBackfillKey = []byte("backfill") // Next height to be indexed by the backfill routine
+	addressTxPrefix         = []byte("a")        // address + reversed height and tx index -> tx
+	addressBlockPrefix      = []byte("k")        // block hash -> keys of the txs indexed, for removing the block
+)
+
+// AddressTx is a transaction related to an address
+type AddressTx struct {
+	TxHash   common.Hash
+	Height   uint64
+	TxIndex  uint16
+	Type     int8
+	Category string
+	Roles    int
+}
+
+// addressTxCategory returns the category of the transaction type
+func addressTxCategory(txType int8) string {
+	switch txType {
+	case types.TransactionTypeTransfer, types.TransactionTypeMultiSigExecute:
+		return AddressTxTransfer
+	case types.TransactionTypeContractCreate, types.TransactionTypeContractCall:
+		return AddressTxContract
+	case types.TransactionTypeStakeAdd, types.TransactionTypeMinerAbort, types.TransactionTypeStakeReduce,
+		types.TransactionTypeStakeRefund, types.TransactionTypeApplyGuardMiner, types.TransactionTypeVoteMinerPool,
+		types.TransactionTypeChangeFundGuardMode:
+		return AddressTxStake
+	case types.TransactionTypeReward:
+		return AddressTxReward
+	}
+	return AddressTxOther
+}
+
+// addressIndex indexes the transactions by the addresses they touch: the source, the target,
+// the contract created and the reward recipients. It's updated in the same batch as the blocks
+// committed and removed, and the blocks existing before the index created are indexed by the backfill routine
+type addressIndex struct {
+	db *tasdb.PrefixedDatabase
+
+	// rewardRecipients returns the addresses rewarded by the reward transaction
+	rewardRecipients func(tx *types.Transaction) ([]common.Address, error)
+
+	start      uint64
+	backfilled uint64
+	lock       sync.RWMutex
+}
+
+func initAddressIndex(db *tasdb.PrefixedDatabase, top uint64, rm *rewardManager) (*addressIndex, error) {
+	idx := &addressIndex{db: db}
+	idx.rewardRecipients = func(tx *types.Transaction) ([]common.Address, error) {
+		_, targets, _, _, err := rm.ParseRewardTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]common.Address, 0, len(targets))
+		for _, id := range targets {
+			addrs = append(addrs, common.BytesToAddress(id))
+		}
+		return addrs, nil
+	}
+	bs, err := db.Get(addressIndexStartKey)
+	if err != nil || bs == nil {
+		if err = db.Put(addressIndexStartKey, common.UInt64ToByte(top)); err != nil {
+			return nil, err
+		}
+		if err = db.Put(addressIndexBackfillKey, common.UInt64ToByte(0)); err != nil {
+			return nil, err
+		}
+		idx.start = top
+		return idx, nil
+	}
+	idx.start = common.ByteToUInt64(bs)
+	if bs, _ = db.Get(addressIndexBackfillKey); bs != nil {
+		idx.backfilled = common.ByteToUInt64(bs)
+	}
+	return idx, nil
+}
+
+// unindexedRange returns the height range not indexed yet. ok is false if all heights are indexed
+func (idx *addressIndex) unindexedRange() (from, to uint64, ok bool) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return idx.backfilled, idx.start, idx.backfilled <= idx.start
+}
+
+// setBackfilled updates the backfill progress after the batch written
+func (idx *addressIndex) setBackfilled(batch tasdb.Batch, next uint64) error {
+	if err := idx.db.AddKv(batch, addressIndexBackfillKey, common.UInt64ToByte(next)); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.lock.Lock()
+	idx.backfilled = next
+	idx.lock.Unlock()
+	return nil
+}
+
+// addressTxKey sorts the transactions of the address from the newest
+func addressTxKey(addr common.Address, height uint64, txIndex uint16) []byte {
+	return common.BytesCombine(addressTxPrefix, addr.Bytes(), common.UInt64ToByte(^height), common.UInt16ToByte(^txIndex))
+}
+
+func addressBlockKey(hash common.Hash) []byte {
+	return common.BytesCombine(addressBlockPrefix, hash.Bytes())
+}
+
+// addBlock puts the transactions of the block into the batch. The receipts are used to find the contracts created
+func (idx *addressIndex) addBlock(batch tasdb.Batch, bh *types.BlockHeader, txs []*types.RawTransaction, receipts types.Receipts) error {
+	created := make(map[uint16]common.Address)
+	for _, rc := range receipts {
+		if rc.ContractAddress != (common.Address{}) {
+			created[rc.TxIndex] = rc.ContractAddress
+		}
+	}
+
+	keys := make([]byte, 0)
+	for i, raw := range txs {
+		txIndex := uint16(i)
+		roles := make(map[common.Address]int)
+		if raw.Source != nil {
+			roles[*raw.Source] |= AddressRoleSender
+		}
+		if raw.Type == types.TransactionTypeReward {
+			tx := types.NewTransaction(raw, raw.GenHash())
+			recipients, err := idx.rewardRecipients(tx)
+			if err != nil {
+				Logger.Warnf("address index parse reward tx %v error:%v", tx.Hash.Hex(), err)
+			}
+			// The proposer of the block packing the reward transaction gets the pack fee
+			recipients = append(recipients, common.BytesToAddress(bh.Castor))
+			for _, addr := range recipients {
+				roles[addr] |= AddressRoleRewarded
+			}
+		} else if raw.Target != nil {
+			roles[*raw.Target] |= AddressRoleReceiver
+		}
+		if addr, ok := created[txIndex]; ok {
+			roles[addr] |= AddressRoleReceiver
+		}
+
+		hash := raw.GenHash()
+		for addr, role := range roles {
+			key := addressTxKey(addr, bh.Height, txIndex)
+			value := common.BytesCombine([]byte{byte(raw.Type), byte(role)}, hash.Bytes())
+			if err := idx.db.AddKv(batch, key, value); err != nil {
+				return err
+			}
+			keys = append(keys, key...)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return idx.db.AddKv(batch, addressBlockKey(bh.Hash), keys)
+}
+
+// removeBlock deletes the transactions of the block from the index in the batch
+func (idx *addressIndex) removeBlock(batch tasdb.Batch, hash common.Hash) error {
+	keys, err := idx.db.Get(addressBlockKey(hash))
+	if err != nil || len(keys) == 0 {
+		return nil
+	}
+	for i := 0; i+addressTxKeyLength <= len(keys); i += addressTxKeyLength {
+		if err = idx.db.AddKv(batch, keys[i:i+addressTxKeyLength], nil); err != nil {
+			return err
+		}
+	}
+	return idx.db.AddKv(batch, addressBlockKey(hash), nil)
+}
+
+// parseAddressTxCursor parses the cursor in the form of "height-txIndex"
+func parseAddressTxCursor(cursor string) (height uint64, txIndex uint16, err error) {
+	if _, err = fmt.Sscanf(cursor, "%d-%d", &height, &txIndex); err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %v", cursor)
+	}
+	return
+}
+
+// query returns at most limit transactions of the address from the cursor, newest first, and the cursor of the next page.
+// The transactions of the categories not in the filter are skipped, unless the filter is empty
+func (idx *addressIndex) query(addr common.Address, cursor string, limit int, filter map[string]bool) ([]*AddressTx, string, error) {
+	iter := idx.db.NewIteratorWithPrefix(common.BytesCombine(addressTxPrefix, addr.Bytes()))
+	defer iter.Release()
+
+	seek := []byte{}
+	if cursor != "" {
+		height, txIndex, err := parseAddressTxCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		seek = common.BytesCombine(common.UInt64ToByte(^height), common.UInt16ToByte(^txIndex))
+	}
+	ret := make([]*AddressTx, 0)
+	for ok := iter.Seek(seek); ok; ok = iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		if len(key) != 10 || len(value) != 2+common.HashLength {
+			continue
+		}
+		tx := &AddressTx{
+			Height:  ^common.ByteToUInt64(key[:8]),
+			TxIndex: ^common.ByteToUInt16(key[8:]),
+			Type:    int8(value[0]),
+			Roles:   int(value[1]),
+			TxHash:  common.BytesToHash(value[2:]),
+		}
+		tx.Category = addressTxCategory(tx.Type)
+		if len(filter) > 0 && !filter[tx.Category] {
+			continue
+		}
+		if len(ret) == limit {
+			return ret, fmt.Sprintf("%d-%d", tx.Height, tx.TxIndex), nil
+		}
+		ret = append(ret, tx)
+	}
+	return ret, "", nil
+}
+
+// AddressTransactions returns the transactions related to the address, newest first, from the cursor returned
+// by the previous page, or from the latest if the cursor is empty. Only the given categories are returned if not empty.
+// The cursor of the next page is returned, empty if no more
+func (chain *FullBlockChain) AddressTransactions(addr common.Address, cursor string, limit int, categories []string) ([]*AddressTx, string, error) {
+	if chain.addressIndex == nil {
+		return nil, "", fmt.Errorf("address index not enabled")
+	}
+	// The transactions are paged from the newest to the genesis
+	if err := chain.CheckHistoryAvailable(0); err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 || limit > MaxAddressTxsLimit {
+		limit = MaxAddressTxsLimit
+	}
+	filter := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		switch c {
+		case AddressTxTransfer, AddressTxContract, AddressTxStake, AddressTxReward, AddressTxOther:
+			filter[c] = true
+		default:
+			return nil, "", fmt.Errorf("unknown transaction category %v", c)
+		}
+	}
+	chain.rwLock.RLock()
+	defer chain.rwLock.RUnlock()
+	return chain.addressIndex.query(addr, cursor, limit, filter)
+}
+
+// backfillAddressIndex indexes the blocks existing before the address index created
+func (chain *FullBlockChain) backfillAddressIndex() {
+	if _, _, ok := chain.addressIndex.unindexedRange(); ok {
+		Logger.Infof("address index backfill started")
+	}
+	for {
+		done, err := chain.backfillAddressIndexStep()
+		if err != nil {
+			Logger.Errorf("address index backfill error:%v", err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
+
+func (chain *FullBlockChain) backfillAddressIndexStep() (done bool, err error) {
+	idx := chain.addressIndex
+	from, to, ok := idx.unindexedRange()
+	if !ok {
+		return true, nil
+	}
+	if to-from >= addressIndexBackfillStep {
+		to = from + addressIndexBackfillStep - 1
+	}
+
+	// Hold the write lock so that the blocks indexed won't be removed concurrently
+	chain.rwLock.Lock()
+	defer chain.rwLock.Unlock()
+	if atomic.LoadInt32(&chain.shutdowning) == 1 {
+		return true, nil
+	}
+
+	batch := idx.db.CreateLDBBatch()
+	for _, h := range chain.scanBlockHeightsInRange(from, to) {
+		bh := chain.queryBlockHeaderByHeight(h)
+		if bh == nil {
+			continue
+		}
+		txs := chain.queryBlockTransactionsAll(bh.Hash)
+		if err = idx.addBlock(batch, bh, txs, chain.blockReceipts(txs)); err != nil {
+			return
+		}
+	}
+	if err = idx.setBackfilled(batch, to+1); err != nil {
+		return
+	}
+	if _, _, ok = idx.unindexedRange(); !ok {
+		Logger.Infof("address index backfill finished at %v", to)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,145 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const addressIndexTestDb = "test_address_index_db"
+
+func addressTestTx(txType int8, source, target *common.Address, nonce uint64) *types.RawTransaction {
+	return &types.RawTransaction{Type: txType, Source: source, Target: target, Nonce: nonce, Value: types.NewBigInt(1)}
+}
+
+func addressTestBlock(t *testing.T, idx *addressIndex, bh *types.BlockHeader, txs []*types.RawTransaction, receipts types.Receipts) {
+	batch := idx.db.CreateLDBBatch()
+	if err := idx.addBlock(batch, bh, txs, receipts); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkAddressTxs(t *testing.T, txs []*AddressTx, heights []uint64, indexes []uint16) {
+	t.Helper()
+	if len(txs) != len(heights) {
+		t.Fatalf("unexpected count of txs, want %v, got %v", len(heights), len(txs))
+	}
+	for i, tx := range txs {
+		if tx.Height != heights[i] || tx.TxIndex != indexes[i] {
+			t.Fatalf("unexpected tx at %v, want %v-%v, got %v-%v", i, heights[i], indexes[i], tx.Height, tx.TxIndex)
+		}
+	}
+}
+
+func TestAddressIndex_AddAndRemove(t *testing.T) {
+	defer os.RemoveAll(addressIndexTestDb)
+	ds, err := tasdb.NewDataSource(addressIndexTestDb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := ds.NewPrefixDatabase("ad")
+	alice := common.BytesToAddress(genHash("alice"))
+	bob := common.BytesToAddress(genHash("bob"))
+	miner := common.BytesToAddress(genHash("miner"))
+	verifier := common.BytesToAddress(genHash("verifier"))
+	proposer := common.BytesToAddress(genHash("proposer"))
+	contract := common.BytesToAddress(genHash("contract"))
+	idx := &addressIndex{db: db, rewardRecipients: func(tx *types.Transaction) ([]common.Address, error) {
+		return []common.Address{verifier, alice}, nil
+	}}
+
+	b1 := &types.BlockHeader{Height: 1, Hash: common.BytesToHash(genHash("b1")), Castor: proposer.Bytes()}
+	addressTestBlock(t, idx, b1, []*types.RawTransaction{
+		addressTestTx(types.TransactionTypeTransfer, &alice, &bob, 1),
+		addressTestTx(types.TransactionTypeContractCreate, &alice, nil, 2),
+		addressTestTx(types.TransactionTypeStakeAdd, &alice, &miner, 3),
+	}, types.Receipts{{TxIndex: 1, ContractAddress: contract}})
+	b2 := &types.BlockHeader{Height: 2, Hash: common.BytesToHash(genHash("b2")), Castor: proposer.Bytes()}
+	addressTestBlock(t, idx, b2, []*types.RawTransaction{
+		addressTestTx(types.TransactionTypeReward, nil, nil, 0),
+		addressTestTx(types.TransactionTypeTransfer, &bob, &alice, 1),
+	}, nil)
+
+	txs, next, err := idx.query(alice, "", 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAddressTxs(t, txs, []uint64{2, 2, 1, 1, 1}, []uint16{1, 0, 2, 1, 0})
+	if next != "" {
+		t.Fatalf("unexpected next cursor %v", next)
+	}
+	if txs[0].Roles != AddressRoleReceiver || txs[1].Roles != AddressRoleRewarded || txs[4].Roles != AddressRoleSender {
+		t.Fatalf("unexpected roles %v %v %v", txs[0].Roles, txs[1].Roles, txs[4].Roles)
+	}
+	if txs[1].Category != AddressTxReward || txs[2].Category != AddressTxStake || txs[3].Category != AddressTxContract {
+		t.Fatalf("unexpected categories")
+	}
+
+	// Paginate with the cursor
+	txs, next, _ = idx.query(alice, "", 2, nil)
+	checkAddressTxs(t, txs, []uint64{2, 2}, []uint16{1, 0})
+	if next != "1-2" {
+		t.Fatalf("unexpected next cursor %v", next)
+	}
+	txs, next, _ = idx.query(alice, next, 2, nil)
+	checkAddressTxs(t, txs, []uint64{1, 1}, []uint16{2, 1})
+	txs, next, _ = idx.query(alice, next, 2, nil)
+	checkAddressTxs(t, txs, []uint64{1}, []uint16{0})
+	if next != "" {
+		t.Fatalf("unexpected next cursor %v", next)
+	}
+	if _, _, err = idx.query(alice, "bad", 2, nil); err == nil {
+		t.Fatalf("invalid cursor accepted")
+	}
+
+	// Filter the categories
+	txs, _, _ = idx.query(alice, "", 10, map[string]bool{AddressTxTransfer: true})
+	checkAddressTxs(t, txs, []uint64{2, 1}, []uint16{1, 0})
+	txs, _, _ = idx.query(contract, "", 10, nil)
+	checkAddressTxs(t, txs, []uint64{1}, []uint16{1})
+	for _, addr := range []common.Address{miner, verifier, proposer} {
+		if txs, _, _ = idx.query(addr, "", 10, nil); len(txs) != 1 {
+			t.Fatalf("tx of %v not indexed", addr.AddrPrefixString())
+		}
+	}
+
+	// Remove the block 2 as it's removed from the chain
+	batch := db.CreateLDBBatch()
+	if err = idx.removeBlock(batch, b2.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if err = batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	txs, _, _ = idx.query(alice, "", 10, nil)
+	checkAddressTxs(t, txs, []uint64{1, 1, 1}, []uint16{2, 1, 0})
+	for _, addr := range []common.Address{verifier, proposer} {
+		if txs, _, _ = idx.query(addr, "", 10, nil); len(txs) != 0 {
+			t.Fatalf("tx of %v not removed", addr.AddrPrefixString())
+		}
+	}
+	if bs, _ := db.Get(addressBlockKey(b2.Hash)); bs != nil {
+		t.Fatalf("block record not removed")
+	}
+}
@@ -134,6 +134,7 @@ func InitBlockSyncer(chain *FullBlockChain) {
 	if blockSync.blockNotifyEnable && len(blockSync.blockNotifyNodes) > 0 {
 		notify.BUS.Subscribe(notify.BlockAddSucc, blockSync.onBlockAddSuccess)
 	}
+	registerSyncMetrics(blockSync)
 }
 
 func (bs *blockSyncer) onBlockAddSuccess(message notify.Message) error {
@@ -353,6 +354,10 @@ func (bs *blockSyncer) detectLowFork() (string, *types.BlockHeader) {
 }
 
 func (bs *blockSyncer) trySyncRoutine() bool {
+	if snapshotSync.isSyncing() {
+		bs.logger.Debugf("snapshot syncing, won't sync")
+		return false
+	}
 	// Detect low fork(more than one epoch blocks lower than local)
 	peer, peerTop := bs.detectLowFork()
 	if peerTop != nil {
@@ -105,6 +105,9 @@ func setupGenesisStateDB(stateDB *account.AccountDB, genesisInfo *types.GenesisI
 		addr := common.BytesToAddress(mem.ID())
 		stateDB.SetBalance(addr, genesisBalance)
 	}
+	for addr, balance := range genesisInfo.Alloc {
+		stateDB.AddBalance(addr, balance)
+	}
 }
 
 func setupFoundationContract(stateDB *account.AccountDB, adminAddr common.Address, totalToken, nonce uint64) *common.Address {
@@ -41,6 +41,7 @@ import (
 
 const (
 	blockStatusKey = "bcurrent"
+	snapshotKey    = "bsnapshot"
 	configSec      = "chain"
 	prune          = "prune"
 )
@@ -68,10 +69,18 @@ type BlockChainConfig struct {
 	reward      string
 	tx          string
 	receipt     string
+	logIndex    string
+	contract    string
+	token       string
+	address     string
 	// Whether running node in pruning mode
 	pruneMode bool
 	// pruning mode config
 	pruneConfig *PruneConfig
+	// Max gas of a transaction simulated
+	callGasCap uint64
+	// Max wall time of the executions not changing the chain, e.g. simulating and tracing
+	callTimeout time.Duration
 }
 
 type PruneConfig struct {
@@ -89,6 +98,7 @@ type FullBlockChain struct {
 	blockHeight     *tasdb.PrefixedDatabase
 	txDb            *tasdb.PrefixedDatabase
 	stateDb         *tasdb.PrefixedDatabase
+	logIndexDb      *tasdb.PrefixedDatabase
 	smallStateDb    *smallStateStore
 	cacheDb         *tasdb.PrefixedDatabase
 	batch           tasdb.Batch
@@ -132,6 +142,16 @@ type FullBlockChain struct {
 	types.Account
 
 	cpChecker *cpChecker
+
+	logIndex *logIndex // Bloom-bits index of the contract logs
+
+	contractRegistry *contractRegistry // Verified contract sources and abis
+	tokenIndexer     *tokenIndexer     // Token balances and transfers, nil if not enabled
+	addressIndex     *addressIndex     // Transactions of each address, nil if not enabled
+
+	// Height of the snapshot the chain synced from, 0 if synced from the genesis. Must be accessed atomically.
+	// The blocks and receipts before it don't exist locally, nor the indexes of them
+	snapshotHeight uint64
 }
 
 func getPruneConfig(pruneMode bool) *PruneConfig {
@@ -185,8 +205,14 @@ func getBlockChainConfig() *BlockChainConfig {
 		reward:      "nu",
 		tx:          "tx",
 		receipt:     "rc",
+		logIndex:    "lb",
+		contract:    "cv",
+		token:       "tk",
+		address:     "ad",
 		pruneConfig: getPruneConfig(pruneMode),
 		pruneMode:   pruneMode,
+		callGasCap:  uint64(common.GlobalConf.GetInt(configSec, "call_gas_cap", GasLimitPerTransaction)),
+		callTimeout: time.Duration(common.GlobalConf.GetInt(configSec, "call_timeout", defaultCallTimeout)) * time.Second,
 	}
 }
 
@@ -262,6 +288,17 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 		Logger.Errorf("Init block chain error! Error:%s", err.Error())
 		return err
 	}
+	chain.logIndexDb, err = ds.NewPrefixDatabase(chain.config.logIndex)
+	if err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
+	}
+	contractDb, err := ds.NewPrefixDatabase(chain.config.contract)
+	if err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
+	}
+	chain.contractRegistry = newContractRegistry(contractDb)
 
 	var sdbOptions *opt.Options
 	if chain.config.pruneMode {
@@ -292,6 +329,7 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 	chain.stateCache = account.NewDatabaseWithCache(chain.stateDb, chain.config.pruneMode, stateCacheSize, conf.GetString("state_cache_dir", ""))
 
 	latestBH := chain.loadCurrentBlock()
+	chain.snapshotHeight = chain.loadSnapshotHeight()
 
 	GroupManagerImpl = group.NewManager(chain, helper)
 
@@ -308,6 +346,27 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 		return err
 	}
 	latestBH = chain.latestBlock
+	var top uint64
+	if nil != latestBH {
+		top = latestBH.Height
+	}
+	chain.logIndex, err = initLogIndex(chain.logIndexDb, top)
+	if err != nil {
+		Logger.Errorf("init log index error:%v", err)
+		return err
+	}
+	if common.GlobalConf.GetBool(configSec, "index_addresses", false) {
+		addressDb, err := ds.NewPrefixDatabase(chain.config.address)
+		if err != nil {
+			Logger.Errorf("Init block chain error! Error:%s", err.Error())
+			return err
+		}
+		chain.addressIndex, err = initAddressIndex(addressDb, top, chain.rewardManager)
+		if err != nil {
+			Logger.Errorf("init address index error:%v", err)
+			return err
+		}
+	}
 	if nil != latestBH {
 		if !chain.versionValidate() {
 			fmt.Println("Illegal data version! Please delete the directory d0 and restart the program!")
@@ -328,6 +387,24 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 		chain.insertGenesisBlock()
 	}
 
+	go chain.backfillLogIndex()
+	if chain.addressIndex != nil {
+		go chain.backfillAddressIndex()
+	}
+
+	if common.GlobalConf.GetBool(configSec, "index_tokens", false) {
+		tokenDb, err := ds.NewPrefixDatabase(chain.config.token)
+		if err != nil {
+			Logger.Errorf("Init block chain error! Error:%s", err.Error())
+			return err
+		}
+		chain.tokenIndexer, err = initTokenIndexer(chain, tokenDb, chain.queryBlockHeaderByHeight(0))
+		if err != nil {
+			Logger.Errorf("init token index error:%v", err)
+			return err
+		}
+	}
+
 	chain.forkProcessor = initForkProcessor(chain, helper)
 
 	BlockChainImpl = chain
@@ -359,6 +436,7 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 	initStakeGetter(MinerManagerImpl, chain)
 
 	chain.LogDbStats()
+	registerChainMetrics(chain)
 	return nil
 }
 
@@ -0,0 +1,141 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+const defaultCallTimeout = 5 // seconds
+
+// vmLock serializes the executions of the vm as its controller is a singleton. The block executing takes it
+// under chain.mu, while the executions not changing the chain take it alone so that they never block
+// the casting and adding of blocks on the chain lock
+var vmLock = make(chan struct{}, 1)
+
+func lockVM()   { vmLock <- struct{}{} }
+func unlockVM() { <-vmLock }
+
+// runOnVM runs f holding the vm lock and returns an error if the vm isn't available or f isn't finished
+// within the timeout. As the vm can't be interrupted, f keeps running to the end with the lock held
+// in the latter case, its gas limit bounds the execution
+func runOnVM(timeout time.Duration, f func()) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case vmLock <- struct{}{}:
+	case <-timer.C:
+		return fmt.Errorf("vm busy, timeout after %v", timeout)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer unlockVM()
+		f()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("execution timeout after %v", timeout)
+	}
+}
+
+// CallResult is the result of a transaction executed without committing the state
+type CallResult struct {
+	Result  string                  // Content returned by the vm
+	Logs    []*types.Log            // Logs generated during the execution
+	GasUsed uint64                  // Total gas used including the intrinsic gas
+	Status  types.ReceiptStatus     // Status as the receipt will record
+	Message string                  // Error message if the execution failed
+	Error   *types.TransactionError // Error returned by the vm
+}
+
+// SimulateTransaction executes the transaction on a throwaway state of the given height. The nonce,
+// signature and gas fee of the transaction are not checked and nothing will be committed.
+// If the gas limit not set, the gas cap of the calls is used
+func (chain *FullBlockChain) SimulateTransaction(tx *types.Transaction, height uint64) (*CallResult, error) {
+	if tx.Source == nil {
+		return nil, fmt.Errorf("source is nil")
+	}
+	if tx.Type >= types.SystemTransactionOffset {
+		return nil, fmt.Errorf("unsupported transaction type %v", tx.Type)
+	}
+	gasCap := chain.config.callGasCap
+	if gasCap == 0 || gasCap > GasLimitPerTransaction {
+		gasCap = GasLimitPerTransaction
+	}
+	if tx.GasLimit == nil || tx.GasLimit.Uint64() == 0 {
+		tx.GasLimit = types.NewBigInt(gasCap)
+	}
+	if tx.GasLimit.Uint64() > gasCap {
+		return nil, fmt.Errorf("gas limit too high, the cap is %v", gasCap)
+	}
+	if tx.Value == nil {
+		tx.Value = types.NewBigInt(0)
+	}
+	if tx.GasPrice == nil {
+		tx.GasPrice = types.NewBigInt(0)
+	}
+	intrinsic := intrinsicGas(tx)
+	if tx.GasLimit.Cmp(intrinsic) < 0 {
+		return nil, fmt.Errorf("gas limit less than intrinsic gas %v", intrinsic)
+	}
+
+	bh := chain.QueryBlockHeaderFloor(height)
+	if bh == nil {
+		return nil, fmt.Errorf("block not found at height %v", height)
+	}
+	// A new state of the block is never committed, the chain lock isn't needed
+	state, err := chain.AccountDBAt(bh.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := newStateTransition(state, tx, bh)
+	var ret *result
+	err = runOnVM(chain.config.callTimeout, func() {
+		if err := ss.ParseTransaction(); err != nil {
+			ret = newResult()
+			ret.setError(err, types.RSParseFail)
+		} else {
+			ret = doTransition(state, ss)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cr := &CallResult{
+		Logs:    ret.logs,
+		GasUsed: ss.GasUsed().Uint64(),
+		Status:  ret.transitionStatus,
+		Error:   ret.vmErr,
+	}
+	if cr.Logs == nil {
+		cr.Logs = make([]*types.Log, 0)
+	}
+	if ret.vmResult != nil && ret.vmErr == nil {
+		cr.Result = ret.vmResult.Content
+	}
+	if ret.err != nil {
+		cr.Message = ret.err.Error()
+	}
+	return cr, nil
+}
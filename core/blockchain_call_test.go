@@ -0,0 +1,116 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestSimulateTransaction(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	chain := BlockChainImpl
+
+	source := common.BytesToAddress([]byte("simulate_source"))
+	target := common.BytesToAddress([]byte("simulate_target"))
+	newTx := func(typ int8, value uint64) *types.Transaction {
+		raw := &types.RawTransaction{Type: typ, Source: &source, Target: &target, Value: types.NewBigInt(value)}
+		return &types.Transaction{RawTransaction: raw}
+	}
+
+	cr, err := chain.SimulateTransaction(newTx(types.TransactionTypeTransfer, 0), chain.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Status != types.RSSuccess || cr.GasUsed != TransactionGasCost {
+		t.Fatalf("unexpected result:%+v", cr)
+	}
+
+	cr, err = chain.SimulateTransaction(newTx(types.TransactionTypeTransfer, 100), chain.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Status != types.RSBalanceNotEnough {
+		t.Fatalf("expect balance not enough, got %+v", cr)
+	}
+
+	cr, err = chain.SimulateTransaction(newTx(types.TransactionTypeContractCall, 0), chain.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Status != types.RSNoCodeError || cr.Error == nil || cr.Error.Code != types.TVMNoCodeError {
+		t.Fatalf("expect no code error, got %+v", cr)
+	}
+
+	if _, err = chain.SimulateTransaction(newTx(types.TransactionTypeReward, 0), chain.Height()); err == nil {
+		t.Fatal("reward transaction should not be simulated")
+	}
+	tx := newTx(types.TransactionTypeTransfer, 0)
+	tx.GasLimit = types.NewBigInt(GasLimitPerTransaction + 1)
+	if _, err = chain.SimulateTransaction(tx, chain.Height()); err == nil {
+		t.Fatal("should fail when gas limit too high")
+	}
+
+	// Nothing committed
+	if nonce := chain.GetNonce(source); nonce != 0 {
+		t.Fatalf("nonce should not be changed:%v", nonce)
+	}
+
+	// Not blocked by the chain lock
+	chain.mu.Lock()
+	_, err = chain.SimulateTransaction(newTx(types.TransactionTypeTransfer, 0), chain.Height())
+	chain.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.config.callGasCap = 100000
+	tx = newTx(types.TransactionTypeTransfer, 0)
+	tx.GasLimit = types.NewBigInt(100001)
+	if _, err = chain.SimulateTransaction(tx, chain.Height()); err == nil {
+		t.Fatal("should fail when gas limit over the cap")
+	}
+	tx = newTx(types.TransactionTypeTransfer, 0)
+	if _, err = chain.SimulateTransaction(tx, chain.Height()); err != nil || tx.GasLimit.Uint64() != 100000 {
+		t.Fatalf("gas limit should be the cap: %v %v", tx.GasLimit, err)
+	}
+}
+
+func TestRunOnVMTimeout(t *testing.T) {
+	lockVM()
+	if err := runOnVM(10*time.Millisecond, func() {}); err == nil {
+		t.Fatal("should time out when the vm busy")
+	}
+	unlockVM()
+
+	release := make(chan struct{})
+	if err := runOnVM(10*time.Millisecond, func() { <-release }); err == nil {
+		t.Fatal("should time out when the execution doesn't finish")
+	}
+	close(release)
+
+	executed := false
+	if err := runOnVM(time.Second, func() { executed = true }); err != nil || !executed {
+		t.Fatalf("should be executed after the vm released: %v", err)
+	}
+}
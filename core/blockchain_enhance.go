@@ -64,9 +64,12 @@ type localBlockProvider struct {
 	top   uint64
 }
 
+// NewLocalBlockProvider opens the chain database of the dir read-only, the configured one is used if dir is empty
 func NewLocalBlockProvider(dir string) (BlockProvider, error) {
 	config := getBlockChainConfig()
-	config.dbfile = dir
+	if dir != "" {
+		config.dbfile = dir
+	}
 	chain := &FullBlockChain{
 		config:          config,
 		latestBlock:     nil,
@@ -126,18 +129,24 @@ func (p *localBlockProvider) Height() uint64 {
 	return p.top
 }
 
+// failableProvider is implemented by the block providers which may fail in the middle of providing
+type failableProvider interface {
+	Err() error
+}
+
 type fastReplayer struct {
 	blocksCh chan []*types.Block
-	finishCh chan struct{}
+	quitCh   chan struct{}
 	provider BlockProvider
 	chain    *FullBlockChain
 	out      io.Writer
+	err      error // Error of the provider, set before blocksCh closed
 }
 
 func NewFastReplayer(bp BlockProvider, chain *FullBlockChain, out io.Writer) Replayer {
 	return &fastReplayer{
 		blocksCh: make(chan []*types.Block, 1),
-		finishCh: make(chan struct{}),
+		quitCh:   make(chan struct{}),
 		provider: bp,
 		chain:    chain,
 		out:      out,
@@ -146,59 +155,66 @@ func NewFastReplayer(bp BlockProvider, chain *FullBlockChain, out io.Writer) Rep
 
 func (fr *fastReplayer) produce(begin uint64) {
 	const step = 200
-	for {
+	defer close(fr.blocksCh)
+	for begin <= fr.provider.Height() {
 		blocks := fr.provider.Provide(begin, begin+step)
-
+		if fp, ok := fr.provider.(failableProvider); ok && fp.Err() != nil {
+			fr.err = fp.Err()
+			return
+		}
 		if len(blocks) == 0 {
 			begin += step
 			continue
 		}
 		begin = blocks[len(blocks)-1].Header.Height + 1
-		top := fr.provider.Height()
 
-		fr.blocksCh <- blocks
-
-		if top <= begin {
-			fr.finishCh <- struct{}{}
-			break
+		select {
+		case fr.blocksCh <- blocks:
+		case <-fr.quitCh:
+			return
 		}
 	}
 }
 
 func (fr *fastReplayer) consume() error {
+	defer close(fr.quitCh)
 	begin := time.Now()
 	cnt := 0
-	for {
-		select {
-		case blocks := <-fr.blocksCh:
-			t := time.Now()
-			if len(blocks) == 0 {
-				continue
-			}
-			for _, b := range blocks {
-				ret, err := fr.chain.addBlockOnChain("", b)
-				if ret != types.AddBlockSucc {
-					return fmt.Errorf("consume block %v %v error:%v", b.Header.Hash, b.Header.Height, err)
-				}
+	for blocks := range fr.blocksCh {
+		t := time.Now()
+		for _, b := range blocks {
+			ret, err := fr.chain.addBlockOnChain("", b)
+			if ret != types.AddBlockSucc {
+				return fmt.Errorf("consume block %v %v error:%v", b.Header.Hash, b.Header.Height, err)
 			}
-			cnt += len(blocks)
-			cost := time.Since(t)
-			bps := float64(len(blocks)) / cost.Seconds()
-			top := fr.provider.Height()
-			last := blocks[len(blocks)-1].Header.Height + 1
-			remainT := time.Duration(float64(top-last)/bps) * time.Second
-
-			fr.out.Write([]byte(fmt.Sprintf("replay block %v finished, bps %v, remain %v\n", last-1, bps, remainT.String())))
-		case <-fr.finishCh:
-			fr.out.Write([]byte(fmt.Sprintf("replay total %v blocks finished, cost %v\n", cnt, time.Since(begin).String())))
-			break
 		}
+		cnt += len(blocks)
+		cost := time.Since(t)
+		bps := float64(len(blocks)) / cost.Seconds()
+		top := fr.provider.Height()
+		last := blocks[len(blocks)-1].Header.Height + 1
+		remainT := time.Duration(float64(top-last)/bps) * time.Second
+
+		fr.out.Write([]byte(fmt.Sprintf("replay block %v finished, bps %v, remain %v\n", last-1, bps, remainT.String())))
 	}
+	if fr.err != nil {
+		return fr.err
+	}
+	fr.out.Write([]byte(fmt.Sprintf("replay total %v blocks finished, cost %v\n", cnt, time.Since(begin).String())))
 	return nil
 }
 
+// Replay adds the blocks higher than the local top onto the chain, so it resumes from where it stopped if interrupted
 func (fr *fastReplayer) Replay(provider BlockProvider, out io.Writer) error {
 	fr.out.Write([]byte(fmt.Sprintf("source chain height %v\n", fr.provider.Height())))
-	go fr.produce(fr.chain.Height() + 1)
+	begin := fr.chain.Height() + 1
+	if begin > fr.provider.Height() {
+		fr.out.Write([]byte(fmt.Sprintf("local chain height %v, nothing to replay\n", begin-1)))
+		return nil
+	}
+	if begin > 1 {
+		fr.out.Write([]byte(fmt.Sprintf("replay from height %v\n", begin)))
+	}
+	go fr.produce(begin)
 	return fr.consume()
 }
@@ -0,0 +1,90 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/account"
+	"github.com/zvchain/zvchain/storage/trie"
+)
+
+// AccountProof is the merkle proof of an account and its storage data against the state tree of a block
+type AccountProof struct {
+	Header        *types.BlockHeader
+	Account       *account.Account // Nil if the account not exists
+	Proof         [][]byte         // Trie nodes from the state tree root to the account
+	StorageProofs []*StorageProof
+}
+
+// StorageProof is the merkle proof of a storage key against the storage root of the account
+type StorageProof struct {
+	Key   []byte
+	Value []byte // Nil if the key not exists
+	Proof [][]byte
+}
+
+// GetProof returns the merkle proofs of the account and the given storage keys on the state of the
+// block at the given height. If no block at the height, the highest block lower than the height is used
+func (chain *FullBlockChain) GetProof(addr common.Address, keys [][]byte, height uint64) (*AccountProof, error) {
+	chain.rwLock.RLock()
+	bh := chain.queryBlockHeaderByHeightFloor(height)
+	if bh == nil {
+		chain.rwLock.RUnlock()
+		return nil, fmt.Errorf("block not found at height %v", height)
+	}
+	db, err := account.NewAccountDB(bh.StateTree, chain.stateCache)
+	chain.rwLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := db.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	// Read the account out of the proof, which also makes sure the proof is valid
+	acc, err := account.VerifyAccountProof(bh.StateTree, addr, proof)
+	if err != nil {
+		return nil, err
+	}
+	ret := &AccountProof{
+		Header:        bh,
+		Account:       acc,
+		Proof:         proof,
+		StorageProofs: make([]*StorageProof, 0, len(keys)),
+	}
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	proofs, err := db.GetStorageProof(addr, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		sp := &StorageProof{Key: key, Proof: proofs[i]}
+		if acc != nil {
+			if sp.Value, err = trie.VerifyProof(acc.Root, key, proofs[i]); err != nil {
+				return nil, err
+			}
+		}
+		ret.StorageProofs = append(ret.StorageProofs, sp)
+	}
+	return ret, nil
+}
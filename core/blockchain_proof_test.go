@@ -0,0 +1,55 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/account"
+)
+
+func TestGetProof(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	chain := BlockChainImpl
+
+	p, err := chain.GetProof(common.MinerPoolAddr, [][]byte{[]byte("not_exist")}, chain.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Account == nil || p.Account.Nonce != 1 {
+		t.Fatalf("unexpected account:%+v", p.Account)
+	}
+	acc, err := account.VerifyAccountProof(p.Header.StateTree, common.MinerPoolAddr, p.Proof)
+	if err != nil || acc == nil || acc.Nonce != 1 {
+		t.Fatalf("verify proof fail:%v", err)
+	}
+	if len(p.StorageProofs) != 1 || p.StorageProofs[0].Value != nil {
+		t.Fatalf("unexpected storage proofs:%+v", p.StorageProofs)
+	}
+
+	p, err = chain.GetProof(common.BytesToAddress([]byte("proof_not_exist")), nil, chain.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Account != nil || len(p.Proof) == 0 {
+		t.Fatalf("expect absence proof, got %+v", p)
+	}
+}
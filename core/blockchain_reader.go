@@ -22,6 +22,7 @@ import (
 	"github.com/zvchain/zvchain/storage/account"
 	"math"
 	"math/big"
+	"sync/atomic"
 )
 
 // Height of chain
@@ -296,9 +297,27 @@ func (chain *FullBlockChain) ScanBlockHeightsInRange(startHeight uint64, endHeig
 
 func (chain *FullBlockChain) CheckPointAt(h uint64) *types.BlockHeader {
 	cp := chain.cpChecker.checkpointAt(h)
+	// The blocks before the snapshot aren't synced, and the snapshot block is trusted as the checkpoint
+	if sh := chain.SnapshotHeight(); cp < sh && h >= sh {
+		cp = sh
+	}
 	return chain.QueryBlockHeaderFloor(cp)
 }
 
+// SnapshotHeight returns the height of the snapshot the chain synced from, 0 if synced from the genesis
+func (chain *FullBlockChain) SnapshotHeight() uint64 {
+	return atomic.LoadUint64(&chain.snapshotHeight)
+}
+
+// CheckHistoryAvailable returns an error if the blocks from the given height aren't all executed locally,
+// in which case the receipts and the indexes of them are not available
+func (chain *FullBlockChain) CheckHistoryAvailable(from uint64) error {
+	if sh := chain.SnapshotHeight(); sh > 0 && from <= sh {
+		return fmt.Errorf("history not available before height %v, the node is synced from the snapshot", sh+1)
+	}
+	return nil
+}
+
 func (chain *FullBlockChain) LatestCheckPoint() *types.BlockHeader {
 	cpBh := chain.latestCP.Load()
 	if cpBh != nil && cpBh.Height < chain.Height() {
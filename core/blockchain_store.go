@@ -270,10 +270,28 @@ func (chain *FullBlockChain) commitBlock(block *types.Block, ps *executePostStat
 	if err = chain.saveBlockTxs(bh.Hash, bodyBytes); err != nil {
 		return
 	}
+	// Fill the receipt blooms after the receipt tree verified, so they are stored with the receipts
+	for _, rc := range ps.receipts {
+		if len(rc.Logs) > 0 {
+			rc.Bloom = types.BytesToBloom(types.LogsBloom(rc.Logs).Bytes())
+		}
+	}
 	// Save hash to receipt key value pair
 	if err = chain.transactionPool.SaveReceipts(bh.Hash, ps.receipts); err != nil {
 		return
 	}
+	// Add the block to the log index
+	w := chain.logIndex.newWriter(chain.batch)
+	w.update(bh.Height, ps.receipts, true)
+	if err = w.flush(); err != nil {
+		return
+	}
+	// Add the block to the address index
+	if chain.addressIndex != nil {
+		if err = chain.addressIndex.addBlock(chain.batch, bh, block.Transactions, ps.receipts); err != nil {
+			return
+		}
+	}
 	// Save current block
 	if err = chain.saveCurrentBlock(bh.Hash); err != nil {
 		return
@@ -308,6 +326,70 @@ func (chain *FullBlockChain) commitBlock(block *types.Block, ps *executePostStat
 	return
 }
 
+// commitSnapshotBlock persists the block without executing it and sets it as the top.
+// The state of the block should have been synced into the state database
+func (chain *FullBlockChain) commitSnapshotBlock(block *types.Block) error {
+	if err := chain.saveSnapshotBlock(block); err != nil {
+		return err
+	}
+	// The snapshot block is trusted as the checkpoint, and the groups of the checkpoint epoch are read
+	// from the state synced. It's done without the lock held as the groups are read with it
+	chain.latestCP.Store(block.Header)
+	chain.cpChecker.reset(types.EpochAt(block.Header.Height))
+	return nil
+}
+
+// saveSnapshotBlock saves the block and sets it as the top, the state of it should be already in the database
+func (chain *FullBlockChain) saveSnapshotBlock(block *types.Block) error {
+	bh := block.Header
+	state, err := account.NewAccountDB(bh.StateTree, chain.stateCache)
+	if err != nil {
+		return fmt.Errorf("open state error:%v", err)
+	}
+	headerBytes, err := types.MarshalBlockHeader(bh)
+	if err != nil {
+		return err
+	}
+	bodyBytes, err := encodeBlockTransactions(block)
+	if err != nil {
+		return err
+	}
+
+	chain.rwLock.Lock()
+	defer chain.rwLock.Unlock()
+	defer chain.batch.Reset()
+
+	if err = chain.saveBlockHeader(bh.Hash, headerBytes); err != nil {
+		return err
+	}
+	if err = chain.saveBlockHeight(bh.Height, bh.Hash.Bytes()); err != nil {
+		return err
+	}
+	if err = chain.saveBlockTxs(bh.Hash, bodyBytes); err != nil {
+		return err
+	}
+	if err = chain.saveCurrentBlock(bh.Hash); err != nil {
+		return err
+	}
+	if err = chain.blocks.AddKv(chain.batch, []byte(snapshotKey), common.UInt64ToByte(bh.Height)); err != nil {
+		return err
+	}
+	if err = chain.batch.Write(); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&chain.snapshotHeight, bh.Height)
+	chain.updateLatestBlock(state, bh)
+	return nil
+}
+
+func (chain *FullBlockChain) loadSnapshotHeight() uint64 {
+	bs, err := chain.blocks.Get([]byte(snapshotKey))
+	if err != nil || len(bs) == 0 {
+		return 0
+	}
+	return common.ByteToUInt64(bs)
+}
+
 func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	if !chain.isAdjusting {
 		chain.isAdjusting = true
@@ -344,6 +426,8 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	delReceipts := make([]common.Hash, 0)
 	removeBlocks := make([]*types.BlockHeader, 0)
 	removeSDBHeights := make([]uint64, 0)
+	removedMsgs := make([]*notify.BlockRemovedMessage, 0)
+	logIndexWriter := chain.logIndex.newWriter(chain.batch)
 	for curr.Hash != block.Hash {
 		// Delete the old block header
 		if err = chain.saveBlockHeader(curr.Hash, nil); err != nil {
@@ -358,11 +442,23 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 			return err
 		}
 		rawTxs := chain.queryBlockTransactionsAll(curr.Hash)
+		receipts := make(types.Receipts, 0, len(rawTxs))
 		for _, rawTx := range rawTxs {
 			tHash := rawTx.GenHash()
 			recoverTxs = append(recoverTxs, types.NewTransaction(rawTx, tHash))
 			delReceipts = append(delReceipts, tHash)
+			// Read the receipts before deleted to revert the log index
+			if rc := chain.transactionPool.GetReceipt(tHash); rc != nil {
+				receipts = append(receipts, rc)
+			}
 		}
+		logIndexWriter.update(curr.Height, receipts, false)
+		if chain.addressIndex != nil {
+			if err = chain.addressIndex.removeBlock(chain.batch, curr.Hash); err != nil {
+				return err
+			}
+		}
+		removedMsgs = append(removedMsgs, &notify.BlockRemovedMessage{Block: &types.Block{Header: curr, Transactions: rawTxs}})
 		removeSDBHeights = append(removeSDBHeights, curr.Height)
 		chain.removeTopBlock(curr.Hash)
 		removeBlocks = append(removeBlocks, curr)
@@ -376,6 +472,10 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	if err = chain.transactionPool.DeleteReceipts(delReceipts); err != nil {
 		return err
 	}
+	// Remove the discard blocks from the log index
+	if err = logIndexWriter.flush(); err != nil {
+		return err
+	}
 	// Reset the current block
 	if err = chain.saveCurrentBlock(block.Hash); err != nil {
 		return err
@@ -412,6 +512,11 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	// invalidate latest cp cache
 	chain.latestCP.Reset()
 
+	// Notify the removed blocks. The handlers run asynchronously, so the subscribers can't rely on
+	// the order of the messages, and should follow the chain from their own tip instead
+	for _, msg := range removedMsgs {
+		notify.BUS.Publish(notify.BlockRemoved, msg)
+	}
 	// Notify reset top message
 	notify.BUS.Publish(notify.NewTopBlock, &newTopMessage{bh: block})
 
@@ -445,20 +550,35 @@ func (chain *FullBlockChain) removeOrphan(block *types.Block) error {
 		return err
 	}
 	txs := chain.queryBlockTransactionsAll(hash)
+	receipts := make(types.Receipts, 0, len(txs))
 	if txs != nil {
 		txHashs := make([]common.Hash, len(txs))
 		for i, tx := range txs {
 			txHashs[i] = tx.GenHash()
+			if rc := chain.transactionPool.GetReceipt(txHashs[i]); rc != nil {
+				receipts = append(receipts, rc)
+			}
 		}
 		if err = chain.transactionPool.DeleteReceipts(txHashs); err != nil {
 			return err
 		}
+		w := chain.logIndex.newWriter(chain.batch)
+		w.update(height, receipts, false)
+		if err = w.flush(); err != nil {
+			return err
+		}
+	}
+	if chain.addressIndex != nil {
+		if err = chain.addressIndex.removeBlock(chain.batch, hash); err != nil {
+			return err
+		}
 	}
 
 	if err = chain.batch.Write(); err != nil {
 		return err
 	}
 	chain.removeTopBlock(hash)
+	notify.BUS.Publish(notify.BlockRemoved, &notify.BlockRemovedMessage{Block: block})
 	return nil
 }
 
@@ -0,0 +1,139 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/tvm"
+)
+
+// TxTrace is the trace of a transaction re-executed in its block
+type TxTrace struct {
+	TxHash  common.Hash
+	Type    int8
+	Status  types.ReceiptStatus
+	GasUsed uint64
+	Error   string         // Error message if the execution failed
+	Call    *tvm.CallFrame // Trace of the vm, nil if the transaction doesn't run the vm
+}
+
+// TraceTransaction re-executes the block of the transaction up to it on the state before the block,
+// and returns the trace of the transaction
+func (chain *FullBlockChain) TraceTransaction(hash common.Hash) (*TxTrace, error) {
+	rc := chain.transactionPool.GetReceipt(hash)
+	if rc == nil {
+		return nil, fmt.Errorf("transaction not found in the chain")
+	}
+	b := chain.QueryBlockByHeight(rc.Height)
+	if b == nil {
+		return nil, fmt.Errorf("block not found at height %v", rc.Height)
+	}
+	traces, err := chain.traceBlock(b, &hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("transaction not found in the block")
+	}
+	return traces[len(traces)-1], nil
+}
+
+// TraceBlock re-executes the block of the height on the state before it and returns the traces of all transactions
+func (chain *FullBlockChain) TraceBlock(height uint64) ([]*TxTrace, error) {
+	b := chain.QueryBlockByHeight(height)
+	if b == nil {
+		return nil, fmt.Errorf("block not found at height %v", height)
+	}
+	return chain.traceBlock(b, nil)
+}
+
+// traceBlock executes the transactions of the block in order and stops after the target one if given.
+// Only the target one is traced in that case
+func (chain *FullBlockChain) traceBlock(b *types.Block, target *common.Hash) ([]*TxTrace, error) {
+	if b.Header.Height == 0 {
+		return nil, fmt.Errorf("can't trace the genesis block")
+	}
+	pre := chain.QueryBlockHeaderByHash(b.Header.PreHash)
+	if pre == nil {
+		return nil, fmt.Errorf("parent block not found of %v", b.Header.Hash.Hex())
+	}
+
+	// A new state of the parent block is never committed, the chain lock isn't needed
+	state, err := chain.AccountDBAt(pre.Height)
+	if err != nil {
+		return nil, err
+	}
+	var traces []*TxTrace
+	err = runOnVM(chain.config.callTimeout, func() {
+		traces = traceTransactions(state, b, target)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// traceTransactions executes the transactions of the block on the state, it must be called with the vm lock held
+func traceTransactions(state types.AccountDB, b *types.Block, target *common.Hash) []*TxTrace {
+	traces := make([]*TxTrace, 0)
+	for _, raw := range b.Transactions {
+		tx := types.NewTransaction(raw, raw.GenHash())
+		traced := target == nil || *target == tx.Hash
+		var tracer *tvm.CallTracer
+		if traced {
+			tracer = tvm.NewCallTracer()
+		}
+		trace := &TxTrace{TxHash: tx.Hash, Type: tx.Type}
+
+		var (
+			ret *result
+			err error
+		)
+		if tracer != nil {
+			ret, err = applyTracedStateTransition(state, tx, b.Header, tracer)
+		} else {
+			ret, err = applyStateTransition(state, tx, b.Header)
+		}
+		if err != nil {
+			// Shouldn't happen as the transaction is executed successfully when the block added
+			trace.Status = types.RSFail
+			trace.Error = err.Error()
+		} else {
+			if tx.Source != nil {
+				state.SetNonce(*tx.Source, tx.Nonce)
+			}
+			trace.Status = ret.transitionStatus
+			if ret.cumulativeGasUsed != nil {
+				trace.GasUsed = ret.cumulativeGasUsed.Uint64()
+			}
+			if ret.err != nil {
+				trace.Error = ret.err.Error()
+			}
+		}
+		if !traced {
+			continue
+		}
+		trace.Call = tracer.Result()
+		traces = append(traces, trace)
+		if target != nil {
+			break
+		}
+	}
+	return traces
+}
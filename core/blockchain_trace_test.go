@@ -0,0 +1,89 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestTraceBlock(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	initBalance()
+	chain := BlockChainImpl
+
+	txs := []*types.Transaction{genTestTx(500, "100", 1, 1), genTestTx(500, "2", 2, 1)}
+	call := genTestTx(500, "3", 3, 0)
+	call.Type = types.TransactionTypeContractCall
+	call.Data = []byte(`{"func_name": "test", "args": []}`)
+	call.Hash = call.GenHash()
+	sign, _ := common.HexToSecKey(privateKey).Sign(call.Hash.Bytes())
+	call.Sign = sign.Bytes()
+	txs = append(txs, call)
+	for _, tx := range txs {
+		if _, err := chain.GetTransactionPool().AddTransaction(tx); err != nil {
+			t.Fatalf("add transaction fail:%v", err)
+		}
+	}
+
+	block := chain.CastBlock(1, common.Hex2Bytes("12"), 0, []byte{}, common.HexToHash("ab454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7ff4"))
+	if block == nil || len(block.Transactions) != len(txs) {
+		t.Fatalf("cast block fail")
+	}
+	if chain.AddBlockOnChain("", block) != types.AddBlockSucc {
+		t.Fatalf("add block fail")
+	}
+
+	traces, err := chain.TraceBlock(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != len(txs) {
+		t.Fatalf("unexpected traces count %v", len(traces))
+	}
+	for _, trace := range traces {
+		rc := chain.GetTransactionPool().GetReceipt(trace.TxHash)
+		if rc == nil {
+			t.Fatalf("receipt not found %v", trace.TxHash.Hex())
+		}
+		if trace.Status != rc.Status || trace.GasUsed != rc.CumulativeGasUsed {
+			t.Fatalf("trace %+v mismatch the receipt %+v", trace, rc)
+		}
+		if trace.Call != nil {
+			t.Fatalf("unexpected vm trace of %v", trace.TxHash.Hex())
+		}
+	}
+
+	trace, err := chain.TraceTransaction(call.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.TxHash != call.Hash || trace.Status != types.RSNoCodeError || trace.Error == "" {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	if _, err := chain.TraceTransaction(common.HexToHash("0x1234")); err == nil {
+		t.Fatal("expect not found error")
+	}
+	if _, err := chain.TraceBlock(0); err == nil {
+		t.Fatal("expect genesis error")
+	}
+}
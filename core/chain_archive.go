@@ -0,0 +1,356 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// The chain archive is a portable stream of blocks in ascending height order.
+//
+// Layout:
+//
+//	header: magic(4) | version(2) | flags(2) | from(8) | to(8) | crc32 of the previous fields(4)
+//	body:   record* | terminator, gzip compressed if archiveFlagCompressed is set
+//	record: length(4) | crc32 of the payload(4) | payload, payload is the protobuf encoded block
+//	terminator: zero length(4) | block count(8)
+//
+// All integers are big endian.
+const (
+	ArchiveVersion = 1
+
+	archiveFlagCompressed = 1 << 0
+
+	archiveHeaderSize = 28
+	maxArchiveRecord  = 64 << 20
+)
+
+var archiveMagic = []byte("ZVCA")
+
+// ArchiveHeader describes the block range of the archive
+type ArchiveHeader struct {
+	Version    uint16
+	Compressed bool
+	From       uint64 // Lower bound of the block heights
+	To         uint64 // Upper bound of the block heights, no greater than the source top when exported
+}
+
+func (h *ArchiveHeader) encode() []byte {
+	buf := make([]byte, archiveHeaderSize)
+	copy(buf, archiveMagic)
+	binary.BigEndian.PutUint16(buf[4:], h.Version)
+	var flags uint16
+	if h.Compressed {
+		flags |= archiveFlagCompressed
+	}
+	binary.BigEndian.PutUint16(buf[6:], flags)
+	binary.BigEndian.PutUint64(buf[8:], h.From)
+	binary.BigEndian.PutUint64(buf[16:], h.To)
+	binary.BigEndian.PutUint32(buf[24:], crc32.ChecksumIEEE(buf[:24]))
+	return buf
+}
+
+func decodeArchiveHeader(buf []byte) (*ArchiveHeader, error) {
+	if !bytes.Equal(buf[:4], archiveMagic) {
+		return nil, fmt.Errorf("not a chain archive")
+	}
+	if binary.BigEndian.Uint32(buf[24:]) != crc32.ChecksumIEEE(buf[:24]) {
+		return nil, fmt.Errorf("archive header checksum mismatch")
+	}
+	h := &ArchiveHeader{
+		Version: binary.BigEndian.Uint16(buf[4:]),
+		From:    binary.BigEndian.Uint64(buf[8:]),
+		To:      binary.BigEndian.Uint64(buf[16:]),
+	}
+	if h.Version != ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %v", h.Version)
+	}
+	flags := binary.BigEndian.Uint16(buf[6:])
+	if flags&^archiveFlagCompressed != 0 {
+		return nil, fmt.Errorf("unknown archive flags %x", flags)
+	}
+	h.Compressed = flags&archiveFlagCompressed != 0
+	if h.From > h.To {
+		return nil, fmt.Errorf("invalid archive range [%v, %v]", h.From, h.To)
+	}
+	return h, nil
+}
+
+// ArchiveWriter writes blocks of the range given by the header into the archive
+type ArchiveWriter struct {
+	header *ArchiveHeader
+	w      *bufio.Writer
+	gz     *gzip.Writer
+	body   io.Writer
+	count  uint64
+	last   uint64
+}
+
+// NewArchiveWriter writes the archive header to w and returns the writer of the blocks in the range [from, to]
+func NewArchiveWriter(w io.Writer, from, to uint64, compress bool) (*ArchiveWriter, error) {
+	h := &ArchiveHeader{Version: ArchiveVersion, Compressed: compress, From: from, To: to}
+	if from > to {
+		return nil, fmt.Errorf("invalid archive range [%v, %v]", from, to)
+	}
+	aw := &ArchiveWriter{header: h, w: bufio.NewWriter(w)}
+	if _, err := aw.w.Write(h.encode()); err != nil {
+		return nil, err
+	}
+	aw.body = aw.w
+	if compress {
+		aw.gz = gzip.NewWriter(aw.w)
+		aw.body = aw.gz
+	}
+	return aw, nil
+}
+
+// WriteBlock appends the block, which should be higher than the previous one and in the range of the archive
+func (aw *ArchiveWriter) WriteBlock(b *types.Block) error {
+	height := b.Header.Height
+	if height < aw.header.From || height > aw.header.To {
+		return fmt.Errorf("block %v out of the archive range [%v, %v]", height, aw.header.From, aw.header.To)
+	}
+	if aw.count > 0 && height <= aw.last {
+		return fmt.Errorf("block %v not in ascending order, last %v", height, aw.last)
+	}
+	payload, err := types.MarshalBlock(b)
+	if err != nil {
+		return err
+	}
+	var prefix [8]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(prefix[4:], crc32.ChecksumIEEE(payload))
+	if _, err := aw.body.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err := aw.body.Write(payload); err != nil {
+		return err
+	}
+	aw.count++
+	aw.last = height
+	return nil
+}
+
+// Count returns the number of blocks written
+func (aw *ArchiveWriter) Count() uint64 {
+	return aw.count
+}
+
+// Close writes the terminator and flushes the archive. The underlying writer is not closed
+func (aw *ArchiveWriter) Close() error {
+	var term [12]byte
+	binary.BigEndian.PutUint64(term[4:], aw.count)
+	if _, err := aw.body.Write(term[:]); err != nil {
+		return err
+	}
+	if aw.gz != nil {
+		if err := aw.gz.Close(); err != nil {
+			return err
+		}
+	}
+	return aw.w.Flush()
+}
+
+// ArchiveReader reads the blocks from the archive and verifies the checksums
+type ArchiveReader struct {
+	header *ArchiveHeader
+	body   io.Reader
+	count  uint64
+	done   bool
+}
+
+// NewArchiveReader reads and verifies the archive header from r
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	br := bufio.NewReader(r)
+	buf := make([]byte, archiveHeaderSize)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, fmt.Errorf("read archive header error:%v", err)
+	}
+	h, err := decodeArchiveHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	ar := &ArchiveReader{header: h, body: br}
+	if h.Compressed {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		ar.body = gz
+	}
+	return ar, nil
+}
+
+// Header returns the header of the archive
+func (ar *ArchiveReader) Header() *ArchiveHeader {
+	return ar.header
+}
+
+// Next returns the next block of the archive, io.EOF is returned after the last block
+func (ar *ArchiveReader) Next() (*types.Block, error) {
+	if ar.done {
+		return nil, io.EOF
+	}
+	var prefix [8]byte
+	if _, err := io.ReadFull(ar.body, prefix[:4]); err != nil {
+		return nil, ar.truncated(err)
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size == 0 {
+		var count [8]byte
+		if _, err := io.ReadFull(ar.body, count[:]); err != nil {
+			return nil, ar.truncated(err)
+		}
+		if n := binary.BigEndian.Uint64(count[:]); n != ar.count {
+			return nil, fmt.Errorf("archive block count mismatch, expect %v, read %v", n, ar.count)
+		}
+		ar.done = true
+		return nil, io.EOF
+	}
+	if size > maxArchiveRecord {
+		return nil, fmt.Errorf("archive record too large: %v", size)
+	}
+	if _, err := io.ReadFull(ar.body, prefix[4:]); err != nil {
+		return nil, ar.truncated(err)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(ar.body, payload); err != nil {
+		return nil, ar.truncated(err)
+	}
+	if binary.BigEndian.Uint32(prefix[4:]) != crc32.ChecksumIEEE(payload) {
+		return nil, fmt.Errorf("archive record %v checksum mismatch", ar.count)
+	}
+	b, err := types.UnMarshalBlock(payload)
+	if err != nil {
+		return nil, err
+	}
+	if h := b.Header.Height; h < ar.header.From || h > ar.header.To {
+		return nil, fmt.Errorf("block %v out of the archive range [%v, %v]", h, ar.header.From, ar.header.To)
+	}
+	ar.count++
+	return b, nil
+}
+
+func (ar *ArchiveReader) truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("archive truncated after %v blocks", ar.count)
+	}
+	return err
+}
+
+// ExportArchive writes the blocks of the height range [from, to] provided by the provider to w.
+// The range is cut to the height of the provider. It returns the number of the exported blocks
+func ExportArchive(provider BlockProvider, from, to uint64, w io.Writer, compress bool) (uint64, error) {
+	const step = 200
+	if top := provider.Height(); to > top {
+		to = top
+	}
+	aw, err := NewArchiveWriter(w, from, to, compress)
+	if err != nil {
+		return 0, err
+	}
+	for begin := from; begin <= to; begin += step {
+		end := begin + step
+		if end > to+1 {
+			end = to + 1
+		}
+		for _, b := range provider.Provide(begin, end) {
+			if err := aw.WriteBlock(b); err != nil {
+				return aw.Count(), err
+			}
+		}
+	}
+	return aw.Count(), aw.Close()
+}
+
+// ArchiveBlockProvider provides the blocks from the archive file in a forward only way, so that the
+// archive can be imported by the fast replayer. Blocks lower than the requested range are skipped,
+// which makes it possible to resume the import from the local top after a crash
+type ArchiveBlockProvider struct {
+	file    *os.File
+	reader  *ArchiveReader
+	pending *types.Block
+	err     error
+}
+
+// NewArchiveBlockProvider opens the archive file
+func NewArchiveBlockProvider(file string) (*ArchiveBlockProvider, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewArchiveReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &ArchiveBlockProvider{file: f, reader: reader}, nil
+}
+
+// Header returns the header of the archive
+func (p *ArchiveBlockProvider) Header() *ArchiveHeader {
+	return p.reader.Header()
+}
+
+// Provide returns the blocks of the archive in the range [begin, end)
+func (p *ArchiveBlockProvider) Provide(begin, end uint64) []*types.Block {
+	blocks := make([]*types.Block, 0)
+	for p.err == nil {
+		b := p.pending
+		p.pending = nil
+		if b == nil {
+			var err error
+			if b, err = p.reader.Next(); err != nil {
+				if err != io.EOF {
+					p.err = err
+				}
+				break
+			}
+		}
+		if b.Header.Height < begin {
+			continue
+		}
+		if b.Header.Height >= end {
+			p.pending = b
+			break
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// Height returns the upper bound of the block heights of the archive
+func (p *ArchiveBlockProvider) Height() uint64 {
+	return p.reader.Header().To
+}
+
+// Err returns the error occurs while reading the archive
+func (p *ArchiveBlockProvider) Err() error {
+	return p.err
+}
+
+func (p *ArchiveBlockProvider) Close() error {
+	return p.file.Close()
+}
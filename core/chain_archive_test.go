@@ -0,0 +1,207 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// memBlockProvider provides blocks from memory, heights in skip have no block
+type memBlockProvider struct {
+	blocks []*types.Block
+}
+
+func newMemBlockProvider(top uint64, skip ...uint64) *memBlockProvider {
+	p := &memBlockProvider{}
+	skipped := make(map[uint64]bool)
+	for _, h := range skip {
+		skipped[h] = true
+	}
+	for h := uint64(1); h <= top; h++ {
+		if skipped[h] {
+			continue
+		}
+		bh := &types.BlockHeader{Height: h, Nonce: 1, ExtraData: []byte("archive")}
+		bh.Hash = bh.GenHash()
+		p.blocks = append(p.blocks, &types.Block{Header: bh})
+	}
+	return p
+}
+
+func (p *memBlockProvider) Provide(begin, end uint64) []*types.Block {
+	ret := make([]*types.Block, 0)
+	for _, b := range p.blocks {
+		if b.Header.Height >= begin && b.Header.Height < end {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
+
+func (p *memBlockProvider) Height() uint64 {
+	return p.blocks[len(p.blocks)-1].Header.Height
+}
+
+func readArchive(t *testing.T, data []byte) ([]*types.Block, error) {
+	ar, err := NewArchiveReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := make([]*types.Block, 0)
+	for {
+		b, err := ar.Next()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, b)
+	}
+}
+
+func TestChainArchive(t *testing.T) {
+	src := newMemBlockProvider(500, 10, 201, 202)
+	for _, compress := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		cnt, err := ExportArchive(src, 5, common.MaxUint64, buf, compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cnt != 493 {
+			t.Fatalf("unexpected exported count %v", cnt)
+		}
+		ar, _ := NewArchiveReader(bytes.NewReader(buf.Bytes()))
+		if h := ar.Header(); h.From != 5 || h.To != 500 || h.Compressed != compress {
+			t.Fatalf("unexpected header %+v", h)
+		}
+		blocks, err := readArchive(t, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := src.Provide(5, 501)
+		if len(blocks) != len(expect) {
+			t.Fatalf("unexpected blocks count %v", len(blocks))
+		}
+		for i, b := range blocks {
+			if b.Header.Hash != expect[i].Header.Hash || b.Header.Height != expect[i].Header.Height {
+				t.Fatalf("block %v mismatch", i)
+			}
+		}
+
+		if _, err := readArchive(t, buf.Bytes()[:buf.Len()-20]); err == nil {
+			t.Fatalf("compress %v: expect truncated error", compress)
+		}
+	}
+
+	// Corrupt the payload of the first record
+	buf := new(bytes.Buffer)
+	if _, err := ExportArchive(src, 1, 3, buf, false); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[archiveHeaderSize+10] ^= 0xff
+	if blocks, err := readArchive(t, data); err == nil || len(blocks) != 0 {
+		t.Fatal("expect checksum error")
+	}
+	data[0] = 'X'
+	if _, err := NewArchiveReader(bytes.NewReader(data)); err == nil {
+		t.Fatal("expect bad magic error")
+	}
+
+	aw, _ := NewArchiveWriter(new(bytes.Buffer), 1, 3, false)
+	if err := aw.WriteBlock(src.blocks[3]); err == nil {
+		t.Fatal("expect out of range error")
+	}
+	aw.WriteBlock(src.blocks[1])
+	if err := aw.WriteBlock(src.blocks[0]); err == nil {
+		t.Fatal("expect order error")
+	}
+}
+
+func writeArchiveFile(t *testing.T, file string, src BlockProvider, compress bool) {
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := ExportArchive(src, 1, src.Height(), f, compress); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveBlockProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain_archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := newMemBlockProvider(1000, 300, 301, 1000)
+	file := filepath.Join(dir, "chain.zva")
+	writeArchiveFile(t, file, src, true)
+
+	// Resume from the middle, the lower blocks are skipped
+	p, err := NewArchiveBlockProvider(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Height() != 999 {
+		t.Fatalf("unexpected height %v", p.Height())
+	}
+	fr := NewFastReplayer(p, nil, ioutil.Discard).(*fastReplayer)
+	go fr.produce(250)
+	var next uint64 = 250
+	for blocks := range fr.blocksCh {
+		for _, b := range blocks {
+			if next == 300 {
+				next = 302
+			}
+			if b.Header.Height != next {
+				t.Fatalf("expect block %v, got %v", next, b.Header.Height)
+			}
+			next++
+		}
+	}
+	if fr.err != nil || next != 1000 {
+		t.Fatalf("unexpected produce end %v %v", next, fr.err)
+	}
+	p.Close()
+
+	// The provider error stops the producing
+	data, _ := ioutil.ReadFile(file)
+	ioutil.WriteFile(file, data[:len(data)/2], 0644)
+	p, err = NewArchiveBlockProvider(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	fr = NewFastReplayer(p, nil, ioutil.Discard).(*fastReplayer)
+	go fr.produce(1)
+	for range fr.blocksCh {
+	}
+	if fr.err == nil || p.Err() == nil {
+		t.Fatal("expect truncated error")
+	}
+}
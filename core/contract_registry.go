@@ -0,0 +1,131 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/tasdb"
+	"github.com/zvchain/zvchain/tvm"
+	"golang.org/x/crypto/sha3"
+)
+
+// VerifiedContract is the metadata of a contract whose source has been verified against the code deployed
+type VerifiedContract struct {
+	Address    common.Address `json:"address"`
+	Name       string         `json:"name"`        // Class name of the contract
+	Source     string         `json:"source"`      // Python source code
+	ABI        string         `json:"abi"`         // ABI exported by the tvm
+	CodeHash   common.Hash    `json:"code_hash"`   // Hash of the code stored in the account
+	TVMVersion string         `json:"tvm_version"` // Version of the node whose tvm exported the abi
+	Height     uint64         `json:"height"`      // Height of the state verified against
+}
+
+// contractRegistry stores the verified contracts keyed by the address. It's maintained by the node
+// locally and not part of the consensus
+type contractRegistry struct {
+	db *tasdb.PrefixedDatabase
+}
+
+func newContractRegistry(db *tasdb.PrefixedDatabase) *contractRegistry {
+	return &contractRegistry{db: db}
+}
+
+func (r *contractRegistry) get(addr common.Address) *VerifiedContract {
+	bs, err := r.db.Get(addr.Bytes())
+	if err != nil || bs == nil {
+		return nil
+	}
+	vc := &VerifiedContract{}
+	if err := json.Unmarshal(bs, vc); err != nil {
+		Logger.Errorf("decode verified contract %v error:%v", addr.AddrPrefixString(), err)
+		return nil
+	}
+	return vc
+}
+
+func (r *contractRegistry) put(vc *VerifiedContract) error {
+	bs, err := json.Marshal(vc)
+	if err != nil {
+		return err
+	}
+	return r.db.Put(vc.Address.Bytes(), bs)
+}
+
+// VerifyContract checks the source against the code deployed at the address on the latest state.
+// The code is re-derived from the source and the contract name stored, as the deploy transaction encodes it,
+// and its hash must equal to the code hash of the account. The source and the abi exported are stored then
+func (chain *FullBlockChain) VerifyContract(addr common.Address, source string) (*VerifiedContract, error) {
+	top := chain.QueryTopBlock()
+	state, err := chain.AccountDBAt(top.Height)
+	if err != nil {
+		return nil, err
+	}
+	codeHash := state.GetCodeHash(addr)
+	code := state.GetCode(addr)
+	if codeHash == (common.Hash{}) || len(code) == 0 {
+		return nil, fmt.Errorf("no code at the given address %v", addr.AddrPrefixString())
+	}
+	deployed := &tvm.Contract{}
+	if err := json.Unmarshal(code, deployed); err != nil {
+		return nil, fmt.Errorf("decode code error:%v", err)
+	}
+
+	contract := &tvm.Contract{Code: source, ContractName: deployed.ContractName}
+	derived, err := json.Marshal(contract)
+	if err != nil {
+		return nil, err
+	}
+	if derivedHash := sha3.Sum256(derived); common.BytesToHash(derivedHash[:]) != codeHash {
+		return nil, fmt.Errorf("source not match the code deployed, code hash %v", codeHash.Hex())
+	}
+
+	var (
+		abi       string
+		exportErr error
+	)
+	err = runOnVM(chain.config.callTimeout, func() {
+		vm := tvm.NewTVM(nil, contract, top.Height)
+		abi, exportErr = vm.ExportABI()
+		vm.DelTVM()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = exportErr; err != nil {
+		return nil, fmt.Errorf("export abi error:%v", err)
+	}
+	vc := &VerifiedContract{
+		Address:    addr,
+		Name:       contract.ContractName,
+		Source:     source,
+		ABI:        abi,
+		CodeHash:   codeHash,
+		TVMVersion: common.GzvVersion,
+		Height:     top.Height,
+	}
+	if err := chain.contractRegistry.put(vc); err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
+
+// GetVerifiedContract returns the verified contract at the address, nil if not verified
+func (chain *FullBlockChain) GetVerifiedContract(addr common.Address) *VerifiedContract {
+	return chain.contractRegistry.get(addr)
+}
@@ -0,0 +1,60 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+func TestVerifyContract(t *testing.T) {
+	err := initContext4Test(t)
+	defer clearSelf(t)
+	if err != nil {
+		t.Fatalf("failed to initContext4Test")
+	}
+
+	// The business foundation contract deployed in the genesis block
+	addr := common.BytesToAddress(common.Sha256(common.BytesCombine(AdminAddr().Bytes(), common.Uint64ToByte(1))))
+	source := fmt.Sprintf(foundationContract, AdminAddr().AddrPrefixString(), businessFoundationToken)
+
+	if BlockChainImpl.GetVerifiedContract(addr) != nil {
+		t.Fatalf("contract verified before")
+	}
+	if _, err := BlockChainImpl.VerifyContract(addr, source+"\n"); err == nil {
+		t.Fatalf("source not matched verified")
+	}
+	if _, err := BlockChainImpl.VerifyContract(common.BytesToAddress(genHash("1")), source); err == nil {
+		t.Fatalf("account without code verified")
+	}
+	if BlockChainImpl.GetVerifiedContract(addr) != nil {
+		t.Fatalf("contract stored after failed verification")
+	}
+
+	vc, err := BlockChainImpl.VerifyContract(addr, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vc.Name != "Foundation" || vc.CodeHash != BlockChainImpl.latestStateDB.GetCodeHash(addr) {
+		t.Fatalf("unexpected verified contract %+v", vc)
+	}
+	stored := BlockChainImpl.GetVerifiedContract(addr)
+	if stored == nil || stored.Source != source || stored.Address != addr || stored.TVMVersion != common.GzvVersion {
+		t.Fatalf("unexpected stored contract %+v", stored)
+	}
+}
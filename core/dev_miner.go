@@ -0,0 +1,132 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/notify"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// devPollInterval is the interval of checking the pool for the transactions missed by the notification,
+// such as the ones promoted from the queue after a block added
+const devPollInterval = 500 * time.Millisecond
+
+// DevMiner produces the blocks of the single node development chain. The blocks are cast by the
+// real transaction pool and state processor, while the vrf proving and the group signing are skipped.
+type DevMiner struct {
+	chain    *FullBlockChain
+	castor   []byte
+	seed     common.Hash   // Seed of the genesis group, which casts all the blocks
+	interval time.Duration // Interval of the block production, blocks are produced once transactions arrive if zero
+
+	lock    sync.Mutex
+	pending chan struct{}
+	quit    chan struct{}
+}
+
+// NewDevMiner creates the dev miner casting blocks on the chain with the castor id
+func NewDevMiner(chain *FullBlockChain, castor []byte, interval time.Duration) *DevMiner {
+	return &DevMiner{
+		chain:    chain,
+		castor:   castor,
+		seed:     chain.consensusHelper.GenerateGenesisInfo().Group.Header().Seed(),
+		interval: interval,
+		pending:  make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start starts producing blocks in background
+func (m *DevMiner) Start() {
+	if m.interval <= 0 {
+		notify.BUS.Subscribe(notify.TransactionAdded, m.onTransactionAdded)
+	}
+	go m.loop()
+}
+
+// Stop stops the block production
+func (m *DevMiner) Stop() {
+	close(m.quit)
+}
+
+func (m *DevMiner) onTransactionAdded(message notify.Message) error {
+	select {
+	case m.pending <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (m *DevMiner) loop() {
+	if m.interval > 0 {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.quit:
+				return
+			case <-ticker.C:
+				if _, err := m.Mine(); err != nil {
+					Logger.Errorf("dev miner fail: %v", err)
+				}
+			}
+		}
+	}
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.quit:
+			return
+		case <-ticker.C:
+			m.minePending()
+		case <-m.pending:
+			m.minePending()
+		}
+	}
+}
+
+// minePending produces blocks until no transaction can be packed
+func (m *DevMiner) minePending() {
+	for len(m.chain.GetTransactionPool().PackForCast()) > 0 {
+		if _, err := m.Mine(); err != nil {
+			Logger.Errorf("dev miner fail: %v", err)
+			return
+		}
+	}
+}
+
+// Mine casts a block on the top with the transactions in the pool and adds it on chain
+func (m *DevMiner) Mine() (*types.Block, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	height := m.chain.Height() + 1
+	block := m.chain.CastBlock(height, []byte{}, 1, m.castor, m.seed)
+	if block == nil {
+		return nil, fmt.Errorf("cast block fail at height %v", height)
+	}
+	if ret := m.chain.AddBlockOnChain("", block); ret != types.AddBlockSucc {
+		return nil, fmt.Errorf("add block fail at height %v, ret=%v", height, ret)
+	}
+	Logger.Infof("dev miner produced block, height=%v, hash=%v, txs=%v", height, block.Header.Hash, len(block.Transactions))
+	return block, nil
+}
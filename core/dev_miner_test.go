@@ -0,0 +1,66 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevMiner(t *testing.T) {
+	err := initContext4Test(t)
+	defer clearSelf(t)
+	if err != nil {
+		t.Fatalf("failed to initContext4Test")
+	}
+	initBalance()
+
+	miner := NewDevMiner(BlockChainImpl, genHash("castor"), 0)
+	pool := BlockChainImpl.GetTransactionPool()
+	if _, err := pool.AddTransaction(genTestTx(500, "100", 1, 1)); err != nil {
+		t.Fatalf("fail to add transaction %v", err)
+	}
+	block, err := miner.Mine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Header.Height != 1 || len(block.Transactions) != 1 || BlockChainImpl.Height() != 1 {
+		t.Fatalf("unexpected block, height=%v, txs=%v", block.Header.Height, len(block.Transactions))
+	}
+	if BlockChainImpl.GetTransactionByHash(false, block.Transactions[0].GenHash()) == nil {
+		t.Fatalf("transaction not on chain")
+	}
+	source := *block.Transactions[0].Source
+
+	// Blocks are produced once the transactions arrive in the instant mode
+	miner.Start()
+	defer miner.Stop()
+	for nonce := uint64(2); nonce <= 3; nonce++ {
+		if _, err := pool.AddTransaction(genTestTx(500, "100", nonce, 1)); err != nil {
+			t.Fatalf("fail to add transaction %v", err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for BlockChainImpl.GetNonce(source) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("transactions not mined, height=%v", BlockChainImpl.Height())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if pool.TxNum() != 0 {
+		t.Fatalf("transactions left in pool: %v", pool.TxNum())
+	}
+}
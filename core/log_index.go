@@ -0,0 +1,372 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const (
+	// logIndexSectionSize is the count of heights covered by a bit vector
+	logIndexSectionSize = 4096
+	logIndexVectorSize  = logIndexSectionSize / 8
+
+	// logIndexBackfillStep is the count of heights indexed each time by the backfill routine
+	logIndexBackfillStep = 256
+)
+
+var (
+	// logIndexStartKey stores the top height when the index created. Blocks higher than it
+	// are indexed when committed
+	logIndexStartKey = []byte("start")
+	// logIndexBackfillKey stores the next height to be indexed by the backfill routine
+	logIndexBackfillKey = []byte("backfill")
+)
+
+// logIndex is the bloom-bits index of the contract logs.
+// Heights are divided into sections, and for each bit of the bloom there is a bit vector per section
+// recording which heights of the section have the bit set in the block bloom. A range query only needs
+// to read 3 vectors for each address or topic in a section instead of decoding all the receipts.
+// The vectors with no bit set are not stored.
+type logIndex struct {
+	db *tasdb.PrefixedDatabase
+
+	start      uint64
+	backfilled uint64
+	lock       sync.RWMutex
+}
+
+func initLogIndex(db *tasdb.PrefixedDatabase, top uint64) (*logIndex, error) {
+	idx := &logIndex{db: db}
+	bs, err := db.Get(logIndexStartKey)
+	if err != nil || bs == nil {
+		// First start with the index, the existing blocks will be indexed by the backfill routine
+		if err = db.Put(logIndexStartKey, common.UInt64ToByte(top)); err != nil {
+			return nil, err
+		}
+		if err = db.Put(logIndexBackfillKey, common.UInt64ToByte(0)); err != nil {
+			return nil, err
+		}
+		idx.start = top
+		return idx, nil
+	}
+	idx.start = common.ByteToUInt64(bs)
+	if bs, _ = db.Get(logIndexBackfillKey); bs != nil {
+		idx.backfilled = common.ByteToUInt64(bs)
+	}
+	return idx, nil
+}
+
+// unindexedRange returns the height range not indexed yet. ok is false if all heights are indexed
+func (idx *logIndex) unindexedRange() (from, to uint64, ok bool) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return idx.backfilled, idx.start, idx.backfilled <= idx.start
+}
+
+// setBackfilled updates the backfill progress after the batch written
+func (idx *logIndex) setBackfilled(batch tasdb.Batch, next uint64) error {
+	if err := idx.db.AddKv(batch, logIndexBackfillKey, common.UInt64ToByte(next)); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.lock.Lock()
+	idx.backfilled = next
+	idx.lock.Unlock()
+	return nil
+}
+
+func logIndexVectorKey(section uint64, bit uint) []byte {
+	return append(common.UInt64ToByte(section), common.UInt16ToByte(uint16(bit))...)
+}
+
+func (idx *logIndex) getVector(section uint64, bit uint) []byte {
+	bs, _ := idx.db.Get(logIndexVectorKey(section, bit))
+	return bs
+}
+
+// bloomBitIndexes returns the indexes of the set bits in the given bloom
+func bloomBitIndexes(b *big.Int) []uint {
+	ret := make([]uint, 0)
+	for i := 0; i < b.BitLen(); i++ {
+		if b.Bit(i) == 1 {
+			ret = append(ret, uint(i))
+		}
+	}
+	return ret
+}
+
+// logIndexWriter updates the bit vectors in a batch. The modified vectors are cached so that
+// multiple blocks of the same section can be updated in one batch
+type logIndexWriter struct {
+	idx   *logIndex
+	batch tasdb.Batch
+	dirty map[string][]byte
+}
+
+func (idx *logIndex) newWriter(batch tasdb.Batch) *logIndexWriter {
+	return &logIndexWriter{
+		idx:   idx,
+		batch: batch,
+		dirty: make(map[string][]byte),
+	}
+}
+
+// update sets or clears the bits of the block at the given height
+func (w *logIndexWriter) update(height uint64, receipts types.Receipts, set bool) {
+	if len(receipts) == 0 {
+		return
+	}
+	bloom := types.CreateBloom(receipts)
+	section, pos := height/logIndexSectionSize, height%logIndexSectionSize
+	for _, bit := range bloomBitIndexes(bloom.Big()) {
+		key := string(logIndexVectorKey(section, bit))
+		vec, ok := w.dirty[key]
+		if !ok {
+			vec = make([]byte, logIndexVectorSize)
+			copy(vec, w.idx.getVector(section, bit))
+		}
+		if set {
+			vec[pos/8] |= 1 << (7 - pos%8)
+		} else {
+			vec[pos/8] &^= 1 << (7 - pos%8)
+		}
+		w.dirty[key] = vec
+	}
+}
+
+// flush puts the modified vectors into the batch
+func (w *logIndexWriter) flush() error {
+	for key, vec := range w.dirty {
+		empty := true
+		for _, b := range vec {
+			if b != 0 {
+				empty = false
+				break
+			}
+		}
+		var value []byte
+		if !empty {
+			value = vec
+		}
+		if err := w.idx.db.AddKv(w.batch, []byte(key), value); err != nil {
+			return err
+		}
+	}
+	w.dirty = make(map[string][]byte)
+	return nil
+}
+
+// matchSection returns the bit vector of the heights in the section matching all the given groups.
+// A height matches a group if it matches any term of the group, and a term is matched if all its bits are set
+func (idx *logIndex) matchSection(section uint64, groups [][][]uint) []byte {
+	cache := make(map[uint][]byte)
+	vector := func(bit uint) []byte {
+		if vec, ok := cache[bit]; ok {
+			return vec
+		}
+		vec := idx.getVector(section, bit)
+		cache[bit] = vec
+		return vec
+	}
+
+	var result []byte
+	for _, group := range groups {
+		groupVec := make([]byte, logIndexVectorSize)
+		for _, term := range group {
+			termVec := make([]byte, logIndexVectorSize)
+			for i := range termVec {
+				termVec[i] = 0xff
+			}
+			for _, bit := range term {
+				vec := vector(bit)
+				if vec == nil {
+					termVec = nil
+					break
+				}
+				for i := range termVec {
+					termVec[i] &= vec[i]
+				}
+			}
+			for i := range termVec {
+				groupVec[i] |= termVec[i]
+			}
+		}
+		if result == nil {
+			result = groupVec
+		} else {
+			for i := range result {
+				result[i] &= groupVec[i]
+			}
+		}
+	}
+	return result
+}
+
+func bloomTerms(values [][]byte) [][]uint {
+	terms := make([][]uint, 0, len(values))
+	for _, v := range values {
+		terms = append(terms, bloomBitIndexes(types.Bloom9(v)))
+	}
+	return terms
+}
+
+// LogCandidateHeights returns the heights of the blocks in [from, to] that may contain logs
+// matching the given addresses and topics. Empty addresses or topics matches any value.
+// False positives are possible and the caller should check the logs of the returned blocks
+func (chain *FullBlockChain) LogCandidateHeights(from, to uint64, addresses []common.Address, topics []common.Hash) []uint64 {
+	if from > to {
+		return []uint64{}
+	}
+	groups := make([][][]uint, 0, 2)
+	if len(addresses) > 0 {
+		values := make([][]byte, 0, len(addresses))
+		for _, addr := range addresses {
+			values = append(values, addr.Bytes())
+		}
+		groups = append(groups, bloomTerms(values))
+	}
+	if len(topics) > 0 {
+		values := make([][]byte, 0, len(topics))
+		for _, topic := range topics {
+			values = append(values, topic.Bytes())
+		}
+		groups = append(groups, bloomTerms(values))
+	}
+	// Nothing to filter with the index
+	if len(groups) == 0 {
+		return chain.ScanBlockHeightsInRange(from, to)
+	}
+
+	idx := chain.logIndex
+	heights := make([]uint64, 0)
+	for section := from / logIndexSectionSize; section <= to/logIndexSectionSize; section++ {
+		vec := idx.matchSection(section, groups)
+		for i, b := range vec {
+			if b == 0 {
+				continue
+			}
+			for j := uint64(0); j < 8; j++ {
+				if b&(1<<(7-j)) == 0 {
+					continue
+				}
+				h := section*logIndexSectionSize + uint64(i)*8 + j
+				if h >= from && h <= to {
+					heights = append(heights, h)
+				}
+			}
+		}
+	}
+
+	// The blocks not indexed yet should be all checked
+	if s, e, ok := idx.unindexedRange(); ok && s <= to && e >= from {
+		if s < from {
+			s = from
+		}
+		if e > to {
+			e = to
+		}
+		seen := make(map[uint64]struct{}, len(heights))
+		for _, h := range heights {
+			seen[h] = struct{}{}
+		}
+		for _, h := range chain.ScanBlockHeightsInRange(s, e) {
+			if _, ok := seen[h]; !ok {
+				heights = append(heights, h)
+			}
+		}
+		sort.Slice(heights, func(i, j int) bool {
+			return heights[i] < heights[j]
+		})
+	}
+	return heights
+}
+
+// blockReceipts returns the receipts of the given transactions
+func (chain *FullBlockChain) blockReceipts(txs []*types.RawTransaction) types.Receipts {
+	receipts := make(types.Receipts, 0, len(txs))
+	for _, tx := range txs {
+		if rc := chain.transactionPool.GetReceipt(tx.GenHash()); rc != nil {
+			receipts = append(receipts, rc)
+		}
+	}
+	return receipts
+}
+
+// backfillLogIndex indexes the blocks existing before the log index created
+func (chain *FullBlockChain) backfillLogIndex() {
+	if _, _, ok := chain.logIndex.unindexedRange(); ok {
+		Logger.Infof("log index backfill started")
+	}
+	for {
+		done, err := chain.backfillLogIndexStep()
+		if err != nil {
+			Logger.Errorf("log index backfill error:%v", err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
+
+func (chain *FullBlockChain) backfillLogIndexStep() (done bool, err error) {
+	idx := chain.logIndex
+	from, to, ok := idx.unindexedRange()
+	if !ok {
+		return true, nil
+	}
+	if to-from >= logIndexBackfillStep {
+		to = from + logIndexBackfillStep - 1
+	}
+
+	// Hold the write lock so that the vectors won't be modified by the block committing concurrently
+	chain.rwLock.Lock()
+	defer chain.rwLock.Unlock()
+	if atomic.LoadInt32(&chain.shutdowning) == 1 {
+		return true, nil
+	}
+
+	batch := idx.db.CreateLDBBatch()
+	w := idx.newWriter(batch)
+	for _, h := range chain.scanBlockHeightsInRange(from, to) {
+		hash := chain.queryBlockHash(h)
+		if hash == nil {
+			continue
+		}
+		w.update(h, chain.blockReceipts(chain.queryBlockTransactionsAll(*hash)), true)
+	}
+	if err = w.flush(); err != nil {
+		return
+	}
+	if err = idx.setBackfilled(batch, to+1); err != nil {
+		return
+	}
+	if _, _, ok = idx.unindexedRange(); !ok {
+		Logger.Infof("log index backfill finished at %v", to)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,128 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const logIndexTestDb = "test_log_index_db"
+
+func newLogIndexTestChain(t *testing.T) *FullBlockChain {
+	ds, err := tasdb.NewDataSource(logIndexTestDb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heightDb, _ := ds.NewPrefixDatabase("hi")
+	indexDb, _ := ds.NewPrefixDatabase("lb")
+	idx, err := initLogIndex(indexDb, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mark all heights indexed
+	if err = idx.setBackfilled(indexDb.CreateLDBBatch(), 1); err != nil {
+		t.Fatal(err)
+	}
+	return &FullBlockChain{blockHeight: heightDb, logIndex: idx}
+}
+
+func logIndexTestReceipts(addr common.Address, topic common.Hash) types.Receipts {
+	return types.Receipts{{Logs: []*types.Log{{Address: addr, Topic: topic}}}}
+}
+
+func commitLogIndexTest(t *testing.T, chain *FullBlockChain, height uint64, receipts types.Receipts, set bool) {
+	batch := chain.logIndex.db.CreateLDBBatch()
+	w := chain.logIndex.newWriter(batch)
+	w.update(height, receipts, set)
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	value := common.Sha256(common.UInt64ToByte(height))
+	if !set {
+		value = nil
+	}
+	if err := chain.blockHeight.AddKv(batch, common.UInt64ToByte(height), value); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogCandidateHeights(t *testing.T) {
+	defer os.RemoveAll(logIndexTestDb)
+	chain := newLogIndexTestChain(t)
+
+	addr1 := common.BytesToAddress([]byte("contract1"))
+	addr2 := common.BytesToAddress([]byte("contract2"))
+	topic1 := common.BytesToHash(common.Sha256([]byte("transfer")))
+	topic2 := common.BytesToHash(common.Sha256([]byte("approve")))
+
+	commitLogIndexTest(t, chain, 10, logIndexTestReceipts(addr1, topic1), true)
+	commitLogIndexTest(t, chain, 11, logIndexTestReceipts(addr2, topic2), true)
+	commitLogIndexTest(t, chain, logIndexSectionSize+5, logIndexTestReceipts(addr1, topic2), true)
+
+	hs := chain.LogCandidateHeights(0, 2*logIndexSectionSize, []common.Address{addr1}, nil)
+	if len(hs) != 2 || hs[0] != 10 || hs[1] != logIndexSectionSize+5 {
+		t.Fatalf("unexpected heights by address:%v", hs)
+	}
+	hs = chain.LogCandidateHeights(0, 2*logIndexSectionSize, nil, []common.Hash{topic2})
+	if len(hs) != 2 || hs[0] != 11 || hs[1] != logIndexSectionSize+5 {
+		t.Fatalf("unexpected heights by topic:%v", hs)
+	}
+	hs = chain.LogCandidateHeights(0, 2*logIndexSectionSize, []common.Address{addr1}, []common.Hash{topic2})
+	if len(hs) != 1 || hs[0] != logIndexSectionSize+5 {
+		t.Fatalf("unexpected heights by address and topic:%v", hs)
+	}
+	hs = chain.LogCandidateHeights(11, 2*logIndexSectionSize, []common.Address{addr1, addr2}, nil)
+	if len(hs) != 2 || hs[0] != 11 {
+		t.Fatalf("unexpected heights in range:%v", hs)
+	}
+
+	// Revert the block of the second section
+	commitLogIndexTest(t, chain, logIndexSectionSize+5, logIndexTestReceipts(addr1, topic2), false)
+	hs = chain.LogCandidateHeights(0, 2*logIndexSectionSize, []common.Address{addr1}, nil)
+	if len(hs) != 1 || hs[0] != 10 {
+		t.Fatalf("unexpected heights after reverted:%v", hs)
+	}
+	if v := chain.logIndex.getVector(1, bloomBitIndexes(types.Bloom9(addr1.Bytes()))[0]); v != nil {
+		t.Fatalf("empty vector should be deleted")
+	}
+}
+
+func TestLogCandidateHeightsUnindexed(t *testing.T) {
+	defer os.RemoveAll(logIndexTestDb)
+	chain := newLogIndexTestChain(t)
+	addr := common.BytesToAddress([]byte("contract"))
+	topic := common.BytesToHash(common.Sha256([]byte("transfer")))
+
+	commitLogIndexTest(t, chain, 3, logIndexTestReceipts(addr, topic), true)
+	// Blocks not indexed yet
+	commitLogIndexTest(t, chain, 20, nil, true)
+	commitLogIndexTest(t, chain, 21, nil, true)
+	chain.logIndex.start = 30
+	chain.logIndex.backfilled = 20
+
+	hs := chain.LogCandidateHeights(0, 100, []common.Address{addr}, nil)
+	if len(hs) != 3 || hs[0] != 3 || hs[1] != 20 || hs[2] != 21 {
+		t.Fatalf("unexpected heights:%v", hs)
+	}
+}
@@ -0,0 +1,57 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/zvchain/zvchain/middleware/metrics"
+)
+
+var snapshotEntriesCounter = metrics.NewCounter("snapshot_sync_entries_total", "Number of the state entries written by the snapshot sync")
+
+// registerChainMetrics publishes the chain height, the transaction pool sizes and the db stats of the chain
+func registerChainMetrics(chain *FullBlockChain) {
+	metrics.NewGaugeFunc("chain_height", "Height of the top block", func() float64 {
+		return float64(chain.Height())
+	})
+	if pool, ok := chain.transactionPool.(*txPool); ok {
+		metrics.NewGaugeFunc("txpool_pending", "Number of the executable transactions in the pool", func() float64 {
+			return float64(pool.received.Len() - int(pool.TxQueueNum()))
+		})
+		metrics.NewGaugeFunc("txpool_queued", "Number of the transactions waiting for the nonce in the pool", func() float64 {
+			return float64(pool.TxQueueNum())
+		})
+		metrics.NewGaugeFunc("txpool_reward", "Number of the reward transactions in the pool", func() float64 {
+			return float64(pool.bonPool.len())
+		})
+	}
+	metrics.OnCollect("chain_state_db", func() {
+		chain.stateDb.PublishStats("state")
+	})
+}
+
+// registerSyncMetrics publishes the peer counts of the block syncer
+func registerSyncMetrics(bs *blockSyncer) {
+	metrics.NewGaugeFunc("sync_candidates", "Number of the candidate peers of the block sync", func() float64 {
+		bs.lock.RLock()
+		defer bs.lock.RUnlock()
+		return float64(len(bs.candidatePool))
+	})
+	metrics.NewGaugeFunc("sync_syncing_peers", "Number of the peers requested blocks from", func() float64 {
+		bs.lock.RLock()
+		defer bs.lock.RUnlock()
+		return float64(len(bs.syncingPeers))
+	})
+}
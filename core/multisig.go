@@ -0,0 +1,215 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/params"
+)
+
+var multiSigConfigKey = []byte("multisig_config")
+
+// isMultiSigType returns whether the transaction type is related to the multisig account
+func isMultiSigType(typ int8) bool {
+	return typ == types.TransactionTypeMultiSigCreate || typ == types.TransactionTypeMultiSigExecute
+}
+
+// GetMultiSigConfig returns the config of the multisig account, nil if the address is not a multisig account
+func GetMultiSigConfig(db types.AccountDB, addr common.Address) (*types.MultiSigConfig, error) {
+	bs := db.GetData(addr, multiSigConfigKey)
+	if len(bs) == 0 {
+		return nil, nil
+	}
+	return types.DecodeMultiSigConfig(bs)
+}
+
+func setMultiSigConfig(db types.AccountDB, addr common.Address, c *types.MultiSigConfig) error {
+	bs, err := types.EncodeMultiSigConfig(c)
+	if err != nil {
+		return err
+	}
+	db.SetData(addr, multiSigConfigKey, bs)
+	return nil
+}
+
+// verifyMultiSigTx checks the member signs of the multisig execute transaction reach the threshold of the
+// source account. It takes the place of the source recovering of the normal transactions
+func verifyMultiSigTx(db types.AccountDB, tx *types.Transaction) error {
+	c, err := GetMultiSigConfig(db, *tx.Source)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("%v is not a multisig account", tx.Source.AddrPrefixString())
+	}
+	signs, err := types.DecodeMultiSigSigns(tx.Sign)
+	if err != nil {
+		return err
+	}
+	signers, err := types.RecoverMultiSigSigners(tx.Hash.Bytes(), signs)
+	if err != nil {
+		return err
+	}
+	for _, s := range signers {
+		if !c.IsMember(s) {
+			return fmt.Errorf("%v is not a member of the multisig account", s.AddrPrefixString())
+		}
+	}
+	if len(signers) < int(c.Threshold) {
+		return fmt.Errorf("not enough member signs, receive %v, expect %v", len(signers), c.Threshold)
+	}
+	return nil
+}
+
+func multiSigCreateValidator(tx *types.Transaction) error {
+	if !params.GetChainConfig().IsZIP007(BlockChainImpl.Height()) {
+		return fmt.Errorf("unknown transaction type")
+	}
+	if len(tx.Data) == 0 {
+		return fmt.Errorf("data is empty")
+	}
+	if tx.Target != nil {
+		return fmt.Errorf("target should be nil")
+	}
+	c, err := types.DecodeMultiSigConfig(tx.Data)
+	if err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return valueValidate(tx)
+}
+
+func multiSigExecuteValidator(tx *types.Transaction, validateState bool) error {
+	if !params.GetChainConfig().IsZIP007(BlockChainImpl.Height()) {
+		return fmt.Errorf("unknown transaction type")
+	}
+	if tx.Target == nil {
+		return fmt.Errorf("target is nil")
+	}
+	if err := valueValidate(tx); err != nil {
+		return err
+	}
+	// Data is only used to update the config of the account itself
+	if len(tx.Data) > 0 {
+		if *tx.Target != *tx.Source {
+			return fmt.Errorf("data should be empty")
+		}
+		c, err := types.DecodeMultiSigConfig(tx.Data)
+		if err != nil {
+			return err
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	if validateState {
+		db, err := BlockChainImpl.LatestAccountDB()
+		if err != nil {
+			return err
+		}
+		return verifyMultiSigTx(db, tx)
+	}
+	_, err := types.DecodeMultiSigSigns(tx.Sign)
+	return err
+}
+
+// multiSigCreateOp creates the multisig account and transfers the value to it
+type multiSigCreateOp struct {
+	*transitionContext
+	source common.Address
+	config *types.MultiSigConfig
+}
+
+func (ss *multiSigCreateOp) ParseTransaction() error {
+	c, err := types.DecodeMultiSigConfig(ss.msg.Payload())
+	if err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	ss.source = *ss.msg.Operator()
+	ss.config = c
+	return nil
+}
+
+func (ss *multiSigCreateOp) Transition() *result {
+	ret := newResult()
+	addr := types.MultiSigAddress(ss.source, ss.msg.GetNonce())
+	if c, _ := GetMultiSigConfig(ss.accountDB, addr); c != nil || ss.accountDB.GetCodeHash(addr) != (common.Hash{}) {
+		ret.setError(fmt.Errorf("multisig address conflict"), types.RSFail)
+		return ret
+	}
+	if err := setMultiSigConfig(ss.accountDB, addr, ss.config); err != nil {
+		ret.setError(err, types.RSFail)
+		return ret
+	}
+	if !transfer(ss.accountDB, ss.source, addr, ss.msg.Amount()) {
+		ret.setError(errBalanceNotEnough, types.RSBalanceNotEnough)
+		return ret
+	}
+	ret.contractAddress = addr
+	return ret
+}
+
+// multiSigExecuteOp transfers from the multisig account, or updates its config if the target is itself
+type multiSigExecuteOp struct {
+	*transitionContext
+	source common.Address
+	target common.Address
+	value  *big.Int
+	config *types.MultiSigConfig // New config if not nil
+}
+
+func (ss *multiSigExecuteOp) ParseTransaction() error {
+	ss.source = *ss.msg.Operator()
+	ss.target = *ss.msg.OpTarget()
+	ss.value = ss.msg.Amount()
+	if len(ss.msg.Payload()) > 0 {
+		if ss.target != ss.source {
+			return fmt.Errorf("data should be empty")
+		}
+		c, err := types.DecodeMultiSigConfig(ss.msg.Payload())
+		if err != nil {
+			return err
+		}
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		ss.config = c
+	}
+	return nil
+}
+
+func (ss *multiSigExecuteOp) Transition() *result {
+	ret := newResult()
+	if ss.config != nil {
+		if err := setMultiSigConfig(ss.accountDB, ss.source, ss.config); err != nil {
+			ret.setError(err, types.RSFail)
+			return ret
+		}
+	}
+	if !transfer(ss.accountDB, ss.source, ss.target, ss.value) {
+		ret.setError(errBalanceNotEnough, types.RSBalanceNotEnough)
+	}
+	return ret
+}
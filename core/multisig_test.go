@@ -0,0 +1,149 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/params"
+	"github.com/zvchain/zvchain/storage/account"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+func newMultiSigTx(source common.Address, target *common.Address, nonce uint64, value uint64, typ int8, data []byte) *types.Transaction {
+	tx := &types.Transaction{
+		RawTransaction: &types.RawTransaction{
+			Data:     data,
+			Value:    types.NewBigInt(value),
+			Nonce:    nonce,
+			Target:   target,
+			Type:     typ,
+			GasLimit: types.NewBigInt(10000),
+			GasPrice: types.NewBigInt(1000),
+			Source:   &source,
+		},
+	}
+	tx.Hash = tx.GenHash()
+	return tx
+}
+
+func signMultiSigTx(tx *types.Transaction, keys ...common.PrivateKey) {
+	signs := make([][]byte, 0)
+	for _, k := range keys {
+		signs = append(signs, signData(k, tx.Hash.Bytes()))
+	}
+	tx.Sign, _ = types.EncodeMultiSigSigns(signs)
+}
+
+func TestMultiSigTransition(t *testing.T) {
+	cfg := params.GetChainConfig()
+	zip007 := cfg.ZIP007
+	defer func() { cfg.ZIP007 = zip007 }()
+
+	db, _ := tasdb.NewMemDatabase()
+	defer db.Close()
+	state, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(db, false))
+	bh := &types.BlockHeader{Height: 1}
+
+	creator := generateKey()
+	creatorAddr := creator.GetPubKey().GetAddress()
+	members := []common.PrivateKey{generateKey(), generateKey(), generateKey()}
+	config := &types.MultiSigConfig{Threshold: 2}
+	for _, m := range members {
+		config.Members = append(config.Members, m.GetPubKey().GetAddress())
+	}
+	data, _ := types.EncodeMultiSigConfig(config)
+	state.AddBalance(creatorAddr, new(big.Int).SetUint64(common.TAS2RA(100)))
+
+	createTx := newMultiSigTx(creatorAddr, nil, 1, common.TAS2RA(10), types.TransactionTypeMultiSigCreate, data)
+	createTx.Sign = signData(creator, createTx.Hash.Bytes())
+
+	cfg.ZIP007 = 10
+	if _, err := applyStateTransition(state, createTx, bh); err == nil {
+		t.Fatal("expect error before zip007")
+	}
+	cfg.ZIP007 = 0
+
+	ret, err := applyStateTransition(state, createTx, bh)
+	if err != nil || ret.err != nil {
+		t.Fatalf("create multisig fail: %v %v", err, ret.err)
+	}
+	msAddr := types.MultiSigAddress(creatorAddr, 1)
+	if ret.contractAddress != msAddr {
+		t.Fatalf("unexpected multisig address %v", ret.contractAddress.AddrPrefixString())
+	}
+	if c, err := GetMultiSigConfig(state, msAddr); err != nil || c == nil || c.Threshold != 2 || len(c.Members) != 3 {
+		t.Fatalf("unexpected stored config %+v %v", c, err)
+	}
+	if state.GetBalance(msAddr).Uint64() != common.TAS2RA(10) {
+		t.Fatalf("unexpected multisig balance %v", state.GetBalance(msAddr))
+	}
+	state.SetNonce(creatorAddr, 1)
+
+	receiver := common.StringToAddress("zv11223344")
+	transferTx := func(nonce uint64, keys ...common.PrivateKey) *types.Transaction {
+		tx := newMultiSigTx(msAddr, &receiver, nonce, common.TAS2RA(1), types.TransactionTypeMultiSigExecute, nil)
+		signMultiSigTx(tx, keys...)
+		return tx
+	}
+
+	balance := state.GetBalance(msAddr).Uint64()
+	for name, tx := range map[string]*types.Transaction{
+		"below threshold":  transferTx(1, members[0]),
+		"duplicate member": transferTx(1, members[0], members[0]),
+		"not a member":     transferTx(1, members[0], creator),
+	} {
+		if _, err := applyStateTransition(state, tx, bh); err == nil {
+			t.Fatalf("%v: expect error", name)
+		}
+	}
+	// Rejected transactions don't consume the gas of the multisig account
+	if state.GetBalance(msAddr).Uint64() != balance {
+		t.Fatal("balance of the multisig account changed")
+	}
+
+	ret, err = applyStateTransition(state, transferTx(1, members[2], members[0]), bh)
+	if err != nil || ret.err != nil {
+		t.Fatalf("execute multisig fail: %v %v", err, ret.err)
+	}
+	if state.GetBalance(receiver).Uint64() != common.TAS2RA(1) {
+		t.Fatalf("unexpected receiver balance %v", state.GetBalance(receiver))
+	}
+	state.SetNonce(msAddr, 1)
+
+	// Update the config by the current threshold
+	config.Threshold = 3
+	data, _ = types.EncodeMultiSigConfig(config)
+	updateTx := newMultiSigTx(msAddr, &msAddr, 2, 0, types.TransactionTypeMultiSigExecute, data)
+	signMultiSigTx(updateTx, members[0], members[1])
+	ret, err = applyStateTransition(state, updateTx, bh)
+	if err != nil || ret.err != nil {
+		t.Fatalf("update multisig fail: %v %v", err, ret.err)
+	}
+	state.SetNonce(msAddr, 2)
+	if c, _ := GetMultiSigConfig(state, msAddr); c.Threshold != 3 {
+		t.Fatalf("threshold not updated %v", c.Threshold)
+	}
+	if _, err := applyStateTransition(state, transferTx(3, members[0], members[1]), bh); err == nil {
+		t.Fatal("expect error below the updated threshold")
+	}
+	if _, err := applyStateTransition(state, transferTx(3, members...), bh); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/network"
 )
 
 const (
@@ -42,8 +43,9 @@ type peerMeter struct {
 	evilExpireTime time.Time
 }
 
+// isEvil returns whether the peer is in the sync backoff, or banned by the network reputation
 func (m *peerMeter) isEvil() bool {
-	return time.Since(m.evilExpireTime) < 0
+	return time.Since(m.evilExpireTime) < 0 || network.IsPeerBanned(m.id)
 }
 
 func (m *peerMeter) addEvilCountWithLock() {
@@ -143,6 +145,7 @@ func (bpm *peerManager) heardFromPeer(id string) {
 	}
 	pm := bpm.getOrAddPeer(id)
 	pm.resetTimeoutMeter()
+	network.ReportPeer(id, network.PeerEventUseful)
 }
 
 func (bpm *peerManager) timeoutPeer(id string) {
@@ -151,12 +154,16 @@ func (bpm *peerManager) timeoutPeer(id string) {
 	}
 	pm := bpm.getOrAddPeer(id)
 	pm.increaseTimeout()
+	network.ReportPeer(id, network.PeerEventTimeout)
 }
 
 func (bpm *peerManager) isEvil(id string) bool {
 	if id == "" {
 		return false
 	}
+	if network.IsPeerBanned(id) {
+		return true
+	}
 	if !bpm.isPeerExists(id) {
 		return false
 	}
@@ -170,6 +177,7 @@ func (bpm *peerManager) addEvilCount(id string) {
 	}
 	pm := bpm.getOrAddPeer(id)
 	pm.addEvilCountWithLock()
+	network.ReportPeer(id, network.PeerEventMisbehave)
 }
 
 func (bpm *peerManager) resetEvilCount(id string) {
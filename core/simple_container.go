@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"container/heap"
 	"fmt"
+	"math/big"
 	"sort"
 	"sync"
 	"time"
@@ -30,7 +31,10 @@ import (
 	"github.com/zvchain/zvchain/middleware/types"
 )
 
-const maxSyncCountPreSource = 50 // max count of tx with same source to sync to neighbour node
+const (
+	maxSyncCountPreSource = 50 // max count of tx with same source to sync to neighbour node
+	defaultTxPriceBump    = 10 // default min gas price bump percentage to replace a transaction with the same nonce
+)
 
 type simpleContainer struct {
 	txsMap     map[common.Hash]*TransactionWithTime
@@ -39,10 +43,20 @@ type simpleContainer struct {
 	queue      map[common.Hash]*types.Transaction
 	queueLimit int
 	txTimeout  time.Duration
+	priceBump  uint64
+
+	// onReplaced is called when a transaction is replaced by another one with the same source and nonce
+	onReplaced func(replaced, by *types.Transaction)
 
 	lock sync.RWMutex
 }
 
+// txReplacement records a transaction replaced by another one with the same source and nonce
+type txReplacement struct {
+	replaced *types.Transaction
+	by       *types.Transaction
+}
+
 type TransactionWithTime struct {
 	item  *types.Transaction
 	begin time.Time
@@ -73,6 +87,23 @@ func (tx *orderByNonceTx) Compare(e datacommon.Comparator) int {
 	return 0
 }
 
+// minReplacePrice returns the min gas price of the transaction to replace the given one
+func minReplacePrice(old *types.Transaction, priceBump uint64) *big.Int {
+	price := new(big.Int).Mul(old.GasPrice.Value(), new(big.Int).SetUint64(100+priceBump))
+	return price.Div(price, big.NewInt(100))
+}
+
+// replaceable checks if the transaction can replace the old one with the same source and nonce.
+// The gas price should be higher than the old one and bumped by at least priceBump percent
+func replaceable(old, tx *types.Transaction, priceBump uint64) bool {
+	return tx.GasPrice.Cmp(old.GasPrice.Value()) > 0 && tx.GasPrice.Cmp(minReplacePrice(old, priceBump)) >= 0
+}
+
+// isReplacement checks if the transactions have the same source and nonce
+func isReplacement(tx1, tx2 *types.Transaction) bool {
+	return tx1.Hash != tx2.Hash && tx1.Nonce == tx2.Nonce && *tx1.Source == *tx2.Source
+}
+
 type priceHeap []*types.Transaction
 
 func (h priceHeap) Len() int           { return len(h) }
@@ -92,8 +123,9 @@ func (h *priceHeap) Pop() interface{} {
 }
 
 type pendingContainer struct {
-	limit int
-	size  int
+	limit     int
+	size      int
+	priceBump uint64
 
 	waitingMap map[common.Address]*skip.SkipList //*orderByNonceTx. Map of transactions group by source for waiting
 }
@@ -105,7 +137,7 @@ func (s *pendingContainer) push(tx *types.Transaction, stateNonce uint64) (added
 		existSource := s.waitingMap[*tx.Source].Get(newTxNode)[0]
 
 		if existSource != nil {
-			if existSource.(*orderByNonceTx).item.GasPrice.Cmp(tx.GasPrice.Value()) < 0 {
+			if replaceable(existSource.(*orderByNonceTx).item, tx, s.priceBump) {
 				//replace the existing one
 				deleted := s.waitingMap[*tx.Source].Delete(existSource)
 				s.size = s.size - len(deleted)
@@ -251,10 +283,11 @@ func newOrderByNonceTx(tx *types.Transaction) *orderByNonceTx {
 	return s
 }
 
-func newPendingContainer(limit int) *pendingContainer {
+func newPendingContainer(limit int, priceBump uint64) *pendingContainer {
 	s := &pendingContainer{
 		limit:      limit,
 		size:       0,
+		priceBump:  priceBump,
 		waitingMap: make(map[common.Address]*skip.SkipList),
 	}
 	return s
@@ -264,15 +297,21 @@ func newSimpleContainer(pendingLimit int, queueLimit int, chain types.BlockChain
 	//timeOutDuration is the max time of a tx can keeped in tx pool, default value is 30 minutes
 	timeOutDuration := common.GlobalConf.GetInt(configSec, "tx_timeout_duration", 60*30)
 	timeout := time.Second * time.Duration(timeOutDuration)
+	//priceBump is the min gas price bump percentage to replace a transaction with the same nonce
+	priceBump := common.GlobalConf.GetInt(configSec, "tx_price_bump", defaultTxPriceBump)
+	if priceBump < 0 {
+		priceBump = defaultTxPriceBump
+	}
 
 	c := &simpleContainer{
 		lock:       sync.RWMutex{},
 		chain:      chain.(*FullBlockChain),
 		txsMap:     make(map[common.Hash]*TransactionWithTime),
-		pending:    newPendingContainer(pendingLimit),
+		pending:    newPendingContainer(pendingLimit, uint64(priceBump)),
 		queue:      make(map[common.Hash]*types.Transaction),
 		queueLimit: queueLimit,
 		txTimeout:  timeout,
+		priceBump:  uint64(priceBump),
 	}
 
 	ticker := time.NewTicker(30 * time.Second)
@@ -332,6 +371,9 @@ func (c *simpleContainer) eachForSync(f func(tx *types.Transaction) bool) {
 
 // push try to push transaction to pool. if error return means the transaction is discarded and the error can be ignored
 func (c *simpleContainer) push(tx *types.Transaction) (err error) {
+	var replacements []txReplacement
+	// Deferred before the unlock so that the callback runs after the lock released
+	defer func() { c.notifyReplaced(replacements) }()
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -354,15 +396,40 @@ func (c *simpleContainer) push(tx *types.Transaction) (err error) {
 	}
 	c.txsMap[tx.Hash] = warpTransaction(tx)
 	if evicted != nil {
-		Logger.Debugf("Tx %v replaced by %v as higher gas price when push()", evicted.Hash, tx.Hash)
-		if evicted.Hash == tx.Hash {
-			err = fmt.Errorf("existing a transaction with same nonce: %v", conflicted.Hash.Hex())
-		}
 		delete(c.txsMap, evicted.Hash)
+		if evicted.Hash != tx.Hash {
+			Logger.Debugf("Tx %v evicted by %v when push()", evicted.Hash, tx.Hash)
+			replacements = appendReplacement(replacements, evicted, tx)
+		} else if conflicted.Hash != tx.Hash {
+			err = fmt.Errorf("replacement transaction underpriced: existing transaction %v with gas price %v, the gas price should be at least %v",
+				conflicted.Hash.Hex(), conflicted.GasPrice.Value(), minReplacePrice(conflicted, c.priceBump))
+		} else {
+			err = fmt.Errorf("tx pool is full and the gas price is too low")
+		}
 	}
 	return
 }
 
+// appendReplacement collects the replacement of the transaction with the same source and nonce
+func appendReplacement(replacements []txReplacement, evicted, tx *types.Transaction) []txReplacement {
+	if !isReplacement(evicted, tx) {
+		return replacements
+	}
+	Logger.Debugf("Tx %v replaced by %v with gas price %v", evicted.Hash, tx.Hash, tx.GasPrice.Value())
+	return append(replacements, txReplacement{replaced: evicted, by: tx})
+}
+
+// notifyReplaced calls the onReplaced callback with the replacements collected.
+// It must be called without the container lock held
+func (c *simpleContainer) notifyReplaced(replacements []txReplacement) {
+	if c.onReplaced == nil {
+		return
+	}
+	for _, r := range replacements {
+		c.onReplaced(r.replaced, r.by)
+	}
+}
+
 func (c *simpleContainer) addToQueue(tx *types.Transaction) (evicted *types.Transaction, conflicted *types.Transaction, err error) {
 	if len(c.queue) > c.queueLimit {
 		err = fmt.Errorf("tx_pool's queue is full. current queue size: %d", len(c.queue))
@@ -370,7 +437,7 @@ func (c *simpleContainer) addToQueue(tx *types.Transaction) (evicted *types.Tran
 	}
 	for _, old := range c.queue {
 		if old.Nonce == tx.Nonce && bytes.Equal(old.Source.Bytes(), tx.Source.Bytes()) {
-			if old.GasPrice.Cmp(tx.GasPrice.Value()) >= 0 {
+			if !replaceable(old, tx, c.priceBump) {
 				evicted = tx
 				conflicted = old
 				return
@@ -422,6 +489,8 @@ func mapToNonceTxSlice(txMap map[common.Hash]*types.Transaction) *nonceTxSlice {
 
 // promoteQueueToPending tris to move the transactions to the pending list for casting and syncing if possible
 func (c *simpleContainer) promoteQueueToPending() {
+	var replacements []txReplacement
+	defer func() { c.notifyReplaced(replacements) }()
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	nonceCache := make(map[common.Address]uint64)
@@ -436,9 +505,12 @@ func (c *simpleContainer) promoteQueueToPending() {
 		}
 		success, evicted, _ := c.pending.push(tx, stateNonce)
 		if evicted != nil {
-			Logger.Debugf("Tx %v replaced by %v as higher gas price when promoteQueueToPending", evicted.Hash, tx.Hash)
+			Logger.Debugf("Tx %v evicted when promote %v to pending", evicted.Hash, tx.Hash)
 			delete(c.txsMap, evicted.Hash)
 			delete(c.queue, evicted.Hash)
+			if evicted.Hash != tx.Hash {
+				replacements = appendReplacement(replacements, evicted, tx)
+			}
 		}
 		if success {
 			delete(c.queue, tx.Hash)
@@ -17,6 +17,7 @@ package core
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -94,7 +95,7 @@ func execute(t *testing.T, tx types.Transaction) {
 
 func Test_push(t *testing.T) {
 	t1 := genTx4Test("d3b14a7bab3c68e9369d0e433e5be9a514e843593f0f149cb0906e7bc085d881", 1, types.NewBigInt(20000), gasLimit, &addr1)
-	t2 := genTx4Test("d3b14a7bab3c68e9369d0e433e5be9a514e843593f0f149cb0906e7bc085d882", 1, types.NewBigInt(19999), gasLimit, &addr1)
+	t2 := genTx4Test("d3b14a7bab3c68e9369d0e433e5be9a514e843593f0f149cb0906e7bc085d882", 1, types.NewBigInt(18000), gasLimit, &addr1)
 	t3 := genTx4Test("d3b14a7bab3c68e9369d0e433e5be9a514e843593f0f149cb0906e7bc085d883", 2, types.NewBigInt(20000), gasLimit, &addr1)
 
 	err := initContext4Test(t)
@@ -150,6 +151,59 @@ func Test_push(t *testing.T) {
 	checkPendingSize(t)
 }
 
+func TestReplaceByFee(t *testing.T) {
+	err := initContext4Test(t)
+	defer clearSelf(t)
+	if err != nil {
+		t.Fatalf("failed to initContext4Test")
+	}
+	common.GlobalConf.SetInt(configSec, "tx_price_bump", 10)
+	container = newSimpleContainer(10, 3, BlockChainImpl)
+	replaced := make(map[common.Hash]common.Hash)
+	container.onReplaced = func(old, by *types.Transaction) {
+		replaced[old.Hash] = by.Hash
+		// The callback is called after the container lock released, otherwise it blocks here
+		if !container.contains(by.Hash) {
+			t.Errorf("tx %v should be in the container", by.Hash)
+		}
+	}
+
+	p1 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f01", 1, types.NewBigInt(10000), gasLimit, &addr1)
+	p2 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f02", 1, types.NewBigInt(10999), gasLimit, &addr1)
+	p3 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f03", 1, types.NewBigInt(11000), gasLimit, &addr1)
+	if err = container.push(p1); err != nil {
+		t.Fatal(err)
+	}
+	if err = container.push(p2); err == nil || !strings.Contains(err.Error(), "underpriced") {
+		t.Fatalf("expect underpriced error, got %v", err)
+	}
+	if container.get(p1.Hash) == nil || container.get(p2.Hash) != nil {
+		t.Fatal("underpriced tx should not replace the pending one")
+	}
+	if err = container.push(p3); err != nil {
+		t.Fatal(err)
+	}
+	if container.get(p1.Hash) != nil || container.get(p3.Hash) == nil || replaced[p1.Hash] != p3.Hash {
+		t.Fatal("pending tx should be replaced")
+	}
+	checkPendingSize(t)
+
+	// Transactions in the queue
+	q1 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f04", 3, types.NewBigInt(10000), gasLimit, &addr1)
+	q2 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f05", 3, types.NewBigInt(10500), gasLimit, &addr1)
+	q3 := genTx4Test("0b454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f06", 3, types.NewBigInt(12000), gasLimit, &addr1)
+	_ = container.push(q1)
+	if err = container.push(q2); err == nil || !strings.Contains(err.Error(), "underpriced") {
+		t.Fatalf("expect underpriced error, got %v", err)
+	}
+	if err = container.push(q3); err != nil {
+		t.Fatal(err)
+	}
+	if container.queue[q1.Hash] != nil || container.queue[q3.Hash] == nil || replaced[q1.Hash] != q3.Hash {
+		t.Fatal("queued tx should be replaced")
+	}
+}
+
 func Test_simpleContainer_forEach(t *testing.T) {
 	err := initContext4Test(t)
 	defer clearSelf(t)
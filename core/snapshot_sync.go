@@ -0,0 +1,533 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/log"
+	"github.com/zvchain/zvchain/middleware/notify"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/network"
+	"github.com/zvchain/zvchain/storage/account"
+	"github.com/zvchain/zvchain/storage/rlp"
+	"github.com/zvchain/zvchain/storage/sha3"
+	"github.com/zvchain/zvchain/storage/tasdb"
+	"github.com/zvchain/zvchain/storage/trie"
+)
+
+const (
+	snapshotSyncInterval          = 1               // Interval of scheduling the state nodes requests
+	snapshotCheckpointReqInterval = 5               // Interval of querying the checkpoint from neighbors
+	stateNodesReqTimeout          = 10              // Timeout of requesting state nodes from neighbor
+	maxStateNodesReqCount         = 384             // Max number of state nodes requested at a time
+	maxStateNodesRespSize         = 2 * 1024 * 1024 // Soft limit of the response size of state nodes
+	defaultSnapshotSyncMinPeers   = 3               // Default number of neighbors should have the checkpoint
+)
+
+const (
+	tickerSnapshotSync              = "snapshot_sync"
+	configSnapshotSyncMinPeers      = "snapshot_sync_min_peers"
+	configSnapshotTrustedCheckpoint = "snapshot_trusted_checkpoint"
+)
+
+const (
+	snapshotPhaseCheckpoint int32 = iota // Querying the trusted checkpoint from the neighbors
+	snapshotPhaseState                   // Downloading the state at the checkpoint
+	snapshotPhaseDone                    // Snapshot sync is finished or disabled
+)
+
+// emptyStateEntry is the hash of the empty storage trie and the empty code of an account
+var emptyStateEntry = common.Hash(sha3.Sum256(nil))
+
+var snapshotSync *snapshotSyncer
+
+// stateNodeData is a state entry in the state nodes response
+type stateNodeData struct {
+	Hash common.Hash
+	Data []byte
+}
+
+type stateNodesRequest struct {
+	hashes []common.Hash
+	sent   time.Time
+}
+
+type checkpointVote struct {
+	block *types.Block
+	peers map[string]struct{}
+}
+
+// snapshotSyncer downloads the state at a trusted checkpoint instead of executing all blocks from the genesis.
+// The hash of the checkpoint is configured by the operator and obtained out of band, e.g. from the explorers
+// or the release notes, as the groups signing the blocks are created in the state which isn't available
+// before it's downloaded. The header received from the neighbors is checked against the hash before
+// downloading any state. The account trie and the storage tries are downloaded node by node,
+// each node is verified by the hash which is referenced by its verified parent, so the state is
+// verified against the state root of the checkpoint. The checkpoint block is then set as the top
+// and the normal block sync continues from it.
+type snapshotSyncer struct {
+	chain       *FullBlockChain
+	networkImpl network.Network
+	stateDb     tasdb.Database // Database the state nodes written to
+	logger      *logrus.Logger
+	minPeers    int
+	trusted     common.Hash // Hash of the trusted checkpoint, the only one accepted as the pivot
+
+	phase int32
+
+	lock      sync.Mutex
+	votes     map[common.Hash]*checkpointVote // Checkpoints reported by the neighbors
+	voted     map[string]common.Hash          // Checkpoint reported by each neighbor
+	lastQuery time.Time
+
+	pivot   *types.Block                  // The checkpoint block which state is downloading
+	peers   map[string]struct{}           // Neighbors agreed on the pivot
+	sched   *trie.Sync                    // Scheduler of the state nodes
+	pending map[string]*stateNodesRequest // In-flight requests of each neighbor
+	retry   []common.Hash                 // Hashes of the failed requests to be requested again
+	synced  uint64                        // Number of the state entries written
+}
+
+func newSnapshotSyncer(chain *FullBlockChain, networkImpl network.Network) *snapshotSyncer {
+	minPeers := common.GlobalConf.GetInt(configSec, configSnapshotSyncMinPeers, defaultSnapshotSyncMinPeers)
+	if minPeers <= 0 {
+		minPeers = 1
+	}
+	return &snapshotSyncer{
+		chain:       chain,
+		networkImpl: networkImpl,
+		stateDb:     chain.stateDb,
+		logger:      log.BlockSyncLogger,
+		minPeers:    minPeers,
+		trusted:     common.HexToHash(common.GlobalConf.GetString(configSec, configSnapshotTrustedCheckpoint, "")),
+		phase:       snapshotPhaseDone,
+		votes:       make(map[common.Hash]*checkpointVote),
+		voted:       make(map[string]common.Hash),
+		pending:     make(map[string]*stateNodesRequest),
+	}
+}
+
+// InitSnapshotSyncer initialize the snapshotSyncer which serves the state to neighbors.
+// If enable is set and the local chain is empty, the state is synced at the trusted checkpoint
+// configured, and the block sync is paused until it's finished.
+// It should be called before InitBlockSyncer
+func InitSnapshotSyncer(chain *FullBlockChain, enable bool) {
+	snapshotSync = newSnapshotSyncer(chain, network.GetNetInstance())
+	notify.BUS.Subscribe(notify.SnapshotCheckpointReq, snapshotSync.checkpointReqHandler)
+	notify.BUS.Subscribe(notify.StateNodesReq, snapshotSync.stateNodesReqHandler)
+
+	if !enable {
+		return
+	}
+	if chain.Height() > 0 {
+		snapshotSync.logger.Warnf("local chain height is %v, snapshot sync is skipped", chain.Height())
+		return
+	}
+	if snapshotSync.trusted == (common.Hash{}) {
+		snapshotSync.logger.Warnf("snapshot sync requires the trusted checkpoint configured by %v, sync from the genesis", configSnapshotTrustedCheckpoint)
+		return
+	}
+	notify.BUS.Subscribe(notify.SnapshotCheckpointResponse, snapshotSync.checkpointResponseHandler)
+	notify.BUS.Subscribe(notify.StateNodesResponse, snapshotSync.stateNodesResponseHandler)
+
+	atomic.StoreInt32(&snapshotSync.phase, snapshotPhaseCheckpoint)
+	chain.ticker.RegisterPeriodicRoutine(tickerSnapshotSync, snapshotSync.syncRoutine, snapshotSyncInterval)
+	chain.ticker.StartTickerRoutine(tickerSnapshotSync, false)
+	snapshotSync.logger.Infof("snapshot sync started at the checkpoint %v, min peers %v", snapshotSync.trusted, snapshotSync.minPeers)
+}
+
+func (ss *snapshotSyncer) isSyncing() bool {
+	if ss == nil {
+		return false
+	}
+	return atomic.LoadInt32(&ss.phase) != snapshotPhaseDone
+}
+
+// neighbors returns the peers known by the block syncer
+func (ss *snapshotSyncer) neighbors() []string {
+	if blockSync == nil {
+		return nil
+	}
+	blockSync.lock.RLock()
+	defer blockSync.lock.RUnlock()
+	ids := make([]string, 0, len(blockSync.candidatePool))
+	for id := range blockSync.candidatePool {
+		if !peerManagerImpl.isEvil(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (ss *snapshotSyncer) syncRoutine() bool {
+	if !ss.isSyncing() {
+		ss.chain.ticker.RemoveRoutine(tickerSnapshotSync)
+		return false
+	}
+	queries := ss.checkpointQueries(ss.neighbors())
+	for _, id := range queries {
+		ss.networkImpl.Send(id, network.Message{Code: network.SnapshotCheckpointReq, Body: ss.trusted.Bytes()})
+	}
+	reqs := ss.scheduleRequests()
+	for id, req := range reqs {
+		body := bytes.NewBuffer(make([]byte, 0, len(req.hashes)*common.HashLength))
+		for _, h := range req.hashes {
+			body.Write(h.Bytes())
+		}
+		ss.networkImpl.Send(id, network.Message{Code: network.StateNodesReq, Body: body.Bytes()})
+	}
+	return true
+}
+
+// checkpointQueries returns the neighbors haven't reported the trusted checkpoint
+func (ss *snapshotSyncer) checkpointQueries(neighbors []string) []string {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if time.Since(ss.lastQuery) < snapshotCheckpointReqInterval*time.Second {
+		return nil
+	}
+	ss.lastQuery = time.Now()
+	ids := make([]string, 0)
+	for _, id := range neighbors {
+		if _, ok := ss.voted[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// scheduleRequests re-schedules the timeout requests and assigns the missing state nodes to the idle peers
+func (ss *snapshotSyncer) scheduleRequests() map[string]*stateNodesRequest {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if atomic.LoadInt32(&ss.phase) != snapshotPhaseState {
+		return nil
+	}
+	// The state may be already in the database
+	if ss.sched.Pending() == 0 {
+		ss.finish()
+		return nil
+	}
+	for id, req := range ss.pending {
+		if time.Since(req.sent) > stateNodesReqTimeout*time.Second {
+			ss.logger.Warnf("request state nodes from %v timeout", id)
+			peerManagerImpl.timeoutPeer(id)
+			ss.retry = append(ss.retry, req.hashes...)
+			delete(ss.pending, id)
+		}
+	}
+	reqs := make(map[string]*stateNodesRequest)
+	for id := range ss.peers {
+		if _, ok := ss.pending[id]; ok {
+			continue
+		}
+		if peerManagerImpl.isEvil(id) {
+			delete(ss.peers, id)
+			continue
+		}
+		n := len(ss.retry)
+		if n > maxStateNodesReqCount {
+			n = maxStateNodesReqCount
+		}
+		hashes := append([]common.Hash{}, ss.retry[:n]...)
+		ss.retry = ss.retry[n:]
+		if len(hashes) < maxStateNodesReqCount {
+			hashes = append(hashes, ss.sched.Missing(maxStateNodesReqCount-len(hashes))...)
+		}
+		if len(hashes) == 0 {
+			break
+		}
+		req := &stateNodesRequest{hashes: hashes, sent: time.Now()}
+		ss.pending[id] = req
+		reqs[id] = req
+	}
+	if len(ss.peers) == 0 {
+		ss.logger.Warnf("no peer to sync the state at %v, waiting for the checkpoint reported", ss.pivot.Header.Hash)
+	}
+	return reqs
+}
+
+// checkpointReqHandler responds the checkpoint block of the hash requested if it's on the local chain and not
+// higher than the latest checkpoint, or the latest checkpoint if no hash requested
+func (ss *snapshotSyncer) checkpointReqHandler(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	cp := ss.chain.LatestCheckPoint()
+	if cp == nil || cp.Height == 0 {
+		return nil
+	}
+	hash := cp.Hash
+	if body := m.Body(); len(body) == common.HashLength {
+		hash = common.BytesToHash(body)
+		bh := ss.chain.QueryBlockHeaderByHash(hash)
+		if bh == nil || bh.Height > cp.Height {
+			return nil
+		}
+		if onChain := ss.chain.QueryBlockHeaderByHeight(bh.Height); onChain == nil || onChain.Hash != hash {
+			return nil
+		}
+	} else if len(body) != 0 {
+		return fmt.Errorf("bad checkpoint request from %v, size %v", m.Source(), len(body))
+	}
+	b := ss.chain.QueryBlockByHash(hash)
+	if b == nil {
+		return fmt.Errorf("checkpoint block not found %v", hash)
+	}
+	body, err := types.MarshalBlock(b)
+	if err != nil {
+		return err
+	}
+	ss.networkImpl.Send(m.Source(), network.Message{Code: network.SnapshotCheckpointResponse, Body: body})
+	return nil
+}
+
+// verifyCheckpointBlock checks the header hash and the transactions of the block reported
+func verifyCheckpointBlock(b *types.Block) error {
+	if b == nil || b.Header == nil {
+		return fmt.Errorf("empty block")
+	}
+	if b.Header.GenHash() != b.Header.Hash {
+		return fmt.Errorf("block hash error")
+	}
+	txs := make(txSlice, 0, len(b.Transactions))
+	for _, raw := range b.Transactions {
+		txs = append(txs, types.NewTransaction(raw, raw.GenHash()))
+	}
+	if txs.calcTxTree() != b.Header.TxTree {
+		return fmt.Errorf("tx tree error")
+	}
+	return nil
+}
+
+func (ss *snapshotSyncer) checkpointResponseHandler(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	b, err := types.UnMarshalBlock(m.Body())
+	if err == nil {
+		err = verifyCheckpointBlock(b)
+	}
+	if err != nil {
+		peerManagerImpl.addEvilCount(m.Source())
+		return fmt.Errorf("discard checkpoint from %v: %v", m.Source(), err)
+	}
+	// The header is anchored to the trusted hash before any state downloaded. The peers of the old version
+	// report their latest checkpoint instead, which is ignored
+	if b.Header.Hash != ss.trusted {
+		return fmt.Errorf("discard checkpoint %v-%v from %v: not the trusted one", b.Header.Height, b.Header.Hash, m.Source())
+	}
+	peerManagerImpl.heardFromPeer(m.Source())
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.addVote(m.Source(), b)
+	return nil
+}
+
+// addVote records the checkpoint reported by the peer, and starts downloading the state
+// once enough peers agree on a checkpoint. Should be called with lock held
+func (ss *snapshotSyncer) addVote(id string, b *types.Block) {
+	hash := b.Header.Hash
+	if old, ok := ss.voted[id]; ok && old != hash {
+		if v := ss.votes[old]; v != nil {
+			delete(v.peers, id)
+		}
+	}
+	ss.voted[id] = hash
+	v := ss.votes[hash]
+	if v == nil {
+		v = &checkpointVote{block: b, peers: make(map[string]struct{})}
+		ss.votes[hash] = v
+	}
+	v.peers[id] = struct{}{}
+
+	switch atomic.LoadInt32(&ss.phase) {
+	case snapshotPhaseState:
+		// Peers agreed on the pivot later also serve the state
+		if hash == ss.pivot.Header.Hash {
+			ss.peers[id] = struct{}{}
+		}
+	case snapshotPhaseCheckpoint:
+		ss.checkAgreed()
+	}
+}
+
+// checkAgreed starts downloading the state once a checkpoint agreed. Should be called with lock held
+func (ss *snapshotSyncer) checkAgreed() {
+	pivot := ss.agreedCheckpoint()
+	if pivot == nil {
+		return
+	}
+	if pivot.block.Header.Height <= ss.chain.Height() {
+		ss.logger.Infof("agreed checkpoint %v is not higher than the local, snapshot sync is skipped", pivot.block.Header.Height)
+		atomic.StoreInt32(&ss.phase, snapshotPhaseDone)
+		return
+	}
+	peers := make(map[string]struct{}, len(pivot.peers))
+	for p := range pivot.peers {
+		peers[p] = struct{}{}
+	}
+	ss.startStateSync(pivot.block, peers)
+}
+
+// agreedCheckpoint returns the highest checkpoint reported by at least minPeers peers
+func (ss *snapshotSyncer) agreedCheckpoint() *checkpointVote {
+	var agreed *checkpointVote
+	for _, v := range ss.votes {
+		if len(v.peers) < ss.minPeers {
+			continue
+		}
+		if agreed == nil || v.block.Header.Height > agreed.block.Header.Height {
+			agreed = v
+		}
+	}
+	return agreed
+}
+
+// startStateSync starts downloading the state of the pivot block. Should be called with lock held
+func (ss *snapshotSyncer) startStateSync(pivot *types.Block, peers map[string]struct{}) {
+	ss.logger.Infof("start syncing state at checkpoint %v-%v, root %v, peers %v", pivot.Header.Height, pivot.Header.Hash, pivot.Header.StateTree, len(peers))
+	ss.pivot = pivot
+	ss.peers = peers
+	ss.sched = trie.NewSync(pivot.Header.StateTree, ss.stateDb, ss.onAccountLeaf)
+	atomic.StoreInt32(&ss.phase, snapshotPhaseState)
+}
+
+// onAccountLeaf schedules the storage trie and the code of the account
+func (ss *snapshotSyncer) onAccountLeaf(leaf []byte, parent common.Hash) error {
+	var acc account.Account
+	if err := rlp.DecodeBytes(leaf, &acc); err != nil {
+		return err
+	}
+	if acc.Root != emptyStateEntry {
+		ss.sched.AddSubTrie(acc.Root, 64, parent, nil)
+	}
+	if code := common.BytesToHash(acc.CodeHash); len(acc.CodeHash) > 0 && code != emptyStateEntry {
+		ss.sched.AddRawEntry(code, 64, parent)
+	}
+	return nil
+}
+
+func (ss *snapshotSyncer) stateNodesReqHandler(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	body := m.Body()
+	if len(body)%common.HashLength != 0 || len(body)/common.HashLength > maxStateNodesReqCount {
+		return fmt.Errorf("bad state nodes request from %v, size %v", m.Source(), len(body))
+	}
+	triedb := ss.chain.stateCache.TrieDB()
+	nodes := make([]*stateNodeData, 0)
+	size := 0
+	for i := 0; i < len(body) && size < maxStateNodesRespSize; i += common.HashLength {
+		hash := common.BytesToHash(body[i : i+common.HashLength])
+		data, err := triedb.Node(hash)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		nodes = append(nodes, &stateNodeData{Hash: hash, Data: data})
+		size += len(data)
+	}
+	resp, err := rlp.EncodeToBytes(nodes)
+	if err != nil {
+		return err
+	}
+	ss.networkImpl.Send(m.Source(), network.Message{Code: network.StateNodesResponse, Body: resp})
+	return nil
+}
+
+func (ss *snapshotSyncer) stateNodesResponseHandler(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	id := m.Source()
+	var nodes []*stateNodeData
+	if err := rlp.DecodeBytes(m.Body(), &nodes); err != nil {
+		peerManagerImpl.addEvilCount(id)
+		return fmt.Errorf("decode state nodes response from %v error:%v", id, err)
+	}
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	req := ss.pending[id]
+	if req == nil || atomic.LoadInt32(&ss.phase) != snapshotPhaseState {
+		return nil
+	}
+	delete(ss.pending, id)
+
+	requested := make(map[common.Hash]struct{}, len(req.hashes))
+	for _, h := range req.hashes {
+		requested[h] = struct{}{}
+	}
+	results := make([]trie.SyncResult, 0, len(nodes))
+	for _, n := range nodes {
+		if _, ok := requested[n.Hash]; ok {
+			results = append(results, trie.SyncResult{Hash: n.Hash, Data: n.Data})
+		}
+	}
+	_, index, err := ss.sched.Process(results)
+	if err != nil {
+		// Entries from the failed one are not processed
+		results = results[:index]
+		peerManagerImpl.addEvilCount(id)
+		delete(ss.peers, id)
+		ss.logger.Warnf("process state nodes from %v error:%v", id, err)
+	} else if len(results) == 0 {
+		// The peer doesn't have the state
+		delete(ss.peers, id)
+	} else {
+		peerManagerImpl.heardFromPeer(id)
+	}
+	for _, r := range results {
+		delete(requested, r.Hash)
+	}
+	for h := range requested {
+		ss.retry = append(ss.retry, h)
+	}
+
+	batch := ss.stateDb.NewBatch()
+	written, err := ss.sched.Commit(batch)
+	if err == nil {
+		err = batch.Write()
+	}
+	if err != nil {
+		ss.logger.Errorf("write state nodes error:%v", err)
+		return err
+	}
+	ss.synced += uint64(written)
+	snapshotEntriesCounter.Add(float64(written))
+	ss.logger.Debugf("state nodes from %v: %v delivered, %v written, %v synced, %v pending", id, len(results), written, ss.synced, ss.sched.Pending())
+
+	if ss.sched.Pending() == 0 {
+		ss.finish()
+	}
+	return nil
+}
+
+// finish sets the pivot as the top of the chain and resumes the block sync. Should be called with lock held
+func (ss *snapshotSyncer) finish() {
+	if err := ss.chain.commitSnapshotBlock(ss.pivot); err != nil {
+		ss.logger.Errorf("commit snapshot block %v error:%v", ss.pivot.Header.Hash, err)
+		return
+	}
+	ss.logger.Infof("snapshot sync finished at %v-%v, %v state entries synced", ss.pivot.Header.Height, ss.pivot.Header.Hash, ss.synced)
+	ss.pending = make(map[string]*stateNodesRequest)
+	ss.retry = nil
+	atomic.StoreInt32(&ss.phase, snapshotPhaseDone)
+}
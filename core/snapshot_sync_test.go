@@ -0,0 +1,197 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/notify"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/network"
+	"github.com/zvchain/zvchain/storage/account"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+// snapshotNet4Test delivers the messages to the syncer of the peer directly
+type snapshotNet4Test struct {
+	NetTest
+	from string
+	peer *snapshotSyncer
+}
+
+func (s *snapshotNet4Test) Send(id string, msg network.Message) error {
+	m := notify.NewDefaultMessage(msg.Body, s.from, 0, 0)
+	switch msg.Code {
+	case network.SnapshotCheckpointReq:
+		return s.peer.checkpointReqHandler(m)
+	case network.SnapshotCheckpointResponse:
+		return s.peer.checkpointResponseHandler(m)
+	case network.StateNodesReq:
+		return s.peer.stateNodesReqHandler(m)
+	case network.StateNodesResponse:
+		return s.peer.stateNodesResponseHandler(m)
+	}
+	return nil
+}
+
+// newSnapshotSyncer4Test adds a block on chain, and returns the syncer syncing the state of it from a serving one
+func newSnapshotSyncer4Test(t *testing.T) (*types.Block, *snapshotSyncer) {
+	chain := BlockChainImpl
+	for i := uint64(1); i <= 3; i++ {
+		if _, err := chain.GetTransactionPool().AddTransaction(genTestTx(500, string(rune('a'+i)), i, 10*i)); err != nil {
+			t.Fatalf("add transaction fail:%v", err)
+		}
+	}
+	block := chain.CastBlock(1, common.Hex2Bytes("12"), 0, []byte{}, common.HexToHash("ab454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7ff4"))
+	if block == nil || chain.AddBlockOnChain("", block) != types.AddBlockSucc {
+		t.Fatalf("add block fail")
+	}
+
+	server := newSnapshotSyncer(chain, nil)
+	client := newSnapshotSyncer(chain, nil)
+	client.stateDb, _ = tasdb.NewMemDatabase()
+	server.networkImpl = &snapshotNet4Test{from: "server", peer: client}
+	client.networkImpl = &snapshotNet4Test{from: "client", peer: server}
+	return block, client
+}
+
+func TestSnapshotSync(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	initPeerManager()
+	initBalance()
+	chain := BlockChainImpl
+	block, client := newSnapshotSyncer4Test(t)
+
+	client.startStateSync(block, map[string]struct{}{"server": {}})
+	for i := 0; i < 100 && client.isSyncing(); i++ {
+		client.syncRoutine()
+	}
+	if client.isSyncing() {
+		t.Fatalf("snapshot sync not finished, %v pending", client.sched.Pending())
+	}
+	if client.synced == 0 {
+		t.Fatal("nothing synced")
+	}
+
+	synced, err := account.NewAccountDB(block.Header.StateTree, account.NewDatabase(client.stateDb, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := chain.LatestAccountDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range block.Transactions {
+		for _, addr := range []*common.Address{tx.Source, tx.Target} {
+			if synced.GetBalance(*addr).Cmp(local.GetBalance(*addr)) != 0 || synced.GetNonce(*addr) != local.GetNonce(*addr) {
+				t.Fatalf("account %v mismatch", addr.AddrPrefixString())
+			}
+		}
+	}
+	if chain.QueryTopBlock().Hash != block.Header.Hash {
+		t.Fatal("snapshot block should be the top")
+	}
+	if chain.SnapshotHeight() != block.Header.Height || chain.loadSnapshotHeight() != block.Header.Height {
+		t.Fatalf("snapshot height should be recorded, got %v", chain.SnapshotHeight())
+	}
+	if cp := chain.LatestCheckPoint(); cp == nil || cp.Hash != block.Header.Hash {
+		t.Fatal("snapshot block should be the checkpoint")
+	}
+	if chain.CheckHistoryAvailable(block.Header.Height) == nil || chain.CheckHistoryAvailable(block.Header.Height+1) != nil {
+		t.Fatal("history should be available only after the snapshot")
+	}
+}
+
+func TestSnapshotTrustedCheckpoint(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	initPeerManager()
+	initBalance()
+	block, client := newSnapshotSyncer4Test(t)
+	client.minPeers = 1
+	client.phase = snapshotPhaseCheckpoint
+
+	body, err := types.MarshalBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.trusted = common.HexToHash("0x1234")
+	if err = client.checkpointResponseHandler(notify.NewDefaultMessage(body, "p1", 0, 0)); err == nil {
+		t.Fatal("expect the checkpoint not trusted discarded")
+	}
+	if client.phase != snapshotPhaseCheckpoint || len(client.votes) != 0 {
+		t.Fatalf("expect no state synced of the checkpoint not trusted, phase %v", client.phase)
+	}
+
+	client.trusted = block.Header.Hash
+	if err = client.checkpointResponseHandler(notify.NewDefaultMessage(body, "p1", 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Agreed, but skipped as the local chain has it already
+	if v := client.votes[block.Header.Hash]; v == nil || len(v.peers) != 1 || client.phase != snapshotPhaseDone {
+		t.Fatalf("expect the trusted checkpoint agreed, phase %v", client.phase)
+	}
+}
+
+func TestSnapshotCheckpointVote(t *testing.T) {
+	err := initContext4Test(t)
+	if err != nil {
+		t.Fatalf("init fail:%v", err)
+	}
+	defer clearSelf(t)
+	initPeerManager()
+	chain := BlockChainImpl
+
+	newCheckpoint := func(height uint64) *types.Block {
+		bh := &types.BlockHeader{Height: height, StateTree: common.HexToHash("0x1234"), TxTree: common.EmptyHash}
+		bh.Hash = bh.GenHash()
+		return &types.Block{Header: bh}
+	}
+	ss := newSnapshotSyncer(chain, nil)
+	ss.minPeers = 2
+	ss.phase = snapshotPhaseCheckpoint
+	ss.stateDb, _ = tasdb.NewMemDatabase()
+
+	cp1, cp2 := newCheckpoint(100), newCheckpoint(200)
+	ss.addVote("p1", cp1)
+	ss.addVote("p2", cp2)
+	ss.addVote("p3", cp1)
+	if ss.phase != snapshotPhaseState || ss.pivot != cp1 || len(ss.peers) != 2 {
+		t.Fatalf("expect syncing state of the checkpoint agreed, phase %v", ss.phase)
+	}
+	ss.addVote("p4", cp2)
+	if ss.pivot != cp1 {
+		t.Fatal("pivot shouldn't change once the state sync started")
+	}
+	ss.addVote("p2", cp1)
+	if _, ok := ss.peers["p2"]; !ok || len(ss.votes[cp2.Header.Hash].peers) != 1 {
+		t.Fatal("peer agreed later should serve the state")
+	}
+
+	forged := newCheckpoint(300)
+	forged.Header.Height = 301
+	if verifyCheckpointBlock(forged) == nil {
+		t.Fatal("expect hash error")
+	}
+}
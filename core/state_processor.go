@@ -65,7 +65,13 @@ type checkpointUpdator interface {
 type statePostProcessor func(db types.AccountDB, bh *types.BlockHeader)
 
 func newStateTransition(db types.AccountDB, tx *types.Transaction, bh *types.BlockHeader) stateTransition {
+	return newTracedStateTransition(db, tx, bh, nil)
+}
+
+// newTracedStateTransition creates the transition whose vm execution is recorded by the tracer if not nil
+func newTracedStateTransition(db types.AccountDB, tx *types.Transaction, bh *types.BlockHeader, tracer tvm.Tracer) stateTransition {
 	base := newTransitionContext(db, tx, bh, bh.Height)
+	base.tracer = tracer
 	base.intrinsicGasUsed = intrinsicGas(tx)
 	base.gasUsed = base.intrinsicGasUsed
 	return getOpByType(base, tx.Type)
@@ -97,6 +103,10 @@ func getOpByType(base *transitionContext, txType int8) stateTransition {
 		return &groupOperator{transitionContext: base}
 	case types.TransactionTypeBlacklistUpdate:
 		return &blackUpdateTx{transitionContext: base}
+	case types.TransactionTypeMultiSigCreate:
+		return &multiSigCreateOp{transitionContext: base}
+	case types.TransactionTypeMultiSigExecute:
+		return &multiSigExecuteOp{transitionContext: base}
 	default:
 		return &unSupported{typ: txType}
 	}
@@ -109,6 +119,7 @@ type transitionContext struct {
 	intrinsicGasUsed *big.Int
 	gasUsed          *big.Int
 	height           uint64
+	tracer           tvm.Tracer // Records the vm execution if not nil
 }
 
 func (tc *transitionContext) GasUsed() *big.Int {
@@ -121,6 +132,9 @@ type result struct {
 	err               error
 	logs              []*types.Log   // Generated when calls contract
 	contractAddress   common.Address // Generated when creates contract
+
+	vmResult *tvm.ExecuteResult      // Returned by the vm when creates or calls contract
+	vmErr    *types.TransactionError // Error returned by the vm
 }
 
 func newResult() *result {
@@ -143,6 +157,15 @@ func checkState(db types.AccountDB, tx *types.Transaction, height uint64) error
 	if !validateNonce(db, tx) {
 		return errNonceError
 	}
+	if isMultiSigType(tx.Type) && !params.GetChainConfig().IsZIP007(height) {
+		return fmt.Errorf("unSupported tx type %v", tx.Type)
+	}
+	// The threshold of the multisig account must be reached before consuming its gas
+	if tx.Type == types.TransactionTypeMultiSigExecute {
+		if err := verifyMultiSigTx(db, tx); err != nil {
+			return err
+		}
+	}
 	// validate the state again before pack
 	if _, err := stateValidate(db, tx, height); err != nil {
 		return err
@@ -247,8 +270,16 @@ func (ss *contractCreator) Transition() *result {
 		if !isTransferSuccess {
 			ret.setError(fmt.Errorf("balance not enough ,address is %v", ss.source.AddrPrefixString()), types.RSBalanceNotEnough)
 		} else {
-			_, logs, err := controller.Deploy(contract)
+			if ss.tracer != nil {
+				controller.Tracer = ss.tracer
+				ss.tracer.CaptureStart("CREATE", ss.source, contractAddress, "", ss.msg.GetValue(), controller.GetGasLeft())
+			}
+			vmResult, logs, err := controller.Deploy(contract)
+			if ss.tracer != nil {
+				ss.tracer.CaptureEnd(vmResult, err, controller.GetGasLeft())
+			}
 			ret.logs = logs
+			ret.vmResult, ret.vmErr = vmResult, err
 			if err != nil {
 				if err.Code == types.TVMGasNotEnoughError {
 					ret.setError(fmt.Errorf(err.Message), types.RSGasNotEnoughError)
@@ -281,13 +312,22 @@ func (ss *contractCaller) Transition() *result {
 	contract := tvm.LoadContract(*ss.msg.OpTarget())
 	if contract.Code == "" {
 		ret.setError(fmt.Errorf("no code at the given address %v", ss.msg.OpTarget().AddrPrefixString()), types.RSNoCodeError)
+		ret.vmErr = types.NewTransactionError(types.TVMNoCodeError, ret.err.Error())
 	} else {
 		isTransferSuccess := transfer(ss.accountDB, *ss.msg.Operator(), *contract.ContractAddress, ss.msg.Amount())
 		if !isTransferSuccess {
 			ret.setError(fmt.Errorf("balance not enough ,address is %v", ss.msg.Operator().AddrPrefixString()), types.RSBalanceNotEnough)
 		} else {
-			_, logs, err := controller.ExecuteAbiEval(ss.msg.Operator(), contract, string(ss.msg.Payload()))
+			if ss.tracer != nil {
+				controller.Tracer = ss.tracer
+				ss.tracer.CaptureStart("CALL", *ss.msg.Operator(), *contract.ContractAddress, string(ss.msg.Payload()), ss.msg.GetValue(), controller.GetGasLeft())
+			}
+			vmResult, logs, err := controller.ExecuteAbiEval(ss.msg.Operator(), contract, string(ss.msg.Payload()))
+			if ss.tracer != nil {
+				ss.tracer.CaptureEnd(vmResult, err, controller.GetGasLeft())
+			}
 			ret.logs = logs
+			ret.vmResult, ret.vmErr = vmResult, err
 			if err != nil {
 				if err.Code == types.TVMCheckABIError {
 					ret.setError(fmt.Errorf(err.Message), types.RSAbiError)
@@ -393,6 +433,11 @@ func doTransition(accountDB types.AccountDB, ss stateTransition) *result {
 }
 
 func applyStateTransition(accountDB types.AccountDB, tx *types.Transaction, bh *types.BlockHeader) (*result, error) {
+	return applyTracedStateTransition(accountDB, tx, bh, nil)
+}
+
+// applyTracedStateTransition applies the transaction with the vm execution recorded by the tracer if not nil
+func applyTracedStateTransition(accountDB types.AccountDB, tx *types.Transaction, bh *types.BlockHeader, tracer tvm.Tracer) (*result, error) {
 	var ret *result
 
 	// Reward tx is treated different from others
@@ -409,7 +454,7 @@ func applyStateTransition(accountDB types.AccountDB, tx *types.Transaction, bh *
 		if err := checkState(accountDB, tx, bh.Height); err != nil {
 			return nil, err
 		}
-		ss := newStateTransition(accountDB, tx, bh)
+		ss := newTracedStateTransition(accountDB, tx, bh, tracer)
 
 		// pre consume the gas limit for the normal transaction types
 		gasLimitFee := new(big.Int).Mul(tx.GasLimit.Value(), tx.GasPrice.Value())
@@ -435,6 +480,9 @@ func applyStateTransition(accountDB types.AccountDB, tx *types.Transaction, bh *
 
 // process executes all types transactions and returns the receipts
 func (executor *stateProcessor) process(accountDB *account.AccountDB, bh *types.BlockHeader, txs []*types.Transaction, pack bool, preHeader *types.BlockHeader) (state common.Hash, evits []common.Hash, executed txSlice, recps []*types.Receipt, gasFee uint64, err error) {
+	lockVM()
+	defer unlockVM()
+
 	beginTime := time.Now()
 	receipts := make([]*types.Receipt, 0)
 	transactions := make(txSlice, 0)
@@ -562,12 +610,17 @@ func createContract(accountDB types.AccountDB, transaction types.TxMessage) (com
 	return contractAddr, nil
 }
 
-func validGasPrice(gasPrice *big.Int, height uint64) bool {
+// MinGasPrice returns the min gas price of the transactions in the block of the given height
+func MinGasPrice(height uint64) uint64 {
 	times := height / adjustGasPricePeriod
 	if times > adjustGasPriceTimes {
 		times = adjustGasPriceTimes
 	}
-	if gasPrice.Cmp(big.NewInt(0).SetUint64(initialMinGasPrice<<times)) < 0 {
+	return initialMinGasPrice << times
+}
+
+func validGasPrice(gasPrice *big.Int, height uint64) bool {
+	if gasPrice.Cmp(big.NewInt(0).SetUint64(MinGasPrice(height))) < 0 {
 		return false
 	}
 	return true
@@ -72,6 +72,10 @@ func NewOfflineTailor(genesisGroup *types.GenesisInfo, dbDir string, sdbDir stri
 		reward:      "nu",
 		tx:          "tx",
 		receipt:     "rc",
+		logIndex:    "lb",
+		contract:    "cv",
+		token:       "tk",
+		address:     "ad",
 		pruneMode:   false,
 	}
 	chain := &FullBlockChain{
@@ -0,0 +1,440 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/notify"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const (
+	// tokenTransferEvent is the name of the event emitted by the token contracts on each transfer,
+	// with the from address, the to address and the value as the args
+	tokenTransferEvent = "Transfer"
+
+	// TokenTransfersPageSize is the count of transfers returned in a page
+	TokenTransfersPageSize = 20
+)
+
+var (
+	tokenTransferTopic = common.BytesToHash(common.Sha256([]byte(tokenTransferEvent)))
+
+	tokenIndexTipKey    = []byte("s") // Block the index has been applied up to
+	tokenBalancePrefix  = []byte("b") // holder + token -> balance
+	tokenTransferPrefix = []byte("t") // address + reversed height and indexes -> transfer
+	tokenBlockPrefix    = []byte("k") // block hash -> transfers applied, for reverting the block
+)
+
+// TokenTransfer is a transfer decoded from the Transfer event of a token contract
+type TokenTransfer struct {
+	Token    common.Address `json:"token"`
+	From     common.Address `json:"from"` // Empty for the minting
+	To       common.Address `json:"to"`   // Empty for the burning
+	Value    *big.Int       `json:"value"`
+	TxHash   common.Hash    `json:"tx_hash"`
+	Height   uint64         `json:"height"`
+	TxIndex  uint16         `json:"tx_index"`
+	LogIndex uint           `json:"log_index"`
+}
+
+// TokenBalance is the balance of a holder in a token contract, summed from the transfers indexed
+type TokenBalance struct {
+	Token   common.Address
+	Balance *big.Int
+}
+
+type tokenIndexTip struct {
+	Height uint64      `json:"height"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// tokenIndexBlock is stored for each block applied. The receipts of the removed blocks are deleted,
+// so the transfers are kept to revert the balances
+type tokenIndexBlock struct {
+	PreHeight uint64           `json:"pre_height"`
+	PreHash   common.Hash      `json:"pre_hash"`
+	Transfers []*TokenTransfer `json:"transfers"`
+}
+
+// tokenIndexer indexes the token balances and transfers from the Transfer events in the receipts.
+// It follows the canonical chain from its own tip: the blocks added and removed are notified asynchronously
+// and may arrive out of order, so the notifications only wake up the sync routine, which reverts the tip
+// until it's on the chain again and then applies the blocks after it
+type tokenIndexer struct {
+	chain *FullBlockChain
+	db    *tasdb.PrefixedDatabase
+	tip   tokenIndexTip
+
+	signal chan struct{}
+}
+
+func initTokenIndexer(chain *FullBlockChain, db *tasdb.PrefixedDatabase, genesis *types.BlockHeader) (*tokenIndexer, error) {
+	idx := &tokenIndexer{
+		chain:  chain,
+		db:     db,
+		signal: make(chan struct{}, 1),
+	}
+	bs, err := db.Get(tokenIndexTipKey)
+	if err != nil || bs == nil {
+		// The blocks existing are indexed by the sync routine from the genesis
+		idx.tip = tokenIndexTip{Height: genesis.Height, Hash: genesis.Hash}
+	} else if err = json.Unmarshal(bs, &idx.tip); err != nil {
+		return nil, err
+	}
+	notify.BUS.Subscribe(notify.BlockAddSucc, idx.onBlockChanged)
+	notify.BUS.Subscribe(notify.BlockRemoved, idx.onBlockChanged)
+	notify.BUS.Subscribe(notify.NewTopBlock, idx.onBlockChanged)
+	idx.notify()
+	go idx.loop()
+	return idx, nil
+}
+
+func (idx *tokenIndexer) onBlockChanged(message notify.Message) error {
+	idx.notify()
+	return nil
+}
+
+func (idx *tokenIndexer) notify() {
+	select {
+	case idx.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (idx *tokenIndexer) loop() {
+	for range idx.signal {
+		for {
+			done, err := idx.syncStep()
+			if err != nil {
+				Logger.Errorf("token index sync error:%v", err)
+				break
+			}
+			if done {
+				break
+			}
+		}
+	}
+}
+
+// syncStep reverts the tip if it's not on the chain, or applies the next block. done is true if the tip is the top
+func (idx *tokenIndexer) syncStep() (done bool, err error) {
+	chain := idx.chain
+	// Hold the read lock so that the chain won't be changed during the step
+	chain.rwLock.RLock()
+	defer chain.rwLock.RUnlock()
+	// Not indexed on the chain synced from the snapshot as the transfers before it are missing
+	if atomic.LoadInt32(&chain.shutdowning) == 1 || chain.SnapshotHeight() > 0 {
+		return true, nil
+	}
+
+	if hash := chain.queryBlockHash(idx.tip.Height); hash == nil || *hash != idx.tip.Hash {
+		return false, idx.revertTip()
+	}
+	next := chain.queryBlockHashCeil(idx.tip.Height + 1)
+	if next == nil {
+		return true, nil
+	}
+	bh := chain.queryBlockHeaderByHash(*next)
+	if bh == nil {
+		return true, nil
+	}
+	if bh.PreHash != idx.tip.Hash {
+		return false, idx.revertTip()
+	}
+	return false, idx.apply(bh, chain.blockReceipts(chain.queryBlockTransactionsAll(bh.Hash)))
+}
+
+// decodeTokenTransfer decodes the transfer from the log data, which is the json of the event args.
+// Both the positional args [from, to, value] and the named args {from, to, value} are accepted
+func decodeTokenTransfer(log *types.Log) *TokenTransfer {
+	if log.Topic != tokenTransferTopic {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(log.Data))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil
+	}
+	var args []interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		args = v
+	case map[string]interface{}:
+		args = make([]interface{}, 3)
+		for i, names := range [][]string{{"from", "_from", "0"}, {"to", "_to", "1"}, {"value", "_value", "2"}} {
+			for _, name := range names {
+				if arg, ok := v[name]; ok {
+					args[i] = arg
+					break
+				}
+			}
+		}
+	}
+	if len(args) != 3 {
+		return nil
+	}
+	from, ok1 := args[0].(string)
+	to, ok2 := args[1].(string)
+	value, ok3 := args[2].(json.Number)
+	if !ok1 || !ok2 || !ok3 {
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(value.String(), 10)
+	if !ok || amount.Sign() < 0 {
+		return nil
+	}
+	tt := &TokenTransfer{
+		Token:    log.Address,
+		Value:    amount,
+		TxHash:   log.TxHash,
+		LogIndex: log.Index,
+	}
+	if from = strings.TrimSpace(from); common.ValidateAddress(from) {
+		tt.From = common.StringToAddress(from)
+	}
+	if to = strings.TrimSpace(to); common.ValidateAddress(to) {
+		tt.To = common.StringToAddress(to)
+	}
+	if tt.From == (common.Address{}) && tt.To == (common.Address{}) {
+		return nil
+	}
+	return tt
+}
+
+func tokenBalanceKey(holder, token common.Address) []byte {
+	return common.BytesCombine(tokenBalancePrefix, holder.Bytes(), token.Bytes())
+}
+
+// tokenTransferKey sorts the transfers of the address from the newest
+func tokenTransferKey(addr common.Address, tt *TokenTransfer) []byte {
+	return common.BytesCombine(tokenTransferPrefix, addr.Bytes(), common.UInt64ToByte(^tt.Height),
+		common.UInt16ToByte(^tt.TxIndex), common.UInt32ToByte(^uint32(tt.LogIndex)))
+}
+
+func (idx *tokenIndexer) getBalance(holder, token common.Address) *big.Int {
+	bs, _ := idx.db.Get(tokenBalanceKey(holder, token))
+	b, ok := new(big.Int).SetString(string(bs), 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return b
+}
+
+// tokenIndexWriter updates the balances and transfers in a batch
+type tokenIndexWriter struct {
+	idx      *tokenIndexer
+	batch    tasdb.Batch
+	balances map[string]*big.Int
+}
+
+func (idx *tokenIndexer) newWriter() *tokenIndexWriter {
+	return &tokenIndexWriter{
+		idx:      idx,
+		batch:    idx.db.CreateLDBBatch(),
+		balances: make(map[string]*big.Int),
+	}
+}
+
+func (w *tokenIndexWriter) addBalance(holder, token common.Address, delta *big.Int) {
+	if holder == (common.Address{}) {
+		return
+	}
+	key := string(tokenBalanceKey(holder, token))
+	b, ok := w.balances[key]
+	if !ok {
+		b = w.idx.getBalance(holder, token)
+		w.balances[key] = b
+	}
+	b.Add(b, delta)
+}
+
+// update applies or reverts the transfer
+func (w *tokenIndexWriter) update(tt *TokenTransfer, apply bool) error {
+	delta := new(big.Int).Set(tt.Value)
+	if !apply {
+		delta.Neg(delta)
+	}
+	w.addBalance(tt.From, tt.Token, new(big.Int).Neg(delta))
+	w.addBalance(tt.To, tt.Token, delta)
+
+	var value []byte
+	if apply {
+		bs, err := json.Marshal(tt)
+		if err != nil {
+			return err
+		}
+		value = bs
+	}
+	for _, addr := range []common.Address{tt.From, tt.To} {
+		if addr == (common.Address{}) {
+			continue
+		}
+		if err := w.idx.db.AddKv(w.batch, tokenTransferKey(addr, tt), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *tokenIndexWriter) flush(tip tokenIndexTip) error {
+	for key, b := range w.balances {
+		var value []byte
+		if b.Sign() != 0 {
+			value = []byte(b.String())
+		}
+		if err := w.idx.db.AddKv(w.batch, []byte(key), value); err != nil {
+			return err
+		}
+	}
+	bs, err := json.Marshal(tip)
+	if err != nil {
+		return err
+	}
+	if err = w.idx.db.AddKv(w.batch, tokenIndexTipKey, bs); err != nil {
+		return err
+	}
+	if err = w.batch.Write(); err != nil {
+		return err
+	}
+	w.idx.tip = tip
+	return nil
+}
+
+// apply indexes the transfers of the block after the tip
+func (idx *tokenIndexer) apply(bh *types.BlockHeader, receipts types.Receipts) error {
+	w := idx.newWriter()
+	record := &tokenIndexBlock{PreHeight: idx.tip.Height, PreHash: idx.tip.Hash, Transfers: make([]*TokenTransfer, 0)}
+	for _, rc := range receipts {
+		if rc.Status != types.RSSuccess {
+			continue
+		}
+		for _, log := range rc.Logs {
+			tt := decodeTokenTransfer(log)
+			if tt == nil {
+				continue
+			}
+			tt.Height, tt.TxIndex = bh.Height, rc.TxIndex
+			if err := w.update(tt, true); err != nil {
+				return err
+			}
+			record.Transfers = append(record.Transfers, tt)
+		}
+	}
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = idx.db.AddKv(w.batch, common.BytesCombine(tokenBlockPrefix, bh.Hash.Bytes()), bs); err != nil {
+		return err
+	}
+	return w.flush(tokenIndexTip{Height: bh.Height, Hash: bh.Hash})
+}
+
+// revertTip reverts the transfers of the tip block and moves the tip to its parent
+func (idx *tokenIndexer) revertTip() error {
+	key := common.BytesCombine(tokenBlockPrefix, idx.tip.Hash.Bytes())
+	bs, err := idx.db.Get(key)
+	if err != nil || bs == nil {
+		return fmt.Errorf("token index record of %v not found", idx.tip.Hash.Hex())
+	}
+	record := &tokenIndexBlock{}
+	if err = json.Unmarshal(bs, record); err != nil {
+		return err
+	}
+	w := idx.newWriter()
+	for i := len(record.Transfers) - 1; i >= 0; i-- {
+		if err = w.update(record.Transfers[i], false); err != nil {
+			return err
+		}
+	}
+	if err = idx.db.AddKv(w.batch, key, nil); err != nil {
+		return err
+	}
+	Logger.Debugf("token index reverted block %v at %v", idx.tip.Hash.Hex(), idx.tip.Height)
+	return w.flush(tokenIndexTip{Height: record.PreHeight, Hash: record.PreHash})
+}
+
+func (idx *tokenIndexer) balances(holder common.Address) []*TokenBalance {
+	prefix := common.BytesCombine(tokenBalancePrefix, holder.Bytes())
+	iter := idx.db.NewIteratorWithPrefix(prefix)
+	defer iter.Release()
+	ret := make([]*TokenBalance, 0)
+	for iter.Next() {
+		b, ok := new(big.Int).SetString(string(iter.Value()), 10)
+		if !ok {
+			continue
+		}
+		ret = append(ret, &TokenBalance{Token: common.BytesToAddress(iter.Key()), Balance: b})
+	}
+	return ret
+}
+
+func (idx *tokenIndexer) transfers(addr common.Address, page int) []*TokenTransfer {
+	iter := idx.db.NewIteratorWithPrefix(common.BytesCombine(tokenTransferPrefix, addr.Bytes()))
+	defer iter.Release()
+	ret := make([]*TokenTransfer, 0)
+	for skip := page * TokenTransfersPageSize; iter.Next(); {
+		if skip > 0 {
+			skip--
+			continue
+		}
+		tt := &TokenTransfer{}
+		if err := json.Unmarshal(iter.Value(), tt); err != nil {
+			continue
+		}
+		ret = append(ret, tt)
+		if len(ret) >= TokenTransfersPageSize {
+			break
+		}
+	}
+	return ret
+}
+
+// TokenBalances returns the token balances of the holder, summed from the transfers indexed
+func (chain *FullBlockChain) TokenBalances(holder common.Address) ([]*TokenBalance, error) {
+	if chain.tokenIndexer == nil {
+		return nil, fmt.Errorf("token index not enabled")
+	}
+	// The balances are summed from the genesis
+	if err := chain.CheckHistoryAvailable(0); err != nil {
+		return nil, err
+	}
+	return chain.tokenIndexer.balances(holder), nil
+}
+
+// TokenTransfers returns the token transfers from or to the address from the newest, page starts from 0
+func (chain *FullBlockChain) TokenTransfers(addr common.Address, page int) ([]*TokenTransfer, error) {
+	if chain.tokenIndexer == nil {
+		return nil, fmt.Errorf("token index not enabled")
+	}
+	if page < 0 {
+		return nil, fmt.Errorf("invalid page %v", page)
+	}
+	if err := chain.CheckHistoryAvailable(0); err != nil {
+		return nil, err
+	}
+	return chain.tokenIndexer.transfers(addr, page), nil
+}
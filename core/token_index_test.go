@@ -0,0 +1,145 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+const tokenIndexTestDb = "test_token_index_db"
+
+var (
+	tokenTestContract = common.BytesToAddress(genHash("token"))
+	tokenTestAlice    = common.BytesToAddress(genHash("alice"))
+	tokenTestBob      = common.BytesToAddress(genHash("bob"))
+)
+
+func tokenTestLog(data string) *types.Log {
+	return &types.Log{Address: tokenTestContract, Topic: tokenTransferTopic, Data: []byte(data)}
+}
+
+func tokenTestReceipt(index uint16, datas ...string) *types.Receipt {
+	rc := &types.Receipt{TxIndex: index, Status: types.RSSuccess}
+	for i, data := range datas {
+		log := tokenTestLog(data)
+		log.Index = uint(i)
+		rc.Logs = append(rc.Logs, log)
+	}
+	return rc
+}
+
+func checkTokenBalance(t *testing.T, idx *tokenIndexer, holder common.Address, want int64) {
+	t.Helper()
+	if got := idx.getBalance(holder, tokenTestContract); got.Int64() != want {
+		t.Fatalf("unexpected balance of %v, want %v, got %v", holder.AddrPrefixString(), want, got)
+	}
+}
+
+func TestDecodeTokenTransfer(t *testing.T) {
+	alice, bob := tokenTestAlice.AddrPrefixString(), tokenTestBob.AddrPrefixString()
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{fmt.Sprintf(`["%v", "%v", 100]`, alice, bob), true},
+		{fmt.Sprintf(`{"from": "%v", "to": "%v", "value": 100}`, alice, bob), true},
+		{fmt.Sprintf(`{"0": "%v", "1": "%v", "2": 100}`, alice, bob), true},
+		{fmt.Sprintf(`["%v", "%v", "100"]`, alice, bob), false},
+		{fmt.Sprintf(`["%v", "%v", -1]`, alice, bob), false},
+		{fmt.Sprintf(`["%v", 100]`, alice), false},
+		{`["", "", 100]`, false},
+		{`not json`, false},
+	}
+	for _, test := range tests {
+		tt := decodeTokenTransfer(tokenTestLog(test.data))
+		if (tt != nil) != test.valid {
+			t.Fatalf("unexpected decode result of %v: %+v", test.data, tt)
+		}
+		if tt != nil && (tt.From != tokenTestAlice || tt.To != tokenTestBob || tt.Value.Int64() != 100 || tt.Token != tokenTestContract) {
+			t.Fatalf("unexpected transfer decoded from %v: %+v", test.data, tt)
+		}
+	}
+	log := tokenTestLog(fmt.Sprintf(`["%v", "%v", 100]`, alice, bob))
+	log.Topic = common.BytesToHash(common.Sha256([]byte("Approval")))
+	if decodeTokenTransfer(log) != nil {
+		t.Fatalf("other event decoded")
+	}
+}
+
+func TestTokenIndexer_ApplyAndRevert(t *testing.T) {
+	defer os.RemoveAll(tokenIndexTestDb)
+	ds, err := tasdb.NewDataSource(tokenIndexTestDb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := ds.NewPrefixDatabase("tk")
+	genesis := &types.BlockHeader{Height: 0, Hash: common.BytesToHash(genHash("genesis"))}
+	idx := &tokenIndexer{db: db, tip: tokenIndexTip{Height: genesis.Height, Hash: genesis.Hash}}
+	alice, bob := tokenTestAlice.AddrPrefixString(), tokenTestBob.AddrPrefixString()
+
+	b1 := &types.BlockHeader{Height: 1, Hash: common.BytesToHash(genHash("b1")), PreHash: genesis.Hash}
+	mint := fmt.Sprintf(`["", "%v", 1000]`, alice)
+	if err := idx.apply(b1, types.Receipts{tokenTestReceipt(0, mint)}); err != nil {
+		t.Fatal(err)
+	}
+	b3 := &types.BlockHeader{Height: 3, Hash: common.BytesToHash(genHash("b3")), PreHash: b1.Hash}
+	failed := tokenTestReceipt(0, fmt.Sprintf(`["%v", "%v", 500]`, alice, bob))
+	failed.Status = types.RSTvmError
+	transfer := fmt.Sprintf(`["%v", "%v", 100]`, alice, bob)
+	if err := idx.apply(b3, types.Receipts{failed, tokenTestReceipt(1, transfer, transfer)}); err != nil {
+		t.Fatal(err)
+	}
+	if idx.tip.Hash != b3.Hash {
+		t.Fatalf("tip not updated")
+	}
+	checkTokenBalance(t, idx, tokenTestAlice, 800)
+	checkTokenBalance(t, idx, tokenTestBob, 200)
+	if balances := idx.balances(tokenTestBob); len(balances) != 1 || balances[0].Token != tokenTestContract {
+		t.Fatalf("unexpected balances %+v", balances)
+	}
+	transfers := idx.transfers(tokenTestAlice, 0)
+	if len(transfers) != 3 || transfers[0].Height != 3 || transfers[0].LogIndex != 1 || transfers[2].Height != 1 {
+		t.Fatalf("unexpected transfers %+v", transfers)
+	}
+	if len(idx.transfers(tokenTestBob, 0)) != 2 || len(idx.transfers(tokenTestBob, 1)) != 0 {
+		t.Fatalf("unexpected transfers of bob")
+	}
+
+	// Revert the block 3 as it's removed by the chain reorganization
+	if err := idx.revertTip(); err != nil {
+		t.Fatal(err)
+	}
+	if idx.tip.Hash != b1.Hash || idx.tip.Height != 1 {
+		t.Fatalf("tip not reverted %+v", idx.tip)
+	}
+	checkTokenBalance(t, idx, tokenTestAlice, 1000)
+	checkTokenBalance(t, idx, tokenTestBob, 0)
+	if len(idx.balances(tokenTestBob)) != 0 || len(idx.transfers(tokenTestBob, 0)) != 0 || len(idx.transfers(tokenTestAlice, 0)) != 1 {
+		t.Fatalf("transfers not reverted")
+	}
+	if err := idx.revertTip(); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.revertTip(); err == nil {
+		t.Fatalf("genesis reverted")
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/zvchain/zvchain/common/secp256k1"
+	"github.com/zvchain/zvchain/middleware/notify"
 	"github.com/zvchain/zvchain/network"
 
 	"github.com/zvchain/zvchain/common"
@@ -37,7 +38,7 @@ const (
 	txAccumulateSizeMaxPerBlock = 1024 * 1024
 
 	txMaxSize              = 64000   // Maximum size per transaction
-	gasLimitPerTransaction = 500000  // the max gas limit for a transaction
+	GasLimitPerTransaction = 500000  // the max gas limit for a transaction
 	GasLimitPerBlock       = 2000000 // the max gas limit for a block
 )
 
@@ -83,12 +84,21 @@ func newTransactionPool(chain *FullBlockChain, receiptDb *tasdb.PrefixedDatabase
 		gasPriceLowerBound: types.NewBigInt(uint64(common.GlobalConf.GetInt("chain", "gasprice_lower_bound", 1))),
 	}
 	pool.received = newSimpleContainer(maxPendingSize, maxQueueSize, chain)
+	pool.received.onReplaced = pool.onTxReplaced
 	pool.bonPool = newRewardPool(chain.rewardManager, rewardTxMaxSize)
 	initTxSyncer(chain, pool, network.GetNetInstance())
 
 	return pool
 }
 
+// onTxReplaced is called when a transaction replaced by a higher gas price one with the same source and nonce
+func (pool *txPool) onTxReplaced(replaced, by *types.Transaction) {
+	Logger.Infof("tx replaced: old=%v, new=%v, source=%v, nonce=%v", replaced.Hash.Hex(), by.Hash.Hex(), by.Source.AddrPrefixString(), by.Nonce)
+	if TxSyncer != nil {
+		TxSyncer.removeReplaced(replaced.Hash)
+	}
+}
+
 func (pool *txPool) tryAddTransaction(tx *types.Transaction) (ok bool, err error) {
 	defer func() {
 		if ok {
@@ -96,6 +106,7 @@ func (pool *txPool) tryAddTransaction(tx *types.Transaction) (ok bool, err error
 				Logger.Debugf("transaction added to pool: hash=%v, block=%v", tx.Hash.Hex(), parseRewardBlockHash(tx).Hex())
 			} else {
 				Logger.Debugf("transaction added to pool: hash=%v", tx.Hash.Hex())
+				notify.BUS.Publish(notify.TransactionAdded, &notify.TransactionAddedMessage{Tx: tx})
 			}
 		}
 		if err != nil {
@@ -0,0 +1,211 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// SourcePoolContent is the transactions of a source in the transaction pool
+type SourcePoolContent struct {
+	Source       common.Address
+	StateNonce   uint64               // Nonce of the source on the latest state
+	PendingNonce uint64               // Next nonce to use taking the pool transactions into account
+	Pending      []*types.Transaction // Executable transactions ordered by nonce
+	Queued       []*types.Transaction // Transactions waiting for missing nonces, ordered by nonce
+	Rewards      []*types.Transaction // Reward transactions signed by the source
+	NonceGaps    []uint64             // Missing nonces between the state nonce and the highest nonce in the pool
+}
+
+// SourcePoolStatus is the transaction count of a source in the transaction pool
+type SourcePoolStatus struct {
+	Source       common.Address
+	StateNonce   uint64
+	PendingNonce uint64
+	Pending      int
+	Queued       int
+	Rewards      int
+	NonceGaps    []uint64
+}
+
+// PoolStatus is the transaction count of the transaction pool broken down by source
+type PoolStatus struct {
+	Pending int
+	Queued  int
+	Rewards int
+	Sources []*SourcePoolStatus // Ordered by source address
+}
+
+// sourceTxs returns the pending and queued transactions of the source, both ordered by nonce
+func (c *simpleContainer) sourceTxs(addr common.Address) (pending, queued []*types.Transaction) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if list := c.pending.waitingMap[addr]; list != nil {
+		for iter := list.IterAtPosition(0); iter.Next(); {
+			pending = append(pending, iter.Value().(*orderByNonceTx).item)
+		}
+	}
+	for _, tx := range c.queue {
+		if *tx.Source == addr {
+			queued = append(queued, tx)
+		}
+	}
+	sort.Sort(nonceTxSlice(queued))
+	return
+}
+
+// countBySource returns the pending and queued transaction count of each source
+func (c *simpleContainer) countBySource() (pending, queued map[common.Address]int) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	pending = make(map[common.Address]int, len(c.pending.waitingMap))
+	queued = make(map[common.Address]int)
+	for addr, list := range c.pending.waitingMap {
+		pending[addr] = int(list.Len())
+	}
+	for _, tx := range c.queue {
+		queued[*tx.Source]++
+	}
+	return
+}
+
+// rewardsBySource groups the reward transactions in the pool by source
+func (pool *txPool) rewardsBySource() map[common.Address][]*types.Transaction {
+	ret := make(map[common.Address][]*types.Transaction)
+	pool.bonPool.forEachByBlock(func(blockHash common.Hash, txs []*types.Transaction) bool {
+		for _, tx := range txs {
+			if tx.Source != nil {
+				ret[*tx.Source] = append(ret[*tx.Source], tx)
+			}
+		}
+		return true
+	})
+	return ret
+}
+
+// nonceInfo computes the next nonce to use and the missing nonces of a source with the given state nonce
+// and the transactions in the pool
+func nonceInfo(stateNonce uint64, txs ...[]*types.Transaction) (pendingNonce uint64, gaps []uint64) {
+	nonces := make(map[uint64]struct{})
+	highest := stateNonce
+	for _, list := range txs {
+		for _, tx := range list {
+			if tx.Nonce <= stateNonce {
+				continue
+			}
+			nonces[tx.Nonce] = struct{}{}
+			if tx.Nonce > highest {
+				highest = tx.Nonce
+			}
+		}
+	}
+	pendingNonce = stateNonce + 1
+	for {
+		if _, ok := nonces[pendingNonce]; !ok {
+			break
+		}
+		pendingNonce++
+	}
+	gaps = make([]uint64, 0)
+	for n := pendingNonce; n < highest; n++ {
+		if _, ok := nonces[n]; !ok {
+			gaps = append(gaps, n)
+		}
+	}
+	return
+}
+
+func (pool *txPool) content(addr common.Address, stateNonce uint64) *SourcePoolContent {
+	pending, queued := pool.received.sourceTxs(addr)
+	ret := &SourcePoolContent{
+		Source:     addr,
+		StateNonce: stateNonce,
+		Pending:    pending,
+		Queued:     queued,
+		Rewards:    pool.rewardsBySource()[addr],
+	}
+	ret.PendingNonce, ret.NonceGaps = nonceInfo(stateNonce, pending, queued)
+	return ret
+}
+
+func (pool *txPool) status(stateNonce func(addr common.Address) uint64) *PoolStatus {
+	pending, queued := pool.received.countBySource()
+	rewards := pool.rewardsBySource()
+
+	sources := make(map[common.Address]*SourcePoolStatus)
+	get := func(addr common.Address) *SourcePoolStatus {
+		if s, ok := sources[addr]; ok {
+			return s
+		}
+		s := &SourcePoolStatus{Source: addr}
+		sources[addr] = s
+		return s
+	}
+	ret := &PoolStatus{}
+	for addr, n := range pending {
+		get(addr).Pending = n
+		ret.Pending += n
+	}
+	for addr, n := range queued {
+		get(addr).Queued = n
+		ret.Queued += n
+	}
+	for addr, txs := range rewards {
+		get(addr).Rewards = len(txs)
+		ret.Rewards += len(txs)
+	}
+
+	ret.Sources = make([]*SourcePoolStatus, 0, len(sources))
+	for addr, s := range sources {
+		s.StateNonce = stateNonce(addr)
+		if s.Pending > 0 || s.Queued > 0 {
+			p, q := pool.received.sourceTxs(addr)
+			s.PendingNonce, s.NonceGaps = nonceInfo(s.StateNonce, p, q)
+		} else {
+			s.PendingNonce, s.NonceGaps = nonceInfo(s.StateNonce)
+		}
+		ret.Sources = append(ret.Sources, s)
+	}
+	sort.Slice(ret.Sources, func(i, j int) bool {
+		return bytes.Compare(ret.Sources[i].Source.Bytes(), ret.Sources[j].Source.Bytes()) < 0
+	})
+	return ret
+}
+
+// PendingNonce returns the next nonce the address should use, which is the state nonce plus one
+// skipping the consecutive nonces already taken by the transactions in the pool
+func (chain *FullBlockChain) PendingNonce(addr common.Address) uint64 {
+	pool := chain.transactionPool.(*txPool)
+	pending, queued := pool.received.sourceTxs(addr)
+	nonce, _ := nonceInfo(chain.GetNonce(addr), pending, queued)
+	return nonce
+}
+
+// TxPoolContent returns the transactions of the address in the transaction pool
+func (chain *FullBlockChain) TxPoolContent(addr common.Address) *SourcePoolContent {
+	return chain.transactionPool.(*txPool).content(addr, chain.GetNonce(addr))
+}
+
+// TxPoolStatus returns the transaction count of the transaction pool broken down by source
+func (chain *FullBlockChain) TxPoolStatus() *PoolStatus {
+	return chain.transactionPool.(*txPool).status(chain.GetNonce)
+}
@@ -0,0 +1,74 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestTxPoolContent(t *testing.T) {
+	err := initContext4Test(t)
+	defer clearSelf(t)
+	if err != nil {
+		t.Fatalf("failed to initContext4Test")
+	}
+	chain := BlockChainImpl
+	pool := chain.transactionPool.(*txPool)
+	stateNonce := chain.GetNonce(addr1)
+
+	if n := chain.PendingNonce(addr1); n != stateNonce+1 {
+		t.Fatalf("expect pending nonce %v of empty pool, got %v", stateNonce+1, n)
+	}
+	hashes := []string{
+		"0c454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f01",
+		"0c454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f02",
+		"0c454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f04",
+		"0c454fdea57373b25b150497e016fcfdc06b55a66518e3756305e46f3dda7f06",
+	}
+	for i, nonce := range []uint64{1, 2, 4, 6} {
+		tx := genTx4Test(hashes[i], stateNonce+nonce, types.NewBigInt(10000), gasLimit, &addr1)
+		if err = pool.received.push(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := chain.PendingNonce(addr1); n != stateNonce+3 {
+		t.Fatalf("expect pending nonce %v, got %v", stateNonce+3, n)
+	}
+	c := chain.TxPoolContent(addr1)
+	if len(c.Pending) != 2 || len(c.Queued) != 2 {
+		t.Fatalf("unexpected pending %v and queued %v", len(c.Pending), len(c.Queued))
+	}
+	if c.Queued[0].Nonce != stateNonce+4 || c.Queued[1].Nonce != stateNonce+6 {
+		t.Fatal("queued transactions should be ordered by nonce")
+	}
+	if gaps := []uint64{stateNonce + 3, stateNonce + 5}; !reflect.DeepEqual(c.NonceGaps, gaps) {
+		t.Fatalf("expect nonce gaps %v, got %v", gaps, c.NonceGaps)
+	}
+
+	s := chain.TxPoolStatus()
+	if s.Pending != 2 || s.Queued != 2 {
+		t.Fatalf("unexpected status %+v", s)
+	}
+	for _, src := range s.Sources {
+		if src.Source == addr1 && (src.Pending != 2 || src.Queued != 2 || src.PendingNonce != stateNonce+3) {
+			t.Fatalf("unexpected source status %+v", src)
+		}
+	}
+}
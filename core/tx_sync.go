@@ -52,6 +52,7 @@ type txSyncer struct {
 	chain         *FullBlockChain
 	rctNotifiy    *lru.Cache
 	nonceErrTxs   *lru.Cache
+	replacedTxs   *lru.Cache
 	ticker        *ticker.GlobalTicker
 	candidateKeys *lru.Cache
 	networkImpl   network.Network
@@ -136,6 +137,7 @@ func initTxSyncer(chain *FullBlockChain, pool *txPool, networkImpl network.Netwo
 	s := &txSyncer{
 		rctNotifiy:    common.MustNewLRUCache(txPeerMaxLimit),
 		nonceErrTxs:   common.MustNewLRUCache(3000),
+		replacedTxs:   common.MustNewLRUCache(3000),
 		pool:          pool,
 		ticker:        ticker.NewGlobalTicker("tx_syncer"),
 		candidateKeys: common.MustNewLRUCache(3000),
@@ -174,6 +176,18 @@ func (ts *txSyncer) clearJob() {
 	ts.pool.ClearRewardTxs()
 }
 
+// removeReplaced clears the broadcast state of the transaction replaced in the pool,
+// so that it won't be requested from the neighbors again
+func (ts *txSyncer) removeReplaced(txHash common.Hash) {
+	ts.rctNotifiy.Remove(txHash)
+	ts.replacedTxs.Add(txHash, 1)
+	for _, k := range ts.candidateKeys.Keys() {
+		if v, ok := ts.candidateKeys.Peek(k); ok {
+			v.(*peerTxsHashes).removeHashes([]common.Hash{txHash})
+		}
+	}
+}
+
 func (ts *txSyncer) checkTxCanBroadcast(txHash common.Hash) bool {
 	if t, ok := ts.rctNotifiy.Get(txHash); !ok || time.Since(t.(time.Time)).Seconds() > float64(txNotifyGap) {
 		return true
@@ -312,8 +326,7 @@ func (ts *txSyncer) reqTxsRoutine() bool {
 		rqs := make([]common.Hash, 0)
 		ptk.forEach(func(k common.Hash) bool {
 			if exist, _ := BlockChainImpl.GetTransactionPool().IsTransactionExisted(k); !exist {
-				_, ok := ts.nonceErrTxs.Peek(k)
-				if !ok {
+				if !ts.nonceErrTxs.Contains(k) && !ts.replacedTxs.Contains(k) {
 					rqs = append(rqs, k)
 					ptk.addSendHash(k)
 				}
@@ -428,10 +441,18 @@ func (ts *txSyncer) onTxResponse(msg notify.Message) error {
 	}
 	ts.logger.Debugf("Rcv rawTxs from %v, size %v", nm.Source(), len(rawTxs))
 
-	evilCount := 0
+	txs := make([]*types.Transaction, 0, len(rawTxs))
 	for _, tx := range rawTxs {
+		txs = append(txs, types.NewTransaction(tx, tx.GenHash()))
+	}
+	if !ts.getOrAddCandidateKeys(nm.Source()).checkReceivedHashesInHitRate(txs) {
+		ts.logger.Debugf("rec txs from %v miss the hashes requested", nm.Source())
+		network.ReportPeer(nm.Source(), network.PeerEventLowHitRate)
+	}
+
+	evilCount := 0
+	for _, txx := range txs {
 		// this error can be ignored
-		txx := types.NewTransaction(tx, tx.GenHash())
 		_, err := ts.pool.AddTransaction(txx)
 		if err != nil {
 			if err == ErrNonce {
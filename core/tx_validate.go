@@ -68,7 +68,7 @@ func gasValidate(tx *types.Transaction) error {
 	if !tx.GasPrice.IsUint64() {
 		return fmt.Errorf("gas price is not uint64")
 	}
-	if tx.GasLimit.Uint64() > gasLimitPerTransaction {
+	if tx.GasLimit.Uint64() > GasLimitPerTransaction {
 		return fmt.Errorf("gas limit too high")
 	}
 	// Check if the gasLimit less than the intrinsic gas
@@ -124,7 +124,7 @@ func stateValidate(accountDB types.AccountDB, tx *types.Transaction, height uint
 	if gasLimitFee.Cmp(balance) > 0 {
 		return nil, fmt.Errorf("balance not enough for paying gas, %v", src)
 	}
-	if tx.Type == types.TransactionTypeTransfer || tx.Type == types.TransactionTypeContractCreate || tx.Type == types.TransactionTypeContractCall || tx.Type == types.TransactionTypeStakeAdd {
+	if tx.Type == types.TransactionTypeTransfer || tx.Type == types.TransactionTypeContractCreate || tx.Type == types.TransactionTypeContractCall || tx.Type == types.TransactionTypeStakeAdd || isMultiSigType(tx.Type) {
 		totalCost := new(types.BigInt).Add(gasLimitFee, tx.Value.Value())
 		if totalCost.Cmp(balance) > 0 {
 			return nil, fmt.Errorf("balance not enough for paying gas and value, %v", src)
@@ -386,6 +386,11 @@ func getValidator(tx *types.Transaction, validateState bool) validator {
 				err = groupValidator(tx)
 			case types.TransactionTypeBlacklistUpdate:
 				err = blackUpdateValidate(tx, validateState)
+			case types.TransactionTypeMultiSigCreate:
+				err = multiSigCreateValidator(tx)
+			case types.TransactionTypeMultiSigExecute:
+				// The member signs are verified in the validator instead of recovering the source
+				return multiSigExecuteValidator(tx, validateState)
 			default:
 				err = fmt.Errorf("no such kind of tx")
 			}
@@ -3,6 +3,8 @@ package log
 import (
 	"os"
 	"sync"
+
+	"github.com/sirupsen/logrus"
 )
 
 func Init() {
@@ -35,6 +37,7 @@ func Init() {
 	TVMLogger = RusPlus.Logger(logsDir+"tvm", MaxFileSize, DefaultMaxFiles, Level)
 	PerformLogger = RusPlus.Logger(logsDir+"perform", MaxFileSize, DefaultMaxFiles, Level)
 	MeterLogger = RusPlus.Logger(logsDir+"meter", MaxFileSize, DefaultMaxFiles, Level)
+	RPCAuditLogger = RusPlus.Logger(logsDir+"rpc_audit", MaxFileSize, DefaultMaxFiles, logrus.InfoLevel)
 	Recorder = TimeRecorder{m: sync.Map{}}
 	InitElk(logsDir)
 }
@@ -0,0 +1,415 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package metrics implements a light registry of counters, gauges and histograms
+// which the subsystems publish into, exported in the prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Namespace is the prefix of the metric names
+const Namespace = "zvchain"
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefBuckets are the default buckets of the latency histograms in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// sample is a value of the metric with the label values
+type sample struct {
+	suffix      string
+	labelValues []string
+	extraLabel  string // Label appended to the labels, used for the le of the histogram bucket
+	extraValue  string
+	value       float64
+}
+
+type metric interface {
+	name() string
+	help() string
+	typ() string
+	labelNames() []string
+	samples() []sample
+}
+
+type desc struct {
+	fullName string
+	helpText string
+	labels   []string
+}
+
+func newDesc(name, help string, labels []string) desc {
+	return desc{fullName: Namespace + "_" + name, helpText: help, labels: labels}
+}
+
+func (d *desc) name() string {
+	return d.fullName
+}
+
+func (d *desc) help() string {
+	return d.helpText
+}
+
+func (d *desc) labelNames() []string {
+	return d.labels
+}
+
+// atomicFloat is a float64 can be updated concurrently
+type atomicFloat struct {
+	bits uint64
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+func (f *atomicFloat) store(v float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(v))
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := atomic.LoadUint64(&f.bits)
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&f.bits, old, n) {
+			return
+		}
+	}
+}
+
+// Counter is a value only goes up
+type Counter struct {
+	desc
+	v atomicFloat
+}
+
+func newCounter(name, help string) *Counter {
+	return &Counter{desc: newDesc(name, help, nil)}
+}
+
+// Inc increases the counter by 1
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add increases the counter by the given value, negative value is ignored
+func (c *Counter) Add(v float64) {
+	if v > 0 {
+		c.v.add(v)
+	}
+}
+
+// Value returns the current value
+func (c *Counter) Value() float64 {
+	return c.v.load()
+}
+
+func (c *Counter) typ() string {
+	return typeCounter
+}
+
+func (c *Counter) samples() []sample {
+	return []sample{{value: c.Value()}}
+}
+
+// Gauge is a value can go up and down
+type Gauge struct {
+	desc
+	v atomicFloat
+}
+
+func newGauge(name, help string) *Gauge {
+	return &Gauge{desc: newDesc(name, help, nil)}
+}
+
+// Set sets the gauge to the given value
+func (g *Gauge) Set(v float64) {
+	g.v.store(v)
+}
+
+// Add adds the given value to the gauge, which may be negative
+func (g *Gauge) Add(v float64) {
+	g.v.add(v)
+}
+
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return g.v.load()
+}
+
+func (g *Gauge) typ() string {
+	return typeGauge
+}
+
+func (g *Gauge) samples() []sample {
+	return []sample{{value: g.Value()}}
+}
+
+// GaugeFunc is a gauge which value is read by the function when collected
+type GaugeFunc struct {
+	desc
+	f func() float64
+}
+
+func newGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	return &GaugeFunc{desc: newDesc(name, help, nil), f: f}
+}
+
+func (g *GaugeFunc) typ() string {
+	return typeGauge
+}
+
+func (g *GaugeFunc) samples() []sample {
+	return []sample{{value: g.f()}}
+}
+
+// Histogram counts the observed values in the configured buckets
+type Histogram struct {
+	desc
+	buckets []float64 // Upper bounds of the buckets in increasing order
+	counts  []uint64  // Count of the values in each bucket, not cumulative
+	count   uint64
+	sum     atomicFloat
+}
+
+func newHistogram(name, help string, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	bs := append([]float64{}, buckets...)
+	sort.Float64s(bs)
+	return &Histogram{desc: newDesc(name, help, nil), buckets: bs, counts: make([]uint64, len(bs))}
+}
+
+// Observe adds a value to the histogram
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.add(v)
+}
+
+// ObserveDuration adds the duration in seconds to the histogram
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Count returns the number of the observed values
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+func (h *Histogram) typ() string {
+	return typeHistogram
+}
+
+func (h *Histogram) samples() []sample {
+	ret := make([]sample, 0, len(h.buckets)+3)
+	var cumulative uint64
+	for i, b := range h.buckets {
+		cumulative += atomic.LoadUint64(&h.counts[i])
+		ret = append(ret, sample{suffix: "_bucket", extraLabel: "le", extraValue: formatFloat(b), value: float64(cumulative)})
+	}
+	count := h.Count()
+	ret = append(ret,
+		sample{suffix: "_bucket", extraLabel: "le", extraValue: "+Inf", value: float64(count)},
+		sample{suffix: "_sum", value: h.sum.load()},
+		sample{suffix: "_count", value: float64(count)},
+	)
+	return ret
+}
+
+// vec holds the children of a metric partitioned by the label values
+type vec struct {
+	desc
+	lock     sync.RWMutex
+	children map[string]metric
+	values   map[string][]string
+	newChild func() metric
+}
+
+func newVec(name, help string, labels []string, newChild func() metric) *vec {
+	return &vec{
+		desc:     newDesc(name, help, labels),
+		children: make(map[string]metric),
+		values:   make(map[string][]string),
+		newChild: newChild,
+	}
+}
+
+func (v *vec) child(values []string) metric {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %v expects %v label values, got %v", v.fullName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.lock.RLock()
+	c, ok := v.children[key]
+	v.lock.RUnlock()
+	if ok {
+		return c
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if c, ok = v.children[key]; !ok {
+		c = v.newChild()
+		v.children[key] = c
+		v.values[key] = append([]string{}, values...)
+	}
+	return c
+}
+
+func (v *vec) samples() []sample {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]sample, 0)
+	for _, k := range keys {
+		for _, s := range v.children[k].samples() {
+			s.labelValues = v.values[k]
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// CounterVec is a set of counters partitioned by the label values
+type CounterVec struct {
+	*vec
+}
+
+func newCounterVec(name, help string, labels []string) *CounterVec {
+	return &CounterVec{newVec(name, help, labels, func() metric { return newCounter("", "") })}
+}
+
+// WithLabelValues returns the counter of the label values, which is created if not exists
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.child(values).(*Counter)
+}
+
+func (v *CounterVec) typ() string {
+	return typeCounter
+}
+
+// GaugeVec is a set of gauges partitioned by the label values
+type GaugeVec struct {
+	*vec
+}
+
+func newGaugeVec(name, help string, labels []string) *GaugeVec {
+	return &GaugeVec{newVec(name, help, labels, func() metric { return newGauge("", "") })}
+}
+
+// WithLabelValues returns the gauge of the label values, which is created if not exists
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return v.child(values).(*Gauge)
+}
+
+func (v *GaugeVec) typ() string {
+	return typeGauge
+}
+
+// HistogramVec is a set of histograms partitioned by the label values
+type HistogramVec struct {
+	*vec
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels []string) *HistogramVec {
+	return &HistogramVec{newVec(name, help, labels, func() metric { return newHistogram("", "", buckets) })}
+}
+
+// WithLabelValues returns the histogram of the label values, which is created if not exists
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.child(values).(*Histogram)
+}
+
+func (v *HistogramVec) typ() string {
+	return typeHistogram
+}
+
+// NewCounter creates a counter registered to the DefaultRegistry
+func NewCounter(name, help string) *Counter {
+	c := newCounter(name, help)
+	DefaultRegistry.Register(c)
+	return c
+}
+
+// NewGauge creates a gauge registered to the DefaultRegistry
+func NewGauge(name, help string) *Gauge {
+	g := newGauge(name, help)
+	DefaultRegistry.Register(g)
+	return g
+}
+
+// NewGaugeFunc creates a gauge reading the value from the function, registered to the DefaultRegistry.
+// It replaces the one registered with the same name
+func NewGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	g := newGaugeFunc(name, help, f)
+	DefaultRegistry.Register(g)
+	return g
+}
+
+// NewHistogram creates a histogram registered to the DefaultRegistry, DefBuckets is used if buckets not given
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(name, help, buckets)
+	DefaultRegistry.Register(h)
+	return h
+}
+
+// NewCounterVec creates a counter vector registered to the DefaultRegistry
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := newCounterVec(name, help, labels)
+	DefaultRegistry.Register(v)
+	return v
+}
+
+// NewGaugeVec creates a gauge vector registered to the DefaultRegistry
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := newGaugeVec(name, help, labels)
+	DefaultRegistry.Register(v)
+	return v
+}
+
+// NewHistogramVec creates a histogram vector registered to the DefaultRegistry
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := newHistogramVec(name, help, buckets, labels)
+	DefaultRegistry.Register(v)
+	return v
+}
@@ -0,0 +1,120 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	c := newCounter("test_total", "A counter")
+	g := newGauge("test_gauge", "A gauge\nwith new line")
+	h := newHistogram("test_seconds", "A histogram", []float64{1, 0.1})
+	cv := newCounterVec("test_bytes_total", "A counter vector", []string{"direction", "code"})
+	height := 0.0
+	gf := newGaugeFunc("test_height", "A gauge func", func() float64 { return height })
+	for _, m := range []metric{c, g, h, cv, gf} {
+		r.Register(m)
+	}
+	r.OnCollect("height", func() { height = 10 })
+
+	c.Inc()
+	c.Add(2)
+	c.Add(-1)
+	g.Set(5)
+	g.Dec()
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+	cv.WithLabelValues("send", "10001").Add(100)
+	cv.WithLabelValues("recv", `a"b`).Inc()
+
+	buf := new(bytes.Buffer)
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := `# HELP zvchain_test_bytes_total A counter vector
+# TYPE zvchain_test_bytes_total counter
+zvchain_test_bytes_total{direction="recv",code="a\"b"} 1
+zvchain_test_bytes_total{direction="send",code="10001"} 100
+# HELP zvchain_test_gauge A gauge\nwith new line
+# TYPE zvchain_test_gauge gauge
+zvchain_test_gauge 4
+# HELP zvchain_test_height A gauge func
+# TYPE zvchain_test_height gauge
+zvchain_test_height 10
+# HELP zvchain_test_seconds A histogram
+# TYPE zvchain_test_seconds histogram
+zvchain_test_seconds_bucket{le="0.1"} 1
+zvchain_test_seconds_bucket{le="1"} 2
+zvchain_test_seconds_bucket{le="+Inf"} 3
+zvchain_test_seconds_sum 3.55
+zvchain_test_seconds_count 3
+# HELP zvchain_test_total A counter
+# TYPE zvchain_test_total counter
+zvchain_test_total 3
+`
+	if buf.String() != expect {
+		t.Fatalf("unexpected output:\n%v", buf.String())
+	}
+
+	r.Unregister("test_gauge")
+	buf.Reset()
+	r.WriteTo(buf)
+	if strings.Contains(buf.String(), "zvchain_test_gauge") {
+		t.Fatal("gauge should be unregistered")
+	}
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	c := newCounter("concurrent_total", "")
+	hv := newHistogramVec("concurrent_seconds", "", nil, []string{"op"})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc()
+				hv.WithLabelValues("verify").Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Value() != 10000 || hv.WithLabelValues("verify").Count() != 10000 {
+		t.Fatalf("unexpected value %v %v", c.Value(), hv.WithLabelValues("verify").Count())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	g := NewGauge("handler_test_gauge", "gauge for the handler test")
+	defer DefaultRegistry.Unregister("handler_test_gauge")
+	g.Set(1.5)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected content type %v", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "zvchain_handler_test_gauge 1.5\n") {
+		t.Fatalf("gauge not found in:\n%v", rec.Body.String())
+	}
+}
@@ -0,0 +1,187 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultRegistry is the registry the subsystems publish into, served at /metrics
+var DefaultRegistry = NewRegistry()
+
+// Registry holds the metrics and writes them in the prometheus text format
+type Registry struct {
+	lock    sync.RWMutex
+	metrics map[string]metric
+	hooks   map[string]func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+		hooks:   make(map[string]func()),
+	}
+}
+
+// Register adds the metric to the registry, the one with the same name is replaced
+func (r *Registry) Register(m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics[m.name()] = m
+}
+
+// Unregister removes the metric of the name, which is the name without the namespace
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.metrics, Namespace+"_"+name)
+}
+
+// OnCollect adds a hook called before the metrics written, which is used to refresh the metrics
+// sampled from other components. The one with the same id is replaced
+func (r *Registry) OnCollect(id string, f func()) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.hooks[id] = f
+}
+
+// WriteTo writes all metrics to w in the prometheus text format ordered by the names
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	hooks := make([]func(), 0, len(r.hooks))
+	for _, f := range r.hooks {
+		hooks = append(hooks, f)
+	}
+	r.lock.RUnlock()
+	for _, f := range hooks {
+		f()
+	}
+
+	r.lock.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ms := make([]metric, 0, len(names))
+	for _, name := range names {
+		ms = append(ms, r.metrics[name])
+	}
+	r.lock.RUnlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, m := range ms {
+		writeMetric(cw, m)
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+// Handler returns the http handler serving the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteTo(w)
+	})
+}
+
+// Handler returns the http handler serving the metrics of the DefaultRegistry
+func Handler() http.Handler {
+	return DefaultRegistry.Handler()
+}
+
+// OnCollect adds a hook to the DefaultRegistry called before the metrics written
+func OnCollect(id string, f func()) {
+	DefaultRegistry.OnCollect(id, f)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) writeString(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func writeMetric(w *countWriter, m metric) {
+	samples := m.samples()
+	if len(samples) == 0 {
+		return
+	}
+	w.writeString("# HELP " + m.name() + " " + escapeHelp(m.help()) + "\n")
+	w.writeString("# TYPE " + m.name() + " " + m.typ() + "\n")
+	labels := m.labelNames()
+	for _, s := range samples {
+		w.writeString(m.name() + s.suffix)
+		pairs := make([]string, 0, len(labels)+1)
+		for i, l := range labels {
+			if i < len(s.labelValues) {
+				pairs = append(pairs, l+`="`+escapeLabel(s.labelValues[i])+`"`)
+			}
+		}
+		if s.extraLabel != "" {
+			pairs = append(pairs, s.extraLabel+`="`+s.extraValue+`"`)
+		}
+		if len(pairs) > 0 {
+			w.writeString("{" + strings.Join(pairs, ",") + "}")
+		}
+		w.writeString(" " + formatFloat(s.value) + "\n")
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -21,6 +21,8 @@ const (
 	NewTopBlock      = "new_top_block"
 	BlockSync        = "block_sync"
 	MessageToConsole = "message_to_console"
+	BlockRemoved     = "block_removed"
+	TransactionAdded = "transaction_added"
 
 	BlockInfoNotify = "block_info_notify"
 	BlockReq        = "block_req"
@@ -35,4 +37,9 @@ const (
 	TxSyncNotify   = "tx_sync_notify"
 	TxSyncReq      = "tx_sync_req"
 	TxSyncResponse = "tx_sync_response"
+
+	SnapshotCheckpointReq      = "snapshot_checkpoint_req"
+	SnapshotCheckpointResponse = "snapshot_checkpoint_response"
+	StateNodesReq              = "state_nodes_req"
+	StateNodesResponse         = "state_nodes_response"
 )
@@ -30,6 +30,32 @@ func (m *BlockOnChainSuccMessage) GetData() interface{} {
 	return m.Block
 }
 
+// BlockRemovedMessage is published when a block is reverted from the chain, which
+// happens in the chain reorganization or orphan block removing. The receipts of the block
+// are deleted, subscribers need the logs should keep them when the block added
+type BlockRemovedMessage struct {
+	Block *types.Block
+}
+
+func (m *BlockRemovedMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *BlockRemovedMessage) GetData() interface{} {
+	return m.Block
+}
+
+// TransactionAddedMessage is published when a transaction is admitted by the transaction pool
+type TransactionAddedMessage struct {
+	Tx *types.Transaction
+}
+
+func (m *TransactionAddedMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *TransactionAddedMessage) GetData() interface{} {
+	return m.Tx
+}
+
 type GroupOnChainSuccMessage struct {
 	Group types.GroupI
 }
@@ -83,6 +83,7 @@ func printAndRefresh() {
 }
 
 func AddCount(name string, code uint32, size uint64) {
+	publishCount(name, code, size)
 	if item, ok := countMap.Load(name); ok {
 		citem := item.(*countItem)
 		if item2, ok := countMap.Load(code); ok {
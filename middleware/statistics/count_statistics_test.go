@@ -15,7 +15,13 @@
 
 package statistics
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zvchain/zvchain/middleware/metrics"
+)
 
 func TestCount(t *testing.T) {
 	AddCount("a", 1, 1)
@@ -24,3 +30,27 @@ func TestCount(t *testing.T) {
 	AddCount("a", 2, 1)
 	printAndRefresh()
 }
+
+func TestCountPublished(t *testing.T) {
+	count := countCounter.WithLabelValues("b", "3")
+	size := countSizeCounter.WithLabelValues("b", "3")
+	AddCount("b", 3, 10)
+	AddCount("b", 3, 20)
+	if count.Value() != 2 || size.Value() != 30 {
+		t.Fatalf("unexpected count %v size %v", count.Value(), size.Value())
+	}
+	AddBlockLog(0, RcvNewBlock, 1, 0, 0, 0, 0, "", "", 0, 0)
+
+	var buf bytes.Buffer
+	if _, err := metrics.DefaultRegistry.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`statistics_count_total{name="b",code="3"} 2`,
+		`statistics_block_logs_total{code="RcvNewBlock"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("expect %v published, got\n%v", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package statistics
+
+import (
+	"strconv"
+
+	"github.com/zvchain/zvchain/middleware/metrics"
+)
+
+// The counts and the logs are published to the metrics registry as well, whether the reporting
+// to the statistics server is enabled or not
+var (
+	countCounter     = metrics.NewCounterVec("statistics_count_total", "Number of the items counted by the name and the code", "name", "code")
+	countSizeCounter = metrics.NewCounterVec("statistics_count_bytes_total", "Size of the items counted by the name and the code", "name", "code")
+	logCounter       = metrics.NewCounterVec("statistics_logs_total", "Number of the consensus logs by the status", "status")
+	blockLogCounter  = metrics.NewCounterVec("statistics_block_logs_total", "Number of the block logs by the code", "code")
+)
+
+func publishCount(name string, code uint32, size uint64) {
+	c := strconv.FormatUint(uint64(code), 10)
+	countCounter.WithLabelValues(name, c).Inc()
+	countSizeCounter.WithLabelValues(name, c).Add(float64(size))
+}
@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/zvchain/zvchain/common"
 )
@@ -91,6 +92,7 @@ func NewLogObj(id string) *LogObj {
 }
 
 func AddLog(Hash string, Status int, Time int64, Castor string, Node string) {
+	logCounter.WithLabelValues(strconv.Itoa(Status)).Inc()
 	if enable {
 		log := &LogObj{Hash: Hash, Status: Status, Time: Time, Batch: batch, Castor: Castor, Node: Node}
 		PutLog(log)
@@ -98,6 +100,7 @@ func AddLog(Hash string, Status int, Time int64, Castor string, Node string) {
 }
 
 func AddBlockLog(bootID int, code string, blockHeight uint64, qn uint64, txCount int, size int, timeStamp int64, castor string, groupID string, instanceIndex int, castTime int64) {
+	blockLogCounter.WithLabelValues(code).Inc()
 	if enable {
 		var cn uint8
 		switch code {
@@ -25,6 +25,9 @@ type GenesisInfo struct {
 	Group  GroupI
 	VrfPKs [][]byte
 	Pks    [][]byte
+
+	// Alloc is the extra balance of the accounts funded in the genesis block, only used by the development chain
+	Alloc map[common.Address]*big.Int
 }
 
 // ConsensusHelper are consensus interface collection
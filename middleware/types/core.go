@@ -82,6 +82,10 @@ const (
 
 	TransactionTypeBlacklistUpdate = 10
 
+	// Multisig account related type
+	TransactionTypeMultiSigCreate  = 11 // create a multisig account with the threshold and members
+	TransactionTypeMultiSigExecute = 12 // transfer from the multisig account with the member signs
+
 	// Group operation related type
 	TransactionTypeGroupPiece       = SystemTransactionOffset + 1 //group member upload his encrypted share piece
 	TransactionTypeGroupMpk         = SystemTransactionOffset + 2 //group member upload his mpk
@@ -0,0 +1,136 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/zvchain/zvchain/common"
+)
+
+const (
+	// MinMultiSigMembers and MaxMultiSigMembers limit the member count of a multisig account
+	MinMultiSigMembers = 2
+	MaxMultiSigMembers = 16
+)
+
+var multiSigAddrSalt = []byte("multisig")
+
+// MultiSigConfig is the M-of-N control of a multisig account, which is the payload of the multisig create
+// transaction and also stored in the state of the multisig account
+type MultiSigConfig struct {
+	Threshold uint8
+	Members   []common.Address
+}
+
+// Validate checks the threshold and the members
+func (c *MultiSigConfig) Validate() error {
+	if len(c.Members) < MinMultiSigMembers || len(c.Members) > MaxMultiSigMembers {
+		return fmt.Errorf("member count should be in [%v, %v]", MinMultiSigMembers, MaxMultiSigMembers)
+	}
+	if c.Threshold == 0 || int(c.Threshold) > len(c.Members) {
+		return fmt.Errorf("threshold should be in [1, %v]", len(c.Members))
+	}
+	exists := make(map[common.Address]struct{}, len(c.Members))
+	for _, m := range c.Members {
+		if _, ok := exists[m]; ok {
+			return fmt.Errorf("duplicate member %v", m.AddrPrefixString())
+		}
+		exists[m] = struct{}{}
+	}
+	return nil
+}
+
+// IsMember returns whether the address is one of the members
+func (c *MultiSigConfig) IsMember(addr common.Address) bool {
+	for _, m := range c.Members {
+		if m == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func EncodeMultiSigConfig(c *MultiSigConfig) ([]byte, error) {
+	return msgpack.Marshal(c)
+}
+
+func DecodeMultiSigConfig(bs []byte) (*MultiSigConfig, error) {
+	var c MultiSigConfig
+	if err := msgpack.Unmarshal(bs, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// MultiSigAddress generates the address of the multisig account created by the creator with the nonce
+func MultiSigAddress(creator common.Address, nonce uint64) common.Address {
+	buf := new(bytes.Buffer)
+	buf.Write(multiSigAddrSalt)
+	buf.Write(creator.Bytes())
+	buf.Write(common.Uint64ToByte(nonce))
+	return common.BytesToAddress(common.Sha256(buf.Bytes()))
+}
+
+// multiSigEnvelope carries the member signatures of the multisig execute transaction in the Sign field
+type multiSigEnvelope struct {
+	Signs [][]byte
+}
+
+// EncodeMultiSigSigns encodes the member signatures of the transaction hash into the Sign field
+func EncodeMultiSigSigns(signs [][]byte) ([]byte, error) {
+	return msgpack.Marshal(&multiSigEnvelope{Signs: signs})
+}
+
+// DecodeMultiSigSigns decodes the member signatures from the Sign field
+func DecodeMultiSigSigns(bs []byte) ([][]byte, error) {
+	var e multiSigEnvelope
+	if err := msgpack.Unmarshal(bs, &e); err != nil {
+		return nil, err
+	}
+	if len(e.Signs) == 0 {
+		return nil, fmt.Errorf("no signs in the envelope")
+	}
+	if len(e.Signs) > MaxMultiSigMembers {
+		return nil, fmt.Errorf("too many signs")
+	}
+	return e.Signs, nil
+}
+
+// RecoverMultiSigSigners recovers the distinct signers of the signatures on the message
+func RecoverMultiSigSigners(msg []byte, signs [][]byte) ([]common.Address, error) {
+	signers := make([]common.Address, 0, len(signs))
+	exists := make(map[common.Address]struct{}, len(signs))
+	for _, bs := range signs {
+		sign := common.BytesToSign(bs)
+		if sign == nil {
+			return nil, fmt.Errorf("malformed sign %v", common.ToHex(bs))
+		}
+		pk, err := sign.RecoverPubkey(msg)
+		if err != nil {
+			return nil, err
+		}
+		addr := pk.GetAddress()
+		if _, ok := exists[addr]; ok {
+			return nil, fmt.Errorf("duplicate sign of %v", addr.AddrPrefixString())
+		}
+		exists[addr] = struct{}{}
+		signers = append(signers, addr)
+	}
+	return signers, nil
+}
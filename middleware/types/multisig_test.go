@@ -0,0 +1,87 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+func TestMultiSigConfig(t *testing.T) {
+	a, b, c := common.StringToAddress("zv01"), common.StringToAddress("zv02"), common.StringToAddress("zv03")
+	invalid := []*MultiSigConfig{
+		{Threshold: 1, Members: []common.Address{a}},
+		{Threshold: 0, Members: []common.Address{a, b}},
+		{Threshold: 3, Members: []common.Address{a, b}},
+		{Threshold: 2, Members: []common.Address{a, b, a}},
+		{Threshold: 1, Members: make([]common.Address, MaxMultiSigMembers+1)},
+	}
+	for i, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("config %v should be invalid", i)
+		}
+	}
+
+	cfg := &MultiSigConfig{Threshold: 2, Members: []common.Address{a, b, c}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := EncodeMultiSigConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeMultiSigConfig(bs)
+	if err != nil || decoded.Threshold != 2 || len(decoded.Members) != 3 || !decoded.IsMember(c) || decoded.IsMember(common.StringToAddress("zv04")) {
+		t.Fatalf("unexpected decoded config %+v %v", decoded, err)
+	}
+
+	if MultiSigAddress(a, 1) == MultiSigAddress(a, 2) || MultiSigAddress(a, 1) == MultiSigAddress(b, 1) {
+		t.Fatal("multisig address should differ by the creator and nonce")
+	}
+}
+
+func TestMultiSigSigns(t *testing.T) {
+	msg := common.Sha256([]byte("multisig"))
+	k1, _ := common.GenerateKey("")
+	k2, _ := common.GenerateKey("")
+	s1, _ := k1.Sign(msg)
+	s2, _ := k2.Sign(msg)
+
+	bs, err := EncodeMultiSigSigns([][]byte{s1.Bytes(), s2.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signs, err := DecodeMultiSigSigns(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := RecoverMultiSigSigners(msg, signs)
+	if err != nil || len(signers) != 2 || signers[0] != k1.GetPubKey().GetAddress() || signers[1] != k2.GetPubKey().GetAddress() {
+		t.Fatalf("unexpected signers %v %v", signers, err)
+	}
+	if _, err := RecoverMultiSigSigners(msg, [][]byte{s1.Bytes(), s1.Bytes()}); err == nil {
+		t.Fatal("expect duplicate sign error")
+	}
+	if _, err := RecoverMultiSigSigners(msg, [][]byte{[]byte("bad sign")}); err == nil {
+		t.Fatal("expect malformed sign error")
+	}
+	if bs, _ := EncodeMultiSigSigns(nil); bs != nil {
+		if _, err := DecodeMultiSigSigns(bs); err == nil {
+			t.Fatal("expect empty signs error")
+		}
+	}
+}
@@ -24,10 +24,16 @@ import (
 	"time"
 
 	"github.com/codeskyblue/go-sh"
+	"github.com/zvchain/zvchain/middleware/metrics"
 )
 
 var spaceRe, _ = regexp.Compile("\\s+")
 
+var (
+	cpuGauge = metrics.NewGauge("process_cpu_percent", "CPU usage of the node process in percent")
+	memGauge = metrics.NewGauge("process_memory_mb", "Resident memory of the node process in MB")
+)
+
 const (
 	NtypeVerifier = 1
 	NtypeProposal = 2
@@ -98,6 +104,8 @@ func (ns *NodeResStat) statCPUAndMEM() {
 		}
 		ns.CPU = cpu
 		ns.Mem = mem
+		cpuGauge.Set(cpu)
+		memGauge.Set(mem)
 	} else {
 
 	}
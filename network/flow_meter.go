@@ -16,7 +16,15 @@
 package network
 
 import (
+	"strconv"
 	"sync"
+
+	"github.com/zvchain/zvchain/middleware/metrics"
+)
+
+var (
+	messageBytesCounter = metrics.NewCounterVec("p2p_message_bytes_total", "Bytes of the p2p messages by direction and protocol code", "meter", "direction", "code")
+	messagesCounter     = metrics.NewCounterVec("p2p_messages_total", "Number of the p2p messages by direction and protocol code", "meter", "direction", "code")
 )
 
 type FlowMeterItem struct {
@@ -61,6 +69,7 @@ func (fm *FlowMeter) send(code int64, size int64) {
 	item.count++
 	item.size += size
 	fm.sendSize += size
+	fm.publish("send", code, size)
 }
 
 func (fm *FlowMeter) recv(code int64, size int64) {
@@ -74,6 +83,14 @@ func (fm *FlowMeter) recv(code int64, size int64) {
 	item.count++
 	item.size += size
 	fm.recvSize += size
+	fm.publish("recv", code, size)
+}
+
+// publish adds the message to the metrics, which are not cleared by reset
+func (fm *FlowMeter) publish(direction string, code int64, size int64) {
+	c := strconv.FormatInt(code, 10)
+	messagesCounter.WithLabelValues(fm.name, direction, c).Inc()
+	messageBytesCounter.WithLabelValues(fm.name, direction, c).Add(float64(size))
 }
 
 func (fm *FlowMeter) reset() {
@@ -22,6 +22,7 @@ import (
 	"math"
 	"math/rand"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -44,12 +45,16 @@ type NetworkConfig struct {
 	SeedIDs         []string
 	PK              string
 	SK              string
+	StaticPeers     []string // Peers always reconnected, in the form of id@host:port
+	TrustedPeers    []string // Peers exempt from the connection limits, in the form of id or id@host:port
+	NoDiscover      bool     // Private mode, the peers are not discovered on the network
 }
 
 const (
 	configMaxBroadcastCount = "max_broadcast_count"
 	maxBroadcastCount       = 192
 	configSection           = "p2p"
+	configAcceptPlainPeers  = "accept_plain_peers"
 )
 
 var netServerInstance *Server
@@ -62,6 +67,19 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 	if config != nil {
 		statistics.InitStatistics(*config)
 	}
+	initPeerReputation(config)
+
+	if config != nil {
+		networkConfig.StaticPeers = append(networkConfig.StaticPeers, splitPeers((*config).GetString(configSection, configStaticPeers, ""))...)
+		networkConfig.TrustedPeers = append(networkConfig.TrustedPeers, splitPeers((*config).GetString(configSection, configTrustedPeers, ""))...)
+		networkConfig.NoDiscover = networkConfig.NoDiscover || (*config).GetBool(configSection, configNoDiscover, false)
+		acceptPlainPeers = (*config).GetBool(configSection, configAcceptPlainPeers, true)
+	}
+	staticPeers = newStaticPeerSet()
+	if err = initStaticPeers(networkConfig.StaticPeers, networkConfig.TrustedPeers); err != nil {
+		Logger.Errorf("init static peers error:%v", err)
+		return err
+	}
 
 	nodeID := NewNodeID(networkConfig.NodeIDHex)
 	if nodeID == nil {
@@ -140,10 +158,24 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 		NatIP:              natIP,
 		NatPort:            networkConfig.NatPort,
 		ChainID:            networkConfig.ChainID,
-		ProtocolVersion:    networkConfig.ProtocolVersion}
+		ProtocolVersion:    networkConfig.ProtocolVersion,
+		NoDiscover:         networkConfig.NoDiscover}
+	if networkConfig.NoDiscover {
+		Logger.Infof("peer discovery disabled, static peers:%v", len(networkConfig.StaticPeers))
+	}
 
 	var netCore NetCore
-	n, _ := netCore.InitNetCore(netConfig)
+	n, err := netCore.InitNetCore(netConfig)
+	if err != nil {
+		Logger.Errorf("init net core error:%v", err)
+		return err
+	}
+	for _, node := range staticPeers.staticNodes() {
+		if node.ID != self.ID {
+			n.kad.add(node)
+		}
+	}
+	go n.connectStaticPeers()
 
 	maxCount := maxBroadcastCount
 	if common.GlobalConf != nil {
@@ -159,6 +191,17 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 	return nil
 }
 
+// splitPeers splits the peers separated by comma in the config
+func splitPeers(peers string) []string {
+	result := make([]string, 0)
+	for _, peer := range strings.Split(peers, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			result = append(result, peer)
+		}
+	}
+	return result
+}
+
 func genRandomSeeds(seeds []string) []string {
 	nodesSelect := make(map[int]bool)
 
@@ -188,6 +231,9 @@ func genRandomSeeds(seeds []string) []string {
 }
 
 func GetNetInstance() Network {
+	if simInstance != nil {
+		return simInstance
+	}
 	if netServerInstance == nil {
 		return nil
 	}
@@ -49,6 +49,12 @@ const (
 	TxSyncNotify   uint32 = 10010
 	TxSyncReq      uint32 = 10011
 	TxSyncResponse uint32 = 10012
+
+	//The following four messages are used for state snapshot sync
+	SnapshotCheckpointReq      uint32 = 10015
+	SnapshotCheckpointResponse uint32 = 10016
+	StateNodesReq              uint32 = 10017
+	StateNodesResponse         uint32 = 10018
 )
 
 type Message struct {
@@ -69,6 +69,8 @@ type Kad struct {
 	self *Node
 
 	setupCheckCount int
+
+	noDiscover bool // Private mode, the nodes are not looked up on the network
 }
 
 type NetInterface interface {
@@ -82,8 +84,9 @@ type bucket struct {
 	replacements []*Node // Standby supplementary node
 }
 
-func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node) (*Kad, error) {
+func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node, noDiscover bool) (*Kad, error) {
 	kad := &Kad{
+		noDiscover: noDiscover,
 		net:        t,
 		self:       NewNode(ourID, ourAddr.IP, ourAddr.Port),
 		refreshReq: make(chan chan struct{}),
@@ -190,6 +193,13 @@ func (kad *Kad) lookup(targetID NodeID, refreshIfEmpty bool) []*Node {
 
 	asked[kad.self.ID] = true
 
+	// Only the nodes known are returned in private mode
+	if kad.noDiscover {
+		kad.mutex.Lock()
+		defer kad.mutex.Unlock()
+		return kad.closest(target, bucketSize).entries
+	}
+
 	for {
 		kad.mutex.Lock()
 		result = kad.closest(target, bucketSize)
@@ -293,6 +303,9 @@ loop:
 func (kad *Kad) doRefresh(done chan struct{}) {
 	defer close(done)
 	kad.loadSeedNodes(true)
+	if kad.noDiscover {
+		return
+	}
 
 	kad.lookup(kad.self.ID, false)
 
@@ -31,8 +31,17 @@ import (
 	zvTime "github.com/zvchain/zvchain/middleware/time"
 )
 
-// Version is p2p proto version
-const Version = 1
+// Version is p2p proto version, the version 2 encrypts the data messages
+const Version = 2
+
+const (
+	minVersion       = 1 // The oldest version accepted
+	encryptedVersion = 2 // The version since which the data messages are encrypted
+)
+
+// acceptPlainPeers accepts the peers of the versions before encryptedVersion, exchanging the data messages
+// with them in plain, for the transition period of the upgrade
+var acceptPlainPeers = true
 
 const (
 	PacketTypeSize           = 4
@@ -51,9 +60,13 @@ var (
 	errExpired          = errors.New("expired")
 	errUnsolicitedReply = errors.New("unsolicited reply")
 	//errGroupEmpty       = errors.New("group empty")
-	errTimeout   = errors.New("RPC timeout")
-	errClockWarp = errors.New("reply deadline too far in the future")
-	errClosed    = errors.New("socket closed")
+	errTimeout    = errors.New("RPC timeout")
+	errClockWarp  = errors.New("reply deadline too far in the future")
+	errClosed     = errors.New("socket closed")
+	errNotSecured = errors.New("session not secured")
+	errPlainData  = errors.New("data message not encrypted")
+	errDropped    = errors.New("frame dropped")
+	errOldVersion = errors.New("p2p version too old")
 )
 
 // Timeouts
@@ -86,6 +99,7 @@ type NetCore struct {
 	flowMeter       *FlowMeter
 	bufferPool      *BufferPool
 	proposerManager *ProposerManager
+	transport       p2pTransport
 	chainID         uint16 // Chain ID
 	protocolVersion uint16 // Protocol ID
 }
@@ -142,6 +156,7 @@ type NetCoreConfig struct {
 	NatIP           string
 	ChainID         uint16
 	ProtocolVersion uint16
+	NoDiscover      bool   // Private mode, the kad lookups are disabled
 }
 
 // MakeEndPoint create the node description object
@@ -177,6 +192,7 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 	nc.proposerManager = newProposerManager()
 	nc.flowMeter = newFlowMeter("p2p")
 	nc.bufferPool = newBufferPool()
+	nc.transport = newCgoTransport()
 	realAddr := cfg.ListenAddr
 
 	Logger.Infof("kad ID: %v ", nc.ID.GetHexString())
@@ -185,17 +201,17 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 	Logger.Infof("P2PConfig: %v ", nc.netID)
 	Logger.Infof("local addr: %v %v", realAddr.IP.String(), uint16(realAddr.Port))
 	nc.ourEndPoint = MakeEndPoint(realAddr, int32(realAddr.Port))
-	P2PConfig(nc.netID)
+	nc.transport.config(nc.netID)
 
 	if cfg.NatTraversalEnable {
 		Logger.Infof("P2PProxy: %v %v", nc.peerManager.natIP, uint16(nc.peerManager.natPort))
-		P2PProxy(nc.peerManager.natIP, uint16(nc.peerManager.natPort))
+		nc.transport.proxy(nc.peerManager.natIP, uint16(nc.peerManager.natPort))
 	} else {
 		Logger.Infof("P2PListen: %v %v", realAddr.IP.String(), uint16(realAddr.Port))
-		P2PListen(realAddr.IP.String(), uint16(realAddr.Port))
+		nc.transport.listen(realAddr.IP.String(), uint16(realAddr.Port))
 	}
 
-	kad, err := newKad(nc, cfg.ID, realAddr, cfg.Seeds)
+	kad, err := newKad(nc, cfg.ID, realAddr, cfg.Seeds, cfg.NoDiscover)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +224,7 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 }
 
 func (nc *NetCore) close() {
-	P2PClose()
+	nc.transport.close()
 	close(nc.closing)
 }
 
@@ -247,6 +263,7 @@ func (nc *NetCore) ping(toID NodeID, toAddr *net.UDPAddr) {
 		req.PK = authContext.PK
 		req.CurTime = authContext.CurTime
 		req.Sign = authContext.Sign
+		req.EphemeralPK = authContext.EphemeralPK
 	}
 	Logger.Debugf("[send ping] ID : %v  ip:%v port:%v", toID.GetHexString(), nc.ourEndPoint.IP, nc.ourEndPoint.Port)
 
@@ -312,7 +329,7 @@ func (nc *NetCore) decodeLoop() {
 		for {
 
 			err := nc.handleMessage(peer)
-			if err != nil || peer.isEmpty() {
+			if (err != nil && err != errDropped) || peer.isEmpty() {
 				break
 			}
 		}
@@ -327,6 +344,7 @@ func (nc *NetCore) loop() {
 		flowMeter         = time.NewTicker(flowMeterInterval)
 		groupRefresh      = time.NewTicker(groupRefreshInterval)
 		peerCheck         = time.NewTicker(peerCheckInterval)
+		staticPeerCheck   = time.NewTicker(staticPeerCheckInterval)
 		timeout           = time.NewTimer(0)
 		nextTimeout       *pending
 		contTimeouts      = 0
@@ -336,6 +354,7 @@ func (nc *NetCore) loop() {
 	defer timeout.Stop()
 	defer flowMeter.Stop()
 	defer peerCheck.Stop()
+	defer staticPeerCheck.Stop()
 
 	// ignore first timeout
 	<-timeout.C
@@ -401,6 +420,8 @@ func (nc *NetCore) loop() {
 			nc.messageManager.clear()
 		case <-peerCheck.C:
 			nc.peerManager.checkPeers()
+		case <-staticPeerCheck.C:
+			go nc.connectStaticPeers()
 		case <-flowMeter.C:
 			nc.flowMeter.print()
 			nc.flowMeter.reset()
@@ -639,6 +660,27 @@ func (nc *NetCore) decodeMessage(p *Peer) (MessageType, int, proto.Message, *byt
 		return msgType, packetSize, nil, packetBuffer, err
 	}
 
+	// The data messages are only accepted encrypted by the session keys, which proves the peer authenticated,
+	// unless the peer is of the old version not supporting the encryption
+	if msgType == MessageType_MessageData && !p.isPlain() {
+		p.report(PeerEventBadMessage)
+		return msgType, packetSize, nil, packetBuffer, errPlainData
+	}
+	if msgType == MessageType_MessageEncrypted {
+		packet, err := p.openPacket(data)
+		nc.bufferPool.freeBuffer(packetBuffer)
+		if err != nil {
+			// Only the frame is dropped, the following ones of the session are still opened
+			Logger.Debugf("open packet from %v error:%v, dropped", p.ID.GetHexString(), err)
+			p.report(PeerEventBadMessage)
+			return msgType, packetSize, nil, nil, errDropped
+		}
+		msgType, packetSize = MessageType_MessageData, len(packet)
+		packetBuffer = nc.bufferPool.getBuffer(packetSize)
+		packetBuffer.Write(packet)
+		data = packet[PacketHeadSize:]
+	}
+
 	var req proto.Message
 	switch msgType {
 	case MessageType_MessagePing:
@@ -669,6 +711,11 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 	if expired(req.Expiration) {
 		return errExpired
 	}
+	if req.Version < minVersion || (req.Version < encryptedVersion && !acceptPlainPeers) {
+		Logger.Debugf("ping from %v with old version %v", p.ID.GetHexString(), req.Version)
+		return errOldVersion
+	}
+	p.setVersion(req.Version)
 
 	ip := net.ParseIP(req.From.IP)
 	port := int(req.From.Port)
@@ -688,11 +735,16 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 	}
 
 	if len(req.PK) > 0 && len(req.Sign) > 0 && req.CurTime > 0 {
-		pac := &PeerAuthContext{PK: req.PK, Sign: req.Sign, CurTime: req.CurTime}
-		p.verify(pac)
+		pac := &PeerAuthContext{PK: req.PK, Sign: req.Sign, CurTime: req.CurTime, EphemeralPK: req.EphemeralPK}
+		// The net id claimed may not be the banned one, check again with the node id verified
+		if p.verify(pac) && IsPeerBanned(p.ID.GetHexString()) {
+			Logger.Infof("disconnect banned peer %v", p.ID.GetHexString())
+			nc.peerManager.disconnect(p.ID)
+			return errBadPeer
+		}
 	}
 
-	pongMsg := MsgPong{Version: 0, VerifyResult: p.verifyResult}
+	pongMsg := MsgPong{Version: Version, VerifyResult: p.verifyResult}
 
 	nc.sendMessageToNode(p.ID, nil, MessageType_MessagePong, &pongMsg, P2PMessageCodeBase+uint32(MessageType_MessagePong))
 
@@ -706,6 +758,10 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 }
 
 func (nc *NetCore) handlePong(req *MsgPong, p *Peer) error {
+	// The old versions reply no version
+	if req.Version > 0 {
+		p.setVersion(req.Version)
+	}
 
 	p.setRemoteVerifyResult(req.VerifyResult)
 	Logger.Debugf("Pong from:%v, VerifyResult:%v, RemoteVerifyResult:%v,isAuthSucceed:%v",
@@ -722,6 +778,10 @@ func (nc *NetCore) handleFindNode(req *MsgFindNode, p *Peer) error {
 	if expired(req.Expiration) {
 		return errExpired
 	}
+	// The nodes known are not revealed in private mode
+	if nc.kad.noDiscover {
+		return nil
+	}
 
 	target := req.Target
 	nc.kad.mutex.Lock()
@@ -31,6 +31,7 @@ const (
 	MessageType_MessageFindnode  MessageType = 3
 	MessageType_MessageNeighbors MessageType = 4
 	MessageType_MessageData      MessageType = 5
+	MessageType_MessageEncrypted MessageType = 6
 )
 
 var MessageType_name = map[int32]string{
@@ -40,6 +41,7 @@ var MessageType_name = map[int32]string{
 	3: "MessageFindnode",
 	4: "MessageNeighbors",
 	5: "MessageData",
+	6: "MessageEncrypted",
 }
 
 var MessageType_value = map[string]int32{
@@ -49,6 +51,7 @@ var MessageType_value = map[string]int32{
 	"MessageFindnode":  3,
 	"MessageNeighbors": 4,
 	"MessageData":      5,
+	"MessageEncrypted": 6,
 }
 
 func (x MessageType) String() string {
@@ -223,6 +226,7 @@ type MsgPing struct {
 	PK                   []byte       `protobuf:"bytes,6,opt,name=PK,proto3" json:"PK,omitempty"`
 	Sign                 []byte       `protobuf:"bytes,7,opt,name=Sign,proto3" json:"Sign,omitempty"`
 	CurTime              uint64       `protobuf:"varint,8,opt,name=CurTime,proto3" json:"CurTime,omitempty"`
+	EphemeralPK          []byte       `protobuf:"bytes,9,opt,name=EphemeralPK,proto3" json:"EphemeralPK,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
@@ -317,6 +321,13 @@ func (m *MsgPing) GetCurTime() uint64 {
 	return 0
 }
 
+func (m *MsgPing) GetEphemeralPK() []byte {
+	if m != nil {
+		return m.EphemeralPK
+	}
+	return nil
+}
+
 type MsgPong struct {
 	Version              int32    `protobuf:"varint,1,opt,name=Version,proto3" json:"Version,omitempty"`
 	VerifyResult         bool     `protobuf:"varint,2,opt,name=VerifyResult,proto3" json:"VerifyResult,omitempty"`
@@ -616,45 +627,47 @@ func init() {
 func init() { proto.RegisterFile("p2p.proto", fileDescriptor_e7fdddb109e6467a) }
 
 var fileDescriptor_e7fdddb109e6467a = []byte{
-	// 607 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x5d, 0x4f, 0xdb, 0x30,
-	0x14, 0xc5, 0x69, 0x42, 0xe9, 0x6d, 0x01, 0xcf, 0x43, 0x53, 0x1e, 0xa6, 0x2a, 0x8a, 0xa6, 0x29,
-	0x42, 0x1a, 0xd2, 0xba, 0xe7, 0xbd, 0xd0, 0x02, 0xaa, 0x50, 0xab, 0xc8, 0x54, 0xbc, 0x07, 0x62,
-	0x42, 0xb4, 0xd4, 0x37, 0x72, 0xd2, 0x31, 0xa6, 0xfd, 0x90, 0xfd, 0xa4, 0x69, 0x4f, 0xfb, 0x09,
-	0x13, 0x7b, 0xdc, 0x9f, 0x98, 0x6c, 0x92, 0x34, 0x30, 0xed, 0xe3, 0xa9, 0xbe, 0xc7, 0xc7, 0xc7,
-	0xf7, 0xdc, 0xe3, 0x06, 0x7a, 0xf9, 0x28, 0x3f, 0xc8, 0x15, 0x96, 0xc8, 0xba, 0x52, 0x94, 0x37,
-	0xa8, 0xde, 0xf9, 0x6f, 0xa1, 0xcb, 0xf3, 0xcb, 0x39, 0xc6, 0x82, 0xed, 0x80, 0x35, 0x0d, 0x5d,
-	0xe2, 0x91, 0xa0, 0xc7, 0xad, 0x69, 0xc8, 0x18, 0xd8, 0x21, 0xaa, 0xd2, 0xb5, 0x3c, 0x12, 0x38,
-	0xdc, 0xac, 0x0d, 0x67, 0xe2, 0x76, 0x2a, 0xce, 0xc4, 0x7f, 0x0d, 0x7d, 0x9e, 0x5f, 0x1e, 0xc9,
-	0x38, 0xc4, 0x54, 0x96, 0xff, 0x23, 0xe1, 0xff, 0x24, 0xd0, 0x9d, 0x15, 0x49, 0x98, 0xca, 0x84,
-	0xb9, 0xd0, 0x3d, 0x17, 0xaa, 0x48, 0x51, 0x9a, 0x43, 0x0e, 0xaf, 0x4b, 0x16, 0x80, 0x7d, 0xac,
-	0x70, 0x69, 0x4e, 0xf6, 0x47, 0x7b, 0x07, 0x55, 0xbf, 0x07, 0xad, 0xdb, 0xb8, 0x61, 0xb0, 0x17,
-	0x60, 0x2d, 0xd0, 0xb4, 0xf4, 0x27, 0x9e, 0xb5, 0x40, 0x7d, 0xd3, 0xe5, 0x75, 0x94, 0xca, 0xe9,
-	0xc4, 0xb5, 0x3d, 0x12, 0x6c, 0xf3, 0xba, 0x64, 0x43, 0x80, 0xa3, 0x0f, 0x79, 0xaa, 0xa2, 0x52,
-	0xb7, 0xe1, 0x78, 0x24, 0xb0, 0x79, 0x0b, 0xd1, 0x9e, 0xc2, 0x53, 0x77, 0xd3, 0x23, 0xc1, 0x80,
-	0x5b, 0xe1, 0xa9, 0xf6, 0x74, 0x96, 0x26, 0xd2, 0xed, 0x1a, 0xc4, 0xac, 0xb5, 0xfa, 0x78, 0xa5,
-	0x16, 0xe9, 0x52, 0xb8, 0x5b, 0x46, 0xa0, 0x2e, 0xfd, 0x93, 0x7b, 0xb3, 0xf8, 0x57, 0xb3, 0x3e,
-	0x0c, 0xce, 0x85, 0x4a, 0xaf, 0x6e, 0xb9, 0x28, 0x56, 0xd9, 0xfd, 0xb8, 0xb6, 0xf8, 0x03, 0xcc,
-	0x3f, 0x82, 0xfe, 0xac, 0x48, 0x8e, 0x53, 0x19, 0x9b, 0xb0, 0x9e, 0xc1, 0xe6, 0x22, 0x52, 0x89,
-	0x28, 0x8d, 0xd6, 0x80, 0x57, 0xd5, 0x23, 0x37, 0xd6, 0x63, 0x37, 0xfe, 0x39, 0x0c, 0x66, 0x45,
-	0x32, 0x17, 0x69, 0x72, 0x7d, 0x81, 0xaa, 0x60, 0x2f, 0xc1, 0xd1, 0x7a, 0x85, 0x4b, 0xbc, 0x4e,
-	0xd0, 0x1f, 0xd1, 0xf6, 0x00, 0xf5, 0x06, 0xbf, 0xdf, 0xfe, 0xa7, 0xee, 0x57, 0xcb, 0x18, 0x9d,
-	0x44, 0x65, 0xc4, 0x5e, 0xc1, 0x96, 0xfe, 0x5d, 0xdc, 0xe6, 0xc2, 0x74, 0xb7, 0x33, 0x7a, 0xd2,
-	0xc8, 0xd6, 0x1b, 0xbc, 0xa1, 0xe8, 0xb9, 0x9c, 0x28, 0x5c, 0xe5, 0xd3, 0x89, 0xd1, 0xed, 0xf1,
-	0xba, 0x7c, 0x74, 0x69, 0xe7, 0xb7, 0x68, 0x9e, 0x43, 0x6f, 0x26, 0x8a, 0x22, 0x4a, 0x44, 0x15,
-	0xab, 0xcd, 0xd7, 0x80, 0x9e, 0xea, 0x61, 0xfa, 0x71, 0x4d, 0x70, 0xcc, 0xa0, 0x1e, 0x60, 0x5a,
-	0xe1, 0x4c, 0x19, 0xa3, 0xd3, 0x49, 0x95, 0xf1, 0x1a, 0xd0, 0x51, 0xeb, 0x2e, 0xeb, 0xa8, 0x8d,
-	0xb9, 0x21, 0x00, 0x17, 0x59, 0x74, 0x3b, 0xc6, 0x95, 0x2c, 0x4d, 0xda, 0x0e, 0x6f, 0x21, 0xcc,
-	0x83, 0x7e, 0x25, 0x3f, 0xc6, 0x58, 0xb8, 0x3d, 0xf3, 0xd8, 0xda, 0x50, 0x8b, 0x31, 0x95, 0x57,
-	0xe8, 0xc2, 0x03, 0x86, 0x86, 0xf6, 0x3f, 0x35, 0x0c, 0x33, 0xa0, 0xdd, 0xa6, 0x9c, 0xa3, 0x14,
-	0x74, 0xa3, 0x05, 0xe8, 0x7f, 0x11, 0x25, 0x6d, 0x00, 0x65, 0x42, 0x2d, 0xf6, 0x14, 0x76, 0x2b,
-	0x40, 0xbf, 0x18, 0x89, 0xb1, 0xa0, 0x1d, 0xb6, 0x07, 0xb4, 0xd6, 0xa9, 0xf3, 0xa7, 0x76, 0xeb,
-	0xac, 0xf6, 0x47, 0x9d, 0xfd, 0xf7, 0xeb, 0xf8, 0xd8, 0x0e, 0x80, 0x5e, 0xcf, 0x51, 0x2d, 0xa3,
-	0x8c, 0x6e, 0xd4, 0xf5, 0x49, 0x86, 0x17, 0x51, 0x46, 0x89, 0x96, 0x5c, 0xd7, 0x3c, 0x92, 0x31,
-	0x2e, 0xa9, 0xc5, 0xb6, 0xa1, 0x67, 0x50, 0x1d, 0x23, 0xed, 0xe8, 0x66, 0x9a, 0x72, 0x8c, 0xd9,
-	0x6a, 0x29, 0xa9, 0xcd, 0x28, 0x0c, 0x1a, 0x90, 0xe3, 0x0d, 0x75, 0x0e, 0xe9, 0x97, 0xbb, 0x21,
-	0xf9, 0x76, 0x37, 0x24, 0xdf, 0xef, 0x86, 0xe4, 0xf3, 0x8f, 0xe1, 0xc6, 0xc5, 0xa6, 0xf9, 0x58,
-	0xbd, 0xf9, 0x15, 0x00, 0x00, 0xff, 0xff, 0x45, 0xc0, 0xdd, 0xe7, 0xb9, 0x04, 0x00, 0x00,
+	// 631 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcf, 0x6e, 0xd3, 0x30,
+	0x1c, 0x9e, 0xd3, 0xa4, 0x5d, 0x7f, 0xed, 0x36, 0x63, 0x26, 0xe4, 0x03, 0xaa, 0xa2, 0x08, 0xa1,
+	0x68, 0x12, 0x93, 0x28, 0x67, 0x2e, 0x6b, 0xbb, 0xa9, 0x9a, 0x5a, 0x45, 0x5e, 0xb5, 0x7b, 0xd6,
+	0x78, 0x59, 0x44, 0x6a, 0x47, 0x4e, 0xca, 0x28, 0xef, 0xc0, 0x85, 0x13, 0x8f, 0x84, 0x38, 0xf1,
+	0x08, 0x68, 0xbc, 0x08, 0xb2, 0x9b, 0xb4, 0x59, 0x91, 0x06, 0xa7, 0xfa, 0xfb, 0xfc, 0xfd, 0x3e,
+	0xff, 0xfe, 0x35, 0xd0, 0xce, 0xfa, 0xd9, 0x69, 0xa6, 0x64, 0x21, 0x49, 0x4b, 0xf0, 0xe2, 0x5e,
+	0xaa, 0x0f, 0xde, 0x7b, 0x68, 0xb1, 0x6c, 0x3e, 0x95, 0x11, 0x27, 0x87, 0x60, 0x8d, 0x03, 0x8a,
+	0x5c, 0xe4, 0xb7, 0x99, 0x35, 0x0e, 0x08, 0x01, 0x3b, 0x90, 0xaa, 0xa0, 0x96, 0x8b, 0x7c, 0x87,
+	0x99, 0xb3, 0xd1, 0x0c, 0x69, 0xa3, 0xd4, 0x0c, 0xbd, 0xb7, 0xd0, 0x61, 0xd9, 0x7c, 0x24, 0xa2,
+	0x40, 0x26, 0xa2, 0xf8, 0x1f, 0x0b, 0xef, 0x8b, 0x05, 0xad, 0x49, 0x1e, 0x07, 0x89, 0x88, 0x09,
+	0x85, 0xd6, 0x35, 0x57, 0x79, 0x22, 0x85, 0x09, 0x72, 0x58, 0x05, 0x89, 0x0f, 0xf6, 0xb9, 0x92,
+	0x0b, 0x13, 0xd9, 0xe9, 0x1f, 0x9f, 0x96, 0xf9, 0x9e, 0xd6, 0x5e, 0x63, 0x46, 0x41, 0x5e, 0x81,
+	0x35, 0x93, 0xb4, 0xf1, 0x84, 0xce, 0x9a, 0x49, 0xfd, 0xd2, 0xfc, 0x2e, 0x4c, 0xc4, 0x78, 0x48,
+	0x6d, 0x17, 0xf9, 0x07, 0xac, 0x82, 0xa4, 0x07, 0x30, 0xfa, 0x94, 0x25, 0x2a, 0x2c, 0x74, 0x1a,
+	0x8e, 0x8b, 0x7c, 0x9b, 0xd5, 0x18, 0x5d, 0x53, 0x70, 0x49, 0x9b, 0x2e, 0xf2, 0xbb, 0xcc, 0x0a,
+	0x2e, 0x75, 0x4d, 0x57, 0x49, 0x2c, 0x68, 0xcb, 0x30, 0xe6, 0xac, 0xdd, 0x07, 0x4b, 0x35, 0x4b,
+	0x16, 0x9c, 0xee, 0x1b, 0x83, 0x0a, 0x12, 0x17, 0x3a, 0xa3, 0xec, 0x8e, 0x2f, 0xb8, 0x0a, 0xd3,
+	0xe0, 0x92, 0xb6, 0x4d, 0x50, 0x9d, 0xf2, 0x2e, 0xd6, 0xed, 0x90, 0x4f, 0xb6, 0xc3, 0x83, 0xee,
+	0x35, 0x57, 0xc9, 0xed, 0x8a, 0xf1, 0x7c, 0x99, 0xae, 0x1b, 0xba, 0xcf, 0x1e, 0x71, 0xde, 0x08,
+	0x3a, 0x93, 0x3c, 0x3e, 0x4f, 0x44, 0x64, 0xc6, 0xf9, 0x02, 0x9a, 0xb3, 0x50, 0xc5, 0xbc, 0x30,
+	0x5e, 0x5d, 0x56, 0xa2, 0x9d, 0x7a, 0xad, 0xdd, 0x7a, 0xbd, 0x6b, 0xe8, 0x4e, 0xf2, 0x78, 0xca,
+	0x93, 0xf8, 0xee, 0x46, 0xaa, 0x9c, 0xbc, 0x06, 0x47, 0xfb, 0xe5, 0x14, 0xb9, 0x0d, 0xbf, 0xd3,
+	0xc7, 0xf5, 0x16, 0xeb, 0x0b, 0xb6, 0xbe, 0xfe, 0xa7, 0xef, 0x8f, 0xf5, 0xdc, 0x87, 0x61, 0x11,
+	0x92, 0x37, 0xb0, 0xaf, 0x7f, 0x67, 0xab, 0x8c, 0x9b, 0xec, 0x0e, 0xfb, 0xcf, 0x36, 0xb6, 0xd5,
+	0x05, 0xdb, 0x48, 0x74, 0x5f, 0x2e, 0x94, 0x5c, 0x66, 0xe3, 0xa1, 0xf1, 0x6d, 0xb3, 0x0a, 0xee,
+	0x3c, 0xda, 0xf8, 0x6b, 0x78, 0x2f, 0xa1, 0x3d, 0xe1, 0x79, 0x1e, 0xc6, 0xbc, 0x1c, 0xbc, 0xcd,
+	0xb6, 0x84, 0xee, 0xea, 0x59, 0xf2, 0x79, 0x2b, 0x70, 0x4c, 0xa3, 0x1e, 0x71, 0xda, 0xe1, 0x4a,
+	0x99, 0x42, 0xc7, 0xc3, 0x72, 0x0b, 0xb6, 0x84, 0x5e, 0x06, 0x9d, 0x65, 0xb5, 0x0c, 0xa6, 0xb8,
+	0x1e, 0x00, 0xe3, 0x69, 0xb8, 0x1a, 0xc8, 0xa5, 0x28, 0xcc, 0x3e, 0x38, 0xac, 0xc6, 0xe8, 0x95,
+	0x28, 0xed, 0x07, 0x32, 0xe2, 0x66, 0x25, 0x0e, 0x58, 0x9d, 0xaa, 0x29, 0xc6, 0xe2, 0x56, 0x52,
+	0x78, 0xa4, 0xd0, 0xd4, 0xc9, 0x57, 0xb4, 0x91, 0x98, 0x0e, 0x1d, 0x6d, 0xe0, 0x54, 0x0a, 0x8e,
+	0xf7, 0x6a, 0x84, 0xfe, 0xa3, 0x61, 0x54, 0x27, 0xa4, 0x88, 0xb1, 0x45, 0x9e, 0xc3, 0x51, 0x49,
+	0xe8, 0x95, 0x11, 0x32, 0xe2, 0xb8, 0x41, 0x8e, 0x01, 0x57, 0x3e, 0xd5, 0x02, 0x60, 0xbb, 0x16,
+	0xab, 0x0b, 0xc4, 0x4e, 0x4d, 0x36, 0x12, 0x73, 0xb5, 0xca, 0x0a, 0x1e, 0xe1, 0xe6, 0xc9, 0xc7,
+	0xed, 0x54, 0xc9, 0x21, 0x80, 0x3e, 0x4f, 0xa5, 0x5a, 0x84, 0x29, 0xde, 0xab, 0xf0, 0x45, 0x2a,
+	0x6f, 0xc2, 0x14, 0x23, 0xed, 0xb0, 0xc5, 0x2c, 0x14, 0x91, 0x5c, 0x60, 0x8b, 0x1c, 0x40, 0xdb,
+	0xb0, 0x7a, 0xba, 0xb8, 0xa1, 0x53, 0xdc, 0xc0, 0x81, 0x4c, 0x97, 0x0b, 0x81, 0x6d, 0x82, 0xa1,
+	0xbb, 0x21, 0x99, 0xbc, 0xc7, 0xce, 0x19, 0xfe, 0xfe, 0xd0, 0x43, 0x3f, 0x1f, 0x7a, 0xe8, 0xd7,
+	0x43, 0x0f, 0x7d, 0xfb, 0xdd, 0xdb, 0xbb, 0x69, 0x9a, 0xaf, 0xdc, 0xbb, 0x3f, 0x03, 0x00, 0xc4,
+	0xdf, 0x29, 0x81, 0xf2, 0x04, 0x00, 0x00,
 }
 
 func (m *RpcNode) Marshal() (dAtA []byte, err error) {
@@ -794,6 +807,12 @@ func (m *MsgPing) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintP2P(dAtA, i, uint64(m.CurTime))
 	}
+	if len(m.EphemeralPK) > 0 {
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(len(m.EphemeralPK)))
+		i += copy(dAtA[i:], m.EphemeralPK)
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -1066,6 +1085,10 @@ func (m *MsgPing) Size() (n int) {
 	if m.CurTime != 0 {
 		n += 1 + sovP2P(uint64(m.CurTime))
 	}
+	l = len(m.EphemeralPK)
+	if l > 0 {
+		n += 1 + l + sovP2P(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1669,6 +1692,40 @@ func (m *MsgPing) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EphemeralPK", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthP2P
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthP2P
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EphemeralPK = append(m.EphemeralPK[:0], dAtA[iNdEx:postIndex]...)
+			if m.EphemeralPK == nil {
+				m.EphemeralPK = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipP2P(dAtA[iNdEx:])
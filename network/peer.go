@@ -36,15 +36,20 @@ const (
 	PeerSourceGroup  PeerSource = 2
 )
 
+// PeerAuthContext proves the control of the key behind the node ID. The ephemeral public key signed
+// is used to derive the keys encrypting the session
 type PeerAuthContext struct {
-	PK      []byte
-	Sign    []byte
-	CurTime uint64
+	PK          []byte
+	Sign        []byte
+	CurTime     uint64
+	EphemeralPK []byte
+
+	ephemeralKey *common.PrivateKey
 }
 
 func (pa *PeerAuthContext) Verify() (bool, string) {
 	pubkey := common.BytesToPublicKey(pa.PK)
-	if pubkey == nil {
+	if pubkey == nil || len(pa.EphemeralPK) == 0 {
 		return false, ""
 	}
 
@@ -60,6 +65,7 @@ func (pa *PeerAuthContext) Verify() (bool, string) {
 	if netServerInstance != nil && netServerInstance.netCore != nil {
 		buffer.Write(netServerInstance.netCore.ID.Bytes())
 	}
+	buffer.Write(pa.EphemeralPK)
 
 	hash := common.BytesToHash(common.Sha256(buffer.Bytes()))
 	sign := common.BytesToSign(pa.Sign)
@@ -78,6 +84,11 @@ func genPeerAuthContext(PK string, SK string, toID *NodeID) *PeerAuthContext {
 	if privateKey.GetPubKey().Hex() != pubkey.Hex() {
 		return nil
 	}
+	ephemeralKey, err := common.GenerateKey("")
+	if err != nil {
+		return nil
+	}
+	ephemeralPK := ephemeralKey.GetPubKey().Bytes()
 
 	buffer := bytes.Buffer{}
 	curTime := uint64(zvTime.TSInstance.Now().UTC().Unix())
@@ -86,6 +97,7 @@ func genPeerAuthContext(PK string, SK string, toID *NodeID) *PeerAuthContext {
 	if toID != nil {
 		buffer.Write(toID.Bytes())
 	}
+	buffer.Write(ephemeralPK)
 	hash := common.BytesToHash(common.Sha256(buffer.Bytes()))
 
 	sign, err := privateKey.Sign(hash.Bytes())
@@ -93,12 +105,13 @@ func genPeerAuthContext(PK string, SK string, toID *NodeID) *PeerAuthContext {
 		return nil
 	}
 
-	return &PeerAuthContext{PK: pubkey.Bytes(), Sign: sign.Bytes(), CurTime: curTime}
+	return &PeerAuthContext{PK: pubkey.Bytes(), Sign: sign.Bytes(), CurTime: curTime, EphemeralPK: ephemeralPK, ephemeralKey: &ephemeralKey}
 }
 
 // Peer is node connection object
 type Peer struct {
 	ID             NodeID
+	netID          uint64
 	sessionID      uint32
 	IP             net.IP
 	Port           int
@@ -118,6 +131,7 @@ type Peer struct {
 	sendWaitCount   int
 	disconnectCount int
 	chainID         uint16
+	trusted         bool // Exempt from the connection limits, not counted in the peer ip set
 
 	connectTime        time.Time
 	authContext        *PeerAuthContext
@@ -125,6 +139,13 @@ type Peer struct {
 	verifyResult       bool
 	remoteVerifyResult bool
 	isAuthSucceed      bool
+
+	// Version of the remote reported in the ping, 0 if unknown
+	version int32
+
+	// Ciphers of the data frames, derived once the remote auth context verified
+	sendCipher *sessionCipher
+	recvCipher *sessionCipher
 }
 
 func newPeer(ID NodeID, sessionID uint32) *Peer {
@@ -292,6 +313,27 @@ func (p *Peer) setRemoteVerifyResult(result bool) {
 	p.verifyUpdate()
 }
 
+// setVersion sets the version of the remote. The version is never lowered in the session,
+// so that the encryption can't be downgraded by a forged ping
+func (p *Peer) setVersion(version int32) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if version > p.version {
+		p.version = version
+	}
+}
+
+// isPlainLocked reports whether the data messages are exchanged in plain as the remote of an old version
+func (p *Peer) isPlainLocked() bool {
+	return acceptPlainPeers && p.version >= minVersion && p.version < encryptedVersion
+}
+
+func (p *Peer) isPlain() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.isPlainLocked()
+}
+
 func (p *Peer) verifyUpdate() {
 
 	if !p.isAuthSucceed && p.verifyResult && p.remoteVerifyResult {
@@ -314,6 +356,7 @@ func (p *Peer) onConnect(id uint64, session uint32, p2pType uint32, isAccepted b
 	p.resetData()
 	p.resetAuthContext()
 	p.connecting = false
+	p.netID = id
 	if session > p.sessionID {
 
 		p.sessionID = session
@@ -338,6 +381,8 @@ func (p *Peer) resetAuthContext() {
 	p.remoteAuthContext = nil
 	p.remoteVerifyResult = false
 	p.verifyResult = false
+	p.sendCipher = nil
+	p.recvCipher = nil
 }
 
 func (p *Peer) resetRemoteVerifyContext() {
@@ -363,12 +408,25 @@ func (p *Peer) verify(pac *PeerAuthContext) bool {
 	if p.isAuthSucceed {
 		return true
 	}
-	p.remoteAuthContext = pac
-	verifyResult, verifyID := p.remoteAuthContext.Verify()
+	verifyResult, verifyID := pac.Verify()
+	nID := NewNodeID(verifyID)
+	if verifyResult && nID != nil {
+		// The key must be the one behind the node dialed, or the net id the session claimed
+		if p.ID.IsValid() && *nID != p.ID {
+			Logger.Infof("peer verify failed, node id %v mismatch %v", nID.GetHexString(), p.ID.GetHexString())
+			verifyResult = false
+		} else if p.netID != 0 && genNetID(*nID) != p.netID {
+			Logger.Infof("peer verify failed, node id %v mismatch net id %v", nID.GetHexString(), p.netID)
+			verifyResult = false
+		}
+	}
+	if verifyResult && !p.deriveCiphers(pac, nID) {
+		verifyResult = false
+	}
 
 	p.verifyResult = verifyResult
-	nID := NewNodeID(verifyID)
-	if nID != nil {
+	if verifyResult {
+		p.remoteAuthContext = pac
 		p.ID = *nID
 	}
 
@@ -376,6 +434,64 @@ func (p *Peer) verify(pac *PeerAuthContext) bool {
 	return p.verifyResult
 }
 
+// deriveCiphers derives the session ciphers from the ephemeral keys, called with the mutex held.
+// The ciphers are kept if the remote resends the same ephemeral key, as the frames may be in flight
+func (p *Peer) deriveCiphers(pac *PeerAuthContext, remoteID *NodeID) bool {
+	if p.recvCipher != nil && p.remoteAuthContext != nil && bytes.Equal(p.remoteAuthContext.EphemeralPK, pac.EphemeralPK) {
+		return true
+	}
+	if p.authContext == nil {
+		p.authContext = genPeerAuthContext(netServerInstance.config.PK, netServerInstance.config.SK, remoteID)
+		if p.authContext == nil {
+			return false
+		}
+	}
+	send, recv, err := newSessionCiphers(p.authContext.ephemeralKey, pac.EphemeralPK)
+	if err != nil {
+		Logger.Infof("derive session ciphers error:%v", err)
+		return false
+	}
+	p.sendCipher, p.recvCipher = send, recv
+	return true
+}
+
+// sealPacket encrypts the data packet to send, called with the mutex held. The data packets are dropped
+// until the session is authenticated in both directions. The other packets, and the data packets to the
+// remote of an old version, are sent as they are
+func (p *Peer) sealPacket(packet []byte) ([]byte, bool) {
+	if len(packet) < PacketHeadSize || MessageType(binary.BigEndian.Uint32(packet)) != MessageType_MessageData {
+		return packet, true
+	}
+	if !p.isAuthSucceed {
+		return nil, false
+	}
+	if p.isPlainLocked() {
+		return packet, true
+	}
+	if p.sendCipher == nil {
+		return nil, false
+	}
+	return encodeEncryptedPacket(p.sendCipher.seal(packet)), true
+}
+
+// openPacket decrypts the data packet received in the encrypted frame
+func (p *Peer) openPacket(sealed []byte) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.recvCipher == nil {
+		return nil, errNotSecured
+	}
+	packet, err := p.recvCipher.open(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(packet) < PacketHeadSize || MessageType(binary.BigEndian.Uint32(packet)) != MessageType_MessageData ||
+		int(binary.BigEndian.Uint32(packet[PacketTypeSize:])) != len(packet)-PacketHeadSize {
+		return nil, errBadPacket
+	}
+	return packet, nil
+}
+
 func (p *Peer) write(packet *bytes.Buffer, code uint32) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
@@ -400,6 +516,13 @@ func (p *Peer) getDataSize() int {
 	return size
 }
 
+// report reports the behaviour of the peer if its node id is known
+func (p *Peer) report(event PeerEvent) {
+	if p.ID.IsValid() {
+		ReportPeer(p.ID.GetHexString(), event)
+	}
+}
+
 func (p *Peer) IsCompatible() bool {
 	return netCore.chainID == p.chainID
 }
@@ -410,7 +533,7 @@ func (p *Peer) disconnect() {
 	defer p.mutex.Unlock()
 
 	if p.sessionID > 0 {
-		P2PShutdown(p.sessionID)
+		netCore.transport.shutdown(p.sessionID)
 		p.sessionID = 0
 	}
 }
@@ -66,6 +66,9 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 		return
 	}
 	netID := genNetID(toid)
+	if reputation != nil && reputation.isNetIDBanned(netID) {
+		return
+	}
 	p := pm.peerByNetID(netID)
 
 	if p == nil {
@@ -97,10 +100,10 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 		}
 
 		if pm.natTraversalEnable {
-			P2PConnect(netID, pm.natIP, pm.natPort)
+			netCore.transport.connect(netID, pm.natIP, pm.natPort)
 			Logger.Infof("connect node ,[nat]: %v ", toid.GetHexString())
 		} else {
-			P2PConnect(netID, toaddr.IP.String(), uint16(toaddr.Port))
+			netCore.transport.connect(netID, toaddr.IP.String(), uint16(toaddr.Port))
 			Logger.Infof("connect node ,[direct]: id: %v ip: %v port:%v ", toid.GetHexString(), toaddr.IP.String(), uint16(toaddr.Port))
 		}
 	}
@@ -108,6 +111,11 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 
 // newConnection handling callbacks for successful connections
 func (pm *PeerManager) newConnection(id uint64, session uint32, p2pType uint32, isAccepted bool, ip string, port uint16) {
+	if reputation != nil && reputation.isNetIDBanned(id) {
+		Logger.Infof("reject connection of banned peer, net id:%v session:%v ip:%v", id, session, ip)
+		netCore.transport.shutdown(session)
+		return
+	}
 
 	p := pm.peerByNetID(id)
 	if p == nil {
@@ -116,7 +124,7 @@ func (pm *PeerManager) newConnection(id uint64, session uint32, p2pType uint32,
 		p.Port = int(port)
 		p.connectTimeout = uint64(time.Now().Add(connectTimeout).Unix())
 		if !pm.addPeer(id, p) {
-			P2PShutdown(session)
+			netCore.transport.shutdown(session)
 			return
 		}
 	}
@@ -143,7 +151,9 @@ func (pm *PeerManager) onDisconnected(id uint64, session uint32, p2pCode uint32)
 		p.onDisonnect(id, session, p2pCode)
 
 		delete(pm.peers, genNetID(p.ID))
-		pm.peerIPSet.Remove(p.IP.String())
+		if !p.trusted {
+			pm.peerIPSet.Remove(p.IP.String())
+		}
 
 	} else {
 		Logger.Infof("OnDisconnected net id：%v session:%v code:%v", id, session, p2pCode)
@@ -162,7 +172,9 @@ func (pm *PeerManager) disconnect(id NodeID) {
 
 		p.disconnect()
 		delete(pm.peers, netID)
-		pm.peerIPSet.Remove(p.IP.String())
+		if !p.trusted {
+			pm.peerIPSet.Remove(p.IP.String())
+		}
 	}
 }
 
@@ -180,7 +192,7 @@ func (pm *PeerManager) checkPeers() {
 				go netServerInstance.netCore.ping(p.ID, nil)
 			}
 			if !p.verifyResult && p.sessionID > 0 {
-				pongMsg := MsgPong{Version: 0, VerifyResult: p.verifyResult}
+				pongMsg := MsgPong{Version: Version, VerifyResult: p.verifyResult}
 
 				packet, _, err := netServerInstance.netCore.encodePacket(MessageType_MessagePong, &pongMsg)
 				if err != nil {
@@ -271,7 +283,9 @@ func (pm *PeerManager) addPeer(netID uint64, peer *Peer) bool {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	if peer.IP != nil && len(peer.IP.String()) > 0 && !pm.peerIPSet.Add(peer.IP.String()) {
+	if staticPeers.isTrusted(netID) {
+		peer.trusted = true
+	} else if peer.IP != nil && len(peer.IP.String()) > 0 && !pm.peerIPSet.Add(peer.IP.String()) {
 		Logger.Debugf("addPeer failed, peer in same IP exceed limit size !Max size:%v, ip:%v", pm.peerIPSet.Limit, peer.IP.String())
 		return false
 	}
@@ -0,0 +1,391 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+// PeerEvent is the behaviour of a peer reported to the reputation
+type PeerEvent int
+
+const (
+	PeerEventUseful     PeerEvent = iota // The peer answered a request
+	PeerEventTimeout                     // A request to the peer timeout
+	PeerEventMisbehave                   // The peer sent invalid blocks, transactions or states
+	PeerEventLowHitRate                  // The transactions responded missed most of the hashes requested
+	PeerEventBadMessage                  // The peer sent a message failed to decode or decrypt
+)
+
+var peerEventScores = map[PeerEvent]float64{
+	PeerEventUseful:     1,
+	PeerEventTimeout:    -5,
+	PeerEventMisbehave:  -25,
+	PeerEventLowHitRate: -10,
+	PeerEventBadMessage: -50,
+}
+
+const (
+	configPeerDB = "db_peers"
+
+	peerScoreMax          = 100
+	peerScoreBanThreshold = -100
+	peerScoreHalfLife     = 30 * time.Minute // The scores decay towards 0 by half in the period
+	peerBanBase           = 10 * time.Minute // The first ban lasts the base, doubled on each ban after
+	peerBanMax            = 24 * time.Hour
+	maxPeerScores         = 4096
+)
+
+// peerBan is the ban of a peer persisted. The ban count is kept after the ban expired,
+// so that the peers misbehaving again are banned longer
+type peerBan struct {
+	Until     int64  `json:"until"` // Unix time the ban expires at, ignored if permanent
+	Permanent bool   `json:"permanent"`
+	Count     int    `json:"count"`
+	Reason    string `json:"reason"`
+}
+
+func (b *peerBan) active(now time.Time) bool {
+	return b.Permanent || now.Unix() < b.Until
+}
+
+type peerScore struct {
+	value   float64
+	updated time.Time
+}
+
+// decay returns the score decayed to the time
+func (s *peerScore) decay(now time.Time) float64 {
+	elapsed := now.Sub(s.updated)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Pow(0.5, float64(elapsed)/float64(peerScoreHalfLife))
+}
+
+// PeerScore is the reputation of a peer
+type PeerScore struct {
+	ID          string
+	Score       float64
+	Banned      bool
+	Permanent   bool
+	BannedUntil time.Time
+	BanCount    int
+	Reason      string
+}
+
+// banStore persists the bans keyed by the node id
+type banStore interface {
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	NewIterator() iterator.Iterator
+}
+
+// PeerReputation scores the peers on the events reported by the network and the chain sync, and bans
+// the peers whose score dropped below the threshold. The bans are enforced when connecting, and
+// persisted across restarts
+type PeerReputation struct {
+	scores map[string]*peerScore
+	bans   map[string]*peerBan
+	netIDs map[uint64]string // Net id -> node id of the bans
+	store  banStore
+	lock   sync.Mutex
+
+	onBan func(id NodeID) // Called on ban without the lock held, disconnecting the peer
+}
+
+var reputation *PeerReputation
+
+func newPeerReputation(store banStore) *PeerReputation {
+	r := &PeerReputation{
+		scores: make(map[string]*peerScore),
+		bans:   make(map[string]*peerBan),
+		netIDs: make(map[uint64]string),
+		store:  store,
+	}
+	if store == nil {
+		return r
+	}
+	iter := store.NewIterator()
+	defer iter.Release()
+	for iter.Next() {
+		ban := &peerBan{}
+		if err := json.Unmarshal(iter.Value(), ban); err != nil {
+			Logger.Errorf("load peer ban %s error:%v", iter.Key(), err)
+			continue
+		}
+		id := string(iter.Key())
+		r.bans[id] = ban
+		if nid := NewNodeID(id); nid != nil {
+			r.netIDs[genNetID(*nid)] = id
+		}
+	}
+	return r
+}
+
+// initPeerReputation loads the bans from the peers database configured, or keeps them in memory only
+// if no config given
+func initPeerReputation(config *common.ConfManager) {
+	var store banStore
+	if config != nil {
+		file := (*config).GetString(configSection, configPeerDB, "d_peers")
+		ds, err := tasdb.NewDataSource(file, nil)
+		if err == nil {
+			store, err = ds.NewPrefixDatabase("ban")
+		}
+		if err != nil {
+			Logger.Errorf("open peers db %v error:%v, the bans are not persisted", file, err)
+			store = nil
+		}
+	}
+	reputation = newPeerReputation(store)
+	reputation.onBan = func(id NodeID) {
+		if netCore != nil {
+			netCore.peerManager.disconnect(id)
+		}
+	}
+}
+
+// normalizeID returns the node id in the form of the message source
+func normalizeID(id string) (string, error) {
+	if !common.ValidateAddress(common.AddrPrefix + strings.TrimPrefix(id, common.AddrPrefix)) {
+		return "", fmt.Errorf("invalid node id %v", id)
+	}
+	return NewNodeID(id).GetHexString(), nil
+}
+
+// report adds the score of the event to the peer, and bans the peer if the score drops below the threshold
+func (r *PeerReputation) report(id string, event PeerEvent) {
+	delta, ok := peerEventScores[event]
+	if !ok || id == "" {
+		return
+	}
+	now := time.Now()
+	r.lock.Lock()
+	s := r.scores[id]
+	if s == nil {
+		if len(r.scores) >= maxPeerScores {
+			r.pruneScores(now)
+		}
+		s = &peerScore{}
+		r.scores[id] = s
+	}
+	s.value = math.Min(s.decay(now)+delta, peerScoreMax)
+	s.updated = now
+
+	banned := false
+	if s.value <= peerScoreBanThreshold {
+		if ban := r.bans[id]; ban == nil || !ban.active(now) {
+			r.banTemporary(id, now, fmt.Sprintf("score %.0f", s.value))
+			banned = true
+		}
+		// Start over after the ban, the next ban will last longer
+		s.value = 0
+	}
+	r.lock.Unlock()
+
+	if banned {
+		r.disconnect(id)
+	}
+}
+
+// pruneScores removes the scores decayed to nearly 0, called with the lock held
+func (r *PeerReputation) pruneScores(now time.Time) {
+	for id, s := range r.scores {
+		if math.Abs(s.decay(now)) < 1 {
+			delete(r.scores, id)
+		}
+	}
+}
+
+// banTemporary bans the peer for the duration doubled on each ban, called with the lock held
+func (r *PeerReputation) banTemporary(id string, now time.Time, reason string) {
+	count := 1
+	if ban := r.bans[id]; ban != nil {
+		count = ban.Count + 1
+	}
+	duration := peerBanMax
+	if count <= 16 {
+		duration = peerBanBase * time.Duration(1<<uint(count-1))
+	}
+	if duration > peerBanMax {
+		duration = peerBanMax
+	}
+	r.setBan(id, &peerBan{Until: now.Add(duration).Unix(), Count: count, Reason: reason})
+	Logger.Infof("ban peer %v for %v, ban count %v, reason:%v", id, duration, count, reason)
+}
+
+// setBan records and persists the ban, called with the lock held
+func (r *PeerReputation) setBan(id string, ban *peerBan) {
+	r.bans[id] = ban
+	if nid := NewNodeID(id); nid != nil {
+		r.netIDs[genNetID(*nid)] = id
+	}
+	if r.store == nil {
+		return
+	}
+	data, err := json.Marshal(ban)
+	if err == nil {
+		err = r.store.Put([]byte(id), data)
+	}
+	if err != nil {
+		Logger.Errorf("save peer ban %v error:%v", id, err)
+	}
+}
+
+func (r *PeerReputation) disconnect(id string) {
+	if r.onBan == nil {
+		return
+	}
+	if nid := NewNodeID(id); nid != nil {
+		r.onBan(*nid)
+	}
+}
+
+// isBanned returns whether the peer is banned now
+func (r *PeerReputation) isBanned(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ban := r.bans[id]
+	return ban != nil && ban.active(time.Now())
+}
+
+// isNetIDBanned returns whether the peer of the net id is banned now
+func (r *PeerReputation) isNetIDBanned(netID uint64) bool {
+	r.lock.Lock()
+	id, ok := r.netIDs[netID]
+	r.lock.Unlock()
+	return ok && r.isBanned(id)
+}
+
+// ban bans the peer for the duration, or permanently if the duration is 0
+func (r *PeerReputation) ban(id string, duration time.Duration, reason string) {
+	r.lock.Lock()
+	ban := &peerBan{Permanent: duration <= 0, Until: time.Now().Add(duration).Unix(), Count: 1, Reason: reason}
+	if old := r.bans[id]; old != nil {
+		ban.Count = old.Count + 1
+	}
+	r.setBan(id, ban)
+	r.lock.Unlock()
+	Logger.Infof("ban peer %v for %v, permanent %v, reason:%v", id, duration, ban.Permanent, reason)
+	r.disconnect(id)
+}
+
+// unban removes the ban and the score of the peer
+func (r *PeerReputation) unban(id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.bans, id)
+	delete(r.scores, id)
+	if nid := NewNodeID(id); nid != nil {
+		delete(r.netIDs, genNetID(*nid))
+	}
+	if r.store != nil {
+		return r.store.Delete([]byte(id))
+	}
+	return nil
+}
+
+// list returns the scores of the peers tracked and the peers banned, sorted by the score
+func (r *PeerReputation) list() []*PeerScore {
+	now := time.Now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	result := make(map[string]*PeerScore)
+	for id, s := range r.scores {
+		result[id] = &PeerScore{ID: id, Score: s.decay(now)}
+	}
+	for id, ban := range r.bans {
+		ps := result[id]
+		if ps == nil {
+			ps = &PeerScore{ID: id}
+			result[id] = ps
+		}
+		ps.Banned = ban.active(now)
+		ps.Permanent = ban.Permanent
+		ps.BanCount = ban.Count
+		ps.Reason = ban.Reason
+		if !ban.Permanent {
+			ps.BannedUntil = time.Unix(ban.Until, 0)
+		}
+	}
+	scores := make([]*PeerScore, 0, len(result))
+	for _, ps := range result {
+		scores = append(scores, ps)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
+
+// ReportPeer reports the behaviour of the peer to the reputation. It does nothing if the network not initialized
+func ReportPeer(id string, event PeerEvent) {
+	if reputation != nil {
+		reputation.report(id, event)
+	}
+}
+
+// IsPeerBanned returns whether the peer is banned
+func IsPeerBanned(id string) bool {
+	return reputation != nil && reputation.isBanned(id)
+}
+
+// PeerScores returns the reputation of the peers tracked
+func PeerScores() ([]*PeerScore, error) {
+	if reputation == nil {
+		return nil, fmt.Errorf("network not initialized")
+	}
+	return reputation.list(), nil
+}
+
+// BanPeer bans the peer for the duration, or permanently if the duration is 0, and disconnects it
+func BanPeer(id string, duration time.Duration, reason string) error {
+	if reputation == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	nid, err := normalizeID(id)
+	if err != nil {
+		return err
+	}
+	reputation.ban(nid, duration, reason)
+	return nil
+}
+
+// UnbanPeer removes the ban of the peer
+func UnbanPeer(id string) error {
+	if reputation == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	nid, err := normalizeID(id)
+	if err != nil {
+		return err
+	}
+	return reputation.unban(nid)
+}
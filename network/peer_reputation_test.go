@@ -0,0 +1,174 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/log"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+func newTestNodeID() string {
+	sk, _ := common.GenerateKey("")
+	pk := sk.GetPubKey()
+	return NewNodeID(pk.GetAddress().AddrPrefixString()).GetHexString()
+}
+
+func TestPeerReputationBan(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	r := newPeerReputation(nil)
+	disconnected := make([]NodeID, 0)
+	r.onBan = func(id NodeID) {
+		disconnected = append(disconnected, id)
+	}
+	id := newTestNodeID()
+
+	for i := 0; i < 3; i++ {
+		r.report(id, PeerEventMisbehave)
+	}
+	if r.isBanned(id) {
+		t.Fatalf("banned before the score drops below the threshold")
+	}
+	r.report(id, PeerEventBadMessage)
+	if !r.isBanned(id) || !r.isNetIDBanned(genNetID(*NewNodeID(id))) {
+		t.Fatalf("expect banned")
+	}
+	if len(disconnected) != 1 || disconnected[0].GetHexString() != id {
+		t.Fatalf("expect the peer disconnected on ban")
+	}
+	first := r.bans[id].Until
+
+	// The ban expired, misbehaving again bans longer
+	r.bans[id].Until = time.Now().Unix() - 1
+	if r.isBanned(id) {
+		t.Fatalf("expect the ban expired")
+	}
+	for i := 0; i < 3; i++ {
+		r.report(id, PeerEventMisbehave)
+	}
+	r.report(id, PeerEventBadMessage)
+	ban := r.bans[id]
+	if !r.isBanned(id) || ban.Count != 2 || ban.Until-time.Now().Unix() <= first-time.Now().Unix() {
+		t.Fatalf("expect banned longer, count %v", ban.Count)
+	}
+
+	if err := r.unban(id); err != nil || r.isBanned(id) {
+		t.Fatalf("expect unbanned, err:%v", err)
+	}
+	if len(r.list()) != 0 {
+		t.Fatalf("expect the score removed on unban")
+	}
+}
+
+func TestPeerReputationDecay(t *testing.T) {
+	r := newPeerReputation(nil)
+	id := newTestNodeID()
+	r.report(id, PeerEventMisbehave)
+	r.scores[id].updated = time.Now().Add(-peerScoreHalfLife)
+	scores := r.list()
+	if len(scores) != 1 || math.Abs(scores[0].Score+12.5) > 0.1 {
+		t.Fatalf("expect the score decayed by half, got %v", scores[0].Score)
+	}
+
+	for i := 0; i < 200; i++ {
+		r.report(id, PeerEventUseful)
+	}
+	if score := r.list()[0].Score; score > peerScoreMax {
+		t.Fatalf("score %v exceeds the max", score)
+	}
+}
+
+func TestPeerReputationPersist(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	dir, err := ioutil.TempDir("", "peer_reputation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	open := func() *tasdb.PrefixedDatabase {
+		ds, err := tasdb.NewDataSource(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, _ := ds.NewPrefixDatabase("ban")
+		return db
+	}
+
+	tempID, permanentID := newTestNodeID(), newTestNodeID()
+	db := open()
+	r := newPeerReputation(db)
+	r.ban(tempID, time.Hour, "test")
+	r.ban(permanentID, 0, "test")
+	db.Close()
+
+	db = open()
+	r = newPeerReputation(db)
+	if !r.isBanned(tempID) || !r.isBanned(permanentID) {
+		t.Fatalf("expect the bans loaded")
+	}
+	if !r.isNetIDBanned(genNetID(*NewNodeID(permanentID))) {
+		t.Fatalf("expect the net id of the ban loaded")
+	}
+	scores := r.list()
+	if len(scores) != 2 {
+		t.Fatalf("expect 2 peers listed, got %v", len(scores))
+	}
+	for _, s := range scores {
+		if s.ID == permanentID && (!s.Permanent || !s.BannedUntil.IsZero()) {
+			t.Fatalf("expect the ban permanent")
+		}
+	}
+	if err := r.unban(tempID); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db = open()
+	defer db.Close()
+	r = newPeerReputation(db)
+	if r.isBanned(tempID) || !r.isBanned(permanentID) {
+		t.Fatalf("expect only the permanent ban loaded")
+	}
+}
+
+func TestBanPeerInvalidID(t *testing.T) {
+	old := reputation
+	defer func() {
+		reputation = old
+	}()
+	reputation = newPeerReputation(nil)
+	if err := BanPeer("zv1234", 0, ""); err == nil {
+		t.Fatalf("expect the invalid id rejected")
+	}
+	id := newTestNodeID()
+	// Accepted without the prefix
+	if err := BanPeer(id[len(common.AddrPrefix):], time.Minute, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !IsPeerBanned(id) {
+		t.Fatalf("expect banned")
+	}
+}
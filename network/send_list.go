@@ -135,12 +135,19 @@ func (sendList *SendList) autoSend(peer *Peer) {
 			}
 
 			buf := e.Value.(*bytes.Buffer)
-			Logger.Debugf("P2PSend  net id:%v session:%v size:%v ", peer.ID.GetHexString(), peer.sessionID, buf.Len())
-			P2PSend(peer.sessionID, buf.Bytes())
+			item.list.Remove(e)
+			packet, ok := peer.sealPacket(buf.Bytes())
+			if !ok {
+				netCore.bufferPool.freeBuffer(buf)
+				Logger.Debugf("session not secured, drop data! net id:%v session:%v", peer.ID.GetHexString(), peer.sessionID)
+				continue
+			}
+			Logger.Debugf("P2PSend  net id:%v session:%v size:%v ", peer.ID.GetHexString(), peer.sessionID, len(packet))
+			netCore.transport.send(peer.sessionID, packet)
 
+			// The packets not sealed are sent in the buffer itself, free it after sent
 			netCore.bufferPool.freeBuffer(buf)
 
-			item.list.Remove(e)
 			sendList.pendingSend++
 
 			item.curQuota++
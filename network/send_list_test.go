@@ -0,0 +1,69 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"testing"
+)
+
+// reuseTransport overwrites the buffers cached in the pool on each send, as the other goroutines
+// getting the buffers from the pool would do, and records the packets sent
+type reuseTransport struct {
+	p2pTransport
+	sent [][]byte
+}
+
+func (t *reuseTransport) send(session uint32, data []byte) {
+	item := netCore.bufferPool.getPoolItem(len(data))
+	bufs := make([]*bytes.Buffer, 0)
+	for i := item.buffers.Len() + 1; i > 0; i-- {
+		buf := netCore.bufferPool.getBuffer(len(data))
+		buf.Write(bytes.Repeat([]byte{0xff}, len(data)))
+		bufs = append(bufs, buf)
+	}
+	t.sent = append(t.sent, append([]byte{}, data...))
+	for _, buf := range bufs {
+		netCore.bufferPool.freeBuffer(buf)
+	}
+}
+
+func TestSendListBufferReused(t *testing.T) {
+	if !InitTestNetwork() {
+		t.Fatalf("init network failed")
+	}
+	tr := &reuseTransport{p2pTransport: netCore.transport}
+	old := netCore.transport
+	netCore.transport = tr
+	defer func() {
+		netCore.transport = old
+	}()
+
+	p := newPeer(netCore.ID, 1)
+	for i := 0; i < 3; i++ {
+		packet, _, err := netCore.encodePacket(MessageType_MessagePong, &MsgPong{Version: int32(i), VerifyResult: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := append([]byte{}, packet.Bytes()...)
+		p.write(packet, P2PMessageCodeBase+uint32(MessageType_MessagePong))
+		netCore.bufferPool.freeBuffer(packet)
+
+		if len(tr.sent) != i+1 || !bytes.Equal(tr.sent[i], expect) {
+			t.Fatalf("packet %v sent overwritten by the buffer reused", i)
+		}
+	}
+}
@@ -178,45 +178,59 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 	begin := time.Now()
 	code := message.Code
 
+	dispatchMessage(s.consensusHandler, message, from)
+
+	if time.Since(begin) > 300*time.Millisecond {
+		Logger.Infof("handle message cost time:%v,hash:%s,code:%d", time.Since(begin), message.Hash(), code)
+	}
+}
+
+// dispatchMessage passes the consensus messages to the handler, and publishes the chain messages to the bus
+func dispatchMessage(consensusHandler MsgHandler, message *Message, from string) {
+	code := message.Code
 	if code < 10000 {
-		err := s.consensusHandler.Handle(from, *message)
+		err := consensusHandler.Handle(from, *message)
 		if err != nil {
 			Logger.Errorf("consensusHandler handle error:%s", err.Error())
 		}
-	} else {
-		topicID := ""
-		switch code {
-		case TxSyncNotify:
-			topicID = notify.TxSyncNotify
-		case TxSyncReq:
-			topicID = notify.TxSyncReq
-		case TxSyncResponse:
-			topicID = notify.TxSyncResponse
-		case BlockInfoNotifyMsg:
-			topicID = notify.BlockInfoNotify
-		case ReqBlock:
-			topicID = notify.BlockReq
-		case BlockResponseMsg:
-			topicID = notify.BlockResponse
-		case NewBlockMsg:
-			topicID = notify.NewBlock
-		case ForkFindAncestorResponse:
-			topicID = notify.ForkFindAncestorResponse
-		case ForkFindAncestorReq:
-			topicID = notify.ForkFindAncestorReq
-		case ForkChainSliceReq:
-			topicID = notify.ForkChainSliceReq
-		case ForkChainSliceResponse:
-			topicID = notify.ForkChainSliceResponse
-		}
-		if topicID != "" {
-			msg := newNotifyMessage(message, from)
-			notify.BUS.PublishWithRecover(topicID, msg)
-		}
+		return
 	}
-
-	if time.Since(begin) > 300*time.Millisecond {
-		Logger.Infof("handle message cost time:%v,hash:%s,code:%d", time.Since(begin), message.Hash(), code)
+	topicID := ""
+	switch code {
+	case TxSyncNotify:
+		topicID = notify.TxSyncNotify
+	case TxSyncReq:
+		topicID = notify.TxSyncReq
+	case TxSyncResponse:
+		topicID = notify.TxSyncResponse
+	case BlockInfoNotifyMsg:
+		topicID = notify.BlockInfoNotify
+	case ReqBlock:
+		topicID = notify.BlockReq
+	case BlockResponseMsg:
+		topicID = notify.BlockResponse
+	case NewBlockMsg:
+		topicID = notify.NewBlock
+	case ForkFindAncestorResponse:
+		topicID = notify.ForkFindAncestorResponse
+	case ForkFindAncestorReq:
+		topicID = notify.ForkFindAncestorReq
+	case ForkChainSliceReq:
+		topicID = notify.ForkChainSliceReq
+	case ForkChainSliceResponse:
+		topicID = notify.ForkChainSliceResponse
+	case SnapshotCheckpointReq:
+		topicID = notify.SnapshotCheckpointReq
+	case SnapshotCheckpointResponse:
+		topicID = notify.SnapshotCheckpointResponse
+	case StateNodesReq:
+		topicID = notify.StateNodesReq
+	case StateNodesResponse:
+		topicID = notify.StateNodesResponse
+	}
+	if topicID != "" {
+		msg := newNotifyMessage(message, from)
+		notify.BUS.PublishWithRecover(topicID, msg)
 	}
 }
 
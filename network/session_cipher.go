@@ -0,0 +1,160 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/common/ecies"
+	"github.com/zvchain/zvchain/common/secp256k1"
+)
+
+const (
+	frameSeqSize     = 8  // Size of the sequence number heading the frame sealed
+	replayWindowSize = 64 // Count of the latest sequence numbers tracked for the frames out of order
+)
+
+// sessionCipher encrypts the frames of a session in one direction with AES-GCM. Each frame carries its sequence
+// number in plain as the nonce, so a frame lost or out of order doesn't affect the others, and the replayed ones
+// are rejected by the replay window
+type sessionCipher struct {
+	aead   cipher.AEAD
+	count  uint64
+	window replayWindow
+}
+
+// replayWindow tracks the sequence numbers opened within the window size below the highest one
+type replayWindow struct {
+	top    uint64
+	bitmap uint64 // Bit i set if top-i has been opened
+	init   bool
+}
+
+func (w *replayWindow) check(seq uint64) bool {
+	if !w.init || seq > w.top {
+		return true
+	}
+	diff := w.top - seq
+	return diff < replayWindowSize && w.bitmap&(1<<diff) == 0
+}
+
+func (w *replayWindow) update(seq uint64) {
+	switch {
+	case !w.init:
+		w.top, w.bitmap, w.init = seq, 1, true
+	case seq > w.top:
+		if shift := seq - w.top; shift < replayWindowSize {
+			w.bitmap = w.bitmap<<shift | 1
+		} else {
+			w.bitmap = 1
+		}
+		w.top = seq
+	default:
+		w.bitmap |= 1 << (w.top - seq)
+	}
+}
+
+func newSessionCipher(key []byte) (*sessionCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionCipher{aead: aead}, nil
+}
+
+func (sc *sessionCipher) nonce(seq uint64) []byte {
+	nonce := make([]byte, sc.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-frameSeqSize:], seq)
+	return nonce
+}
+
+// seal encrypts the frame in the form of sequence number and the cipher text
+func (sc *sessionCipher) seal(plain []byte) []byte {
+	seq := sc.count
+	sc.count++
+	frame := make([]byte, frameSeqSize, frameSeqSize+len(plain)+sc.aead.Overhead())
+	binary.BigEndian.PutUint64(frame, seq)
+	return sc.aead.Seal(frame, sc.nonce(seq), plain, frame[:frameSeqSize])
+}
+
+func (sc *sessionCipher) open(frame []byte) ([]byte, error) {
+	if len(frame) < frameSeqSize+sc.aead.Overhead() {
+		return nil, fmt.Errorf("frame too short")
+	}
+	seq := binary.BigEndian.Uint64(frame)
+	if !sc.window.check(seq) {
+		return nil, fmt.Errorf("frame %v replayed or too old", seq)
+	}
+	plain, err := sc.aead.Open(nil, sc.nonce(seq), frame[frameSeqSize:], frame[:frameSeqSize])
+	if err != nil {
+		return nil, err
+	}
+	sc.window.update(seq)
+	return plain, nil
+}
+
+// newSessionCiphers derives the ciphers of both directions from the ECDH of the ephemeral keys exchanged in
+// the ping. Each direction has its own key, bound to the ephemeral public keys of both sides
+func newSessionCiphers(ephemeralKey *common.PrivateKey, remoteEphemeralPK []byte) (send, recv *sessionCipher, err error) {
+	if ephemeralKey == nil {
+		return nil, nil, fmt.Errorf("ephemeral key is nil")
+	}
+	curve := secp256k1.S256()
+	x, y := elliptic.Unmarshal(curve, remoteEphemeralPK)
+	if x == nil {
+		return nil, nil, fmt.Errorf("invalid ephemeral public key")
+	}
+	remote := &ecies.PublicKey{X: x, Y: y, Curve: curve}
+	shared, err := ecies.ImportECDSA(&ephemeralKey.PrivKey).GenerateShared(remote, 16, 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := ephemeralKey.GetPubKey().Bytes()
+
+	deriveKey := func(from, to []byte) []byte {
+		buffer := bytes.Buffer{}
+		buffer.Write(shared)
+		buffer.Write(from)
+		buffer.Write(to)
+		return common.Sha256(buffer.Bytes())
+	}
+	if send, err = newSessionCipher(deriveKey(local, remoteEphemeralPK)); err != nil {
+		return nil, nil, err
+	}
+	if recv, err = newSessionCipher(deriveKey(remoteEphemeralPK, local)); err != nil {
+		return nil, nil, err
+	}
+	return send, recv, nil
+}
+
+// encodeEncryptedPacket wraps the frame sealed in a packet of MessageEncrypted
+func encodeEncryptedPacket(sealed []byte) []byte {
+	packet := make([]byte, PacketHeadSize+len(sealed))
+	binary.BigEndian.PutUint32(packet, uint32(MessageType_MessageEncrypted))
+	binary.BigEndian.PutUint32(packet[PacketTypeSize:], uint32(len(sealed)))
+	copy(packet[PacketHeadSize:], sealed)
+	return packet
+}
@@ -0,0 +1,195 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+func TestSessionCiphers(t *testing.T) {
+	a, _ := common.GenerateKey("")
+	b, _ := common.GenerateKey("")
+	aSend, aRecv, err := newSessionCiphers(&a, b.GetPubKey().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bSend, bRecv, err := newSessionCiphers(&b, a.GetPubKey().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		msg := []byte{byte(i), 1, 2, 3}
+		plain, err := bRecv.open(aSend.seal(msg))
+		if err != nil || !bytes.Equal(plain, msg) {
+			t.Fatalf("open frame %v error:%v", i, err)
+		}
+	}
+	sealed := bSend.seal([]byte("reply"))
+	if plain, err := aRecv.open(sealed); err != nil || string(plain) != "reply" {
+		t.Fatalf("open reply error:%v", err)
+	}
+	// Replayed
+	if _, err := aRecv.open(sealed); err == nil {
+		t.Fatalf("expect the frame replayed rejected")
+	}
+	// Tampered
+	sealed = aSend.seal([]byte("data"))
+	tampered := append([]byte{}, sealed...)
+	tampered[0] ^= 1
+	if _, err := bRecv.open(tampered); err == nil {
+		t.Fatalf("expect the frame tampered rejected")
+	}
+	// The frame tampered doesn't affect the genuine one
+	if _, err := bRecv.open(sealed); err != nil {
+		t.Fatalf("open frame error:%v", err)
+	}
+
+	// Lost and out of order
+	frames := make([][]byte, replayWindowSize+2)
+	for i := range frames {
+		frames[i] = aSend.seal([]byte{byte(i)})
+	}
+	for _, i := range []int{1, len(frames) - 1, 3} {
+		if plain, err := bRecv.open(frames[i]); err != nil || plain[0] != byte(i) {
+			t.Fatalf("open frame %v error:%v", i, err)
+		}
+	}
+	if _, err := bRecv.open(frames[3]); err == nil {
+		t.Fatalf("expect the frame replayed out of order rejected")
+	}
+	// Out of the window
+	if _, err := bRecv.open(frames[0]); err == nil {
+		t.Fatalf("expect the frame too old rejected")
+	}
+
+	if _, _, err := newSessionCiphers(&a, []byte{4, 1, 2}); err == nil {
+		t.Fatalf("expect the invalid ephemeral key rejected")
+	}
+}
+
+func TestPeerSecureSession(t *testing.T) {
+	if !InitTestNetwork() {
+		t.Fatalf("init network failed")
+	}
+	sk, _ := common.GenerateKey("")
+	pk := sk.GetPubKey()
+	remoteID := NewNodeID(pk.GetAddress().AddrPrefixString())
+	remoteAuth := genPeerAuthContext(pk.Hex(), sk.Hex(), &netCore.ID)
+
+	p := newPeer(NodeID{}, 1)
+	p.chainID = netCore.chainID
+
+	// The session claiming another net id
+	p.netID = genNetID(netCore.ID)
+	if p.verify(remoteAuth) {
+		t.Fatalf("expect verify failed with the net id mismatch")
+	}
+
+	p.netID = genNetID(*remoteID)
+	if !p.verify(remoteAuth) || p.ID != *remoteID {
+		t.Fatalf("verify failed")
+	}
+	remoteSend, remoteRecv, err := newSessionCiphers(remoteAuth.ephemeralKey, p.authContext.EphemeralPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet, _, err := netCore.encodeDataPacket([]byte("hello"), DataType_DataNormal, 1, "", nil, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not sent until the remote verified us
+	if _, ok := p.sealPacket(packet.Bytes()); ok {
+		t.Fatalf("expect the data dropped before authenticated")
+	}
+	p.setRemoteVerifyResult(true)
+	sealed, ok := p.sealPacket(packet.Bytes())
+	if !ok {
+		t.Fatalf("expect the data sealed")
+	}
+	plain, err := remoteRecv.open(sealed[PacketHeadSize:])
+	if err != nil || !bytes.Equal(plain, packet.Bytes()) {
+		t.Fatalf("remote open error:%v", err)
+	}
+
+	// The data received encrypted is decoded
+	p.addRecvData(encodeEncryptedPacket(remoteSend.seal(packet.Bytes())))
+	msgType, _, msg, _, err := netCore.decodeMessage(p)
+	if err != nil || msgType != MessageType_MessageData || string(msg.(*MsgData).Data) != "hello" {
+		t.Fatalf("decode encrypted message error:%v, type:%v", err, msgType)
+	}
+
+	// The data received plain is rejected
+	p.addRecvData(packet.Bytes())
+	if _, _, _, _, err = netCore.decodeMessage(p); err != errPlainData {
+		t.Fatalf("expect the plain data rejected, error:%v", err)
+	}
+
+	// The bad frame is dropped and the session is kept
+	p.addRecvData(encodeEncryptedPacket([]byte("bad frame of the session")))
+	if _, _, _, _, err = netCore.decodeMessage(p); err != errDropped {
+		t.Fatalf("expect the bad frame dropped, error:%v", err)
+	}
+	p.addRecvData(encodeEncryptedPacket(remoteSend.seal(packet.Bytes())))
+	if _, _, msg, _, err = netCore.decodeMessage(p); err != nil || string(msg.(*MsgData).Data) != "hello" {
+		t.Fatalf("decode encrypted message after dropped error:%v", err)
+	}
+}
+
+func TestPeerPlainSession(t *testing.T) {
+	if !InitTestNetwork() {
+		t.Fatalf("init network failed")
+	}
+	p := newPeer(NodeID{}, 1)
+	p.isAuthSucceed = true
+	packet, _, err := netCore.encodeDataPacket([]byte("hello"), DataType_DataNormal, 1, "", nil, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The peer of the old version exchanges the data in plain
+	p.setVersion(1)
+	if sent, ok := p.sealPacket(packet.Bytes()); !ok || !bytes.Equal(sent, packet.Bytes()) {
+		t.Fatalf("expect the data sent in plain")
+	}
+	p.addRecvData(packet.Bytes())
+	if _, _, msg, _, err := netCore.decodeMessage(p); err != nil || string(msg.(*MsgData).Data) != "hello" {
+		t.Fatalf("decode plain message error:%v", err)
+	}
+
+	// Not downgraded once the new version reported
+	p.setVersion(Version)
+	p.setVersion(1)
+	if _, ok := p.sealPacket(packet.Bytes()); ok {
+		t.Fatalf("expect the data dropped without the cipher")
+	}
+	p.addRecvData(packet.Bytes())
+	if _, _, _, _, err = netCore.decodeMessage(p); err != errPlainData {
+		t.Fatalf("expect the plain data rejected, error:%v", err)
+	}
+
+	acceptPlainPeers = false
+	defer func() { acceptPlainPeers = true }()
+	p = newPeer(NodeID{}, 2)
+	p.setVersion(1)
+	if p.isPlain() {
+		t.Fatalf("expect the old version not accepted after the transition")
+	}
+}
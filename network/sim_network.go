@@ -0,0 +1,327 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// simInstance is returned by GetNetInstance instead of the p2p server if set by InitSim
+var simInstance Network
+
+// InitSim makes the node the network instance of the process, so that the modules calling GetNetInstance
+// send messages through the simulated network
+func InitSim(node *SimNode) {
+	simInstance = node
+}
+
+// dispatchHandler handles the messages as the p2p server does
+type dispatchHandler struct {
+	consensusHandler MsgHandler
+}
+
+// NewDispatchHandler returns the handler passing the consensus messages to the consensus handler and
+// publishing the chain messages to the bus as the p2p server does, for hosting a full node on the SimNetwork
+func NewDispatchHandler(consensusHandler MsgHandler) MsgHandler {
+	return &dispatchHandler{consensusHandler: consensusHandler}
+}
+
+func (h *dispatchHandler) Handle(sourceID string, msg Message) error {
+	dispatchMessage(h.consensusHandler, &msg, sourceID)
+	return nil
+}
+
+// SimNetwork is an in-memory network connecting the nodes in the same process, for the integration tests.
+// Each message is delivered to the handler of the target node in its own goroutine after the latency,
+// so the messages may arrive out of order as in the real network. Messages are dropped randomly at the
+// drop rate, and never delivered between the nodes in different partitions.
+// Only the network is simulated: core and consensus still keep process-wide singletons, so a process hosts
+// one full node at most, and the other nodes are the message handlers of the tests
+type SimNetwork struct {
+	nodes map[string]*SimNode
+
+	latency   time.Duration
+	jitter    time.Duration
+	dropRate  float64
+	partition map[string]int // Node id -> partition, nodes not in the map are in the partition 0
+
+	rand *rand.Rand
+	lock sync.RWMutex
+
+	pending   sync.WaitGroup
+	delivered uint64
+	dropped   uint64
+}
+
+// NewSimNetwork creates an empty network without latency and loss
+func NewSimNetwork() *SimNetwork {
+	return &SimNetwork{
+		nodes:     make(map[string]*SimNode),
+		partition: make(map[string]int),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetLatency sets the delay of each message, which is latency plus a random value in [0, jitter)
+func (sn *SimNetwork) SetLatency(latency, jitter time.Duration) {
+	sn.lock.Lock()
+	defer sn.lock.Unlock()
+	sn.latency, sn.jitter = latency, jitter
+}
+
+// SetDropRate sets the probability in [0, 1] of a message being lost
+func (sn *SimNetwork) SetDropRate(rate float64) {
+	sn.lock.Lock()
+	defer sn.lock.Unlock()
+	sn.dropRate = rate
+}
+
+// Partition splits the network, each group of the node ids becomes a partition.
+// The nodes not in any group are in another partition together
+func (sn *SimNetwork) Partition(groups ...[]string) {
+	sn.lock.Lock()
+	defer sn.lock.Unlock()
+	sn.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			sn.partition[id] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions
+func (sn *SimNetwork) Heal() {
+	sn.Partition()
+}
+
+// AddNode joins a node with the id to the network. The messages sent to the node are handled by the handler
+func (sn *SimNetwork) AddNode(id string, handler MsgHandler) *SimNode {
+	node := &SimNode{
+		ID:                id,
+		net:               sn,
+		handler:           handler,
+		groups:            make(map[string][]string),
+		maxBroadcastCount: maxBroadcastCount,
+	}
+	sn.lock.Lock()
+	sn.nodes[id] = node
+	sn.lock.Unlock()
+	return node
+}
+
+// RemoveNode disconnects the node from the network, the messages in flight to it are dropped
+func (sn *SimNetwork) RemoveNode(id string) {
+	sn.lock.Lock()
+	defer sn.lock.Unlock()
+	delete(sn.nodes, id)
+}
+
+// Wait blocks until all the messages in flight delivered or dropped
+func (sn *SimNetwork) Wait() {
+	sn.pending.Wait()
+}
+
+// Stats returns the count of the messages delivered and dropped
+func (sn *SimNetwork) Stats() (delivered, dropped uint64) {
+	return atomic.LoadUint64(&sn.delivered), atomic.LoadUint64(&sn.dropped)
+}
+
+// reachable returns the ids of the nodes the given node can reach, excluding itself
+func (sn *SimNetwork) reachable(from string) []string {
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	ids := make([]string, 0, len(sn.nodes))
+	for id := range sn.nodes {
+		if id != from && sn.partition[id] == sn.partition[from] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// send delivers the message to the node asynchronously, unless it's lost or unreachable
+func (sn *SimNetwork) send(from, to string, msg Message) {
+	sn.lock.Lock()
+	_, ok := sn.nodes[to]
+	lost := !ok || sn.partition[from] != sn.partition[to] || (sn.dropRate > 0 && sn.rand.Float64() < sn.dropRate)
+	delay := sn.latency
+	if sn.jitter > 0 {
+		delay += time.Duration(sn.rand.Int63n(int64(sn.jitter)))
+	}
+	sn.lock.Unlock()
+	if lost {
+		atomic.AddUint64(&sn.dropped, 1)
+		return
+	}
+
+	body := make([]byte, len(msg.Body))
+	copy(body, msg.Body)
+	msg.Body = body
+	sn.pending.Add(1)
+	go func() {
+		defer sn.pending.Done()
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		// The partitions may have changed or the node removed during the delay
+		sn.lock.RLock()
+		node := sn.nodes[to]
+		cut := sn.partition[from] != sn.partition[to]
+		sn.lock.RUnlock()
+		if node == nil || cut {
+			atomic.AddUint64(&sn.dropped, 1)
+			return
+		}
+		atomic.AddUint64(&sn.delivered, 1)
+		if err := node.handler.Handle(from, msg); err != nil && Logger != nil {
+			Logger.Errorf("sim node %v handle message %v error:%v", to, msg.Code, err)
+		}
+	}()
+}
+
+// SimNode is a node of the SimNetwork, implementing the Network interface
+type SimNode struct {
+	ID string
+
+	net       *SimNetwork
+	handler   MsgHandler
+	groups    map[string][]string
+	proposers []string
+	lock      sync.RWMutex
+
+	maxBroadcastCount int
+}
+
+func (n *SimNode) sendTo(ids []string, msg Message) {
+	for _, id := range ids {
+		if id == n.ID {
+			continue
+		}
+		n.net.send(n.ID, id, msg)
+	}
+}
+
+// Send sends the message to the node, the message sent to self is delivered without latency and loss
+func (n *SimNode) Send(id string, msg Message) error {
+	if id == n.ID {
+		n.net.pending.Add(1)
+		go func() {
+			defer n.net.pending.Done()
+			n.handler.Handle(n.ID, msg)
+		}()
+		return nil
+	}
+	n.net.send(n.ID, id, msg)
+	return nil
+}
+
+// SpreadAmongGroup sends the message to the other members of the group built, or to the proposers
+// if it's the full node virtual group
+func (n *SimNode) SpreadAmongGroup(groupID string, msg Message) error {
+	n.lock.RLock()
+	members, ok := n.groups[groupID]
+	if groupID == FullNodeVirtualGroupID {
+		members, ok = n.proposers, true
+	}
+	n.lock.RUnlock()
+	if !ok {
+		return fmt.Errorf("group %v not built", groupID)
+	}
+	n.sendTo(members, msg)
+	return nil
+}
+
+// SpreadToGroup sends the message to the group members given, or the members of the group if built
+func (n *SimNode) SpreadToGroup(groupID string, groupMembers []string, msg Message, digest MsgDigest) error {
+	n.lock.RLock()
+	if members, ok := n.groups[groupID]; ok {
+		groupMembers = members
+	}
+	n.lock.RUnlock()
+	n.sendTo(groupMembers, msg)
+	return nil
+}
+
+// TransmitToNeighbor sends the message to the random nodes reachable not in the blacklist
+func (n *SimNode) TransmitToNeighbor(msg Message, blacklist []string) error {
+	excluded := make(map[string]struct{}, len(blacklist))
+	for _, id := range blacklist {
+		excluded[id] = struct{}{}
+	}
+	ids := make([]string, 0)
+	for _, id := range n.net.reachable(n.ID) {
+		if _, ok := excluded[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+	if len(ids) > n.maxBroadcastCount {
+		ids = ids[:n.maxBroadcastCount]
+	}
+	n.sendTo(ids, msg)
+	return nil
+}
+
+// Broadcast sends the message to all the other nodes reachable
+func (n *SimNode) Broadcast(msg Message) error {
+	n.sendTo(n.net.reachable(n.ID), msg)
+	return nil
+}
+
+// ConnInfo returns the nodes reachable
+func (n *SimNode) ConnInfo() []Conn {
+	ids := n.net.reachable(n.ID)
+	conns := make([]Conn, 0, len(ids))
+	for _, id := range ids {
+		conns = append(conns, Conn{ID: id})
+	}
+	return conns
+}
+
+func (n *SimNode) BuildGroupNet(groupID string, members []string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.groups[groupID] = append([]string{}, members...)
+}
+
+func (n *SimNode) DissolveGroupNet(groupID string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	delete(n.groups, groupID)
+}
+
+func (n *SimNode) BuildProposerGroupNet(proposers []*Proposer) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.proposers = make([]string, 0, len(proposers))
+	for _, p := range proposers {
+		n.proposers = append(n.proposers, p.ID.GetHexString())
+	}
+}
+
+func (n *SimNode) AddProposers(proposers []*Proposer) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, p := range proposers {
+		n.proposers = append(n.proposers, p.ID.GetHexString())
+	}
+}
@@ -0,0 +1,172 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHandler records the messages received by a sim node
+type recordHandler struct {
+	received []string
+	lock     sync.Mutex
+}
+
+func (h *recordHandler) Handle(sourceID string, msg Message) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.received = append(h.received, fmt.Sprintf("%v:%v", sourceID, msg.Code))
+	return nil
+}
+
+func (h *recordHandler) count() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.received)
+}
+
+func newSimTestNetwork(n int) (*SimNetwork, []*SimNode, []*recordHandler) {
+	sn := NewSimNetwork()
+	nodes := make([]*SimNode, n)
+	handlers := make([]*recordHandler, n)
+	for i := range nodes {
+		handlers[i] = &recordHandler{}
+		nodes[i] = sn.AddNode(fmt.Sprintf("node%d", i), handlers[i])
+	}
+	return sn, nodes, handlers
+}
+
+func checkReceived(t *testing.T, handlers []*recordHandler, want ...int) {
+	t.Helper()
+	for i, h := range handlers {
+		if h.count() != want[i] {
+			t.Fatalf("node%d received %v messages, want %v", i, h.count(), want[i])
+		}
+	}
+}
+
+func TestSimNetwork_Spread(t *testing.T) {
+	sn, nodes, handlers := newSimTestNetwork(4)
+	msg := Message{Code: CastVerifyMsg, Body: []byte("proposal")}
+
+	if err := nodes[0].SpreadAmongGroup("g1", msg); err == nil {
+		t.Fatalf("spread to the group not built")
+	}
+	for _, n := range nodes[:3] {
+		n.BuildGroupNet("g1", []string{"node0", "node1", "node2"})
+	}
+	nodes[0].SpreadAmongGroup("g1", msg)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 1, 1, 0)
+
+	// Spread from the outside of the group with the members given
+	nodes[3].SpreadToGroup("g1", []string{"node1", "node2"}, msg, nil)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 2, 2, 0)
+
+	nodes[1].Send("node3", msg)
+	nodes[1].Send("node1", msg)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 3, 2, 1)
+
+	nodes[2].Broadcast(msg)
+	sn.Wait()
+	checkReceived(t, handlers, 1, 4, 2, 2)
+
+	nodes[2].TransmitToNeighbor(msg, []string{"node0", "node1"})
+	sn.Wait()
+	checkReceived(t, handlers, 1, 4, 2, 3)
+
+	nodes[0].BuildProposerGroupNet([]*Proposer{})
+	nodes[0].AddProposers([]*Proposer{{ID: *NewNodeID("0x01")}})
+	if len(nodes[0].proposers) != 1 || nodes[0].proposers[0] != NewNodeID("0x01").GetHexString() {
+		t.Fatalf("unexpected proposers %v", nodes[0].proposers)
+	}
+}
+
+func TestSimNetwork_Partition(t *testing.T) {
+	sn, nodes, handlers := newSimTestNetwork(4)
+	msg := Message{Code: NewBlockMsg}
+
+	sn.Partition([]string{"node0", "node1"})
+	if len(nodes[0].ConnInfo()) != 1 || len(nodes[2].ConnInfo()) != 1 {
+		t.Fatalf("unexpected connections in the partitions")
+	}
+	nodes[0].Broadcast(msg)
+	nodes[2].Send("node1", msg)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 1, 0, 0)
+	if _, dropped := sn.Stats(); dropped != 1 {
+		t.Fatalf("unexpected dropped count %v", dropped)
+	}
+
+	sn.Heal()
+	nodes[0].Broadcast(msg)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 2, 1, 1)
+
+	// The messages in flight are dropped if the partition is made before they arrive
+	sn.SetLatency(50*time.Millisecond, 0)
+	nodes[0].Broadcast(msg)
+	sn.Partition([]string{"node0"})
+	sn.Wait()
+	checkReceived(t, handlers, 0, 2, 1, 1)
+
+	sn.RemoveNode("node1")
+	nodes[3].Send("node1", msg)
+	sn.Wait()
+	checkReceived(t, handlers, 0, 2, 1, 1)
+}
+
+func TestSimNetwork_LatencyAndDrop(t *testing.T) {
+	sn, nodes, handlers := newSimTestNetwork(2)
+	msg := Message{Code: TxSyncNotify}
+
+	sn.SetLatency(30*time.Millisecond, 10*time.Millisecond)
+	begin := time.Now()
+	nodes[0].Send("node1", msg)
+	sn.Wait()
+	if cost := time.Since(begin); cost < 30*time.Millisecond {
+		t.Fatalf("message delivered without latency in %v", cost)
+	}
+	checkReceived(t, handlers, 0, 1)
+
+	sn.SetLatency(0, 0)
+	sn.SetDropRate(1)
+	for i := 0; i < 10; i++ {
+		nodes[0].Send("node1", msg)
+	}
+	// Messages to self are never lost
+	nodes[0].Send("node0", msg)
+	sn.Wait()
+	checkReceived(t, handlers, 1, 1)
+
+	sn.SetDropRate(0.5)
+	for i := 0; i < 1000; i++ {
+		nodes[0].Send("node1", msg)
+	}
+	sn.Wait()
+	if n := handlers[1].count() - 1; n < 350 || n > 650 {
+		t.Fatalf("unexpected count %v delivered at drop rate 0.5", n)
+	}
+	delivered, dropped := sn.Stats()
+	if delivered+dropped != 1011 {
+		t.Fatalf("unexpected stats %v %v", delivered, dropped)
+	}
+}
@@ -0,0 +1,244 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	configStaticPeers  = "static_peers"
+	configTrustedPeers = "trusted_peers"
+	configNoDiscover   = "nodiscover"
+
+	staticPeerCheckInterval = 10 * time.Second
+)
+
+// StaticPeer is a peer trusted, and kept connected if the address given
+type StaticPeer struct {
+	ID        string
+	IP        string
+	Port      int
+	Connected bool
+}
+
+// staticPeerSet holds the static peers, which are always reconnected, and the trusted peers, which are
+// exempt from the connection limits. The static peers are trusted too
+type staticPeerSet struct {
+	static  map[uint64]*Node  // Net id -> node
+	trusted map[uint64]NodeID // Net id -> node id
+	lock    sync.RWMutex
+}
+
+var staticPeers = newStaticPeerSet()
+
+func newStaticPeerSet() *staticPeerSet {
+	return &staticPeerSet{
+		static:  make(map[uint64]*Node),
+		trusted: make(map[uint64]NodeID),
+	}
+}
+
+// parsePeer parses the peer in the form of id@host:port, or the id only. The node returned is nil if no
+// address given
+func parsePeer(peer string) (NodeID, *Node, error) {
+	peer = strings.TrimSpace(peer)
+	idStr, addr := peer, ""
+	if i := strings.Index(peer, "@"); i >= 0 {
+		idStr, addr = peer[:i], peer[i+1:]
+	}
+	hexID, err := normalizeID(idStr)
+	if err != nil {
+		return NodeID{}, nil, err
+	}
+	id := *NewNodeID(hexID)
+	if addr == "" {
+		return id, nil, nil
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return NodeID{}, nil, fmt.Errorf("invalid peer address %v: %v", addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return NodeID{}, nil, fmt.Errorf("invalid peer port %v", portStr)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return NodeID{}, nil, fmt.Errorf("lookup peer host %v error:%v", host, err)
+		}
+		ip = ips[0]
+	}
+	node := NewNode(id, ip, int(port))
+	if err := node.validateComplete(); err != nil {
+		return NodeID{}, nil, fmt.Errorf("invalid peer %v: %v", peer, err)
+	}
+	return id, node, nil
+}
+
+// add adds the peer as static if the address given, or as trusted only. It returns the node to connect
+func (s *staticPeerSet) add(peer string) (*Node, error) {
+	id, node, err := parsePeer(peer)
+	if err != nil {
+		return nil, err
+	}
+	netID := genNetID(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.trusted[netID] = id
+	if node != nil {
+		s.static[netID] = node
+	}
+	return node, nil
+}
+
+// remove removes the peer from both the static and the trusted peers, returns false if not found
+func (s *staticPeerSet) remove(id NodeID) bool {
+	netID := genNetID(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.trusted[netID]
+	delete(s.trusted, netID)
+	delete(s.static, netID)
+	return ok
+}
+
+func (s *staticPeerSet) isTrusted(netID uint64) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok := s.trusted[netID]
+	return ok
+}
+
+func (s *staticPeerSet) staticNodes() []*Node {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	nodes := make([]*Node, 0, len(s.static))
+	for _, n := range s.static {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func (s *staticPeerSet) list() []*StaticPeer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	peers := make([]*StaticPeer, 0, len(s.trusted))
+	for netID, id := range s.trusted {
+		sp := &StaticPeer{ID: id.GetHexString()}
+		if n := s.static[netID]; n != nil {
+			sp.IP = n.IP.String()
+			sp.Port = n.Port
+		}
+		peers = append(peers, sp)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ID < peers[j].ID
+	})
+	return peers
+}
+
+// connectStaticPeers connects the static peers not connected
+func (nc *NetCore) connectStaticPeers() {
+	for _, n := range staticPeers.staticNodes() {
+		if n.ID == nc.ID {
+			continue
+		}
+		p := nc.peerManager.peerByID(n.ID)
+		if p != nil && p.sessionID > 0 {
+			continue
+		}
+		Logger.Debugf("connect static peer %v ip:%v port:%v", n.ID.GetHexString(), n.IP, n.Port)
+		nc.ping(n.ID, n.addr())
+	}
+}
+
+// initStaticPeers adds the static and trusted peers of the network config
+func initStaticPeers(peers []string, trusted []string) error {
+	for _, peer := range peers {
+		node, err := staticPeers.add(peer)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			return fmt.Errorf("address of static peer %v is required", peer)
+		}
+	}
+	for _, peer := range trusted {
+		if _, err := staticPeers.add(peer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPeer adds the peer in the form of id@host:port as a static peer and connects it. The peer given
+// in the form of the id only is trusted without connecting
+func AddPeer(peer string) error {
+	if netCore == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	node, err := staticPeers.add(peer)
+	if err != nil {
+		return err
+	}
+	if node != nil && node.ID != netCore.ID {
+		netCore.kad.add(node)
+		go netCore.connectStaticPeers()
+	}
+	return nil
+}
+
+// RemovePeer removes the static or trusted peer given in the form of id or id@host:port, and disconnects it
+func RemovePeer(peer string) error {
+	if netCore == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	if i := strings.Index(peer, "@"); i >= 0 {
+		peer = peer[:i]
+	}
+	id, _, err := parsePeer(peer)
+	if err != nil {
+		return err
+	}
+	if !staticPeers.remove(id) {
+		return fmt.Errorf("peer %v not found", peer)
+	}
+	netCore.peerManager.disconnect(id)
+	return nil
+}
+
+// StaticPeers returns the static and trusted peers
+func StaticPeers() []*StaticPeer {
+	peers := staticPeers.list()
+	if netCore == nil {
+		return peers
+	}
+	for _, sp := range peers {
+		if p := netCore.peerManager.peerByID(*NewNodeID(sp.ID)); p != nil {
+			sp.Connected = p.isAvailable()
+		}
+	}
+	return peers
+}
@@ -0,0 +1,170 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/zvchain/zvchain/log"
+)
+
+func TestParsePeer(t *testing.T) {
+	hexID := newTestNodeID()
+	id, node, err := parsePeer(" " + hexID + "@127.0.0.1:1122 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.GetHexString() != hexID || node == nil || node.ID != id || node.Port != 1122 || !node.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("parse peer mismatch")
+	}
+	if _, node, err = parsePeer(hexID); err != nil || node != nil {
+		t.Fatalf("expect the id only parsed without node, error:%v", err)
+	}
+
+	for _, peer := range []string{
+		"zv1234@127.0.0.1:1122",
+		hexID + "@127.0.0.1",
+		hexID + "@127.0.0.1:0",
+		hexID + "@0.0.0.0:1122",
+	} {
+		if _, _, err := parsePeer(peer); err == nil {
+			t.Fatalf("expect %v rejected", peer)
+		}
+	}
+}
+
+func TestStaticPeerSet(t *testing.T) {
+	s := newStaticPeerSet()
+	staticID, trustedID := newTestNodeID(), newTestNodeID()
+	if node, err := s.add(staticID + "@127.0.0.1:1122"); err != nil || node == nil {
+		t.Fatalf("add static peer error:%v", err)
+	}
+	if node, err := s.add(trustedID); err != nil || node != nil {
+		t.Fatalf("add trusted peer error:%v", err)
+	}
+	if !s.isTrusted(genNetID(*NewNodeID(staticID))) || !s.isTrusted(genNetID(*NewNodeID(trustedID))) {
+		t.Fatalf("expect both peers trusted")
+	}
+	if nodes := s.staticNodes(); len(nodes) != 1 || nodes[0].ID.GetHexString() != staticID {
+		t.Fatalf("expect only the peer with address static")
+	}
+	if len(s.list()) != 2 {
+		t.Fatalf("expect 2 peers listed")
+	}
+
+	if !s.remove(*NewNodeID(staticID)) || s.remove(*NewNodeID(staticID)) {
+		t.Fatalf("expect the peer removed once")
+	}
+	if s.isTrusted(genNetID(*NewNodeID(staticID))) || len(s.staticNodes()) != 0 {
+		t.Fatalf("expect the peer removed not trusted")
+	}
+}
+
+func TestTrustedPeerExemptFromIPLimit(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	old := staticPeers
+	defer func() {
+		staticPeers = old
+	}()
+	staticPeers = newStaticPeerSet()
+
+	pm := newPeerManager()
+	pm.peerIPSet.Limit = 1
+	newTestPeer := func() (uint64, *Peer) {
+		id := NewNodeID(newTestNodeID())
+		p := newPeer(*id, 0)
+		p.IP = net.ParseIP("10.0.0.1")
+		return genNetID(*id), p
+	}
+
+	netID, p := newTestPeer()
+	if !pm.addPeer(netID, p) {
+		t.Fatalf("expect the first peer of the ip added")
+	}
+	netID, p = newTestPeer()
+	if pm.addPeer(netID, p) {
+		t.Fatalf("expect the peer exceeding the ip limit rejected")
+	}
+	if _, err := staticPeers.add(p.ID.GetHexString()); err != nil {
+		t.Fatal(err)
+	}
+	if !pm.addPeer(netID, p) {
+		t.Fatalf("expect the trusted peer added")
+	}
+	// The trusted peer is not counted in the ip set
+	pm.onDisconnected(netID, 0, 0)
+	if pm.peerIPSet.Len() != 1 {
+		t.Fatalf("expect the ip count kept, got %v", pm.peerIPSet.Len())
+	}
+}
+
+type recordKadNet struct {
+	pinged    int
+	findNodes int
+	lock      sync.Mutex
+}
+
+func (n *recordKadNet) ping(NodeID, *net.UDPAddr) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.pinged++
+}
+
+func (n *recordKadNet) findNode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.findNodes++
+	return nil, nil
+}
+
+func (n *recordKadNet) close() {}
+
+func TestKadNoDiscover(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	kn := &recordKadNet{}
+	self := NewNodeID(newTestNodeID())
+	seed := NewNode(*NewNodeID(newTestNodeID()), net.ParseIP("127.0.0.1"), 1122)
+	kad, err := newKad(kn, *self, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1123}, []*Node{seed}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kad.Close()
+	<-kad.refresh()
+
+	target := NewNodeID(newTestNodeID())
+	nodes := kad.Lookup(*target)
+	if len(nodes) != 1 || nodes[0].ID != seed.ID {
+		t.Fatalf("expect only the seed known returned, got %v", len(nodes))
+	}
+	if kad.resolve(*target) != nil {
+		t.Fatalf("expect the node unknown not resolved")
+	}
+
+	kn.lock.Lock()
+	defer kn.lock.Unlock()
+	if kn.findNodes != 0 {
+		t.Fatalf("expect no node looked up, got %v", kn.findNodes)
+	}
+	if kn.pinged == 0 {
+		t.Fatalf("expect the seed pinged")
+	}
+}
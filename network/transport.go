@@ -0,0 +1,29 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+// p2pTransport is the session layer under the peer manager, implemented by the p2p core library. It establishes
+// the sessions to the nodes identified by the net ids, and reports the sessions and the data received by the
+// callbacks exported in core_callback.go
+type p2pTransport interface {
+	config(id uint64)
+	proxy(ip string, port uint16)
+	listen(ip string, port uint16)
+	close()
+	connect(id uint64, ip string, port uint16)
+	shutdown(session uint32)
+	send(session uint32, data []byte)
+}
@@ -0,0 +1,51 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+// cgoTransport is the transport of the p2p core library, whose callbacks are exported in core_callback.go
+type cgoTransport struct{}
+
+func newCgoTransport() p2pTransport {
+	return &cgoTransport{}
+}
+
+func (t *cgoTransport) config(id uint64) {
+	P2PConfig(id)
+}
+
+func (t *cgoTransport) proxy(ip string, port uint16) {
+	P2PProxy(ip, port)
+}
+
+func (t *cgoTransport) listen(ip string, port uint16) {
+	P2PListen(ip, port)
+}
+
+func (t *cgoTransport) close() {
+	P2PClose()
+}
+
+func (t *cgoTransport) connect(id uint64, ip string, port uint16) {
+	P2PConnect(id, ip, port)
+}
+
+func (t *cgoTransport) shutdown(session uint32) {
+	P2PShutdown(session)
+}
+
+func (t *cgoTransport) send(session uint32, data []byte) {
+	P2PSend(session, data)
+}
@@ -16,6 +16,8 @@
 package params
 
 import (
+	"fmt"
+
 	"github.com/zvchain/zvchain/common"
 )
 
@@ -41,6 +43,9 @@ type ChainConfig struct {
 
 	// zip006 add addressmanager contract
 	ZIP006 uint64
+
+	// zip007 add multisig accounts
+	ZIP007 uint64
 }
 
 var config = &ChainConfig{
@@ -51,12 +56,38 @@ var config = &ChainConfig{
 	ZIP004: 7583800, // effect at : 2020-06-15 14:00:00
 	ZIP005: 10000000,
 	ZIP006: 9464382,
+	ZIP007: common.MaxUint64, // not scheduled on the main net yet
 }
 
+// InitChainConfig initializes the config of the chain. The features not scheduled on the main net stay
+// disabled unless their activation heights are set explicitly
 func InitChainConfig(chainId uint16) {
 	config.ChainId = chainId
 }
 
+// ScheduleZIP007 sets the activation height of zip007 on the test nets. All the nodes of the net must be
+// configured with the same height
+func ScheduleZIP007(height uint64) error {
+	if config.IsMainNet() {
+		return fmt.Errorf("zip007 is not configurable on the main net")
+	}
+	config.ZIP007 = height
+	return nil
+}
+
+// InitDevChainConfig initializes the config of the single node development chain, on which all the
+// features take effect from the first block
+func InitDevChainConfig(chainId uint16) {
+	config.ChainId = chainId
+	config.ZIP001 = 0
+	config.ZIP002 = 0
+	config.ZIP003 = 0
+	config.ZIP004 = 0
+	config.ZIP005 = 1
+	config.ZIP006 = 1
+	config.ZIP007 = 0
+}
+
 func GetChainConfig() *ChainConfig {
 	return config
 }
@@ -102,3 +133,7 @@ func (cfg *ChainConfig) IsZIP006Checkpoint(preHeight, height uint64) bool {
 	}
 	return false
 }
+
+func (cfg *ChainConfig) IsZIP007(h uint64) bool {
+	return isFork(cfg.ZIP007, h)
+}
@@ -18,6 +18,8 @@ package params
 import (
 	"testing"
 	"time"
+
+	"github.com/zvchain/zvchain/common"
 )
 
 func TestCalculateZIP001Height(t *testing.T) {
@@ -38,3 +40,21 @@ func TestCalculateZIP001Height(t *testing.T) {
 	zip001 := beginHeight + blocksDelta
 	t.Log(zip001)
 }
+
+func TestScheduleZIP007(t *testing.T) {
+	old := *config
+	defer func() { *config = old }()
+
+	InitChainConfig(common.MaxUint16)
+	if config.ZIP007 != common.MaxUint64 {
+		t.Fatalf("expect zip007 disabled on the test net unless scheduled")
+	}
+	if err := ScheduleZIP007(100); err != nil || config.ZIP007 != 100 {
+		t.Fatalf("expect zip007 scheduled on the test net, err:%v", err)
+	}
+
+	InitChainConfig(1)
+	if err := ScheduleZIP007(0); err == nil {
+		t.Fatalf("expect zip007 not configurable on the main net")
+	}
+}
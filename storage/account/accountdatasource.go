@@ -78,6 +78,10 @@ type Trie interface {
 	// starts at the key after the given start key.
 	NodeIterator(startKey []byte) trie.NodeIterator
 
+	// Prove returns the encoded trie nodes on the path to the given key, starting with the root node.
+	// The proof of a missing key contains the nodes proving the absence of the key.
+	Prove(key []byte) ([][]byte, error)
+
 	// Traverse is a debug method to iterate over the entire trie stored in
 	// the disk and check whether every node is reachable from the meta root. The goal
 	// is to find any errors that might cause trie nodes missing during prune
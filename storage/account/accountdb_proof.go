@@ -0,0 +1,71 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"fmt"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/rlp"
+	"github.com/zvchain/zvchain/storage/trie"
+)
+
+// GetProof returns the merkle proof of the given account in the account trie.
+// The proof is built on the committed trie, so the modifications not committed yet are not reflected
+func (adb *AccountDB) GetProof(addr common.Address) ([][]byte, error) {
+	return adb.trie.Prove(addr[:])
+}
+
+// GetStorageProof returns the merkle proofs of the given keys in the storage trie of the account.
+// The proofs are against the storage root recorded in the account, and all proofs are empty
+// if the account not exists
+func (adb *AccountDB) GetStorageProof(addr common.Address, keys [][]byte) ([][][]byte, error) {
+	proofs := make([][][]byte, len(keys))
+	stateObject := adb.getAccountObject(addr)
+	if stateObject == nil {
+		for i := range proofs {
+			proofs[i] = make([][]byte, 0)
+		}
+		return proofs, nil
+	}
+	tr, err := adb.db.OpenStorageTrie(stateObject.addrHash, stateObject.data.Root)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		if proofs[i], err = tr.Prove(key); err != nil {
+			return nil, err
+		}
+	}
+	return proofs, nil
+}
+
+// VerifyAccountProof checks the account proof against the given state root and returns the account.
+// A nil account with nil error means the proof is a valid proof of the absence of the account
+func VerifyAccountProof(root common.Hash, addr common.Address, proof [][]byte) (*Account, error) {
+	enc, err := trie.VerifyProof(root, addr[:], proof)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, nil
+	}
+	var data Account
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		return nil, fmt.Errorf("decode account error:%v", err)
+	}
+	return &data, nil
+}
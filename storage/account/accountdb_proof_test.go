@@ -0,0 +1,91 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/tasdb"
+	"github.com/zvchain/zvchain/storage/trie"
+)
+
+func TestAccountProof(t *testing.T) {
+	db, _ := tasdb.NewMemDatabase()
+	triedb := NewDatabase(db, false)
+	state, _ := NewAccountDB(common.Hash{}, triedb)
+
+	contract := common.BytesToAddress([]byte("contract"))
+	for i := byte(1); i < 100; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.AddBalance(addr, big.NewInt(int64(i)*1000))
+		state.SetNonce(addr, uint64(i))
+	}
+	state.AddBalance(contract, big.NewInt(1))
+	state.SetData(contract, []byte("balance@alice"), []byte("100"))
+	state.SetData(contract, []byte("balance@bob"), []byte("200"))
+	root, _ := state.Commit(true)
+	triedb.TrieDB().Commit(0, root, false)
+
+	state, _ = NewAccountDB(root, triedb)
+	addr := common.BytesToAddress([]byte{50})
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := VerifyAccountProof(root, addr, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc == nil || acc.Balance.Int64() != 50000 || acc.Nonce != 50 {
+		t.Fatalf("unexpected account:%+v", acc)
+	}
+
+	// Absent account
+	missing := common.BytesToAddress([]byte("missing"))
+	proof, _ = state.GetProof(missing)
+	if acc, err = VerifyAccountProof(root, missing, proof); acc != nil || err != nil {
+		t.Fatalf("expect absence proof, got %+v %v", acc, err)
+	}
+
+	proof, _ = state.GetProof(contract)
+	acc, err = VerifyAccountProof(root, contract, proof)
+	if err != nil || acc == nil {
+		t.Fatalf("verify contract error:%v", err)
+	}
+	keys := [][]byte{[]byte("balance@alice"), []byte("balance@bob"), []byte("balance@carol")}
+	values := [][]byte{[]byte("100"), []byte("200"), nil}
+	proofs, err := state.GetStorageProof(contract, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		v, err := trie.VerifyProof(acc.Root, key, proofs[i])
+		if err != nil {
+			t.Fatalf("verify storage %s error:%v", key, err)
+		}
+		if !bytes.Equal(v, values[i]) {
+			t.Fatalf("unexpected value of %s: %s", key, v)
+		}
+	}
+
+	// Proof against another root fails
+	if _, err = VerifyAccountProof(common.BytesToHash([]byte("root")), contract, proof); err == nil {
+		t.Fatal("expect error for wrong root")
+	}
+}
@@ -22,10 +22,12 @@ import (
 	"bytes"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zvchain/zvchain/log"
+	"github.com/zvchain/zvchain/middleware/metrics"
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -176,6 +178,45 @@ func (db *PrefixedDatabase) LogStats(logger *logrus.Logger) {
 	}
 }
 
+var (
+	levelSizeGauge       = metrics.NewGaugeVec("leveldb_level_size_bytes", "Size of each level of the leveldb", "db", "level")
+	levelTablesGauge     = metrics.NewGaugeVec("leveldb_level_tables", "Number of the tables of each level of the leveldb", "db", "level")
+	compactionTimeGauge  = metrics.NewGaugeVec("leveldb_compaction_seconds", "Accumulated compaction time of each level of the leveldb", "db", "level")
+	compactionReadGauge  = metrics.NewGaugeVec("leveldb_compaction_read_bytes", "Accumulated bytes read by the compaction of each level", "db", "level")
+	compactionWriteGauge = metrics.NewGaugeVec("leveldb_compaction_write_bytes", "Accumulated bytes written by the compaction of each level", "db", "level")
+	writeDelayCountGauge = metrics.NewGaugeVec("leveldb_write_delay_count", "Accumulated number of the write delays caused by the compaction", "db")
+	writeDelayTimeGauge  = metrics.NewGaugeVec("leveldb_write_delay_seconds", "Accumulated time of the write delays caused by the compaction", "db")
+	ioBytesGauge         = metrics.NewGaugeVec("leveldb_io_bytes", "Accumulated bytes read and written by the leveldb", "db", "direction")
+)
+
+// PublishStats reads the stats of the underlying leveldb into the metrics labeled by the given name
+func (db *PrefixedDatabase) PublishStats(name string) {
+	stats := &leveldb.DBStats{}
+	if err := db.db.db.Stats(stats); err != nil {
+		return
+	}
+	for i := range stats.LevelSizes {
+		level := strconv.Itoa(i)
+		levelSizeGauge.WithLabelValues(name, level).Set(float64(stats.LevelSizes[i]))
+		if i < len(stats.LevelTablesCounts) {
+			levelTablesGauge.WithLabelValues(name, level).Set(float64(stats.LevelTablesCounts[i]))
+		}
+		if i < len(stats.LevelDurations) {
+			compactionTimeGauge.WithLabelValues(name, level).Set(stats.LevelDurations[i].Seconds())
+		}
+		if i < len(stats.LevelRead) {
+			compactionReadGauge.WithLabelValues(name, level).Set(float64(stats.LevelRead[i]))
+		}
+		if i < len(stats.LevelWrite) {
+			compactionWriteGauge.WithLabelValues(name, level).Set(float64(stats.LevelWrite[i]))
+		}
+	}
+	writeDelayCountGauge.WithLabelValues(name).Set(float64(stats.WriteDelayCount))
+	writeDelayTimeGauge.WithLabelValues(name).Set(stats.WriteDelayDuration.Seconds())
+	ioBytesGauge.WithLabelValues(name, "read").Set(float64(stats.IORead))
+	ioBytesGauge.WithLabelValues(name, "write").Set(float64(stats.IOWrite))
+}
+
 func (db *PrefixedDatabase) addDeleteToBatch(b Batch, k []byte) error {
 	key := generateKey(k, db.prefix)
 	return b.Delete(key)
@@ -0,0 +1,139 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+func newProofTestTrie(t *testing.T) (*Trie, map[string][]byte) {
+	trie, _ := newTrieFromMemDB(common.Hash{})
+	vals := make(map[string][]byte)
+	for i := 0; i < 200; i++ {
+		k := []byte(fmt.Sprintf("key%d", i))
+		v := bytes.Repeat([]byte{byte(i)}, i%40+1)
+		trie.Update(k, v)
+		vals[string(k)] = v
+	}
+	// Short keys embedded in their parents
+	trie.Update([]byte("a"), []byte("b"))
+	vals["a"] = []byte("b")
+	if _, err := trie.Commit(nil); err != nil {
+		t.Fatal(err)
+	}
+	return trie, vals
+}
+
+func TestProof(t *testing.T) {
+	trie, vals := newProofTestTrie(t)
+	root := trie.Hash()
+	for k, v := range vals {
+		proof, err := trie.Prove([]byte(k))
+		if err != nil {
+			t.Fatalf("prove %v error:%v", k, err)
+		}
+		val, err := VerifyProof(root, []byte(k), proof)
+		if err != nil {
+			t.Fatalf("verify %v error:%v", k, err)
+		}
+		if !bytes.Equal(val, v) {
+			t.Fatalf("verified value mismatch for key %v: have %x, want %x", k, val, v)
+		}
+	}
+}
+
+func TestProofReloaded(t *testing.T) {
+	trie, vals := newProofTestTrie(t)
+	root := trie.Hash()
+	// Nodes resolved from the database
+	reloaded, err := NewTrie(root, trie.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range vals {
+		proof, err := reloaded.Prove([]byte(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val, err := VerifyProof(root, []byte(k), proof); err != nil || !bytes.Equal(val, v) {
+			t.Fatalf("verify %v failed: %x %v", k, val, err)
+		}
+	}
+}
+
+func TestMissingKeyProof(t *testing.T) {
+	trie, _ := newProofTestTrie(t)
+	root := trie.Hash()
+	for _, k := range []string{"key", "key1000", "b", "zzz"} {
+		proof, err := trie.Prove([]byte(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof) == 0 {
+			t.Fatalf("proof of absence should contain the root")
+		}
+		val, err := VerifyProof(root, []byte(k), proof)
+		if err != nil {
+			t.Fatalf("verify %v error:%v", k, err)
+		}
+		if val != nil {
+			t.Fatalf("verified value for missing key %v: %x", k, val)
+		}
+	}
+}
+
+func TestBadProof(t *testing.T) {
+	trie, _ := newProofTestTrie(t)
+	root := trie.Hash()
+	key := []byte("key100")
+	proof, err := trie.Prove(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Missing node
+	if _, err := VerifyProof(root, key, proof[1:]); err == nil {
+		t.Fatal("expected error for proof missing the root")
+	}
+	// Modified node
+	bad := make([][]byte, len(proof))
+	copy(bad, proof)
+	last := common.CopyBytes(bad[len(bad)-1])
+	last[len(last)-1] ^= 0xff
+	bad[len(bad)-1] = last
+	if _, err := VerifyProof(root, key, bad); err == nil {
+		t.Fatal("expected error for modified proof node")
+	}
+	// Wrong root
+	if _, err := VerifyProof(common.BytesToHash([]byte("root")), key, proof); err == nil {
+		t.Fatal("expected error for wrong root")
+	}
+}
+
+func TestEmptyTrieProof(t *testing.T) {
+	trie, _ := newTrieFromMemDB(common.Hash{})
+	proof, err := trie.Prove([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, err := VerifyProof(trie.Hash(), []byte("key"), proof); val != nil || err != nil {
+		t.Fatalf("unexpected result: %x %v", val, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/storage/sha3"
+	"github.com/zvchain/zvchain/storage/tasdb"
+)
+
+// makeTestTrie create a sample test trie to test node-wise reconstruction.
+func makeTestTrie() (*NodeDatabase, common.Hash, map[string][]byte) {
+	// Create an empty trie
+	diskdb, _ := tasdb.NewMemDatabase()
+	triedb := NewDatabase(diskdb, 0, "", false)
+	trie, _ := NewTrie(common.Hash{}, triedb)
+
+	// Fill it with some arbitrary data
+	content := make(map[string][]byte)
+	for i := byte(0); i < 255; i++ {
+		// Map the same data under multiple keys
+		key, val := common.BytesToHash([]byte{1, i}).Bytes(), []byte{i}
+		content[string(key)] = val
+		trie.Update(key, val)
+
+		key, val = common.BytesToHash([]byte{2, i}).Bytes(), []byte{i}
+		content[string(key)] = val
+		trie.Update(key, val)
+
+		// Add some other data to inflate the trie
+		for j := byte(3); j < 13; j++ {
+			key, val = common.BytesToHash([]byte{j, i}).Bytes(), []byte{j, i}
+			content[string(key)] = val
+			trie.Update(key, val)
+		}
+	}
+	root, _ := trie.Commit(nil)
+	triedb.Commit(0, root, false)
+
+	return triedb, root, content
+}
+
+// checkTrieContents cross references a reconstructed trie with an expected data
+// content map.
+func checkTrieContents(t *testing.T, db *NodeDatabase, root common.Hash, content map[string][]byte) {
+	trie, err := NewTrie(root, db)
+	if err != nil {
+		t.Fatalf("failed to create trie at %x: %v", root, err)
+	}
+	for key, val := range content {
+		if have := trie.Get([]byte(key)); !bytes.Equal(have, val) {
+			t.Errorf("entry %x: content mismatch: have %x, want %x", key, have, val)
+		}
+	}
+}
+
+func syncTrie(t *testing.T, srcDb *NodeDatabase, sched *Sync, diskdb tasdb.Database, batch int) {
+	queue := append([]common.Hash{}, sched.Missing(batch)...)
+	for len(queue) > 0 {
+		results := make([]SyncResult, len(queue))
+		for i, hash := range queue {
+			data, err := srcDb.Node(hash)
+			if err != nil || data == nil {
+				t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+			}
+			results[i] = SyncResult{hash, data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		if _, err := sched.Commit(diskdb); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		queue = append(queue[:0], sched.Missing(batch)...)
+	}
+}
+
+func TestSync(t *testing.T) {
+	srcDb, srcRoot, content := makeTestTrie()
+
+	diskdb, _ := tasdb.NewMemDatabase()
+	triedb := NewDatabase(diskdb, 0, "", false)
+	sched := NewSync(srcRoot, diskdb, nil)
+	syncTrie(t, srcDb, sched, diskdb, 100)
+
+	if sched.Pending() != 0 {
+		t.Fatalf("pending requests left: %v", sched.Pending())
+	}
+	checkTrieContents(t, triedb, srcRoot, content)
+
+	// Sync again on the completed database schedules nothing
+	if missing := NewSync(srcRoot, diskdb, nil).Missing(0); len(missing) != 0 {
+		t.Fatalf("unexpected missing nodes %v", len(missing))
+	}
+}
+
+func TestSyncSubTrieAndRawEntry(t *testing.T) {
+	srcDb, srcRoot, content := makeTestTrie()
+	code := []byte("contract code")
+	codeHash := common.Hash(sha3.Sum256(code))
+	srcDb.InsertBlob(codeHash, code)
+
+	// Build a main trie whose leaves reference the sub trie and the code
+	mainTrie, _ := NewTrie(common.Hash{}, srcDb)
+	mainTrie.Update([]byte("sub"), append(srcRoot.Bytes(), codeHash.Bytes()...))
+	mainRoot, _ := mainTrie.Commit(nil)
+
+	diskdb, _ := tasdb.NewMemDatabase()
+	triedb := NewDatabase(diskdb, 0, "", false)
+	var sched *Sync
+	sched = NewSync(mainRoot, diskdb, func(leaf []byte, parent common.Hash) error {
+		sched.AddSubTrie(common.BytesToHash(leaf[:32]), 64, parent, nil)
+		sched.AddRawEntry(common.BytesToHash(leaf[32:]), 64, parent)
+		return nil
+	})
+	syncTrie(t, srcDb, sched, diskdb, 10)
+
+	checkTrieContents(t, triedb, srcRoot, content)
+	checkTrieContents(t, triedb, mainRoot, map[string][]byte{"sub": append(srcRoot.Bytes(), codeHash.Bytes()...)})
+	if data, _ := diskdb.Get(codeHash.Bytes()); !bytes.Equal(data, code) {
+		t.Fatalf("code mismatch: have %x, want %x", data, code)
+	}
+}
+
+func TestSyncInvalidData(t *testing.T) {
+	srcDb, srcRoot, _ := makeTestTrie()
+
+	diskdb, _ := tasdb.NewMemDatabase()
+	sched := NewSync(srcRoot, diskdb, nil)
+
+	queue := sched.Missing(1)
+	data, _ := srcDb.Node(queue[0])
+	forged := append([]byte{}, data...)
+	forged[len(forged)-1]++
+	if _, _, err := sched.Process([]SyncResult{{queue[0], forged}}); err != ErrHashMismatch {
+		t.Fatalf("expect hash mismatch, got %v", err)
+	}
+	if _, _, err := sched.Process([]SyncResult{{common.HexToHash("0x12"), data}}); err != ErrNotRequested {
+		t.Fatalf("expect not requested, got %v", err)
+	}
+	if _, _, err := sched.Process([]SyncResult{{queue[0], data}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sched.Process([]SyncResult{{queue[0], data}}); err != ErrNotRequested && err != ErrAlreadyProcessed {
+		t.Fatalf("expect processed error, got %v", err)
+	}
+	if len(sched.Missing(0)) == 0 {
+		t.Fatal("expect children of the root scheduled")
+	}
+}
@@ -41,9 +41,15 @@ func Transfer(toAddress *C.char, value *C.char) bool {
 	to := common.StringToAddress(toAddressStr)
 
 	if !controller.AccountDB.CanTransfer(*contractAddr, transValue) {
+		if controller.Tracer != nil {
+			controller.Tracer.CaptureTransfer(*contractAddr, to, transValue, false, uint64(controller.VM.Gas()))
+		}
 		return false
 	}
 	controller.AccountDB.Transfer(*contractAddr, to, transValue)
+	if controller.Tracer != nil {
+		controller.Tracer.CaptureTransfer(*contractAddr, to, transValue, true, uint64(controller.VM.Gas()))
+	}
 	return true
 
 }
@@ -63,7 +69,11 @@ func GetBalance(addressC *C.char) *C.char {
 func GetData(key *C.char, keyLen C.int, value **C.char, valueLen *C.int) {
 	//hash := common.StringToHash(C.GoString(hashC))
 	address := *controller.VM.ContractAddress
-	state := controller.AccountDB.GetData(address, C.GoBytes(unsafe.Pointer(key), keyLen))
+	k := C.GoBytes(unsafe.Pointer(key), keyLen)
+	state := controller.AccountDB.GetData(address, k)
+	if controller.Tracer != nil {
+		controller.Tracer.CaptureStorage(TraceOpGetData, address, k, state, uint64(controller.VM.Gas()))
+	}
 	if state == nil {
 		*value = nil
 		*valueLen = -1
@@ -79,6 +89,9 @@ func SetData(key *C.char, kenLen C.int, value *C.char, valueLen C.int) {
 	k := C.GoBytes(unsafe.Pointer(key), kenLen)
 	v := C.GoBytes(unsafe.Pointer(value), valueLen)
 	controller.AccountDB.SetData(address, k, v)
+	if controller.Tracer != nil {
+		controller.Tracer.CaptureStorage(TraceOpSetData, address, k, v, uint64(controller.VM.Gas()))
+	}
 }
 
 //export BlockHash
@@ -107,7 +120,14 @@ func TxGasLimit() C.ulonglong {
 
 //export ContractCall
 func ContractCall(addressC *C.char, funName *C.char, jsonParms *C.char, cResult unsafe.Pointer) {
+	tracer := controller.Tracer
+	if tracer != nil {
+		tracer.CaptureEnter(*controller.VM.ContractAddress, common.StringToAddress(C.GoString(addressC)), C.GoString(funName), C.GoString(jsonParms), uint64(controller.VM.Gas()))
+	}
 	goResult := CallContract(C.GoString(addressC), C.GoString(funName), C.GoString(jsonParms))
+	if tracer != nil {
+		tracer.CaptureExit(goResult, uint64(controller.VM.Gas()))
+	}
 	ccResult := (*C.struct__tvm_execute_result_t)(cResult)
 	ccResult.result_type = C.int(goResult.ResultType)
 	ccResult.error_code = C.int(goResult.ErrorCode)
@@ -130,6 +150,9 @@ func EventCall(eventName *C.char, data *C.char, dataLen C.int) {
 	// log.BlockHash = controller.BlockHeader.Hash
 
 	controller.VM.Logs = append(controller.VM.Logs, &log)
+	if controller.Tracer != nil {
+		controller.Tracer.CaptureEvent(&log, uint64(controller.VM.Gas()))
+	}
 }
 
 //export RemoveData
@@ -137,4 +160,7 @@ func RemoveData(key *C.char, kenLen C.int) {
 	address := *controller.VM.ContractAddress
 	k := C.GoBytes(unsafe.Pointer(key), kenLen)
 	controller.AccountDB.RemoveData(address, k)
+	if controller.Tracer != nil {
+		controller.Tracer.CaptureStorage(TraceOpRemoveData, address, k, nil, uint64(controller.VM.Gas()))
+	}
 }
@@ -36,6 +36,7 @@ type Controller struct {
 	VM          *TVM
 	VMStack     []*TVM
 	GasLeft     uint64
+	Tracer      Tracer // Receives the operations of the vm if set, reset for each transaction
 	mm          MinerManager
 }
 
@@ -64,6 +65,7 @@ func NewController(accountDB types.AccountDB,
 	controller.VM = nil
 	controller.VMStack = make([]*TVM, 0)
 	controller.GasLeft = transaction.GetGasLimit() - gasUsed
+	controller.Tracer = nil
 	controller.mm = manager
 	return controller
 }
@@ -0,0 +1,194 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+// Operations recorded by the tracer
+const (
+	TraceOpGetData    = "GET_DATA"
+	TraceOpSetData    = "SET_DATA"
+	TraceOpRemoveData = "REMOVE_DATA"
+	TraceOpTransfer   = "TRANSFER"
+	TraceOpEvent      = "EVENT"
+	TraceOpCall       = "CALL"
+)
+
+// Tracer receives the operations of the vm during the execution of a transaction.
+// The gas passed is the gas left when the operation happens
+type Tracer interface {
+	// CaptureStart is called before the vm executes the transaction
+	CaptureStart(typ string, from, to common.Address, input string, value uint64, gas uint64)
+	// CaptureEnd is called after the vm executed the transaction
+	CaptureEnd(result *ExecuteResult, err *types.TransactionError, gasLeft uint64)
+	// CaptureEnter is called when a contract calls another one
+	CaptureEnter(from, to common.Address, method string, params string, gas uint64)
+	// CaptureExit is called when the called contract returns
+	CaptureExit(result *ExecuteResult, gasLeft uint64)
+	// CaptureStorage is called when the contract accesses its storage, value is nil for removing
+	CaptureStorage(op string, addr common.Address, key, value []byte, gas uint64)
+	// CaptureTransfer is called when the contract transfers out
+	CaptureTransfer(from, to common.Address, value *big.Int, success bool, gas uint64)
+	// CaptureEvent is called when the contract emits an event
+	CaptureEvent(log *types.Log, gas uint64)
+}
+
+// CallFrame is the trace of a contract execution
+type CallFrame struct {
+	Type    string       `json:"type"`
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Input   string       `json:"input,omitempty"`
+	Value   uint64       `json:"value,omitempty"`
+	Gas     uint64       `json:"gas"`
+	GasUsed uint64       `json:"gas_used"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Steps   []*TraceStep `json:"steps"`
+}
+
+// TraceStep is an operation during the contract execution
+type TraceStep struct {
+	Op      string     `json:"op"`
+	Gas     uint64     `json:"gas"`
+	Address string     `json:"address,omitempty"`
+	Key     string     `json:"key,omitempty"`
+	Value   string     `json:"value,omitempty"`
+	To      string     `json:"to,omitempty"`
+	Amount  string     `json:"amount,omitempty"`
+	Failed  bool       `json:"failed,omitempty"`
+	Topic   string     `json:"topic,omitempty"`
+	Call    *CallFrame `json:"call,omitempty"`
+}
+
+// CallTracer records the operations as nested call frames
+type CallTracer struct {
+	root  *CallFrame
+	stack []*CallFrame
+}
+
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+// Result returns the root frame, nil if the vm is not executed
+func (t *CallTracer) Result() *CallFrame {
+	return t.root
+}
+
+func (t *CallTracer) current() *CallFrame {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	return t.stack[len(t.stack)-1]
+}
+
+func (t *CallTracer) addStep(step *TraceStep) {
+	if f := t.current(); f != nil {
+		f.Steps = append(f.Steps, step)
+	}
+}
+
+func endFrame(f *CallFrame, result *ExecuteResult, err *types.TransactionError, gasLeft uint64) {
+	if f.Gas > gasLeft {
+		f.GasUsed = f.Gas - gasLeft
+	}
+	if err == nil && result != nil {
+		err = transactionErrorWith(result)
+	}
+	if err != nil {
+		f.Error = err.Message
+	} else if result != nil {
+		f.Output = result.Content
+	}
+}
+
+func (t *CallTracer) CaptureStart(typ string, from, to common.Address, input string, value uint64, gas uint64) {
+	t.root = &CallFrame{
+		Type:  typ,
+		From:  from.AddrPrefixString(),
+		To:    to.AddrPrefixString(),
+		Input: input,
+		Value: value,
+		Gas:   gas,
+		Steps: make([]*TraceStep, 0),
+	}
+	t.stack = []*CallFrame{t.root}
+}
+
+func (t *CallTracer) CaptureEnd(result *ExecuteResult, err *types.TransactionError, gasLeft uint64) {
+	if t.root == nil {
+		return
+	}
+	endFrame(t.root, result, err, gasLeft)
+	t.stack = nil
+}
+
+func (t *CallTracer) CaptureEnter(from, to common.Address, method string, params string, gas uint64) {
+	f := &CallFrame{
+		Type:  TraceOpCall,
+		From:  from.AddrPrefixString(),
+		To:    to.AddrPrefixString(),
+		Input: fmt.Sprintf(`{"func_name": "%s", "args": %s}`, method, params),
+		Gas:   gas,
+		Steps: make([]*TraceStep, 0),
+	}
+	t.addStep(&TraceStep{Op: TraceOpCall, Gas: gas, Address: f.From, To: f.To, Call: f})
+	t.stack = append(t.stack, f)
+}
+
+func (t *CallTracer) CaptureExit(result *ExecuteResult, gasLeft uint64) {
+	if len(t.stack) <= 1 {
+		return
+	}
+	endFrame(t.current(), result, nil, gasLeft)
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *CallTracer) CaptureStorage(op string, addr common.Address, key, value []byte, gas uint64) {
+	step := &TraceStep{Op: op, Gas: gas, Address: addr.AddrPrefixString(), Key: common.ToHex(key)}
+	if value != nil {
+		step.Value = common.ToHex(value)
+	}
+	t.addStep(step)
+}
+
+func (t *CallTracer) CaptureTransfer(from, to common.Address, value *big.Int, success bool, gas uint64) {
+	t.addStep(&TraceStep{
+		Op:      TraceOpTransfer,
+		Gas:     gas,
+		Address: from.AddrPrefixString(),
+		To:      to.AddrPrefixString(),
+		Amount:  value.String(),
+		Failed:  !success,
+	})
+}
+
+func (t *CallTracer) CaptureEvent(log *types.Log, gas uint64) {
+	t.addStep(&TraceStep{
+		Op:      TraceOpEvent,
+		Gas:     gas,
+		Address: log.Address.AddrPrefixString(),
+		Topic:   log.Topic.Hex(),
+		Value:   common.ToHex(log.Data),
+	})
+}
@@ -0,0 +1,63 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/middleware/types"
+)
+
+func TestCallTracer(t *testing.T) {
+	sender := common.StringToAddress("zv01")
+	c1 := common.StringToAddress("zv02")
+	c2 := common.StringToAddress("zv03")
+
+	tracer := NewCallTracer()
+	tracer.CaptureStart("CALL", sender, c1, `{"func_name": "f", "args": []}`, 10, 1000)
+	tracer.CaptureStorage(TraceOpGetData, c1, []byte("k"), nil, 990)
+	tracer.CaptureEnter(c1, c2, "g", "[1]", 980)
+	tracer.CaptureStorage(TraceOpSetData, c2, []byte("k"), []byte("v"), 970)
+	tracer.CaptureEvent(&types.Log{Address: c2, Data: []byte("e")}, 960)
+	tracer.CaptureExit(&ExecuteResult{ResultType: 4, ErrorCode: types.TVMExecutedError, Content: "failed"}, 950)
+	tracer.CaptureTransfer(c1, sender, big.NewInt(5), false, 940)
+	tracer.CaptureEnd(&ExecuteResult{Content: "ok"}, nil, 900)
+
+	root := tracer.Result()
+	if root.GasUsed != 100 || root.Output != "ok" || root.Error != "" || len(root.Steps) != 3 {
+		t.Fatalf("unexpected root frame %+v", root)
+	}
+	if s := root.Steps[0]; s.Op != TraceOpGetData || s.Value != "" || s.Key != common.ToHex([]byte("k")) {
+		t.Fatalf("unexpected step %+v", s)
+	}
+	call := root.Steps[1].Call
+	if root.Steps[1].Op != TraceOpCall || call == nil {
+		t.Fatalf("expect call step, got %+v", root.Steps[1])
+	}
+	if call.GasUsed != 30 || call.Error != "failed" || len(call.Steps) != 2 || call.Steps[1].Op != TraceOpEvent {
+		t.Fatalf("unexpected nested frame %+v", call)
+	}
+	if s := root.Steps[2]; s.Op != TraceOpTransfer || !s.Failed || s.Amount != "5" {
+		t.Fatalf("unexpected step %+v", s)
+	}
+
+	tracer.CaptureStorage(TraceOpSetData, c1, []byte("k"), []byte("v"), 0)
+	if len(root.Steps) != 3 {
+		t.Fatal("steps after the end should be ignored")
+	}
+}
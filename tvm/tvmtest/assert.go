@@ -0,0 +1,78 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvmtest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/zvchain/zvchain/common"
+)
+
+// AssertSuccess fails the test if the execution failed
+func AssertSuccess(t testing.TB, r *Result) {
+	t.Helper()
+	if r.Failed() {
+		t.Fatalf("execution failed, code=%v, msg=%v", r.Err.Code, r.Err.Message)
+	}
+}
+
+// AssertFailed fails the test if the execution succeeded
+func AssertFailed(t testing.TB, r *Result) {
+	t.Helper()
+	if !r.Failed() {
+		t.Fatalf("execution succeeded unexpectedly, return %v", r.Content)
+	}
+}
+
+// AssertReturn fails the test if the execution failed or returned other than want
+func AssertReturn(t testing.TB, r *Result, want string) {
+	t.Helper()
+	AssertSuccess(t, r)
+	if r.Content != want {
+		t.Fatalf("unexpected return, want %v, got %v", want, r.Content)
+	}
+}
+
+// AssertEvents fails the test if the count of the events emitted with the name isn't n
+func AssertEvents(t testing.TB, r *Result, name string, n int) {
+	t.Helper()
+	if got := len(r.Events(name)); got != n {
+		t.Fatalf("unexpected count of event %v, want %v, got %v", name, n, got)
+	}
+}
+
+// AssertBalance fails the test if the balance of the address isn't want
+func (env *Env) AssertBalance(t testing.TB, addr common.Address, want *big.Int) {
+	t.Helper()
+	if got := env.Balance(addr); got.Cmp(want) != 0 {
+		t.Fatalf("unexpected balance of %v, want %v, got %v", addr.AddrPrefixString(), want, got)
+	}
+}
+
+// AssertStorage fails the test if the value stored with the key doesn't equal to want in json
+func (env *Env) AssertStorage(t testing.TB, addr common.Address, key string, want interface{}) {
+	t.Helper()
+	got, _ := json.Marshal(env.Storage(addr, key))
+	expect, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal %v error: %v", want, err)
+	}
+	if string(got) != string(expect) {
+		t.Fatalf("unexpected storage %v of %v, want %s, got %s", key, addr.AddrPrefixString(), expect, got)
+	}
+}
@@ -0,0 +1,349 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package tvmtest provides an in-memory environment for testing the tvm python contracts.
+// Contracts are deployed and called in the same way as the state processor does, while
+// the block height and time are controlled by the tests.
+package tvmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	time2 "github.com/zvchain/zvchain/middleware/time"
+	"github.com/zvchain/zvchain/middleware/types"
+	"github.com/zvchain/zvchain/storage/account"
+	"github.com/zvchain/zvchain/storage/tasdb"
+	"github.com/zvchain/zvchain/tvm"
+)
+
+// DefaultGasLimit is the gas limit of each deploy or call if not changed
+const DefaultGasLimit = 500000
+
+// Result is the outcome of a deploy or call
+type Result struct {
+	Content string       // Return value of the contract function
+	Logs    []*types.Log // Events emitted, empty if failed
+	GasUsed uint64
+	Err     *types.TransactionError // Not nil if the execution failed, the state changes are reverted then
+}
+
+// Failed returns whether the execution failed
+func (r *Result) Failed() bool {
+	return r.Err != nil
+}
+
+// Events returns the logs emitted with the given event name
+func (r *Result) Events(name string) []*types.Log {
+	topic := EventTopic(name)
+	logs := make([]*types.Log, 0)
+	for _, log := range r.Logs {
+		if log.Topic == topic {
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
+
+// EventTopic returns the log topic of the event name
+func EventTopic(name string) common.Hash {
+	return common.BytesToHash(common.Sha256([]byte(name)))
+}
+
+type snapshot struct {
+	revID  int
+	header types.BlockHeader
+}
+
+// Env is the in-memory chain environment the contracts running on
+type Env struct {
+	GasLimit uint64
+
+	db        *account.AccountDB
+	header    *types.BlockHeader
+	snapshots []snapshot
+}
+
+// NewEnv creates an environment with empty state at height 1
+func NewEnv() (*Env, error) {
+	memDB, err := tasdb.NewMemDatabase()
+	if err != nil {
+		return nil, err
+	}
+	db, err := account.NewAccountDB(common.Hash{}, account.NewDatabase(memDB, false))
+	if err != nil {
+		return nil, err
+	}
+	env := &Env{
+		GasLimit: DefaultGasLimit,
+		db:       db,
+		header: &types.BlockHeader{
+			Height:  1,
+			CurTime: time2.TimeToTimeStamp(time.Now()),
+		},
+	}
+	env.header.Hash = blockHash(env.header.Height)
+	return env, nil
+}
+
+// AccountDB returns the state of the environment
+func (env *Env) AccountDB() *account.AccountDB {
+	return env.db
+}
+
+// Now returns the current block time
+func (env *Env) Now() time2.TimeStamp {
+	return env.header.CurTime
+}
+
+// AdvanceBlocks moves the current block forward by n blocks, 3 seconds each
+func (env *Env) AdvanceBlocks(n uint64) {
+	env.header.Height += n
+	env.header.CurTime = env.header.CurTime.AddSeconds(int64(n) * 3)
+	env.header.Hash = blockHash(env.header.Height)
+}
+
+// AdvanceTime moves the current block time forward without changing the height
+func (env *Env) AdvanceTime(d time.Duration) {
+	env.header.CurTime = env.header.CurTime.AddMilliSeconds(int64(d / time.Millisecond))
+}
+
+// Snapshot returns an identifier of the current state and block, which can be reverted to later
+func (env *Env) Snapshot() int {
+	env.snapshots = append(env.snapshots, snapshot{revID: env.db.Snapshot(), header: *env.header})
+	return len(env.snapshots) - 1
+}
+
+// RevertToSnapshot reverts the state and block to the given snapshot.
+// The snapshots taken after it are invalid then
+func (env *Env) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(env.snapshots) {
+		panic(fmt.Errorf("snapshot %v not exist", id))
+	}
+	s := env.snapshots[id]
+	env.db.RevertToSnapshot(s.revID)
+	*env.header = s.header
+	env.snapshots = env.snapshots[:id]
+}
+
+// SetBalance sets the balance of the address in ra
+func (env *Env) SetBalance(addr common.Address, balance *big.Int) {
+	env.db.SetBalance(addr, balance)
+}
+
+// Balance returns the balance of the address in ra
+func (env *Env) Balance(addr common.Address) *big.Int {
+	return env.db.GetBalance(addr)
+}
+
+// Storage returns the decoded value stored by the contract with the key
+func (env *Env) Storage(addr common.Address, key string) interface{} {
+	return tvm.VmDataConvert(env.db.GetData(addr, []byte(key)))
+}
+
+// StorageWithPrefix returns all the decoded values stored by the contract whose keys have the prefix
+func (env *Env) StorageWithPrefix(addr common.Address, prefix string) map[string]interface{} {
+	result := make(map[string]interface{})
+	iter := env.db.DataIterator(addr, []byte(prefix))
+	if iter == nil {
+		return result
+	}
+	for iter.Next() {
+		k := string(iter.Key)
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		result[k] = tvm.VmDataConvert(iter.Value)
+	}
+	return result
+}
+
+// Deploy deploys the contract code whose class is name, and calls its __init__ function.
+// The contract address is derived from the sender and its nonce as the chain does
+func (env *Env) Deploy(sender common.Address, name string, code string) (common.Address, *Result) {
+	return env.DeployWithValue(sender, name, code, 0)
+}
+
+// DeployWithValue deploys the contract with value in ra transferred from the sender
+func (env *Env) DeployWithValue(sender common.Address, name string, code string, value uint64) (common.Address, *Result) {
+	nonce := env.db.GetNonce(sender) + 1
+	env.db.SetNonce(sender, nonce)
+	contractAddress := common.BytesToAddress(common.Sha256(common.BytesCombine(sender[:], common.Uint64ToByte(nonce))))
+
+	contract := &tvm.Contract{ContractName: name, Code: code}
+	jsonBytes, err := json.Marshal(contract)
+	if err != nil {
+		return contractAddress, &Result{Err: types.NewTransactionError(types.TVMExecutedError, err.Error())}
+	}
+	msg := &message{opType: types.TransactionTypeContractCreate, sender: sender, target: contractAddress, value: value, payload: jsonBytes, nonce: nonce, gasLimit: env.GasLimit}
+
+	snap := env.db.Snapshot()
+	if env.db.GetCodeHash(contractAddress) != (common.Hash{}) {
+		return contractAddress, &Result{Err: types.NewTransactionError(types.TVMExecutedError, "contract address conflict")}
+	}
+	env.db.CreateAccount(contractAddress)
+	env.db.SetCode(contractAddress, jsonBytes)
+	env.db.SetNonce(contractAddress, 1)
+	if ret := env.transfer(sender, contractAddress, value); ret != nil {
+		env.db.RevertToSnapshot(snap)
+		return contractAddress, ret
+	}
+
+	controller := tvm.NewController(env.db, env, env.header, msg, 0, nil)
+	vmResult, logs, txErr := controller.Deploy(tvm.LoadContract(contractAddress))
+	ret := env.result(controller, vmResult, logs, txErr)
+	if ret.Failed() {
+		env.db.RevertToSnapshot(snap)
+	}
+	return contractAddress, ret
+}
+
+// Call calls the public function of the contract with the typed arguments, which are encoded
+// to the json args of the abi. Addresses are passed as the prefixed strings
+func (env *Env) Call(sender common.Address, contract common.Address, method string, args ...interface{}) *Result {
+	return env.CallWithValue(sender, contract, 0, method, args...)
+}
+
+// CallWithValue calls the contract function with value in ra transferred from the sender
+func (env *Env) CallWithValue(sender common.Address, contract common.Address, value uint64, method string, args ...interface{}) *Result {
+	if args == nil {
+		args = []interface{}{}
+	}
+	abi := tvm.ABI{FuncName: method, Args: args}
+	abiJSON, err := json.Marshal(abi)
+	if err != nil {
+		return &Result{Err: types.NewTransactionError(types.TVMCheckABIError, err.Error())}
+	}
+	nonce := env.db.GetNonce(sender) + 1
+	env.db.SetNonce(sender, nonce)
+	msg := &message{opType: types.TransactionTypeContractCall, sender: sender, target: contract, value: value, payload: abiJSON, nonce: nonce, gasLimit: env.GasLimit}
+
+	controller := tvm.NewController(env.db, env, env.header, msg, 0, nil)
+	code := tvm.LoadContract(contract)
+	if code.Code == "" {
+		return &Result{Err: types.NewTransactionError(types.TVMNoCodeError, fmt.Sprintf("no code at the given address %v", contract.AddrPrefixString()))}
+	}
+	snap := env.db.Snapshot()
+	if ret := env.transfer(sender, contract, value); ret != nil {
+		env.db.RevertToSnapshot(snap)
+		return ret
+	}
+
+	vmResult, logs, txErr := controller.ExecuteAbiEval(&sender, code, string(abiJSON))
+	ret := env.result(controller, vmResult, logs, txErr)
+	if ret.Failed() {
+		env.db.RevertToSnapshot(snap)
+	}
+	return ret
+}
+
+func (env *Env) transfer(sender common.Address, target common.Address, value uint64) *Result {
+	if value == 0 {
+		return nil
+	}
+	amount := new(big.Int).SetUint64(value)
+	if !env.db.CanTransfer(sender, amount) {
+		return &Result{Err: types.NewTransactionError(types.TVMExecutedError, fmt.Sprintf("balance not enough ,address is %v", sender.AddrPrefixString()))}
+	}
+	env.db.Transfer(sender, target, amount)
+	return nil
+}
+
+func (env *Env) result(controller *tvm.Controller, vmResult *tvm.ExecuteResult, logs []*types.Log, txErr *types.TransactionError) *Result {
+	ret := &Result{Logs: logs, Err: txErr}
+	if vmResult != nil {
+		ret.Content = vmResult.Content
+	}
+	if ret.Logs == nil {
+		ret.Logs = make([]*types.Log, 0)
+	}
+	if gasLeft := controller.GetGasLeft(); gasLeft < env.GasLimit {
+		ret.GasUsed = env.GasLimit - gasLeft
+	}
+	return ret
+}
+
+func blockHash(height uint64) common.Hash {
+	return common.BytesToHash(common.Sha256(common.Uint64ToByte(height)))
+}
+
+// Env implements types.ChainReader, the headers below the current height are derived from the height
+
+// Height returns the current block height
+func (env *Env) Height() uint64 {
+	return env.header.Height
+}
+
+func (env *Env) QueryTopBlock() *types.BlockHeader {
+	return env.header
+}
+
+func (env *Env) QueryBlockHeaderByHash(hash common.Hash) *types.BlockHeader {
+	for h := env.header.Height; ; h-- {
+		if blockHash(h) == hash {
+			return env.QueryBlockHeaderByHeight(h)
+		}
+		if h == 0 {
+			return nil
+		}
+	}
+}
+
+func (env *Env) QueryBlockHeaderByHeight(height uint64) *types.BlockHeader {
+	if height > env.header.Height {
+		return nil
+	}
+	if height == env.header.Height {
+		return env.header
+	}
+	return &types.BlockHeader{Height: height, Hash: blockHash(height)}
+}
+
+func (env *Env) HasBlock(hash common.Hash) bool {
+	return env.QueryBlockHeaderByHash(hash) != nil
+}
+
+func (env *Env) HasHeight(height uint64) bool {
+	return height <= env.header.Height
+}
+
+// message implements types.TxMessage for the deploys and calls
+type message struct {
+	opType   int8
+	sender   common.Address
+	target   common.Address
+	value    uint64
+	payload  []byte
+	nonce    uint64
+	gasLimit uint64
+}
+
+func (m *message) OpType() int8                  { return m.opType }
+func (m *message) Operator() *common.Address     { return &m.sender }
+func (m *message) OpTarget() *common.Address     { return &m.target }
+func (m *message) Amount() *big.Int              { return new(big.Int).SetUint64(m.value) }
+func (m *message) Payload() []byte               { return m.payload }
+func (m *message) GetExtraData() []byte          { return nil }
+func (m *message) GetGasLimit() uint64           { return m.gasLimit }
+func (m *message) GetValue() uint64              { return m.value }
+func (m *message) GetNonce() uint64              { return m.nonce }
+func (m *message) GetGasLimitOriginal() *big.Int { return new(big.Int).SetUint64(m.gasLimit) }
+func (m *message) GetHash() common.Hash {
+	return common.BytesToHash(common.Sha256(common.BytesCombine(m.sender[:], common.Uint64ToByte(m.nonce))))
+}
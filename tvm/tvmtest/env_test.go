@@ -0,0 +1,102 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvmtest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zvchain/zvchain/common"
+	"github.com/zvchain/zvchain/params"
+)
+
+const counterCode = `
+event = Event("added")
+
+class Counter(object):
+    def __init__(self):
+        self.count = 0
+
+    @register.public(int)
+    def add(self, n):
+        if n <= 0:
+            raise Exception("n must be positive")
+        self.count += n
+        event.emit(n)
+        return self.count
+
+    @register.public()
+    def get(self):
+        return self.count
+`
+
+var testSender = common.StringToAddress("zv6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd")
+
+func newTestEnv(t *testing.T) *Env {
+	params.InitChainConfig(1)
+	env, err := NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestEnv_SnapshotBlock(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetBalance(testSender, big.NewInt(100))
+	now := env.Now()
+
+	snapshot := env.Snapshot()
+	env.AdvanceBlocks(10)
+	env.AdvanceTime(time.Minute)
+	env.SetBalance(testSender, big.NewInt(1))
+	if env.Height() != 11 || env.Now().SinceSeconds(now) != 90 {
+		t.Fatalf("unexpected block, height=%v, time=%v", env.Height(), env.Now())
+	}
+	if env.QueryBlockHeaderByHeight(5).Hash != blockHash(5) || !env.HasBlock(blockHash(5)) || env.HasHeight(12) {
+		t.Fatalf("unexpected chain reader")
+	}
+
+	env.RevertToSnapshot(snapshot)
+	if env.Height() != 1 || env.Now() != now {
+		t.Fatalf("block not reverted, height=%v", env.Height())
+	}
+	env.AssertBalance(t, testSender, big.NewInt(100))
+}
+
+func TestEnv_DeployAndCall(t *testing.T) {
+	env := newTestEnv(t)
+	addr, ret := env.Deploy(testSender, "Counter", counterCode)
+	AssertSuccess(t, ret)
+	env.AssertStorage(t, addr, "count", 0)
+
+	ret = env.Call(testSender, addr, "add", 3)
+	AssertReturn(t, ret, "3")
+	AssertEvents(t, ret, "added", 1)
+
+	snapshot := env.Snapshot()
+	AssertReturn(t, env.Call(testSender, addr, "add", 2), "5")
+	env.RevertToSnapshot(snapshot)
+	AssertReturn(t, env.Call(testSender, addr, "get"), "3")
+
+	ret = env.Call(testSender, addr, "add", 0)
+	AssertFailed(t, ret)
+	if len(ret.Logs) != 0 {
+		t.Fatalf("logs of the failed call returned")
+	}
+	env.AssertStorage(t, addr, "count", 3)
+}